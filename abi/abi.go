@@ -114,6 +114,9 @@ func (t Type) String() string {
 	case FaultSet:
 		return "types.FaultSet"
 	default:
+		if ct, ok := lookupCBORType(t); ok {
+			return ct.name
+		}
 		return "<unknown type>"
 	}
 }
@@ -171,6 +174,9 @@ func (av *Value) String() string {
 	case FaultSet:
 		return av.Val.(types.FaultSet).String()
 	default:
+		if _, ok := lookupCBORType(av.Type); ok {
+			return stringCBOR(av.Val)
+		}
 		return "<unknown type>"
 	}
 }
@@ -333,6 +339,9 @@ func (av *Value) Serialize() ([]byte, error) {
 		}
 		return cbor.DumpObject(fs)
 	default:
+		if ct, ok := lookupCBORType(av.Type); ok {
+			return serializeCBOR(ct, av.Val)
+		}
 		return nil, fmt.Errorf("unrecognized Type: %d", av.Type)
 	}
 }
@@ -390,7 +399,11 @@ func ToValues(i []interface{}) ([]*Value, error) {
 		case types.FaultSet:
 			out = append(out, &Value{Type: FaultSet, Val: v})
 		default:
-			return nil, fmt.Errorf("unsupported type: %T", v)
+			t, ok := cborTypeOf(reflect.TypeOf(v))
+			if !ok {
+				return nil, fmt.Errorf("unsupported type: %T", v)
+			}
+			out = append(out, &Value{Type: t, Val: v})
 		}
 	}
 	return out, nil
@@ -563,7 +576,18 @@ func Deserialize(data []byte, t Type) (*Value, error) {
 	case Invalid:
 		return nil, ErrInvalidType
 	default:
-		return nil, fmt.Errorf("unrecognized Type: %d", t)
+		ct, ok := lookupCBORType(t)
+		if !ok {
+			return nil, fmt.Errorf("unrecognized Type: %d", t)
+		}
+		val, err := deserializeCBOR(ct, data)
+		if err != nil {
+			return nil, err
+		}
+		return &Value{
+			Type: t,
+			Val:  val,
+		}, nil
 	}
 }
 
@@ -595,7 +619,8 @@ var typeTable = map[Type]reflect.Type{
 func TypeMatches(t Type, val reflect.Type) bool {
 	rt, ok := typeTable[t]
 	if !ok {
-		return false
+		ct, ok := lookupCBORType(t)
+		return ok && ct.goType == val
 	}
 	return rt == val
 }
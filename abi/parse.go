@@ -0,0 +1,161 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// ParseValues converts the string representation of each argument, as given
+// on the command line, to a value of the matching type.
+func ParseValues(args []string, ts []Type) ([]*Value, error) {
+	if len(args) != len(ts) {
+		return nil, fmt.Errorf("expected %d parameters, got %d", len(ts), len(args))
+	}
+
+	out := make([]*Value, 0, len(args))
+	for i, arg := range args {
+		v, err := ParseValue(arg, ts[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %d: %s", i, err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// ParseValue converts a string to a value of type t. Numbers and addresses
+// are given in their usual string form, byte values in hex and composite
+// values, including types registered with RegisterCBORType, as JSON.
+func ParseValue(s string, t Type) (*Value, error) {
+	var val interface{}
+	var err error
+	switch t {
+	case Address:
+		val, err = address.NewFromString(s)
+	case AttoFIL:
+		v, ok := types.NewAttoFILFromFILString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid FIL amount %q", s)
+		}
+		val = v
+	case BytesAmount:
+		v, ok := types.NewBytesAmountFromString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid bytes amount %q", s)
+		}
+		val = v
+	case ChannelID:
+		v, ok := types.NewChannelIDFromString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid channel id %q", s)
+		}
+		val = v
+	case BlockHeight:
+		v, ok := types.NewBlockHeightFromString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid block height %q", s)
+		}
+		val = v
+	case Integer:
+		v, ok := big.NewInt(0).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		val = v
+	case Bytes:
+		val, err = parseHex(s)
+	case String:
+		val = s
+	case PeerID:
+		val, err = peer.IDB58Decode(s)
+	case SectorID:
+		val, err = strconv.ParseUint(s, 10, 64)
+	case Boolean:
+		val, err = strconv.ParseBool(s)
+	case ProofsMode:
+		var v int
+		v, err = strconv.Atoi(s)
+		val = types.ProofsMode(v)
+	case PoRepProof:
+		var b []byte
+		b, err = parseHex(s)
+		val = types.PoRepProof(b)
+	case PoStProof:
+		var b []byte
+		b, err = parseHex(s)
+		val = types.PoStProof(b)
+	case IntSet:
+		var ints []uint64
+		ints, err = parseUints(s)
+		val = types.NewIntSet(ints...)
+	case FaultSet:
+		var ints []uint64
+		ints, err = parseUints(s)
+		val = types.NewFaultSet(ints)
+	case UintArray:
+		val, err = parseUints(s)
+	case CommitmentsMap, Predicate, Parameters, MinerPoStStates:
+		val, err = parseJSON(s, typeTable[t])
+	case Invalid:
+		return nil, ErrInvalidType
+	default:
+		ct, ok := lookupCBORType(t)
+		if !ok {
+			return nil, fmt.Errorf("unrecognized Type: %d", t)
+		}
+		val, err = parseJSON(s, ct.goType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Value{Type: t, Val: val}, nil
+}
+
+func parseHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// parseUints parses a JSON array or a comma separated list of integers.
+func parseUints(s string) ([]uint64, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		var ints []uint64
+		if err := json.Unmarshal([]byte(s), &ints); err != nil {
+			return nil, err
+		}
+		return ints, nil
+	}
+
+	ints := []uint64{}
+	if s == "" {
+		return ints, nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+// parseJSON decodes s into a new value of type rt.
+func parseJSON(s string, rt reflect.Type) (interface{}, error) {
+	ptr := reflect.New(rt)
+	if err := json.Unmarshal([]byte(s), ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
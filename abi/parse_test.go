@@ -0,0 +1,60 @@
+package abi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestParseValues(t *testing.T) {
+	tf.UnitTest(t)
+
+	addr := address.NewForTestGetter()()
+
+	t.Run("parses each type", func(t *testing.T) {
+		vals, err := ParseValues(
+			[]string{addr.String(), "2", "1024", "17", "0xbeef", "hello", "true", "3,4", "[5]", `{"1":{}}`},
+			[]Type{Address, AttoFIL, BytesAmount, Integer, Bytes, String, Boolean, IntSet, UintArray, CommitmentsMap},
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, addr, vals[0].Val)
+		assert.Equal(t, types.NewAttoFILFromFIL(2), vals[1].Val)
+		assert.Equal(t, types.NewBytesAmount(1024), vals[2].Val)
+		assert.Equal(t, big.NewInt(17), vals[3].Val)
+		assert.Equal(t, []byte{0xbe, 0xef}, vals[4].Val)
+		assert.Equal(t, "hello", vals[5].Val)
+		assert.Equal(t, true, vals[6].Val)
+		assert.Equal(t, []uint64{3, 4}, vals[7].Val.(types.IntSet).Values())
+		assert.Equal(t, []uint64{5}, vals[8].Val)
+		assert.Contains(t, vals[9].Val.(map[string]types.Commitments), "1")
+	})
+
+	t.Run("parsed values encode", func(t *testing.T) {
+		vals, err := ParseValues([]string{addr.String(), "12"}, []Type{Address, SectorID})
+		require.NoError(t, err)
+
+		data, err := EncodeValues(vals)
+		require.NoError(t, err)
+
+		decoded, err := DecodeValues(data, []Type{Address, SectorID})
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{addr, uint64(12)}, FromValues(decoded))
+	})
+
+	t.Run("rejects the wrong number of parameters", func(t *testing.T) {
+		_, err := ParseValues([]string{"1"}, []Type{SectorID, SectorID})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects a malformed parameter", func(t *testing.T) {
+		_, err := ParseValues([]string{"abc"}, []Type{SectorID})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,229 @@
+package abi
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"sync"
+
+	cbor "github.com/ipfs/go-ipld-cbor"
+)
+
+// cborTypeFlag marks the types registered with RegisterCBORType. The rest of
+// such a type is a hash of its name, so the type is the same in every build
+// registering it.
+const cborTypeFlag = Type(1 << 32)
+
+// SchemaKind is the shape of a CBOR encoded type.
+type SchemaKind string
+
+const (
+	// StructKind is a record with named fields.
+	StructKind = SchemaKind("struct")
+	// ArrayKind is a list of values of one type.
+	ArrayKind = SchemaKind("array")
+	// MapKind is a map from keys of one type to values of one type.
+	MapKind = SchemaKind("map")
+)
+
+// SchemaField is a field of a struct.
+type SchemaField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Schema describes a type registered with RegisterCBORType.
+type Schema struct {
+	Name string     `json:"name"`
+	Kind SchemaKind `json:"kind"`
+	// Fields are the fields of a struct.
+	Fields []SchemaField `json:"fields,omitempty"`
+	// Key is the key type of a map.
+	Key string `json:"key,omitempty"`
+	// Elem is the element type of an array or map.
+	Elem string `json:"elem,omitempty"`
+}
+
+type cborType struct {
+	name   string
+	goType reflect.Type
+}
+
+var cborTypes = struct {
+	lk       sync.RWMutex
+	byType   map[Type]*cborType
+	byGoType map[reflect.Type]Type
+}{
+	byType:   make(map[Type]*cborType),
+	byGoType: make(map[reflect.Type]Type),
+}
+
+// RegisterCBORType registers the go type of example as an ABI type encoded as
+// CBOR, so actor methods can take and return it. Structs must also be
+// registered with cbor.RegisterCborType. The name identifies the type in
+// signatures and should be qualified by its package, e.g. "miner.Ask".
+// Registering a name twice for different go types panics.
+func RegisterCBORType(name string, example interface{}) Type {
+	goType := reflect.TypeOf(example)
+	switch kindOf(goType) {
+	case StructKind, ArrayKind, MapKind:
+	default:
+		panic(fmt.Sprintf("abi: %s must be a struct, array or map, got %s", name, goType))
+	}
+
+	for _, rt := range typeTable {
+		if rt == goType {
+			panic(fmt.Sprintf("abi: %s is a builtin type", goType))
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name)) // nolint: errcheck
+	t := cborTypeFlag | Type(h.Sum32())
+
+	cborTypes.lk.Lock()
+	defer cborTypes.lk.Unlock()
+
+	if existing, ok := cborTypes.byType[t]; ok {
+		if existing.name != name || existing.goType != goType {
+			panic(fmt.Sprintf("abi: cannot register %s as %s, type is already registered as %s", goType, name, existing.name))
+		}
+		return t
+	}
+	if _, ok := cborTypes.byGoType[goType]; ok {
+		panic(fmt.Sprintf("abi: %s is already registered", goType))
+	}
+
+	cborTypes.byType[t] = &cborType{name: name, goType: goType}
+	cborTypes.byGoType[goType] = t
+	return t
+}
+
+// SchemaOf returns the schema of a type registered with RegisterCBORType.
+func SchemaOf(t Type) (*Schema, bool) {
+	ct, ok := lookupCBORType(t)
+	if !ok {
+		return nil, false
+	}
+
+	goType := ct.goType
+	if goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+
+	s := &Schema{Name: ct.name, Kind: kindOf(goType)}
+	switch s.Kind {
+	case StructKind:
+		for i := 0; i < goType.NumField(); i++ {
+			f := goType.Field(i)
+			if f.PkgPath != "" {
+				continue // unexported
+			}
+			s.Fields = append(s.Fields, SchemaField{Name: f.Name, Type: typeName(f.Type)})
+		}
+	case ArrayKind:
+		s.Elem = typeName(goType.Elem())
+	case MapKind:
+		s.Key = typeName(goType.Key())
+		s.Elem = typeName(goType.Elem())
+	}
+	return s, true
+}
+
+// Schemas returns the schemas of all types registered with
+// RegisterCBORType, ordered by name.
+func Schemas() []*Schema {
+	cborTypes.lk.RLock()
+	ts := make([]Type, 0, len(cborTypes.byType))
+	for t := range cborTypes.byType {
+		ts = append(ts, t)
+	}
+	cborTypes.lk.RUnlock()
+
+	out := make([]*Schema, 0, len(ts))
+	for _, t := range ts {
+		s, _ := SchemaOf(t)
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func lookupCBORType(t Type) (*cborType, bool) {
+	if t&cborTypeFlag == 0 {
+		return nil, false
+	}
+	cborTypes.lk.RLock()
+	defer cborTypes.lk.RUnlock()
+	ct, ok := cborTypes.byType[t]
+	return ct, ok
+}
+
+func cborTypeOf(goType reflect.Type) (Type, bool) {
+	cborTypes.lk.RLock()
+	defer cborTypes.lk.RUnlock()
+	t, ok := cborTypes.byGoType[goType]
+	return t, ok
+}
+
+func kindOf(goType reflect.Type) SchemaKind {
+	if goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	switch goType.Kind() {
+	case reflect.Struct:
+		return StructKind
+	case reflect.Slice, reflect.Array:
+		return ArrayKind
+	case reflect.Map:
+		return MapKind
+	default:
+		return ""
+	}
+}
+
+// typeName names a go type by its ABI type if it has one.
+func typeName(goType reflect.Type) string {
+	if t, ok := cborTypeOf(goType); ok {
+		return t.String()
+	}
+	for t, rt := range typeTable {
+		if rt == goType {
+			return t.String()
+		}
+	}
+	return goType.String()
+}
+
+func serializeCBOR(ct *cborType, val interface{}) ([]byte, error) {
+	if reflect.TypeOf(val) != ct.goType {
+		return nil, fmt.Errorf("expected type %s, got %T", ct.goType, val)
+	}
+	return cbor.DumpObject(val)
+}
+
+func deserializeCBOR(ct *cborType, data []byte) (interface{}, error) {
+	if ct.goType.Kind() == reflect.Ptr {
+		ptr := reflect.New(ct.goType.Elem())
+		if err := cbor.DecodeInto(data, ptr.Interface()); err != nil {
+			return nil, err
+		}
+		return ptr.Interface(), nil
+	}
+
+	ptr := reflect.New(ct.goType)
+	if err := cbor.DecodeInto(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+func stringCBOR(val interface{}) string {
+	out, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(out)
+}
@@ -0,0 +1,99 @@
+package abi
+
+import (
+	"reflect"
+	"testing"
+
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+type testRecord struct {
+	Owner address.Address
+	Count uint64
+	Tags  []string
+}
+
+type testRecords []testRecord
+
+var (
+	testRecordType  = RegisterCBORType("abi.testRecord", &testRecord{})
+	testRecordsType = RegisterCBORType("abi.testRecords", testRecords{})
+)
+
+func init() {
+	cbor.RegisterCborType(testRecord{})
+}
+
+func TestCBORTypeRoundTrip(t *testing.T) {
+	tf.UnitTest(t)
+
+	addr := address.NewForTestGetter()()
+	rec := &testRecord{Owner: addr, Count: 3, Tags: []string{"a", "b"}}
+	recs := testRecords{*rec, {Owner: addr, Count: 4}}
+
+	vals, err := ToValues([]interface{}{rec, recs})
+	require.NoError(t, err)
+	assert.Equal(t, testRecordType, vals[0].Type)
+	assert.Equal(t, testRecordsType, vals[1].Type)
+
+	data, err := EncodeValues(vals)
+	require.NoError(t, err)
+
+	decoded, err := DecodeValues(data, []Type{testRecordType, testRecordsType})
+	require.NoError(t, err)
+	assert.Equal(t, rec, decoded[0].Val)
+	assert.Equal(t, recs, decoded[1].Val)
+
+	assert.True(t, TypeMatches(testRecordType, reflect.TypeOf(rec)))
+	assert.False(t, TypeMatches(testRecordType, reflect.TypeOf(*rec)))
+}
+
+func TestCBORTypeSchema(t *testing.T) {
+	tf.UnitTest(t)
+
+	assert.Equal(t, "abi.testRecord", testRecordType.String())
+
+	s, ok := SchemaOf(testRecordType)
+	require.True(t, ok)
+	assert.Equal(t, StructKind, s.Kind)
+	assert.Equal(t, []SchemaField{
+		{Name: "Owner", Type: "address.Address"},
+		{Name: "Count", Type: "uint64"},
+		{Name: "Tags", Type: "[]string"},
+	}, s.Fields)
+
+	s, ok = SchemaOf(testRecordsType)
+	require.True(t, ok)
+	assert.Equal(t, ArrayKind, s.Kind)
+	assert.Equal(t, "abi.testRecord", s.Elem)
+
+	_, ok = SchemaOf(Address)
+	assert.False(t, ok)
+
+	assert.Contains(t, Schemas(), s)
+}
+
+func TestCBORTypeParse(t *testing.T) {
+	tf.UnitTest(t)
+
+	addr := address.NewForTestGetter()()
+	v, err := ParseValue(`{"Owner":"`+addr.String()+`","Count":7}`, testRecordType)
+	require.NoError(t, err)
+	assert.Equal(t, &testRecord{Owner: addr, Count: 7}, v.Val)
+	assert.Equal(t, `{"Owner":"`+addr.String()+`","Count":7,"Tags":null}`, v.String())
+}
+
+func TestRegisterCBORTypeConflicts(t *testing.T) {
+	tf.UnitTest(t)
+
+	assert.Equal(t, testRecordType, RegisterCBORType("abi.testRecord", &testRecord{}))
+	assert.Panics(t, func() { RegisterCBORType("abi.testRecord", testRecord{}) })
+	assert.Panics(t, func() { RegisterCBORType("abi.other", &testRecord{}) })
+	assert.Panics(t, func() { RegisterCBORType("abi.uints", []uint64{}) })
+	assert.Panics(t, func() { RegisterCBORType("abi.number", uint64(0)) })
+}
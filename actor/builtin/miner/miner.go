@@ -74,6 +74,11 @@ const (
 	// ErrInvalidPieceInclusionProof indicates that the piece inclusion proof was
 	// malformed or did not succesfully verify.
 	ErrInvalidPieceInclusionProof = 46
+	// ErrInsufficientBalance indicates that a withdrawal exceeds the balance
+	// not required as collateral.
+	ErrInsufficientBalance = 47
+	// ErrMinerTerminated indicates that the miner has been terminated by its owner.
+	ErrMinerTerminated = 48
 )
 
 // Errors map error codes to revert errors this actor may return.
@@ -89,6 +94,8 @@ var Errors = map[uint8]error{
 	ErrGetProofsModeFailed:        errors.NewCodedRevertErrorf(ErrGetProofsModeFailed, "failed to get proofs mode"),
 	ErrInsufficientCollateral:     errors.NewCodedRevertErrorf(ErrInsufficientCollateral, "insufficient collateral"),
 	ErrInvalidPieceInclusionProof: errors.NewCodedRevertErrorf(ErrInvalidPieceInclusionProof, "piece inclusion proof did not validate"),
+	ErrInsufficientBalance:        errors.NewCodedRevertErrorf(ErrInsufficientBalance, "not enough balance above the collateral requirement"),
+	ErrMinerTerminated:            errors.NewCodedRevertErrorf(ErrMinerTerminated, "miner is terminated"),
 }
 
 const (
@@ -119,6 +126,9 @@ type Ask struct {
 	ID     *big.Int
 }
 
+// AskType is the ABI type of an *Ask, returned by getAsk.
+var AskType = abi.RegisterCBORType("miner.Ask", &Ask{})
+
 // State is the miner actors storage.
 type State struct {
 	// Owner is the address of the account that owns this miner. Income and returned
@@ -191,6 +201,15 @@ type State struct {
 	// OwedStorageCollateral is the collateral for sectors that have been slashed.
 	// This collateral can be collected from arbitrated deals, but not de-pledged.
 	OwedStorageCollateral types.AttoFIL
+
+	// ProposedOwner is the address the owner proposed to hand the miner over
+	// to, undefined if no change is proposed. The change takes effect when the
+	// proposed owner accepts it.
+	ProposedOwner address.Address
+
+	// TerminatedAt is the block height at which the owner terminated the
+	// miner, nil while the miner is active.
+	TerminatedAt *types.BlockHeight
 }
 
 // NewActor returns a new miner actor with the provided balance.
@@ -285,6 +304,22 @@ var minerExports = exec.Exports{
 		Params: []abi.Type{abi.Address},
 		Return: []abi.Type{},
 	},
+	"changeOwner": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address},
+		Return: []abi.Type{},
+	},
+	"acceptOwner": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{},
+	},
+	"terminate": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{},
+	},
+	"refundCollateral": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{},
+	},
 	// verifyPieceInclusion is not in spec, but should be.
 	"verifyPieceInclusion": &exec.FunctionSignature{
 		Params: []abi.Type{abi.Bytes, abi.BytesAmount, abi.SectorID, abi.Bytes},
@@ -294,6 +329,14 @@ var minerExports = exec.Exports{
 		Params: nil,
 		Return: []abi.Type{abi.BytesAmount},
 	},
+	"pledge": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{},
+	},
+	"withdraw": &exec.FunctionSignature{
+		Params: []abi.Type{abi.AttoFIL},
+		Return: []abi.Type{},
+	},
 
 	// Non-exported methods below here.
 	// These methods are not part of the actor's protocol specification and should not be exported,
@@ -306,7 +349,7 @@ var minerExports = exec.Exports{
 	},
 	"getAsk": &exec.FunctionSignature{
 		Params: []abi.Type{abi.Integer},
-		Return: []abi.Type{abi.Bytes},
+		Return: []abi.Type{AskType},
 	},
 	"getLastUsedSectorID": &exec.FunctionSignature{
 		Params: nil,
@@ -336,6 +379,22 @@ var minerExports = exec.Exports{
 		Params: []abi.Type{},
 		Return: []abi.Type{abi.AttoFIL},
 	},
+	"getPledgeCollateralRequirement": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{abi.AttoFIL},
+	},
+	"getOwedStorageCollateral": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{abi.AttoFIL},
+	},
+	"getProposedOwner": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{abi.Address},
+	},
+	"getTerminatedAt": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{abi.BlockHeight},
+	},
 }
 
 // Exports returns the miner actors exported functions.
@@ -360,6 +419,10 @@ func (ma *Actor) AddAsk(ctx exec.VMContext, price types.AttoFIL, expiry *big.Int
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
+		if state.TerminatedAt != nil {
+			return nil, Errors[ErrMinerTerminated]
+		}
+
 		id := big.NewInt(0).Set(state.NextAskID)
 		state.NextAskID = state.NextAskID.Add(state.NextAskID, big.NewInt(1))
 
@@ -428,7 +491,7 @@ func (ma *Actor) GetAsks(ctx exec.VMContext) ([]uint64, uint8, error) {
 }
 
 // GetAsk returns an ask by ID
-func (ma *Actor) GetAsk(ctx exec.VMContext, askid *big.Int) ([]byte, uint8, error) {
+func (ma *Actor) GetAsk(ctx exec.VMContext, askid *big.Int) (*Ask, uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
@@ -447,20 +510,15 @@ func (ma *Actor) GetAsk(ctx exec.VMContext, askid *big.Int) ([]byte, uint8, erro
 			return nil, Errors[ErrAskNotFound]
 		}
 
-		out, err := cbor.DumpObject(ask)
-		if err != nil {
-			return nil, err
-		}
-
-		return out, nil
+		return ask, nil
 	})
 	if err != nil {
 		return nil, errors.CodeError(err), err
 	}
 
-	ask, ok := out.([]byte)
+	ask, ok := out.(*Ask)
 	if !ok {
-		return nil, 1, errors.NewRevertErrorf("expected a Bytes return value from call, but got %T instead", out)
+		return nil, 1, errors.NewRevertErrorf("expected an *Ask return value from call, but got %T instead", out)
 	}
 
 	return ask, 0, nil
@@ -632,6 +690,10 @@ func (ma *Actor) CommitSector(ctx exec.VMContext, sectorID uint64, commD, commR,
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
+		if state.TerminatedAt != nil {
+			return nil, Errors[ErrMinerTerminated]
+		}
+
 		if state.SectorCommitments.Has(sectorID) {
 			return nil, Errors[ErrSectorIDInUse]
 		}
@@ -741,6 +803,10 @@ func (ma *Actor) ChangeWorker(ctx exec.VMContext, worker address.Address) (uint8
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
+		if state.TerminatedAt != nil {
+			return nil, Errors[ErrMinerTerminated]
+		}
+
 		state.Worker = worker
 
 		return nil, nil
@@ -752,6 +818,73 @@ func (ma *Actor) ChangeWorker(ctx exec.VMContext, worker address.Address) (uint8
 	return 0, nil
 }
 
+// ChangeOwner proposes to hand the miner over to owner. The current owner
+// stays in charge until the proposed owner accepts with acceptOwner, which
+// guards against handing the miner to an address nobody controls. A new
+// proposal replaces the previous one.
+func (ma *Actor) ChangeOwner(ctx exec.VMContext, owner address.Address) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		state.ProposedOwner = owner
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// AcceptOwner makes the sender, which must be the proposed owner, the owner of
+// the miner.
+func (ma *Actor) AcceptOwner(ctx exec.VMContext) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if state.ProposedOwner.Empty() || ctx.Message().From != state.ProposedOwner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		state.Owner = state.ProposedOwner
+		state.ProposedOwner = address.Undef
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// GetProposedOwner returns the address proposed to become the owner of this
+// miner, undefined if there is none.
+func (ma *Actor) GetProposedOwner(ctx exec.VMContext) (address.Address, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return address.Undef, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	err := actor.ReadState(ctx, &state)
+	if err != nil {
+		return address.Undef, errors.CodeError(err), err
+	}
+
+	return state.ProposedOwner, 0, nil
+}
+
 // GetWorker returns the worker address for this miner.
 func (ma *Actor) GetWorker(ctx exec.VMContext) (address.Address, uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
@@ -803,6 +936,10 @@ func (ma *Actor) UpdatePeerID(ctx exec.VMContext, pid peer.ID) (uint8, error) {
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
+		if storage.TerminatedAt != nil {
+			return nil, Errors[ErrMinerTerminated]
+		}
+
 		storage.PeerID = pid
 
 		return nil, nil
@@ -858,6 +995,217 @@ func (ma *Actor) GetActiveCollateral(ctx exec.VMContext) (types.AttoFIL, uint8,
 	return collateral, 0, nil
 }
 
+// Pledge adds the value of the message to the miner's balance, making it
+// available as collateral. Only the owner may pledge funds.
+func (ma *Actor) Pledge(ctx exec.VMContext) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+		// the value of the message has already been transferred to the actor
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// Withdraw sends amount from the miner's balance to its owner. Only the owner
+// may withdraw, and only the balance above the pledge collateral requirement
+// and the collateral owed for slashed storage.
+func (ma *Actor) Withdraw(ctx exec.VMContext, amount types.AttoFIL) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		if amount.IsNegative() {
+			return nil, errors.NewRevertError("cannot withdraw a negative amount")
+		}
+
+		available := ma.availableBalance(state, ctx.MyBalance(), ctx.BlockHeight())
+		if amount.GreaterThan(available) {
+			return nil, Errors[ErrInsufficientBalance]
+		}
+
+		_, ret, err := ctx.Send(state.Owner, "", amount, []interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		if ret != 0 {
+			return nil, errors.NewRevertErrorf("failed to send withdrawal to owner, exit code %d", ret)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// Terminate shuts the miner down. It stops accepting commitments and asks,
+// but must still submit the PoSt of its current proving period, and can be
+// slashed for it like any other miner. That final PoSt moves its sectors to
+// the done set and removes its power from the storage market. A miner that
+// is not proving any sectors is retired at once. Terminating is rejected
+// while a PoSt is overdue. Once the miner is retired and the penalty window
+// has passed, the owner collects the collateral with refundCollateral.
+func (ma *Actor) Terminate(ctx exec.VMContext) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chainHeight := ctx.BlockHeight()
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		if state.TerminatedAt != nil {
+			return nil, Errors[ErrMinerTerminated]
+		}
+
+		if state.ProvingSet.Size() > 0 && chainHeight.GreaterThan(state.ProvingPeriodEnd) {
+			return nil, errors.NewRevertErrorf("PoSt for the proving period ending %s is overdue", state.ProvingPeriodEnd)
+		}
+
+		state.Asks = nil
+		state.TerminatedAt = chainHeight
+
+		if state.ProvingSet.Size() == 0 {
+			return nil, ma.retire(ctx, &state)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// RefundCollateral releases the collateral of a terminated miner once the
+// penalty window has passed and sends the balance not owed for slashed
+// storage to the owner.
+func (ma *Actor) RefundCollateral(ctx exec.VMContext) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chainHeight := ctx.BlockHeight()
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		if state.TerminatedAt == nil {
+			return nil, errors.NewRevertError("miner is not terminated")
+		}
+
+		if state.SlashedAt != nil && state.SlashedAt.GreaterEqual(state.TerminatedAt) {
+			return nil, errors.NewRevertError("miner was slashed after terminating, its collateral is forfeit")
+		}
+
+		if state.ProvingSet.Size() > 0 {
+			return nil, errors.NewRevertErrorf("collateral is held until the PoSt for the proving period ending %s", state.ProvingPeriodEnd)
+		}
+
+		refundAt := state.TerminatedAt.Add(TerminationPenaltyWindow(state.SectorSize))
+		if chainHeight.LessThan(refundAt) {
+			return nil, errors.NewRevertErrorf("collateral is held until %s", refundAt)
+		}
+
+		state.ActiveCollateral = types.ZeroAttoFIL
+		state.NextDoneSet = types.EmptyIntSet()
+
+		refund := ma.availableBalance(state, ctx.MyBalance(), chainHeight)
+		if refund.IsZero() {
+			return nil, nil
+		}
+		_, ret, err := ctx.Send(state.Owner, "", refund, []interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		if ret != 0 {
+			return nil, errors.NewRevertErrorf("failed to send refund to owner, exit code %d", ret)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// GetTerminatedAt returns the block height at which the miner was terminated,
+// or zero if it is active.
+func (ma *Actor) GetTerminatedAt(ctx exec.VMContext) (*types.BlockHeight, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	err := actor.ReadState(ctx, &state)
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	if state.TerminatedAt == nil {
+		return types.NewBlockHeight(0), 0, nil
+	}
+	return state.TerminatedAt, 0, nil
+}
+
+// GetPledgeCollateralRequirement returns the collateral the miner must hold
+// for the storage it has committed.
+func (ma *Actor) GetPledgeCollateralRequirement(ctx exec.VMContext) (types.AttoFIL, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return types.ZeroAttoFIL, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	err := actor.ReadState(ctx, &state)
+	if err != nil {
+		return types.ZeroAttoFIL, errors.CodeError(err), err
+	}
+
+	return ma.getPledgeCollateralRequirement(state, ctx.BlockHeight()), 0, nil
+}
+
+// GetOwedStorageCollateral returns the collateral reserved for the storage
+// of slashed sectors.
+func (ma *Actor) GetOwedStorageCollateral(ctx exec.VMContext) (types.AttoFIL, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return types.ZeroAttoFIL, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	err := actor.ReadState(ctx, &state)
+	if err != nil {
+		return types.ZeroAttoFIL, errors.CodeError(err), err
+	}
+
+	return state.OwedStorageCollateral, 0, nil
+}
+
 func (ma *Actor) AddFaults(ctx exec.VMContext, faults types.FaultSet) (uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
@@ -901,6 +1249,12 @@ func (ma *Actor) SubmitPoSt(ctx exec.VMContext, poStProof types.PoStProof, fault
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
+		// a terminated miner only submits the PoSt of the proving period it
+		// terminated in, which retires it
+		if state.TerminatedAt != nil && state.ProvingSet.Size() == 0 {
+			return nil, Errors[ErrMinerTerminated]
+		}
+
 		provingPeriodDuration := types.NewBlockHeight(ProvingPeriodDuration(state.SectorSize))
 		nextProvingPeriodEnd := state.ProvingPeriodEnd.Add(provingPeriodDuration)
 
@@ -1009,6 +1363,10 @@ func (ma *Actor) SubmitPoSt(ctx exec.VMContext, poStProof types.PoStProof, fault
 		// transition to the next proving period
 		state.ProvingPeriodEnd = nextProvingPeriodEnd
 
+		if state.TerminatedAt != nil {
+			return nil, ma.retire(ctx, &state)
+		}
+
 		// Update miner power to the amount of data actually proved
 		// during the last proving period.
 		oldPower := state.Power
@@ -1105,6 +1463,11 @@ func (ma *Actor) SlashStorageFault(ctx exec.VMContext) (uint8, error) {
 		// save chain height, so we know when this miner was slashed
 		state.SlashedAt = chainHeight
 
+		// A terminated miner that missed its final PoSt retires here instead.
+		if state.TerminatedAt != nil {
+			return nil, ma.retire(ctx, &state)
+		}
+
 		return nil, nil
 	})
 
@@ -1152,12 +1515,46 @@ func (ma *Actor) burnFunds(ctx exec.VMContext, amount types.AttoFIL) error {
 	return err
 }
 
+// retire moves the remaining sectors of a terminated miner to the done set and
+// removes the miner and its power from the storage market.
+func (ma *Actor) retire(ctx exec.VMContext, state *State) error {
+	sectorIDs, err := state.SectorCommitments.IDs()
+	if err != nil {
+		return err
+	}
+	if err = state.SectorCommitments.Drop(sectorIDs); err != nil {
+		return err
+	}
+	state.NextDoneSet = state.NextDoneSet.Union(types.NewIntSet(sectorIDs...))
+	state.ProvingSet = types.EmptyIntSet()
+
+	_, ret, err := ctx.Send(address.StorageMarketAddress, "removeStorageMiner", types.ZeroAttoFIL, []interface{}{state.Power})
+	if err != nil {
+		return err
+	}
+	if ret != 0 {
+		return Errors[ErrStoragemarketCallFailed]
+	}
+	state.Power = types.NewBytesAmount(0)
+	return nil
+}
+
 func (ma *Actor) getPledgeCollateralRequirement(state State, height *types.BlockHeight) types.AttoFIL {
 	// The pledge collateral is expected to be a function of power and block height, but is currently
 	// a state variable.
 	return state.ActiveCollateral
 }
 
+// availableBalance returns the part of balance that is neither required as
+// pledge collateral nor owed for slashed storage.
+func (ma *Actor) availableBalance(state State, balance types.AttoFIL, height *types.BlockHeight) types.AttoFIL {
+	available := balance.Sub(ma.getPledgeCollateralRequirement(state, height)).Sub(state.OwedStorageCollateral)
+	if available.IsNegative() {
+		return types.ZeroAttoFIL
+	}
+	return available
+}
+
 // getPoStChallengeSeed returns some chain randomness
 func getPoStChallengeSeed(ctx exec.VMContext, state State, sampleAt *types.BlockHeight) (types.PoStChallengeSeed, error) {
 	randomness, err := ctx.SampleChainRandomness(sampleAt)
@@ -1196,6 +1593,12 @@ func CollateralForSector(sectorSize *types.BytesAmount) types.AttoFIL {
 	return MinimumCollateralPerSector
 }
 
+// TerminationPenaltyWindow is the number of blocks after termination during
+// which the collateral of a terminated miner is held.
+func TerminationPenaltyWindow(sectorSize *types.BytesAmount) *types.BlockHeight {
+	return types.NewBlockHeight(ProvingPeriodDuration(sectorSize))
+}
+
 // LatePoStGracePeriod is the number of blocks after a proving period ends
 // after which a storage miner will be subject to storage fault slashing.
 func LatePoStGracePeriod(sectorSize *types.BytesAmount) *types.BlockHeight {
@@ -257,6 +257,238 @@ func TestGetActiveCollateral(t *testing.T) {
 	assert.Equal(t, MinimumCollateralPerSector, coll)
 }
 
+func TestMinerPledgeAndWithdraw(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (state.Tree, vm.StorageMap, address.Address) {
+		st, vms := th.RequireCreateStorages(ctx, t)
+		minerAddr := th.CreateTestMinerWith(types.NewAttoFILFromFIL(1), t, st, vms, address.TestAddress, th.RequireRandomPeerID(t), 0)
+
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", nil, uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(types.TwoPoRepProofPartitions.ProofLen()))
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+		return st, vms, minerAddr
+	}
+
+	balance := func(t *testing.T, st state.Tree, addr address.Address) types.AttoFIL {
+		a, err := st.GetActor(ctx, addr)
+		require.NoError(t, err)
+		return a.Balance
+	}
+
+	t.Run("owner pledges funds to the miner", func(t *testing.T) {
+		st, vms, minerAddr := setup(t)
+
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 2, 3, "pledge", nil)
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+		assert.Equal(t, types.NewAttoFILFromFIL(3), balance(t, st, minerAddr))
+
+		res, err = th.CreateAndApplyTestMessageFrom(t, st, vms, address.TestAddress2, minerAddr, 2, 3, "pledge", nil)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrCallerUnauthorized), res.Receipt.ExitCode)
+		assert.Equal(t, types.NewAttoFILFromFIL(3), balance(t, st, minerAddr))
+	})
+
+	t.Run("owner withdraws the balance above the collateral requirement", func(t *testing.T) {
+		st, vms, minerAddr := setup(t)
+
+		result := callQueryMethodSuccess("getPledgeCollateralRequirement", ctx, t, st, vms, address.TestAddress, minerAddr)
+		assert.Equal(t, MinimumCollateralPerSector, types.NewAttoFILFromBytes(result[0]))
+		result = callQueryMethodSuccess("getOwedStorageCollateral", ctx, t, st, vms, address.TestAddress, minerAddr)
+		assert.Equal(t, types.ZeroAttoFIL, types.NewAttoFILFromBytes(result[0]))
+
+		available := types.NewAttoFILFromFIL(1).Sub(MinimumCollateralPerSector)
+		ownerBalance := balance(t, st, address.TestAddress)
+
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "withdraw", nil, types.NewAttoFILFromFIL(1))
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrInsufficientBalance), res.Receipt.ExitCode)
+
+		res, err = th.CreateAndApplyTestMessageFrom(t, st, vms, address.TestAddress2, minerAddr, 0, 3, "withdraw", nil, available)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrCallerUnauthorized), res.Receipt.ExitCode)
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "withdraw", nil, available)
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+		assert.Equal(t, MinimumCollateralPerSector, balance(t, st, minerAddr))
+		assert.True(t, balance(t, st, address.TestAddress).GreaterThan(ownerBalance), "the owner receives the withdrawal")
+	})
+}
+
+func TestMinerChangeOwner(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	st, vms := th.RequireCreateStorages(ctx, t)
+	minerAddr := th.CreateTestMiner(t, st, vms, address.TestAddress, th.RequireRandomPeerID(t))
+
+	res, err := th.CreateAndApplyTestMessageFrom(t, st, vms, address.TestAddress2, minerAddr, 0, 1, "changeOwner", nil, address.TestAddress2)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(ErrCallerUnauthorized), res.Receipt.ExitCode, "only the owner proposes a new owner")
+
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 1, "changeOwner", nil, address.TestAddress2)
+	require.NoError(t, err)
+	require.NoError(t, res.ExecutionError)
+
+	result := callQueryMethodSuccess("getProposedOwner", ctx, t, st, vms, address.TestAddress, minerAddr)
+	assert.Equal(t, address.TestAddress2, mustDeserializeAddress(t, result))
+	result = callQueryMethodSuccess("getOwner", ctx, t, st, vms, address.TestAddress, minerAddr)
+	assert.Equal(t, address.TestAddress, mustDeserializeAddress(t, result), "the owner changes once the proposal is accepted")
+
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 1, "acceptOwner", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(ErrCallerUnauthorized), res.Receipt.ExitCode, "only the proposed owner accepts")
+
+	res, err = th.CreateAndApplyTestMessageFrom(t, st, vms, address.TestAddress2, minerAddr, 0, 1, "acceptOwner", nil)
+	require.NoError(t, err)
+	require.NoError(t, res.ExecutionError)
+
+	result = callQueryMethodSuccess("getOwner", ctx, t, st, vms, address.TestAddress, minerAddr)
+	assert.Equal(t, address.TestAddress2, mustDeserializeAddress(t, result))
+	result = callQueryMethodSuccess("getProposedOwner", ctx, t, st, vms, address.TestAddress, minerAddr)
+	assert.Equal(t, address.Undef, mustDeserializeAddress(t, result))
+}
+
+func TestMinerTerminate(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	head := builder.AppendManyOn(10, types.UndefTipSet)
+	ancestors := builder.RequireTipSets(head.Key(), 10)
+	provingPeriodEnd := 3 + ProvingPeriodDuration(types.OneKiBSectorSize)
+
+	setup := func(t *testing.T) (state.Tree, vm.StorageMap, address.Address) {
+		st, vms := th.RequireCreateStorages(ctx, t)
+		minerAddr := th.CreateTestMinerWith(types.NewAttoFILFromFIL(1), t, st, vms, address.TestAddress, th.RequireRandomPeerID(t), 0)
+
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", nil, uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(types.TwoPoRepProofPartitions.ProofLen()))
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+		return st, vms, minerAddr
+	}
+
+	t.Run("terminates the miner and refunds its collateral after its final PoSt and the penalty window", func(t *testing.T) {
+		st, vms, minerAddr := setup(t)
+
+		res, err := th.CreateAndApplyTestMessageFrom(t, st, vms, address.TestAddress2, minerAddr, 0, 10, "terminate", nil)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrCallerUnauthorized), res.Receipt.ExitCode)
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 10, "terminate", nil)
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+
+		result := callQueryMethodSuccess("getTerminatedAt", ctx, t, st, vms, address.TestAddress, minerAddr)
+		assert.Equal(t, types.NewBlockHeight(10), types.NewBlockHeightFromBytes(result[0]))
+
+		result = callQueryMethodSuccess("getProvingSetCommitments", ctx, t, st, vms, address.TestAddress, minerAddr)
+		commitments, err := abi.Deserialize(result[0], abi.CommitmentsMap)
+		require.NoError(t, err)
+		assert.Len(t, commitments.Val, 1, "the sector must still be proven")
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 11, "commitSector", nil, uint64(2), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(types.TwoPoRepProofPartitions.ProofLen()))
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrMinerTerminated), res.Receipt.ExitCode)
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 11, "addAsk", nil, types.NewAttoFILFromFIL(1), big.NewInt(10))
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrMinerTerminated), res.Receipt.ExitCode)
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 11, "changeWorker", nil, address.TestAddress2)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrMinerTerminated), res.Receipt.ExitCode)
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 11, "updatePeerID", nil, th.RequireRandomPeerID(t))
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrMinerTerminated), res.Receipt.ExitCode)
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 10, "terminate", nil)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrMinerTerminated), res.Receipt.ExitCode)
+
+		refundAt := types.NewBlockHeight(10).Add(TerminationPenaltyWindow(types.OneKiBSectorSize)).AsBigInt().Uint64()
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, refundAt, "refundCollateral", nil)
+		require.NoError(t, err)
+		require.Error(t, res.ExecutionError)
+		assert.Contains(t, res.ExecutionError.Error(), "until the PoSt")
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, provingPeriodEnd-1, "submitPoSt", ancestors, th.MakeRandomPoStProofForTest(), types.EmptyFaultSet(), types.EmptyIntSet())
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+
+		result = callQueryMethodSuccess("getProvingSetCommitments", ctx, t, st, vms, address.TestAddress, minerAddr)
+		commitments, err = abi.Deserialize(result[0], abi.CommitmentsMap)
+		require.NoError(t, err)
+		assert.Empty(t, commitments.Val)
+		assert.Equal(t, types.NewBytesAmount(0), mustGetMinerState(st, vms, minerAddr).Power)
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, provingPeriodEnd, "submitPoSt", ancestors, th.MakeRandomPoStProofForTest(), types.EmptyFaultSet(), types.EmptyIntSet())
+		require.NoError(t, err)
+		assert.Equal(t, uint8(ErrMinerTerminated), res.Receipt.ExitCode, "only the final PoSt is accepted")
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, refundAt-1, "refundCollateral", nil)
+		require.NoError(t, err)
+		require.Error(t, res.ExecutionError)
+		assert.Contains(t, res.ExecutionError.Error(), "collateral is held")
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, refundAt, "refundCollateral", nil)
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+
+		minerActor, err := st.GetActor(ctx, minerAddr)
+		require.NoError(t, err)
+		assert.Equal(t, types.ZeroAttoFIL, minerActor.Balance)
+	})
+
+	t.Run("slashes a terminated miner that skips its final PoSt", func(t *testing.T) {
+		st, vms, minerAddr := setup(t)
+
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 10, "terminate", nil)
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+
+		slashAt := provingPeriodEnd + LatePoStGracePeriod(types.OneKiBSectorSize).AsBigInt().Uint64() + 1
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, slashAt, "slashStorageFault", nil)
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+		assertSlashStatus(t, st, vms, minerAddr, 0, types.NewBlockHeight(slashAt), types.NewIntSet(1))
+
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, slashAt+1, "refundCollateral", nil)
+		require.NoError(t, err)
+		require.Error(t, res.ExecutionError)
+		assert.Contains(t, res.ExecutionError.Error(), "forfeit")
+	})
+
+	t.Run("retires a miner without sectors at once", func(t *testing.T) {
+		st, vms := th.RequireCreateStorages(ctx, t)
+		minerAddr := th.CreateTestMinerWith(types.NewAttoFILFromFIL(1), t, st, vms, address.TestAddress, th.RequireRandomPeerID(t), 0)
+
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 10, "terminate", nil)
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+
+		refundAt := types.NewBlockHeight(10).Add(TerminationPenaltyWindow(types.OneKiBSectorSize)).AsBigInt().Uint64()
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, refundAt, "refundCollateral", nil)
+		require.NoError(t, err)
+		require.NoError(t, res.ExecutionError)
+	})
+
+	t.Run("rejects termination while a PoSt is overdue", func(t *testing.T) {
+		st, vms, minerAddr := setup(t)
+
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, provingPeriodEnd+1, "terminate", nil)
+		require.NoError(t, err)
+		require.Error(t, res.ExecutionError)
+		assert.Contains(t, res.ExecutionError.Error(), "overdue")
+	})
+}
+
 func TestCBOREncodeState(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -97,6 +97,10 @@ var storageMarketExports = exec.Exports{
 		Params: []abi.Type{abi.BytesAmount},
 		Return: nil,
 	},
+	"removeStorageMiner": &exec.FunctionSignature{
+		Params: []abi.Type{abi.BytesAmount},
+		Return: nil,
+	},
 	"getTotalStorage": &exec.FunctionSignature{
 		Params: []abi.Type{},
 		Return: []abi.Type{abi.BytesAmount},
@@ -198,6 +202,51 @@ func (sma *Actor) UpdateStorage(vmctx exec.VMContext, delta *types.BytesAmount)
 	return 0, nil
 }
 
+// RemoveStorageMiner is called by a terminating miner. It removes the miner's
+// remaining power from the total and forgets the miner, so it can no longer
+// update its storage.
+func (sma *Actor) RemoveStorageMiner(vmctx exec.VMContext, power *types.BytesAmount) (uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		miner := vmctx.Message().From
+		ctx := context.Background()
+
+		miners, err := actor.LoadLookup(ctx, vmctx.Storage(), state.Miners)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not load lookup for miner with CID: %s", state.Miners)
+		}
+
+		err = miners.Find(ctx, miner.String(), nil)
+		if err != nil {
+			if err == hamt.ErrNotFound {
+				return nil, Errors[ErrUnknownMiner]
+			}
+			return nil, errors.FaultErrorWrapf(err, "could not load lookup for miner with address: %s", miner)
+		}
+
+		if err := miners.Delete(ctx, miner.String()); err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not remove miner with address: %s", miner)
+		}
+		state.Miners, err = miners.Commit(ctx)
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not commit miner lookup")
+		}
+
+		state.TotalCommittedStorage = state.TotalCommittedStorage.Sub(power)
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
 func (sma *Actor) GetLateMiners(vmctx exec.VMContext) (*map[string]uint64, uint8, error) {
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
 		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
@@ -305,7 +354,7 @@ func (sma *Actor) getMinerPoStState(vmctx exec.VMContext, minerAddr address.Addr
 // isSupportedSectorSize produces a boolean indicating whether or not the
 // provided sector size is valid given the network's proofs mode.
 func isSupportedSectorSize(mode types.ProofsMode, sectorSize *types.BytesAmount) bool {
-	if mode == types.TestProofsMode {
+	if mode == types.TestProofsMode || mode == types.FakeProofsMode {
 		return sectorSize.Equal(types.OneKiBSectorSize)
 	} else {
 		return sectorSize.Equal(types.TwoHundredFiftySixMiBSectorSize)
@@ -10,6 +10,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/state"
@@ -361,3 +362,30 @@ func assertGetLateMiners(t *testing.T, st state.Tree, vms vm.StorageMap, height
 	miners := dsz.Val.(*map[string]uint64)
 	return miners
 }
+
+func TestRemoveStorageMiner(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	st, vms := th.RequireCreateStorages(ctx, t)
+	minerAddr := th.CreateTestMiner(t, st, vms, address.TestAddress, th.RequireRandomPeerID(t))
+
+	power := types.NewBytesAmount(uint64(3000000))
+	res, err := th.CreateAndApplyTestMessageFrom(t, st, vms, minerAddr, address.StorageMarketAddress, 0, 0, "updateStorage", nil, power)
+	require.NoError(t, err)
+	require.NoError(t, res.ExecutionError)
+
+	res, err = th.CreateAndApplyTestMessageFrom(t, st, vms, minerAddr, address.StorageMarketAddress, 0, 0, "removeStorageMiner", nil, power)
+	require.NoError(t, err)
+	require.NoError(t, res.ExecutionError)
+
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, address.StorageMarketAddress, 0, 0, "getTotalStorage", nil)
+	require.NoError(t, err)
+	require.NoError(t, res.ExecutionError)
+	assert.True(t, types.NewBytesAmount(0).Equal(types.NewBytesAmountFromBytes(res.Receipt.Return[0])))
+
+	// the removed miner can no longer update its storage
+	res, err = th.CreateAndApplyTestMessageFrom(t, st, vms, minerAddr, address.StorageMarketAddress, 0, 0, "updateStorage", nil, power)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(storagemarket.ErrUnknownMiner), res.Receipt.ExitCode)
+}
@@ -0,0 +1,104 @@
+package chain
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// GetTipSetByHeight returns the tipset at `height` on the chain ending in the
+// current head. When no tipset was mined at `height` the closest tipset below
+// it is returned, as its state is the state at that height.
+func (store *Store) GetTipSetByHeight(height uint64) (types.TipSet, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if len(store.heightIndex) == 0 {
+		return types.UndefTipSet, errors.New("no chain head")
+	}
+	if height >= uint64(len(store.heightIndex)) {
+		return types.UndefTipSet, errors.Errorf("height %d is above the chain head at height %d", height, len(store.heightIndex)-1)
+	}
+	ts := store.heightIndex[height]
+	if !ts.Defined() {
+		return types.UndefTipSet, errors.Wrapf(ErrNotFound, "no tipset indexed at height %d", height)
+	}
+	return ts, nil
+}
+
+// indexHeights updates the height index for the new head `ts`. Only the
+// tipsets of the new chain above the point where it joins the indexed chain
+// are visited, so extending the head is cheap and a reorg rewrites just the
+// heights it replaced. The caller must hold store.mu.
+func (store *Store) indexHeights(ts types.TipSet) error {
+	// Collect the new chain from the head down to and including the first
+	// tipset already indexed at its height.
+	var tipsets []types.TipSet
+	joined := false
+	for cur := ts; ; {
+		tipsets = append(tipsets, cur)
+		h, err := cur.Height()
+		if err != nil {
+			return err
+		}
+		if h < uint64(len(store.heightIndex)) && store.heightIndex[h].Equals(cur) {
+			joined = true
+			break
+		}
+
+		parents, err := cur.Parents()
+		if err != nil {
+			return err
+		}
+		if parents.Len() == 0 {
+			joined = true
+			break
+		}
+		cur, err = store.tipIndex.GetTipSet(parents)
+		if err != nil {
+			// The ancestors are not known to the store, so heights below
+			// here cannot be indexed.
+			logStore.Warningf("height index stops at height %d, parent %s not found", h, parents)
+			break
+		}
+	}
+
+	headHeight, err := ts.Height()
+	if err != nil {
+		return err
+	}
+	lowest, err := tipsets[len(tipsets)-1].Height()
+	if err != nil {
+		return err
+	}
+
+	// Resize the index to end at the new head, keeping the entries below the
+	// join, or none of them if the new chain could not be traced that far.
+	index := store.heightIndex
+	if uint64(len(index)) > headHeight+1 {
+		index = index[:headHeight+1]
+	}
+	for uint64(len(index)) < headHeight+1 {
+		index = append(index, types.UndefTipSet)
+	}
+	if !joined {
+		for i := uint64(0); i < lowest; i++ {
+			index[i] = types.UndefTipSet
+		}
+	}
+
+	// Fill each tipset's height and any null rounds above it.
+	next := headHeight + 1
+	for _, t := range tipsets {
+		h, err := t.Height()
+		if err != nil {
+			return err
+		}
+		for i := h; i < next; i++ {
+			index[i] = t
+		}
+		next = h
+	}
+	store.heightIndex = index
+	return nil
+}
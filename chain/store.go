@@ -72,7 +72,10 @@ type Store struct {
 	genesis cid.Cid
 	// head is the tipset at the head of the best known chain.
 	head types.TipSet
-	// Protects head and genesisCid.
+	// heightIndex holds the tipset at each height of the chain ending in
+	// head. A null round holds the closest tipset below it.
+	heightIndex []types.TipSet
+	// Protects head, heightIndex and genesisCid.
 	mu sync.RWMutex
 
 	// headEvents is a pubsub channel that publishes an event every time the head changes.
@@ -122,8 +125,11 @@ func (store *Store) Load(ctx context.Context) (err error) {
 	ctx, span := trace.StartSpan(ctx, "Store.Load")
 	defer tracing.AddErrorEndSpan(ctx, span, &err)
 
-	// Clear the tipset index.
+	// Clear the tipset and height indexes.
 	store.tipIndex = NewTipIndex()
+	store.mu.Lock()
+	store.heightIndex = nil
+	store.mu.Unlock()
 
 	headTsKey, err := store.loadHead()
 	if err != nil {
@@ -240,6 +246,12 @@ func (store *Store) GetTipSet(key types.TipSetKey) (types.TipSet, error) {
 	return store.tipIndex.GetTipSet(key)
 }
 
+// GetBlock returns the block identified by `c` from the block store, whether or
+// not a tipset holding it has been validated and indexed.
+func (store *Store) GetBlock(ctx context.Context, c cid.Cid) (*types.Block, error) {
+	return store.stateAndBlockSource.GetBlock(ctx, c)
+}
+
 // GetTipSetState returns the aggregate state of the tipset identified by `key`.
 func (store *Store) GetTipSetState(ctx context.Context, key types.TipSetKey) (state.Tree, error) {
 	stateCid, err := store.tipIndex.GetTipSetStateRoot(key)
@@ -324,6 +336,9 @@ func (store *Store) setHeadPersistent(ctx context.Context, ts types.TipSet) erro
 		return errors.Wrap(errInner, "failed to write new Head to datastore")
 	}
 
+	if err := store.indexHeights(ts); err != nil {
+		return errors.Wrap(err, "failed to index new head by height")
+	}
 	store.head = ts
 
 	return nil
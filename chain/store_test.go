@@ -352,6 +352,59 @@ func TestHeadEvents(t *testing.T) {
 	assertEmptyCh(t, chB)
 }
 
+// The tipset at each height of the head's chain can be looked up, and the
+// lookups follow the head through a reorg.
+func TestGetTipSetByHeight(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	builder := chain.NewBuilder(t, address.Undef)
+	genTS := builder.NewGenesis()
+	r := repo.NewInMemoryRepo()
+	chainStore := newChainStore(r, genTS.At(0).Cid())
+
+	// Construct test chain data
+	link1 := builder.AppendOn(genTS, 2)
+	link2 := builder.AppendOn(link1, 3)
+	link3 := builder.AppendOn(link2, 1)
+	link4 := builder.BuildOn(link3, 2, func(bb *chain.BlockBuilder, i int) { bb.IncHeight(2) })
+	// A fork from link2 with a null round at height 3.
+	fork := builder.BuildOn(link2, 1, func(bb *chain.BlockBuilder, i int) { bb.IncHeight(1) })
+	requirePutTestChain(ctx, t, chainStore, link4.Key(), builder, 5)
+	requirePutTestChain(ctx, t, chainStore, fork.Key(), builder, 1)
+
+	_, err := chainStore.GetTipSetByHeight(0)
+	assert.Error(t, err)
+
+	assertHeights := func(expected []types.TipSet) {
+		for h, ts := range expected {
+			got, err := chainStore.GetTipSetByHeight(uint64(h))
+			require.NoError(t, err)
+			assert.Equal(t, ts.Key(), got.Key(), "height %d", h)
+		}
+		_, err := chainStore.GetTipSetByHeight(uint64(len(expected)))
+		assert.Error(t, err)
+	}
+
+	assertSetHead(t, chainStore, genTS)
+	assertHeights([]types.TipSet{genTS})
+
+	// Null rounds resolve to the tipset below them.
+	assertSetHead(t, chainStore, link4)
+	assertHeights([]types.TipSet{genTS, link1, link2, link3, link3, link3, link4})
+
+	// A reorg rewrites the heights above the fork point.
+	assertSetHead(t, chainStore, fork)
+	assertHeights([]types.TipSet{genTS, link1, link2, link2, fork})
+
+	assertSetHead(t, chainStore, link4)
+	assertHeights([]types.TipSet{genTS, link1, link2, link3, link3, link3, link4})
+
+	// Moving the head back truncates the index.
+	assertSetHead(t, chainStore, link1)
+	assertHeights([]types.TipSet{genTS, link1})
+}
+
 /* Loading  */
 // Load does not error and gives the chain store access to all blocks and
 // tipset indexes along the heaviest chain.
@@ -404,6 +457,11 @@ func TestLoadAndReboot(t *testing.T) {
 
 	// Check the head
 	assert.Equal(t, link4.Key(), rebootChain.GetHead())
+
+	// Check the height index
+	got3, err := rebootChain.GetTipSetByHeight(5)
+	require.NoError(t, err)
+	assert.Equal(t, link3, got3)
 }
 
 type tipSetGetter interface {
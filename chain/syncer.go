@@ -45,6 +45,7 @@ var logSyncer = logging.Logger("chain.syncer")
 
 type syncerChainReaderWriter interface {
 	GetHead() types.TipSetKey
+	GetBlock(ctx context.Context, c cid.Cid) (*types.Block, error)
 	GetTipSet(tsKey types.TipSetKey) (types.TipSet, error)
 	GetTipSetStateRoot(tsKey types.TipSetKey) (cid.Cid, error)
 	HasTipSetAndState(ctx context.Context, tsKey types.TipSetKey) bool
@@ -147,49 +148,16 @@ func (syncer *Syncer) syncOne(ctx context.Context, grandParent, parent, next typ
 	stopwatch := syncOneTimer.Start(ctx)
 	defer stopwatch.Stop(ctx)
 
-	// Lookup parent state root. It is guaranteed by the syncer that it is in the chainStore.
-	stateRoot, err := syncer.chainStore.GetTipSetStateRoot(parent.Key())
-	if err != nil {
-		return err
-	}
-
-	// Gather ancestor chain needed to process state transition.
-	h, err := next.Height()
-	if err != nil {
-		return err
-	}
-	ancestorHeight := types.NewBlockHeight(h).Sub(types.NewBlockHeight(consensus.AncestorRoundsNeeded))
-	ancestors, err := GetRecentAncestors(ctx, parent, syncer.chainStore, ancestorHeight)
-	if err != nil {
-		return err
-	}
-
-	// Gather tipset messages
-	var nextMessages [][]*types.SignedMessage
-	var nextReceipts [][]*types.MessageReceipt
-	for i := 0; i < next.Len(); i++ {
-		blk := next.At(i)
-		msgs, err := syncer.messageProvider.LoadMessages(ctx, blk.Messages)
-		if err != nil {
-			return errors.Wrapf(err, "syncing tip %s failed loading message list %s for block %s", next.Key(), blk.Messages, blk.Cid())
-		}
-		rcpts, err := syncer.messageProvider.LoadReceipts(ctx, blk.MessageReceipts)
-		if err != nil {
-			return errors.Wrapf(err, "syncing tip %s failed loading receipts list %s for block %s", next.Key(), blk.MessageReceipts, blk.Cid())
-		}
-		nextMessages = append(nextMessages, msgs)
-		nextReceipts = append(nextReceipts, rcpts)
-	}
-
-	// Gather validated parent weight
-	parentWeight, err := syncer.calculateParentWeight(ctx, parent, grandParent)
+	// Gather the parent state root, ancestors and messages. The parent state
+	// root is guaranteed by the syncer to be in the chainStore.
+	in, err := syncer.loadTransitionInputs(ctx, grandParent, parent, next)
 	if err != nil {
 		return err
 	}
 
 	// Run a state transition to validate the tipset and compute
 	// a new state to add to the store.
-	root, err := syncer.stateEvaluator.RunStateTransition(ctx, next, nextMessages, nextReceipts, ancestors, parentWeight, stateRoot)
+	root, err := syncer.stateEvaluator.RunStateTransition(ctx, next, in.messages, in.receipts, in.ancestors, in.parentWeight, in.parentStateRoot)
 	if err != nil {
 		return err
 	}
@@ -242,6 +210,144 @@ func (syncer *Syncer) syncOne(ctx context.Context, grandParent, parent, next typ
 	return nil
 }
 
+// transitionInputs holds what is needed to run the state transition of a
+// tipset on top of its parent.
+type transitionInputs struct {
+	parentStateRoot cid.Cid
+	ancestors       []types.TipSet
+	messages        [][]*types.SignedMessage
+	receipts        [][]*types.MessageReceipt
+	parentWeight    uint64
+}
+
+// loadTransitionInputs gathers the parent state root, ancestors, messages,
+// receipts and parent weight of `next` from the store.
+func (syncer *Syncer) loadTransitionInputs(ctx context.Context, grandParent, parent, next types.TipSet) (*transitionInputs, error) {
+	stateRoot, err := syncer.chainStore.GetTipSetStateRoot(parent.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	// Gather ancestor chain needed to process state transition.
+	h, err := next.Height()
+	if err != nil {
+		return nil, err
+	}
+	ancestorHeight := types.NewBlockHeight(h).Sub(types.NewBlockHeight(consensus.AncestorRoundsNeeded))
+	ancestors, err := GetRecentAncestors(ctx, parent, syncer.chainStore, ancestorHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gather tipset messages
+	var nextMessages [][]*types.SignedMessage
+	var nextReceipts [][]*types.MessageReceipt
+	for i := 0; i < next.Len(); i++ {
+		blk := next.At(i)
+		msgs, err := syncer.messageProvider.LoadMessages(ctx, blk.Messages)
+		if err != nil {
+			return nil, errors.Wrapf(err, "syncing tip %s failed loading message list %s for block %s", next.Key(), blk.Messages, blk.Cid())
+		}
+		rcpts, err := syncer.messageProvider.LoadReceipts(ctx, blk.MessageReceipts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "syncing tip %s failed loading receipts list %s for block %s", next.Key(), blk.MessageReceipts, blk.Cid())
+		}
+		nextMessages = append(nextMessages, msgs)
+		nextReceipts = append(nextReceipts, rcpts)
+	}
+
+	// Gather validated parent weight
+	parentWeight, err := syncer.calculateParentWeight(ctx, parent, grandParent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transitionInputs{
+		parentStateRoot: stateRoot,
+		ancestors:       ancestors,
+		messages:        nextMessages,
+		receipts:        nextReceipts,
+		parentWeight:    parentWeight,
+	}, nil
+}
+
+// ReplayResult reports the outcome of re-running the state transition of a
+// tipset whose blocks are in the store.
+type ReplayResult struct {
+	TipSetKey types.TipSetKey `json:"tipSetKey"`
+	// ParentStateRoot is the state the tipset was replayed on.
+	ParentStateRoot cid.Cid `json:"parentStateRoot"`
+	// StoredStateRoot is the state root the store holds for the tipset,
+	// undefined if the tipset was not accepted into the store.
+	StoredStateRoot cid.Cid `json:"storedStateRoot"`
+	// StateRoot is the state root computed by the replay, undefined if the
+	// transition failed.
+	StateRoot cid.Cid `json:"stateRoot"`
+	// Match is set when the replay computed the stored state root.
+	Match bool `json:"match"`
+	// Mismatch is set when a block of the tipset claims a state root other
+	// than the one computed for it.
+	Mismatch *consensus.StateRootMismatchError `json:"mismatch,omitempty"`
+	// Error is set when the transition failed for any other reason.
+	Error string `json:"error,omitempty"`
+}
+
+// Replay re-runs the state transition of a tipset on the state of its parent
+// and reports the state root computed. The tipset need not have been accepted:
+// the blocks of a tipset the syncer rejected are loaded from the block store,
+// where they were put when fetched. Its parent must be in the store. The store
+// is not modified. An invalid transition is reported in the result rather than
+// returned as an error.
+func (syncer *Syncer) Replay(ctx context.Context, key types.TipSetKey) (*ReplayResult, error) {
+	ts, err := syncer.chainStore.GetTipSet(key)
+	if err != nil {
+		ts, err = LoadTipSetBlocks(ctx, syncer.chainStore, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load blocks of tipset %s", key)
+		}
+	}
+	parentKey, err := ts.Parents()
+	if err != nil {
+		return nil, err
+	}
+	if parentKey.Empty() {
+		return nil, errors.New("cannot replay the genesis tipset")
+	}
+	stored := cid.Undef
+	if syncer.chainStore.HasTipSetAndState(ctx, key) {
+		stored, err = syncer.chainStore.GetTipSetStateRoot(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	parent, grandParent, err := syncer.ancestorsFromStore(ts)
+	if err != nil {
+		return nil, err
+	}
+	in, err := syncer.loadTransitionInputs(ctx, grandParent, parent, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReplayResult{
+		TipSetKey:       key,
+		ParentStateRoot: in.parentStateRoot,
+		StoredStateRoot: stored,
+	}
+	root, err := syncer.stateEvaluator.RunStateTransition(ctx, ts, in.messages, in.receipts, in.ancestors, in.parentWeight, in.parentStateRoot)
+	if err != nil {
+		if mismatch, ok := errors.Cause(err).(*consensus.StateRootMismatchError); ok {
+			result.Mismatch = mismatch
+		} else {
+			result.Error = err.Error()
+		}
+		return result, nil
+	}
+	result.StateRoot = root
+	result.Match = stored.Defined() && root.Equals(stored)
+	return result, nil
+}
+
 // TODO #3537 this should be stored the first time it is computed and retrieved
 // from disk just like aggregate state roots.
 func (syncer *Syncer) calculateParentWeight(ctx context.Context, parent, grandParent types.TipSet) (uint64, error) {
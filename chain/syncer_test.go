@@ -8,11 +8,13 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-hamt-ipld"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/state"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
@@ -452,6 +454,107 @@ func TestSyncerStatus(t *testing.T) {
 	assert.Equal(t, true, s2.SyncingComplete)
 }
 
+func TestReplay(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	builder, store, syncer := setup(ctx, t)
+	genesis := builder.RequireTipSet(store.GetHead())
+
+	t1 := builder.AppendOn(genesis, 1)
+	t2 := builder.AppendOn(t1, 2)
+	require.NoError(t, syncer.HandleNewTipSet(ctx, types.NewChainInfo(peer.ID(""), t2.Key(), heightFromTip(t, t2)), true))
+
+	t.Run("computes the stored state root", func(t *testing.T) {
+		res, err := syncer.Replay(ctx, t2.Key())
+		require.NoError(t, err)
+		assert.True(t, res.Match)
+		assert.Equal(t, t2.Key(), res.TipSetKey)
+		assert.Equal(t, builder.StateForKey(t1.Key()), res.ParentStateRoot)
+		assert.Equal(t, builder.StateForKey(t2.Key()), res.StoredStateRoot)
+		assert.Equal(t, res.StoredStateRoot, res.StateRoot)
+		assert.Nil(t, res.Mismatch)
+		assert.Empty(t, res.Error)
+	})
+
+	t.Run("reports a stored state root that differs", func(t *testing.T) {
+		t3 := builder.AppendOn(t2, 1)
+		bogus := types.NewCidForTestGetter()()
+		require.NoError(t, store.PutTipSetAndState(ctx, &chain.TipSetAndState{TipSetStateRoot: bogus, TipSet: t3}))
+
+		res, err := syncer.Replay(ctx, t3.Key())
+		require.NoError(t, err)
+		assert.False(t, res.Match)
+		assert.Equal(t, bogus, res.StoredStateRoot)
+		assert.Equal(t, builder.StateForKey(t3.Key()), res.StateRoot)
+	})
+
+	t.Run("reports blocks claiming a different state root", func(t *testing.T) {
+		mismatch := &consensus.StateRootMismatchError{
+			Block:    t2.At(0).Cid(),
+			Claimed:  t2.At(0).StateRoot,
+			Computed: types.NewCidForTestGetter()(),
+		}
+		eval := &mismatchEvaluator{err: mismatch}
+		replayer := chain.NewSyncer(eval, &chain.FakeChainSelector{}, store, builder, builder, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)))
+
+		res, err := replayer.Replay(ctx, t2.Key())
+		require.NoError(t, err)
+		assert.False(t, res.Match)
+		assert.Equal(t, mismatch, res.Mismatch)
+		assert.False(t, res.StateRoot.Defined())
+	})
+
+	t.Run("replays a tipset the syncer rejected", func(t *testing.T) {
+		cst := hamt.NewCborStore()
+		rejectingStore := chain.NewStore(repo.NewInMemoryRepo().ChainDatastore(), cst, &state.TreeStateLoader{}, chain.NewStatusReporter(), genesis.At(0).Cid())
+		require.NoError(t, rejectingStore.PutTipSetAndState(ctx, &chain.TipSetAndState{TipSetStateRoot: builder.StateForKey(genesis.Key()), TipSet: genesis}))
+		require.NoError(t, rejectingStore.SetHead(ctx, genesis))
+		accepting := chain.NewSyncer(&chain.FakeStateEvaluator{}, &chain.FakeChainSelector{}, rejectingStore, builder, builder, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)))
+		require.NoError(t, accepting.HandleNewTipSet(ctx, types.NewChainInfo(peer.ID(""), t1.Key(), heightFromTip(t, t1)), true))
+
+		mismatch := &consensus.StateRootMismatchError{
+			Block:    t2.At(0).Cid(),
+			Claimed:  t2.At(0).StateRoot,
+			Computed: types.NewCidForTestGetter()(),
+		}
+		rejecting := chain.NewSyncer(&mismatchEvaluator{err: mismatch}, &chain.FakeChainSelector{}, rejectingStore, builder, builder, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)))
+		require.Error(t, rejecting.HandleNewTipSet(ctx, types.NewChainInfo(peer.ID(""), t2.Key(), heightFromTip(t, t2)), true))
+		require.False(t, rejectingStore.HasTipSetAndState(ctx, t2.Key()))
+
+		// A node's fetcher puts the blocks it fetches in the block store, the
+		// chain builder standing in for it here does not.
+		_, err := rejecting.Replay(ctx, t2.Key())
+		require.Error(t, err)
+		requirePutBlocksToCborStore(t, cst, t2.ToSlice()...)
+
+		res, err := rejecting.Replay(ctx, t2.Key())
+		require.NoError(t, err)
+		assert.False(t, res.Match)
+		assert.Equal(t, mismatch, res.Mismatch)
+		assert.Equal(t, builder.StateForKey(t1.Key()), res.ParentStateRoot)
+		assert.False(t, res.StoredStateRoot.Defined())
+
+		res, err = accepting.Replay(ctx, t2.Key())
+		require.NoError(t, err)
+		assert.False(t, res.Match)
+		assert.Equal(t, builder.StateForKey(t2.Key()), res.StateRoot)
+	})
+
+	t.Run("rejects genesis", func(t *testing.T) {
+		_, err := syncer.Replay(ctx, genesis.Key())
+		assert.Error(t, err)
+	})
+}
+
+// mismatchEvaluator fails every state transition with an error.
+type mismatchEvaluator struct {
+	err error
+}
+
+func (e *mismatchEvaluator) RunStateTransition(context.Context, types.TipSet, [][]*types.SignedMessage, [][]*types.MessageReceipt, []types.TipSet, uint64, cid.Cid) (cid.Cid, error) {
+	return cid.Undef, errors.Wrap(e.err, "failed to validate tipset")
+}
+
 ///// Set-up /////
 
 // Initializes a chain builder, store and syncer.
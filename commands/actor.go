@@ -2,17 +2,21 @@ package commands
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"reflect"
 	"strings"
 
+	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/initactor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
+	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/types"
 
 	"github.com/ipfs/go-cid"
@@ -47,13 +51,196 @@ var actorCmd = &cmds.Command{
 		Tagline: "Interact with actors. Actors are built-in smart contracts.",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"ls": actorLsCmd,
+		"call":    actorCallCmd,
+		"ls":      actorLsCmd,
+		"methods": actorMethodsCmd,
 	},
 }
 
+// ActorCallValue is a value returned by a method, presented to the user.
+type ActorCallValue struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+	Text  string      `json:"text"`
+}
+
+// ActorCallResult is the result of an actor call command.
+type ActorCallResult struct {
+	Return []ActorCallValue `json:"return"`
+}
+
+var actorCallCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Query a method of an actor without sending a message",
+		ShortDescription: `
+Runs a method of an actor against the state at the head of the chain and prints
+the values it returns. Nothing is written to the chain. Parameters are given as
+strings or JSON matching the signature of the method.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address of the actor to query"),
+		cmdkit.StringArg("method", true, false, "The method to query"),
+		cmdkit.StringArg("params", false, true, "Parameters of the method, as strings or JSON matching its signature"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to query from"),
+		atOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		actorAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		method := req.Arguments[1]
+
+		fromAddr, err := optionalAddr(req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		params, err := GetPorcelainAPI(env).ActorParseParams(req.Context, actorAddr, method, req.Arguments[2:])
+		if err != nil {
+			return err
+		}
+
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+
+		vals, err := GetPorcelainAPI(env).ActorCallAt(req.Context, fromAddr, actorAddr, method, baseKey, params...)
+		if err != nil {
+			return err
+		}
+
+		res := &ActorCallResult{Return: make([]ActorCallValue, len(vals))}
+		for i, val := range vals {
+			res.Return[i] = ActorCallValue{
+				Type:  val.Type.String(),
+				Value: val.Val,
+				Text:  val.String(),
+			}
+		}
+		return re.Emit(res)
+	},
+	Type: &ActorCallResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *ActorCallResult) error {
+			sw := NewSilentWriter(w)
+			for _, val := range res.Return {
+				sw.Println(val.Text)
+			}
+			return sw.Error()
+		}),
+	},
+}
+
+// ActorMethodView is a method exported by an actor, presented to the user.
+type ActorMethodView struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params"`
+	Return []string `json:"return"`
+}
+
+// ActorMethodsResult lists the methods of an actor code along with the
+// schemas of the structured types in their signatures, for generating
+// clients.
+type ActorMethodsResult struct {
+	Code    cid.Cid           `json:"code"`
+	Methods []ActorMethodView `json:"methods"`
+	Types   []*abi.Schema     `json:"types"`
+}
+
+var actorMethodsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the methods exported by an actor",
+		ShortDescription: `
+Lists the methods exported by the actor at an address, or by the builtin actor
+with a code cid, along with the types of their parameters and return values.
+Use --enc=json for a description that includes the schemas of structured types.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("actor", true, false, "Address of an actor or cid of an actor code"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var code cid.Cid
+		var methods []porcelain.ActorMethod
+		if actorAddr, err := address.NewFromString(req.Arguments[0]); err == nil {
+			code, methods, err = GetPorcelainAPI(env).ActorMethodsAt(req.Context, actorAddr)
+			if err != nil {
+				return err
+			}
+		} else {
+			code, err = cid.Decode(req.Arguments[0])
+			if err != nil {
+				return fmt.Errorf("%s is neither an address nor a cid", req.Arguments[0])
+			}
+			methods, err = GetPorcelainAPI(env).ActorMethods(req.Context, code)
+			if err != nil {
+				return err
+			}
+		}
+
+		return re.Emit(makeActorMethodsResult(code, methods))
+	},
+	Type: &ActorMethodsResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *ActorMethodsResult) error {
+			sw := NewSilentWriter(w)
+			for _, m := range res.Methods {
+				sw.Printf("%s(%s) (%s)\n", m.Name, strings.Join(m.Params, ", "), strings.Join(m.Return, ", "))
+			}
+			return sw.Error()
+		}),
+	},
+}
+
+func makeActorMethodsResult(code cid.Cid, methods []porcelain.ActorMethod) *ActorMethodsResult {
+	res := &ActorMethodsResult{
+		Code:    code,
+		Methods: make([]ActorMethodView, len(methods)),
+		Types:   []*abi.Schema{},
+	}
+
+	seen := make(map[abi.Type]bool)
+	addSchema := func(t abi.Type) string {
+		if schema, ok := abi.SchemaOf(t); ok && !seen[t] {
+			seen[t] = true
+			res.Types = append(res.Types, schema)
+		}
+		return t.String()
+	}
+
+	for i, m := range methods {
+		view := ActorMethodView{
+			Name:   m.Name,
+			Params: make([]string, len(m.Params)),
+			Return: make([]string, len(m.Return)),
+		}
+		for j, p := range m.Params {
+			view.Params[j] = addSchema(p)
+		}
+		for j, r := range m.Return {
+			view.Return[j] = addSchema(r)
+		}
+		res.Methods[i] = view
+	}
+	return res
+}
+
 var actorLsCmd = &cmds.Command{
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		results, err := GetPorcelainAPI(env).ActorLs(req.Context)
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+
+		results, err := GetPorcelainAPI(env).ActorLsAt(req.Context, baseKey)
 		if err != nil {
 			return err
 		}
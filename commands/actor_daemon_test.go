@@ -8,9 +8,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/commands"
+	"github.com/filecoin-project/go-filecoin/fixtures"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
 )
 
 func TestActorDaemon(t *testing.T) {
@@ -46,3 +49,58 @@ func TestActorDaemon(t *testing.T) {
 		}
 	})
 }
+
+func TestActorCall(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	t.Run("decodes the returns of a method", func(t *testing.T) {
+		owner := d.RunSuccess("actor", "call", fixtures.TestMiners[0], "getOwner").ReadStdoutTrimNewlines()
+		assert.Equal(t, fixtures.TestAddresses[0], owner)
+	})
+
+	t.Run("message send passes typed parameters", func(t *testing.T) {
+		d.RunSuccess("message", "send",
+			"--from", fixtures.TestAddresses[0],
+			"--gas-price", "1", "--gas-limit", "300",
+			fixtures.TestMiners[0], "changeWorker", fixtures.TestAddresses[1],
+		)
+		d.RunSuccess("mining", "once")
+
+		worker := d.RunSuccess("actor", "call", fixtures.TestMiners[0], "getWorker").ReadStdoutTrimNewlines()
+		assert.Equal(t, fixtures.TestAddresses[1], worker)
+	})
+
+	t.Run("rejects parameters not matching the signature", func(t *testing.T) {
+		d.RunFail("invalid parameters", "actor", "call", fixtures.TestMiners[0], "getOwner", "extra")
+	})
+}
+
+func TestActorMethods(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	t.Run("lists the methods of the actor at an address", func(t *testing.T) {
+		out := d.RunSuccess("actor", "methods", address.StorageMarketAddress.String()).ReadStdout()
+		assert.Contains(t, out, "createStorageMiner(")
+	})
+
+	t.Run("describes the methods of a code as json", func(t *testing.T) {
+		out := d.RunSuccess("actor", "methods", "--enc=json", types.MinerActorCodeCid.String()).ReadStdout()
+
+		var res commands.ActorMethodsResult
+		require.NoError(t, json.Unmarshal([]byte(out), &res))
+		assert.Equal(t, types.MinerActorCodeCid, res.Code)
+		assert.Contains(t, res.Methods, commands.ActorMethodView{Name: "getAsk", Params: []string{"*big.Int"}, Return: []string{"miner.Ask"}})
+		require.Len(t, res.Types, 1)
+		assert.Equal(t, "miner.Ask", res.Types[0].Name)
+	})
+
+	t.Run("rejects an argument that is neither an address nor a cid", func(t *testing.T) {
+		d.RunFail("neither an address nor a cid", "actor", "methods", "nope")
+	})
+}
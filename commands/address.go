@@ -93,13 +93,20 @@ var addrsLookupCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("address", true, false, "Miner address to find peerId for"),
 	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		addr, err := address.NewFromString(req.Arguments[0])
 		if err != nil {
 			return err
 		}
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
 
-		v, err := GetPorcelainAPI(env).MinerGetPeerID(req.Context, addr)
+		v, err := GetPorcelainAPI(env).MinerGetPeerIDAt(req.Context, addr, baseKey)
 		if err != nil {
 			return errors.Wrapf(err, "failed to find miner with address %s", addr.String())
 		}
@@ -136,13 +143,20 @@ var balanceCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("address", true, false, "Address to get balance for"),
 	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		addr, err := address.NewFromString(req.Arguments[0])
 		if err != nil {
 			return err
 		}
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
 
-		balance, err := GetPorcelainAPI(env).WalletBalance(req.Context, addr)
+		balance, err := GetPorcelainAPI(env).WalletBalanceAt(req.Context, addr, baseKey)
 		if err != nil {
 			return err
 		}
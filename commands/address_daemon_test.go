@@ -54,6 +54,33 @@ func TestWalletBalance(t *testing.T) {
 	assert.Equal(t, "0", balance.ReadStdoutTrimNewlines())
 }
 
+func TestWalletBalanceAt(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	network := address.NetworkAddress.String()
+	genesisBalance := d.RunSuccess("wallet", "balance", network).ReadStdoutTrimNewlines()
+	d.RunSuccess("mining", "once")
+
+	t.Log("[success] the head by default")
+	headBalance := d.RunSuccess("wallet", "balance", network).ReadStdoutTrimNewlines()
+	assert.NotEqual(t, genesisBalance, headBalance)
+
+	t.Log("[success] a height")
+	balance := d.RunSuccess("wallet", "balance", network, "--at", "0").ReadStdoutTrimNewlines()
+	assert.Equal(t, genesisBalance, balance)
+
+	t.Log("[success] a tipset key")
+	headKey := d.RunSuccess("chain", "head").ReadStdoutTrimNewlines()
+	balance = d.RunSuccess("wallet", "balance", network, "--at", strings.Join(strings.Fields(headKey), ",")).ReadStdoutTrimNewlines()
+	assert.Equal(t, headBalance, balance)
+
+	t.Log("[failure] a height above the head")
+	d.RunFail("above the chain head", "wallet", "balance", network, "--at", "100")
+}
+
 func TestAddrLookupAndUpdate(t *testing.T) {
 	tf.IntegrationTest(t)
 
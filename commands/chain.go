@@ -12,6 +12,7 @@ import (
 	"github.com/ipfs/go-ipfs-cmds"
 	"github.com/libp2p/go-libp2p-core/peer"
 
+	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -20,11 +21,14 @@ var chainCmd = &cmds.Command{
 		Tagline: "Inspect the filecoin blockchain",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"head":     storeHeadCmd,
-		"ls":       storeLsCmd,
-		"status":   storeStatusCmd,
-		"set-head": storeSetHeadCmd,
-		"sync":     storeSyncCmd,
+		"get-tipset":  storeGetTipSetCmd,
+		"head":        storeHeadCmd,
+		"ls":          storeLsCmd,
+		"replay":      storeReplayCmd,
+		"status":      storeStatusCmd,
+		"set-head":    storeSetHeadCmd,
+		"sync":        storeSyncCmd,
+		"wait-height": storeWaitHeightCmd,
 	},
 }
 
@@ -53,6 +57,44 @@ var storeHeadCmd = &cmds.Command{
 	},
 }
 
+var storeGetTipSetCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Get the CIDs of the tipset at a height",
+		ShortDescription: `
+Prints the CIDs of the blocks of the tipset at --height on the chain ending in
+the current head. When the height is a null round the closest tipset below it
+is printed.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("height", "Block height of the tipset"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		height, ok := req.Options["height"].(uint64)
+		if !ok {
+			return fmt.Errorf("must specify --height")
+		}
+
+		ts, err := GetPorcelainAPI(env).ChainTipSetAtHeight(req.Context, height)
+		if err != nil {
+			return err
+		}
+		return re.Emit(ts.Key())
+	},
+	Type: []cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res []cid.Cid) error {
+			for _, r := range res {
+				_, err := fmt.Fprintln(w, r.String())
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
 var storeLsCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "List blocks in the blockchain",
@@ -143,6 +185,58 @@ var storeSetHeadCmd = &cmds.Command{
 	},
 }
 
+var storeReplayCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Re-run the state transition of a tipset and check its state root",
+		ShortDescription: `
+Applies the messages of the tipset with the given key to the state of its parent
+and compares the resulting state root with the one stored for the tipset and the
+ones claimed by its blocks. Tipsets the node fetched but rejected can be replayed
+too, as long as their parent was accepted. The chain is not modified.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cids", true, true, "CID's of the blocks of the tipset to replay."),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		tsCids, err := cidsFromSlice(req.Arguments)
+		if err != nil {
+			return err
+		}
+		res, err := GetPorcelainAPI(env).ChainReplay(req.Context, types.NewTipSetKey(tsCids...))
+		if err != nil {
+			return err
+		}
+		return re.Emit(res)
+	},
+	Type: &chain.ReplayResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *chain.ReplayResult) error {
+			sw := NewSilentWriter(w)
+			sw.Printf("Parent state:   %s\n", res.ParentStateRoot)
+			if res.StoredStateRoot.Defined() {
+				sw.Printf("Stored state:   %s\n", res.StoredStateRoot)
+			} else {
+				sw.Println("Stored state:   none, the tipset was not accepted")
+			}
+			switch {
+			case res.Mismatch != nil:
+				sw.Printf("State root mismatch: block %s claims %s, computed %s\n", res.Mismatch.Block, res.Mismatch.Claimed, res.Mismatch.Computed)
+			case res.Error != "":
+				sw.Printf("Replay failed: %s\n", res.Error)
+			default:
+				sw.Printf("Computed state: %s\n", res.StateRoot)
+				if res.Match {
+					sw.Println("OK")
+				} else {
+					sw.Println("State root mismatch: computed state differs from stored state")
+				}
+			}
+			return sw.Error()
+		}),
+	},
+}
+
 var storeSyncCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Instruct the chain syncer to sync a specific chain head, going to network if required.",
@@ -172,3 +266,44 @@ var storeSyncCmd = &cmds.Command{
 		return GetPorcelainAPI(env).ChainSyncHandleNewTipSet(req.Context, ci, true)
 	},
 }
+
+var storeWaitHeightCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Wait for the chain to reach a height",
+		ShortDescription: `
+Blocks until the chain reaches the given height with at least --confidence rounds
+built on top of it, then prints the CIDs of the earliest tipset at or above that height.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("height", true, false, "Block height to wait for"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("confidence", "Number of rounds that must be built on top of the height").WithDefault(uint64(0)),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		height, ok := types.NewBlockHeightFromString(req.Arguments[0], 10)
+		if !ok {
+			return fmt.Errorf("invalid block height: %s", req.Arguments[0])
+		}
+		confidence, _ := req.Options["confidence"].(uint64)
+
+		ts, err := GetPorcelainAPI(env).ChainWaitForHeight(req.Context, height, confidence)
+		if err != nil {
+			return err
+		}
+		return re.Emit(ts.Key())
+	},
+	Type: []cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res []cid.Cid) error {
+			for _, r := range res {
+				_, err := fmt.Fprintln(w, r.String())
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
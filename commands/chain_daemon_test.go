@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/ipfs/go-cid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/fixtures"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
@@ -122,3 +124,64 @@ func TestChainLs(t *testing.T) {
 		assert.Contains(t, chainLsResult, `"height":"1"`)
 	})
 }
+
+func TestChainWaitHeight(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	blockCid, err := cid.Parse(d.RunSuccess("mining", "once", "--enc", "text").ReadStdoutTrimNewlines())
+	require.NoError(t, err)
+
+	result := d.RunSuccess("chain", "wait-height", "1", "--enc", "json").ReadStdoutTrimNewlines()
+
+	var cidsFromJSON []cid.Cid
+	require.NoError(t, json.Unmarshal([]byte(result), &cidsFromJSON))
+	assert.Equal(t, []cid.Cid{blockCid}, cidsFromJSON)
+}
+
+func TestChainGetTipSet(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	blockCid, err := cid.Parse(d.RunSuccess("mining", "once", "--enc", "text").ReadStdoutTrimNewlines())
+	require.NoError(t, err)
+
+	result := d.RunSuccess("chain", "get-tipset", "--height", "1", "--enc", "json").ReadStdoutTrimNewlines()
+	var cidsFromJSON []cid.Cid
+	require.NoError(t, json.Unmarshal([]byte(result), &cidsFromJSON))
+	assert.Equal(t, []cid.Cid{blockCid}, cidsFromJSON)
+
+	genesis := d.RunSuccess("chain", "get-tipset", "--height", "0").ReadStdoutTrimNewlines()
+	chainLs := d.RunSuccess("chain", "ls").ReadStdoutTrimNewlines()
+	assert.True(t, strings.HasSuffix(chainLs, genesis))
+
+	d.RunFail("above the chain head", "chain", "get-tipset", "--height", "2")
+	d.RunFail("must specify --height", "chain", "get-tipset")
+}
+
+func TestChainReplay(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	genesisCid := d.RunSuccess("chain", "head").ReadStdoutTrimNewlines()
+	blockCid, err := cid.Parse(d.RunSuccess("mining", "once", "--enc", "text").ReadStdoutTrimNewlines())
+	require.NoError(t, err)
+
+	result := d.RunSuccess("chain", "replay", blockCid.String(), "--enc", "json").ReadStdoutTrimNewlines()
+	var res chain.ReplayResult
+	require.NoError(t, json.Unmarshal([]byte(result), &res))
+	assert.True(t, res.Match)
+	assert.Equal(t, types.NewTipSetKey(blockCid), res.TipSetKey)
+	assert.Equal(t, res.StoredStateRoot, res.StateRoot)
+
+	text := d.RunSuccess("chain", "replay", blockCid.String()).ReadStdout()
+	assert.Contains(t, text, "OK")
+
+	d.RunFail("cannot replay the genesis tipset", "chain", "replay", genesisCid)
+}
@@ -113,6 +113,12 @@ Duration should be specified with the number of blocks for which to store the
 data. New blocks are generated about every 30 seconds, so the time given should
 be represented as a count of 30 second intervals. For example, 1 minute would
 be 2, 1 hour would be 120, and 1 day would be 2880.
+
+By default the miner fetches the data from this node. For large datasets the
+data can be delivered to the miner out of band instead, e.g. by shipping a
+drive, by passing --manual. The miner operator then imports the data with:
+
+$ go-filecoin deals import-data <proposal-cid> <file>
 `,
 	},
 	Arguments: []cmdkit.Argument{
@@ -123,9 +129,11 @@ be 2, 1 hour would be 120, and 1 day would be 2880.
 	},
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption("allow-duplicates", "Allows duplicate proposals to be created. Unless this flag is set, you will not be able to make more than one deal per piece per miner. This protection exists to prevent erroneous duplicate deals."),
+		cmdkit.BoolOption("manual", "Deliver the data to the miner out of band instead of having the miner fetch it from this node."),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		allowDuplicates, _ := req.Options["allow-duplicates"].(bool)
+		manual, _ := req.Options["manual"].(bool)
 
 		miner, err := address.NewFromString(req.Arguments[0])
 		if err != nil {
@@ -147,7 +155,7 @@ be 2, 1 hour would be 120, and 1 day would be 2880.
 			return err
 		}
 
-		resp, err := GetStorageAPI(env).ProposeStorageDeal(req.Context, data, miner, askid, duration, allowDuplicates)
+		resp, err := GetStorageAPI(env).ProposeStorageDeal(req.Context, data, miner, askid, duration, allowDuplicates, manual)
 		if err != nil {
 			return err
 		}
@@ -247,8 +255,15 @@ will be returned as a space separated table with miner, id, price and expiration
 respectively.
 `,
 	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		asksCh := GetPorcelainAPI(env).ClientListAsks(req.Context)
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+		asksCh := GetPorcelainAPI(env).ClientListAsksAt(req.Context, baseKey)
 
 		for a := range asksCh {
 			if a.Error != nil {
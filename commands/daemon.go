@@ -21,6 +21,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/clock"
 	"github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/explorer"
 	"github.com/filecoin-project/go-filecoin/node"
 	"github.com/filecoin-project/go-filecoin/paths"
 	"github.com/filecoin-project/go-filecoin/repo"
@@ -34,6 +35,7 @@ var daemonCmd = &cmds.Command{
 		cmdkit.StringOption(SwarmAddress, "multiaddress to listen on for filecoin network connections"),
 		cmdkit.StringOption(SwarmPublicRelayAddress, "public multiaddress for routing circuit relay traffic.  Necessary for relay nodes to provide this if they are not publically dialable"),
 		cmdkit.BoolOption(OfflineMode, "start the node without networking"),
+		cmdkit.BoolOption(FakeProofs, "seal and prove sectors with fakes and accept any proof, for networks in fake proofs mode"),
 		cmdkit.BoolOption(ELStdout),
 		cmdkit.BoolOption(IsRelay, "advertise and allow filecoin network traffic to be relayed through this node"),
 		cmdkit.StringOption(BlockTime, "time a node waits before trying to mine the next block").WithDefault(consensus.DefaultBlockTime.String()),
@@ -80,6 +82,10 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 		opts = append(opts, node.OfflineMode(offlineMode))
 	}
 
+	if fakeProofs, ok := req.Options[FakeProofs].(bool); ok {
+		opts = append(opts, node.FakeProofs(fakeProofs))
+	}
+
 	if isRelay, ok := req.Options[IsRelay].(bool); ok && isRelay {
 		opts = append(opts, node.IsRelay())
 	}
@@ -183,6 +189,12 @@ func RunAPIAndWait(ctx context.Context, nd *node.Node, config *config.APIConfig,
 	handler.Handle("/debug/pprof/", http.DefaultServeMux)
 	handler.Handle(APIPrefix+"/", cmdhttp.NewHandler(servenv, rootCmdDaemon, cfg))
 
+	explorerserv, err := runExplorer(nd)
+	if err != nil {
+		_ = apiListener.Close()
+		return errors.Wrap(err, "failed to start explorer API")
+	}
+
 	apiserv := http.Server{
 		Handler: handler,
 	}
@@ -194,9 +206,25 @@ func RunAPIAndWait(ctx context.Context, nd *node.Node, config *config.APIConfig,
 		}
 	}()
 
+	shutdown := func() {
+		// Allow a grace period for clean shutdown.
+		ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+
+		if err := apiserv.Shutdown(ctx); err != nil {
+			fmt.Println("Error shutting down API server:", err)
+		}
+		if explorerserv != nil {
+			if err := explorerserv.Shutdown(ctx); err != nil {
+				fmt.Println("Error shutting down explorer server:", err)
+			}
+		}
+	}
+
 	// Write the resolved API address to the repo
 	config.Address = apiListener.Multiaddr().String()
 	if err := nd.Repo.SetAPIAddr(config.Address); err != nil {
+		shutdown()
 		return errors.Wrap(err, "Could not save API address to repo")
 	}
 	// Signal that the sever has started and then wait for a signal to stop.
@@ -206,14 +234,43 @@ func RunAPIAndWait(ctx context.Context, nd *node.Node, config *config.APIConfig,
 		fmt.Println("Received signal", received)
 	}
 	fmt.Println("Shutting down...")
+	shutdown()
 
-	// Allow a grace period for clean shutdown.
-	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
-	defer cancel()
+	return nil
+}
 
-	if err := apiserv.Shutdown(ctx); err != nil {
-		fmt.Println("Error shutting down API server:", err)
+// runExplorer starts the read-only explorer API when it is enabled in the
+// node's config. It returns nil when the explorer is disabled.
+func runExplorer(nd *node.Node) (*http.Server, error) {
+	cfg := nd.Repo.Config().Explorer
+	if !cfg.Enabled {
+		return nil, nil
 	}
 
-	return nil
+	maddr, err := ma.NewMultiaddr(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := manet.Listen(maddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The explorer may be exposed publicly, so slow clients must not hold
+	// connections open.
+	server := &http.Server{
+		Handler:           explorer.NewServer(nd.PorcelainAPI),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+	go func() {
+		err := server.Serve(manet.NetListener(listener))
+		if err != nil && err != http.ErrServerClosed {
+			fmt.Println("Explorer API server stopped:", err)
+		}
+	}()
+	fmt.Println("Explorer API listening on", listener.Multiaddr())
+	return server, nil
 }
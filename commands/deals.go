@@ -2,12 +2,14 @@ package commands
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"strconv"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-cmdkit"
 	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-files"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/address"
@@ -25,9 +27,11 @@ var dealsCmd = &cmds.Command{
 		Tagline: "Manage and inspect deals made by or with this node",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"list":   dealsListCmd,
-		"redeem": dealsRedeemCmd,
-		"show":   dealsShowCmd,
+		"import-data": dealsImportDataCmd,
+		"list":        dealsListCmd,
+		"redeem":      dealsRedeemCmd,
+		"retry":       dealsRetryCmd,
+		"show":        dealsShowCmd,
 	},
 }
 
@@ -163,15 +167,74 @@ Redeem vouchers for FIL on the storage deal specified with the given deal CID.
 	},
 }
 
+var dealsRetryCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Retry a storage deal made with this miner",
+		ShortDescription: `
+Retries processing of the storage deal with the given proposal CID. A deal
+waiting to retry a transient failure is retried immediately and a failed deal
+is restarted from the beginning. Only deals made with this node's miner can be
+retried.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of the deal to retry"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		propcid, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid cid "+req.Arguments[0])
+		}
+
+		return GetStorageAPI(env).RetryDeal(req.Context, propcid)
+	},
+}
+
+var dealsImportDataCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Import the data of a manual storage deal made with this miner",
+		ShortDescription: `
+Imports the data of a storage deal whose client delivered it out of band, i.e.
+a deal proposed with client propose-storage-deal --manual. The data must match
+the piece CID and payment conditions of the deal's proposal. Once imported the
+deal's piece is staged and sealed like that of any other deal.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of the deal proposal"),
+		cmdkit.FileArg("file", true, false, "Path to the file holding the deal's data").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		propcid, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid cid "+req.Arguments[0])
+		}
+
+		iter := req.Files.Entries()
+		if !iter.Next() {
+			return fmt.Errorf("no file given: %s", iter.Err())
+		}
+
+		fi, ok := iter.Node().(files.File)
+		if !ok {
+			return fmt.Errorf("given file was not a files.File")
+		}
+
+		return GetStorageAPI(env).ImportDealData(req.Context, propcid, fi)
+	},
+}
+
 // DealsShowResult contains Deal output with Payment Vouchers.
 type DealsShowResult struct {
-	DealCID         cid.Cid                `json:"deal_cid"`
-	State           storagedeal.State      `json:"state"`
-	Miner           *address.Address       `json:"miner_address"`
-	Duration        uint64                 `json:"duration_blocks"`
-	Size            *types.BytesAmount     `json:"deal_size"`
-	TotalPrice      *types.AttoFIL         `json:"total_price"`
-	PaymentVouchers []*PaymenVoucherResult `json:"payment_vouchers"`
+	DealCID          cid.Cid                `json:"deal_cid"`
+	State            storagedeal.State      `json:"state"`
+	Miner            *address.Address       `json:"miner_address"`
+	Duration         uint64                 `json:"duration_blocks"`
+	Size             *types.BytesAmount     `json:"deal_size"`
+	TotalPrice       *types.AttoFIL         `json:"total_price"`
+	PaymentVouchers  []*PaymenVoucherResult `json:"payment_vouchers"`
+	Verified         bool                   `json:"verified"`
+	BytesTransferred uint64                 `json:"bytes_transferred"`
 }
 
 // PaymenVoucherResult is selected PaymentVoucher fields,
@@ -211,13 +274,15 @@ var dealsShowCmd = &cmds.Command{
 		}
 
 		out := &DealsShowResult{
-			DealCID:         deal.Response.ProposalCid,
-			State:           deal.Response.State,
-			Miner:           &deal.Miner,
-			Duration:        deal.Proposal.Duration,
-			Size:            deal.Proposal.Size,
-			TotalPrice:      &deal.Proposal.TotalPrice,
-			PaymentVouchers: vouchers,
+			DealCID:          deal.Response.ProposalCid,
+			State:            deal.Response.State,
+			Miner:            &deal.Miner,
+			Duration:         deal.Proposal.Duration,
+			Size:             deal.Proposal.Size,
+			TotalPrice:       &deal.Proposal.TotalPrice,
+			PaymentVouchers:  vouchers,
+			Verified:         deal.Verified,
+			BytesTransferred: deal.BytesTransferred,
 		}
 
 		if err := re.Emit(out); err != nil {
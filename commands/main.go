@@ -37,6 +37,9 @@ const (
 	// OfflineMode tells us if we should try to connect this Filecoin node to the network
 	OfflineMode = "offline"
 
+	// FakeProofs tells the daemon to seal and prove with fakes and accept any proof.
+	FakeProofs = "fake-proofs"
+
 	// ELStdout tells the daemon to write event logs to stdout.
 	ELStdout = "elstdout"
 
@@ -102,12 +105,14 @@ STORE AND RETRIEVE DATA
 MINE
   go-filecoin miner                  - Manage a single miner actor
   go-filecoin mining                 - Manage all mining operations for a node
+  go-filecoin seal-worker <miner>    - Seal sectors for a mining node
 
 VIEW DATA STRUCTURES
   go-filecoin chain                  - Inspect the filecoin blockchain
   go-filecoin dag                    - Interact with IPLD DAG objects
   go-filecoin deals                  - Manage deals made by or with this node
   go-filecoin show                   - Get human-readable representations of filecoin objects
+  go-filecoin state                  - Inspect state trees
 
 NETWORK COMMANDS
   go-filecoin bitswap                - Explore libp2p bitswap
@@ -152,10 +157,11 @@ var rootCmdDaemon = &cmds.Command{
 
 // all top level commands, not available to daemon
 var rootSubcmdsLocal = map[string]*cmds.Command{
-	"daemon":  daemonCmd,
-	"init":    initCmd,
-	"version": versionCmd,
-	"leb128":  leb128Cmd,
+	"daemon":      daemonCmd,
+	"init":        initCmd,
+	"version":     versionCmd,
+	"leb128":      leb128Cmd,
+	"seal-worker": sealWorkerCmd,
 }
 
 // all top level commands, available on daemon. set during init() to avoid configuration loops.
@@ -184,6 +190,7 @@ var rootSubcmdsDaemon = map[string]*cmds.Command{
 	"protocol":         protocolCmd,
 	"retrieval-client": retrievalClientCmd,
 	"show":             showCmd,
+	"state":            stateCmd,
 	"stats":            statsCmd,
 	"swarm":            swarmCmd,
 	"wallet":           walletCmd,
@@ -305,12 +312,34 @@ func getAPIAddress(req *cmds.Request) (string, error) {
 	return host, nil
 }
 
+// subcommands of daemon commands that run without a daemon
+var localSubcmdPaths = [][]string{
+	{"message", "sign"},
+}
+
 func requiresDaemon(req *cmds.Request) bool {
 	for cmd := range rootSubcmdsLocal {
 		if len(req.Path) > 0 && req.Path[0] == cmd {
 			return false
 		}
 	}
+	for _, path := range localSubcmdPaths {
+		if hasPathPrefix(req.Path, path) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
 	return true
 }
 
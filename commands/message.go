@@ -5,21 +5,29 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-cmdkit"
 	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-files"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/message"
+	"github.com/filecoin-project/go-filecoin/paths"
 	"github.com/filecoin-project/go-filecoin/plumbing/cst"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+	"github.com/filecoin-project/go-filecoin/wallet"
 )
 
 var msgCmd = &cmds.Command{
@@ -27,8 +35,12 @@ var msgCmd = &cmds.Command{
 		Tagline: "Send and monitor messages",
 	},
 	Subcommands: map[string]*cmds.Command{
+		"create": msgCreateCmd,
 		"send":   msgSendCmd,
+		"sign":   msgSignCmd,
 		"status": msgStatusCmd,
+		"submit": msgSubmitCmd,
+		"trace":  msgTraceCmd,
 		"wait":   msgWaitCmd,
 	},
 }
@@ -38,6 +50,7 @@ type MessageSendResult struct {
 	Cid     cid.Cid
 	GasUsed types.GasUnits
 	Preview bool
+	Trace   *vm.Trace `json:",omitempty"`
 }
 
 var msgSendCmd = &cmds.Command{
@@ -47,6 +60,7 @@ var msgSendCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("target", true, false, "Address of the actor to send the message to"),
 		cmdkit.StringArg("method", false, false, "The method to invoke on the target actor"),
+		cmdkit.StringArg("params", false, true, "Parameters of the method, as strings or JSON matching its signature"),
 	},
 	Options: []cmdkit.Option{
 		cmdkit.StringOption("value", "Value to send with message in FIL"),
@@ -54,7 +68,8 @@ var msgSendCmd = &cmds.Command{
 		priceOption,
 		limitOption,
 		previewOption,
-		// TODO: (per dignifiedquire) add an option to set the nonce and method explicitly
+		cmdkit.BoolOption("trace", "With --preview, show the sends executed by the message"),
+		// TODO: (per dignifiedquire) add an option to set the nonce explicitly
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		target, err := address.NewFromString(req.Arguments[0])
@@ -81,9 +96,37 @@ var msgSendCmd = &cmds.Command{
 			return err
 		}
 
-		method, ok := req.Options["method"].(string)
-		if !ok {
-			method = ""
+		method := ""
+		if len(req.Arguments) > 1 {
+			method = req.Arguments[1]
+		}
+
+		var args []string
+		if len(req.Arguments) > 2 {
+			args = req.Arguments[2:]
+		}
+		params, err := GetPorcelainAPI(env).ActorParseParams(req.Context, target, method, args)
+		if err != nil {
+			return err
+		}
+
+		if preview && req.Options["trace"] == true {
+			trace, err := GetPorcelainAPI(env).MessagePreviewTrace(
+				req.Context,
+				fromAddr,
+				target,
+				method,
+				params...,
+			)
+			if err != nil {
+				return err
+			}
+			return re.Emit(&MessageSendResult{
+				Cid:     cid.Cid{},
+				GasUsed: trace.GasUsed,
+				Preview: true,
+				Trace:   trace,
+			})
 		}
 
 		if preview {
@@ -92,6 +135,7 @@ var msgSendCmd = &cmds.Command{
 				fromAddr,
 				target,
 				method,
+				params...,
 			)
 			if err != nil {
 				return err
@@ -111,6 +155,7 @@ var msgSendCmd = &cmds.Command{
 			gasPrice,
 			gasLimit,
 			method,
+			params...,
 		)
 		if err != nil {
 			return err
@@ -128,13 +173,71 @@ var msgSendCmd = &cmds.Command{
 			if res.Preview {
 				output := strconv.FormatUint(uint64(res.GasUsed), 10)
 				_, err := w.Write([]byte(output))
-				return err
+				if err != nil || res.Trace == nil {
+					return err
+				}
+				sw := NewSilentWriter(w)
+				sw.Println()
+				printTrace(sw, res.Trace, 0)
+				return sw.Error()
 			}
 			return PrintString(w, res.Cid)
 		}),
 	},
 }
 
+var msgTraceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the sends executed by a message on chain",
+		ShortDescription: `
+Prints the execution trace of a message on chain: the send of the message and,
+indented below it, the sends made by actors while handling it, with the gas
+each used and its exit code. Unless the node persisted the trace as the message
+joined the chain (see the observability.executionTraces.persist config), the
+message is replayed on the state it was applied to.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of the message to trace"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		msgCid, err := cid.Parse(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid message cid")
+		}
+
+		trace, err := GetPorcelainAPI(env).MessageTrace(req.Context, msgCid)
+		if err != nil {
+			return err
+		}
+		return re.Emit(trace)
+	},
+	Type: vm.Trace{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, trace *vm.Trace) error {
+			sw := NewSilentWriter(w)
+			printTrace(sw, trace, 0)
+			return sw.Error()
+		}),
+	},
+}
+
+// printTrace prints a send and, indented, the sends it made.
+func printTrace(sw *SilentWriter, trace *vm.Trace, depth int) {
+	method := trace.Method
+	if method == "" {
+		method = "<transfer>"
+	}
+	sw.Printf("%s%s -> %s %s value=%s gas=%d exit=%d", strings.Repeat("  ", depth), trace.From, trace.To, method, trace.Value, trace.GasUsed, trace.ExitCode)
+	if trace.Error != "" {
+		sw.Printf(" error=%q", trace.Error)
+	}
+	sw.Println()
+	for _, call := range trace.Calls {
+		printTrace(sw, call, depth+1)
+	}
+}
+
 // WaitResult is the result of a message wait call.
 type WaitResult struct {
 	Message   *types.SignedMessage
@@ -319,3 +422,217 @@ func appendJSON(val interface{}, out []byte) ([]byte, error) {
 	out = append(out, byte('\n'))
 	return out, nil
 }
+
+var msgCreateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Create an unsigned message",
+		ShortDescription: `
+Builds a message without signing or sending it, and prints it as JSON. The
+message can be signed with 'message sign' on a machine holding the key of the
+sender, and sent with 'message submit'. Without --nonce the nonce following
+the sender's messages known to the node is used.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("target", true, false, "Address of the actor to send the message to"),
+		cmdkit.StringArg("method", false, false, "The method to invoke on the target actor"),
+		cmdkit.StringArg("params", false, true, "Parameters of the method, as strings or JSON matching its signature"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("value", "Value to send with message in FIL"),
+		cmdkit.StringOption("from", "Address to send message from"),
+		cmdkit.Uint64Option("nonce", "Nonce of the message"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		target, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		rawVal, _ := req.Options["value"].(string)
+		if rawVal == "" {
+			rawVal = "0"
+		}
+		val, ok := types.NewAttoFILFromFILString(rawVal)
+		if !ok {
+			return errors.New("mal-formed value")
+		}
+
+		fromAddr, err := fromAddrOrDefault(req, env)
+		if err != nil {
+			return err
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		method := ""
+		if len(req.Arguments) > 1 {
+			method = req.Arguments[1]
+		}
+
+		var args []string
+		if len(req.Arguments) > 2 {
+			args = req.Arguments[2:]
+		}
+		params, err := GetPorcelainAPI(env).ActorParseParams(req.Context, target, method, args)
+		if err != nil {
+			return err
+		}
+
+		nonce, ok := req.Options["nonce"].(uint64)
+		if !ok {
+			nonce, err = GetPorcelainAPI(env).MessageNextNonce(req.Context, fromAddr)
+			if err != nil {
+				return err
+			}
+		}
+
+		unsigned, err := GetPorcelainAPI(env).MessageCreate(fromAddr, target, nonce, val, gasPrice, gasLimit, method, params...)
+		if err != nil {
+			return err
+		}
+		return re.Emit(unsigned)
+	},
+	Type: types.MeteredMessage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, unsigned *types.MeteredMessage) error {
+			return json.NewEncoder(w).Encode(unsigned)
+		}),
+	},
+}
+
+var msgSignCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Sign a message created with 'message create'",
+		ShortDescription: `
+Signs a message with the key of its sender and prints the signed message as
+JSON. This command runs without a daemon. The key is read from the wallet of
+the repo, which must not be in use by a running daemon, or from a file written
+by 'wallet export' given with --keyfile.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("message", true, false, "File containing the message to sign").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("keyfile", "File containing the key of the sender, as written by 'wallet export'"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var unsigned types.MeteredMessage
+		if err := decodeFileArg(req, &unsigned); err != nil {
+			return errors.Wrap(err, "invalid message")
+		}
+
+		w, closeWallet, err := localWallet(req)
+		if err != nil {
+			return err
+		}
+		defer closeWallet() // nolint: errcheck
+
+		signed, err := porcelain.MessageSign(w, &unsigned)
+		if err != nil {
+			return err
+		}
+		return re.Emit(signed)
+	},
+	Type: types.SignedMessage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, signed *types.SignedMessage) error {
+			return json.NewEncoder(w).Encode(signed)
+		}),
+	},
+}
+
+var msgSubmitCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Send a message signed with 'message sign'",
+		ShortDescription: `
+Validates a signed message, adds it to the message pool and broadcasts it to
+the network. Prints the CID of the message.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("message", true, false, "File containing the signed message").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var signed types.SignedMessage
+		if err := decodeFileArg(req, &signed); err != nil {
+			return errors.Wrap(err, "invalid message")
+		}
+
+		c, err := GetPorcelainAPI(env).MessageSubmit(req.Context, &signed)
+		if err != nil {
+			return err
+		}
+		return re.Emit(c)
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			return PrintString(w, c)
+		}),
+	},
+}
+
+// decodeFileArg decodes the JSON in the first file argument into v.
+func decodeFileArg(req *cmds.Request, v interface{}) error {
+	iter := req.Files.Entries()
+	if !iter.Next() {
+		return fmt.Errorf("no file given: %s", iter.Err())
+	}
+
+	fi, ok := iter.Node().(files.File)
+	if !ok {
+		return fmt.Errorf("given file was not a files.File")
+	}
+	return json.NewDecoder(fi).Decode(v)
+}
+
+// localWallet opens the wallet of the key file given with --keyfile, or of
+// the repo. The returned function releases the repo.
+func localWallet(req *cmds.Request) (*wallet.Wallet, func() error, error) {
+	if keyFile, _ := req.Options["keyfile"].(string); keyFile != "" {
+		f, err := os.Open(keyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close() // nolint: errcheck
+
+		var wsr WalletSerializeResult
+		if err := json.NewDecoder(f).Decode(&wsr); err != nil {
+			return nil, nil, errors.Wrap(err, "invalid key file")
+		}
+
+		backend, err := wallet.NewDSBackend(repo.NewInMemoryRepo().WalletDatastore())
+		if err != nil {
+			return nil, nil, err
+		}
+		w := wallet.New(backend)
+		if _, err := w.Import(wsr.KeyInfo...); err != nil {
+			return nil, nil, err
+		}
+		return w, func() error { return nil }, nil
+	}
+
+	repoDir, _ := req.Options[OptionRepoDir].(string)
+	repoDir, err := paths.GetRepoPath(repoDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	rep, err := repo.OpenFSRepo(repoDir, repo.Version)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to open repo, stop the daemon using it or use --keyfile")
+	}
+
+	backend, err := wallet.NewDSBackend(rep.WalletDatastore())
+	if err != nil {
+		rep.Close() // nolint: errcheck
+		return nil, nil, err
+	}
+	return wallet.New(backend), rep.Close, nil
+}
@@ -2,6 +2,7 @@ package commands_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 	"testing"
@@ -188,3 +189,70 @@ func TestMessageStatus(t *testing.T) {
 		assert.NotContains(t, status, "On chain")
 	})
 }
+
+func TestMessageCreateSignSubmit(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	unsigned := d.RunSuccess("message", "create",
+		"--from", fixtures.TestAddresses[0],
+		"--gas-price", "1", "--gas-limit", "300",
+		"--value", "10",
+		fixtures.TestAddresses[1],
+	).ReadStdout()
+
+	t.Run("rejects an unsigned message", func(t *testing.T) {
+		d.RunWithStdin(strings.NewReader(unsigned), "message", "submit").AssertFail("signature")
+	})
+
+	signed := d.RunWithStdin(strings.NewReader(unsigned),
+		"message", "sign", "--keyfile", fixtures.KeyFilePaths()[0],
+	).AssertSuccess().ReadStdout()
+
+	var msg types.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(signed), &msg))
+	assert.Equal(t, fixtures.TestAddresses[1], msg.To.String())
+
+	msgCid := d.RunWithStdin(strings.NewReader(signed), "message", "submit").AssertSuccess().ReadStdoutTrimNewlines()
+	expected, err := msg.Cid()
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), msgCid)
+
+	d.RunSuccess("mining", "once")
+	d.RunSuccess("message", "wait", "--timeout=1m", msgCid)
+}
+
+func TestMessageTrace(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	t.Run("traces a preview", func(t *testing.T) {
+		out := d.RunSuccess("message", "send",
+			"--from", fixtures.TestAddresses[0],
+			"--preview", "--trace",
+			fixtures.TestMiners[0], "getOwner",
+		).ReadStdout()
+		assert.Contains(t, out, fmt.Sprintf("%s -> %s getOwner", fixtures.TestAddresses[0], fixtures.TestMiners[0]))
+	})
+
+	t.Run("replays a message on chain", func(t *testing.T) {
+		msgCid := d.RunSuccess("message", "send",
+			"--from", fixtures.TestAddresses[0],
+			"--gas-price", "1", "--gas-limit", "300",
+			fixtures.TestMiners[0], "changeWorker", fixtures.TestAddresses[1],
+		).ReadStdoutTrimNewlines()
+		d.RunSuccess("mining", "once")
+
+		out := d.RunSuccess("message", "trace", msgCid).ReadStdout()
+		assert.Contains(t, out, fmt.Sprintf("%s -> %s changeWorker", fixtures.TestAddresses[0], fixtures.TestMiners[0]))
+		assert.Contains(t, out, "exit=0")
+	})
+
+	t.Run("fails for a message not on chain", func(t *testing.T) {
+		d.RunFail("not found on chain", "message", "trace", types.CidFromString(t, "nope").String())
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"math/big"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-cmdkit"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -24,11 +26,20 @@ var minerCmd = &cmds.Command{
 	},
 	Subcommands: map[string]*cmds.Command{
 		"create":         minerCreateCmd,
+		"faults":         minerFaultsCmd,
 		"owner":          minerOwnerCmd,
+		"post-status":    minerPoStStatusCmd,
 		"power":          minerPowerCmd,
 		"set-price":      minerSetPriceCmd,
 		"update-peerid":  minerUpdatePeerIDCmd,
 		"collateral":     minerCollateralCmd,
+		"balance":        minerBalanceCmd,
+		"pledge":         minerPledgeCmd,
+		"withdraw":       minerWithdrawCmd,
+		"change-owner":   minerChangeOwnerCmd,
+		"accept-owner":   minerAcceptOwnerCmd,
+		"terminate":      minerTerminateCmd,
+		"refund":         minerRefundCollateralCmd,
 		"proving-window": minerProvingWindowCmd,
 		"set-worker":     minerSetWorkerAddressCmd,
 		"worker":         minerWorkerAddressCmd,
@@ -371,15 +382,26 @@ var minerUpdatePeerIDCmd = &cmds.Command{
 
 var minerOwnerCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
-		Tagline:          "Show the actor address of <miner>",
-		ShortDescription: `Given <miner> miner address, output the address of the actor that owns the miner.`,
+		Tagline: "Show the actor address of <miner>",
+		ShortDescription: `Given <miner> miner address, output the address of the actor that owns the miner.
+With --proposed, output the address the owner proposed to hand the miner over
+to instead, which is empty if no change of owner is pending.`,
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		minerAddr, err := optionalAddr(req.Arguments[0])
 		if err != nil {
 			return err
 		}
-		ownerAddr, err := GetPorcelainAPI(env).MinerGetOwnerAddress(req.Context, minerAddr)
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+		var ownerAddr address.Address
+		if proposed, _ := req.Options["proposed"].(bool); proposed {
+			ownerAddr, err = GetPorcelainAPI(env).MinerGetProposedOwnerAt(req.Context, minerAddr, baseKey)
+		} else {
+			ownerAddr, err = GetPorcelainAPI(env).MinerGetOwnerAddressAt(req.Context, minerAddr, baseKey)
+		}
 		if err != nil {
 			return err
 		}
@@ -389,6 +411,10 @@ var minerOwnerCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("miner", true, false, "The address of the miner"),
 	},
+	Options: []cmdkit.Option{
+		atOption,
+		cmdkit.BoolOption("proposed", "Show the proposed new owner of the miner"),
+	},
 	Type: address.Address{},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, a *address.Address) error {
@@ -409,7 +435,11 @@ Values will be output as a ratio where the first number is the miner power and s
 			return err
 		}
 
-		minerPower, err := GetPorcelainAPI(env).MinerGetPower(req.Context, minerAddr)
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+		minerPower, err := GetPorcelainAPI(env).MinerGetPowerAt(req.Context, minerAddr, baseKey)
 		if err != nil {
 			return err
 		}
@@ -419,6 +449,9 @@ Values will be output as a ratio where the first number is the miner power and s
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("miner", true, false, "The address of the miner"),
 	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *porcelain.MinerPower) error {
 			outStr := fmt.Sprintf("%s / %s", out.Power.String(), out.Total.String())
@@ -438,7 +471,11 @@ var minerCollateralCmd = &cmds.Command{
 		if err != nil {
 			return err
 		}
-		collateral, err := GetPorcelainAPI(env).MinerGetCollateral(req.Context, minerAddr)
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+		collateral, err := GetPorcelainAPI(env).MinerGetCollateralAt(req.Context, minerAddr, baseKey)
 		if err != nil {
 			return err
 		}
@@ -447,6 +484,9 @@ var minerCollateralCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("miner", true, false, "The address of the miner"),
 	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Type: types.AttoFIL{},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, af types.AttoFIL) error {
@@ -455,18 +495,287 @@ var minerCollateralCmd = &cmds.Command{
 	},
 }
 
+var minerBalanceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the balance of a miner broken down by what it is held for",
+		ShortDescription: `Shows the balance of a miner actor, the collateral pledged for its storage,
+the collateral owed for its slashed storage and the balance available for
+withdrawal by its owner. Values reported in FIL.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "The address of the miner"),
+	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := optionalAddr(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+		balance, err := GetPorcelainAPI(env).MinerGetBalanceAt(req.Context, minerAddr, baseKey)
+		if err != nil {
+			return err
+		}
+		return re.Emit(&balance)
+	},
+	Type: porcelain.MinerBalance{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, balance *porcelain.MinerBalance) error {
+			_, err := fmt.Fprintf(w, "Total:     %s\nPledged:   %s\nOwed:      %s\nAvailable: %s\n",
+				balance.Total, balance.Pledged, balance.Owed, balance.Available)
+			return err
+		}),
+	},
+}
+
+var minerPledgeCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Pledge <amount> FIL from the miner owner as collateral. Returns a message CID",
+		ShortDescription: `Sends <amount> FIL from the owner of the node's miner to the miner actor,
+where it is available as collateral for committing more sectors.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("amount", true, false, "The amount to pledge, in FIL"),
+	},
+	Options: []cmdkit.Option{
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		amount, ok := types.NewAttoFILFromFILString(req.Arguments[0])
+		if !ok {
+			return ErrInvalidAmount
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		msgCid, err := GetPorcelainAPI(env).MinerPledge(req.Context, amount, gasPrice, gasLimit)
+		if err != nil {
+			return err
+		}
+		return re.Emit(msgCid)
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			return PrintString(w, c)
+		}),
+	},
+}
+
+var minerWithdrawCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Withdraw <amount> FIL from the miner to its owner. Returns a message CID",
+		ShortDescription: `Sends <amount> FIL from the node's miner actor to its owner. Only the balance
+above the pledge collateral requirement and the collateral owed for slashed
+storage may be withdrawn, see 'go-filecoin miner balance'.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("amount", true, false, "The amount to withdraw, in FIL"),
+	},
+	Options: []cmdkit.Option{
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		amount, ok := types.NewAttoFILFromFILString(req.Arguments[0])
+		if !ok {
+			return ErrInvalidAmount
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		msgCid, err := GetPorcelainAPI(env).MinerWithdraw(req.Context, amount, gasPrice, gasLimit)
+		if err != nil {
+			return err
+		}
+		return re.Emit(msgCid)
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			return PrintString(w, c)
+		}),
+	},
+}
+
+var minerChangeOwnerCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Propose <new-owner> as the owner of the miner. Returns a message CID",
+		ShortDescription: `Proposes to hand the node's miner over to <new-owner>. The owner does not
+change until <new-owner> accepts with 'go-filecoin miner accept-owner'.
+Proposing again replaces the previous proposal.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("new-owner", true, false, "The address of the proposed owner"),
+	},
+	Options: []cmdkit.Option{
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		newOwner, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		msgCid, err := GetPorcelainAPI(env).MinerChangeOwner(req.Context, newOwner, gasPrice, gasLimit)
+		if err != nil {
+			return err
+		}
+		return re.Emit(msgCid)
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			return PrintString(w, c)
+		}),
+	},
+}
+
+var minerAcceptOwnerCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Accept the ownership of <miner>. Returns a message CID",
+		ShortDescription: `Makes the sending address the owner of <miner>. The current owner must have
+proposed the address with 'go-filecoin miner change-owner'.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "The address of the miner"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address accepting the ownership, defaults to the wallet default address"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		fromAddr, err := fromAddrOrDefault(req, env)
+		if err != nil {
+			return err
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		msgCid, err := GetPorcelainAPI(env).MinerAcceptOwner(req.Context, fromAddr, minerAddr, gasPrice, gasLimit)
+		if err != nil {
+			return err
+		}
+		return re.Emit(msgCid)
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			return PrintString(w, c)
+		}),
+	},
+}
+
+var minerTerminateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Terminate the miner. Returns a message CID",
+		ShortDescription: `Shuts the node's miner down for good. It accepts no new commitments or asks,
+but must still submit the PoSt of its current proving period and is slashed
+if it does not. That PoSt removes its sectors and power from the network. The
+collateral of its sectors is held for a penalty window of one proving period
+after termination, after which 'go-filecoin miner refund' returns it to the
+owner. Termination is rejected while a PoSt is overdue.`,
+	},
+	Options: []cmdkit.Option{
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		msgCid, err := GetPorcelainAPI(env).MinerTerminate(req.Context, gasPrice, gasLimit)
+		if err != nil {
+			return err
+		}
+		return re.Emit(msgCid)
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			return PrintString(w, c)
+		}),
+	},
+}
+
+var minerRefundCollateralCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Return the collateral of the terminated miner to its owner. Returns a message CID",
+		ShortDescription: `Sends the balance of the node's terminated miner not owed for slashed storage
+to its owner, once the penalty window after termination has passed.`,
+	},
+	Options: []cmdkit.Option{
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		msgCid, err := GetPorcelainAPI(env).MinerRefundCollateral(req.Context, gasPrice, gasLimit)
+		if err != nil {
+			return err
+		}
+		return re.Emit(msgCid)
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			return PrintString(w, c)
+		}),
+	},
+}
+
 var minerProvingWindowCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("miner", true, false, "Miner address to get proving window for"),
 	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		// Get the Miner Address
 		minerAddress, err := address.NewFromString(req.Arguments[0])
 		if err != nil {
 			return err
 		}
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
 
-		mpp, err := GetPorcelainAPI(env).MinerGetProvingWindow(req.Context, minerAddress)
+		mpp, err := GetPorcelainAPI(env).MinerGetProvingWindowAt(req.Context, minerAddress, baseKey)
 		if err != nil {
 			return err
 		}
@@ -542,6 +851,9 @@ var minerWorkerAddressCmd = &cmds.Command{
 		Tagline:          "Show the address of the miner worker",
 		ShortDescription: "Show the address of the miner worker",
 	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		ret, err := GetPorcelainAPI(env).ConfigGet("mining.minerAddress")
 		if err != nil {
@@ -551,7 +863,11 @@ var minerWorkerAddressCmd = &cmds.Command{
 		if !ok {
 			return errors.New("problem converting miner address")
 		}
-		workerAddr, err := GetPorcelainAPI(env).MinerGetWorkerAddress(req.Context, minerAddr, GetPorcelainAPI(env).ChainHeadKey())
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+		workerAddr, err := GetPorcelainAPI(env).MinerGetWorkerAddress(req.Context, minerAddr, baseKey)
 		if err != nil {
 			return errors.Wrap(err, "problem getting worker address")
 		}
@@ -568,3 +884,93 @@ var minerWorkerAddressCmd = &cmds.Command{
 		}),
 	},
 }
+
+var minerFaultsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the unhealthy sectors found by sector health checks",
+		ShortDescription: `
+The miner periodically checks that its sealed sectors can still be read and
+declares the unhealthy ones faulty, both with an addFaults message and in the
+fault set of its next PoSt. Faults are listed until the PoSt removes their
+sectors from the proving set.
+
+Periodic checks are quick: they check that each sector's sealed replica is
+still stored with the size it was sealed with, and read a small sample of it.
+A deep check also reads back part of every sector, unsealing it, which takes
+much longer.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("check", "Check the sectors before listing faults"),
+		cmdkit.BoolOption("deep", "Deeply check the sectors, reading back their data, before listing faults"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		check, _ := req.Options["check"].(bool)
+		deep, _ := req.Options["deep"].(bool)
+		faults, err := GetStorageAPI(env).SectorFaults(req.Context, check, deep)
+		if err != nil {
+			return err
+		}
+		return re.Emit(faults)
+	},
+	Type: []storage.SectorFault{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, faults []storage.SectorFault) error {
+			for _, fault := range faults {
+				declared := "not declared"
+				if fault.DeclaredIn.Defined() {
+					declared = "declared in " + fault.DeclaredIn.String()
+				}
+				_, err := fmt.Fprintf(w, "sector %d: %s (detected %s, %s)\n", fault.SectorID, fault.Error, fault.DetectedAt.Format(time.RFC3339), declared)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var minerPoStStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the progress of the miner's PoSt of its latest proving period",
+		ShortDescription: `
+Shows how far the storage miner got proving its latest proving period: whether
+the proof is computed, the submitPoSt messages sent with their receipts once
+they are mined, and the last failure to compute or send the proof.
+
+A message that fails is sent again with a newly computed proof. A message that
+leaves the outbound queue without being mined is sent again at a higher gas
+price.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		status, err := GetStorageAPI(env).PoStStatus(req.Context)
+		if err != nil {
+			return err
+		}
+		if status == nil {
+			return errors.New("the miner has not started proving a proving period")
+		}
+		return re.Emit(status)
+	},
+	Type: storage.PoStStatus{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, status *storage.PoStStatus) error {
+			sw := NewSilentWriter(w)
+			sw.Printf("Proving period: %s to %s\n", status.Start, status.End)
+			sw.Printf("Stage:          %s\n", status.Stage)
+			for _, msg := range status.Messages {
+				receipt := "not mined"
+				if msg.Receipt != nil {
+					receipt = fmt.Sprintf("mined, exit code %d", msg.Receipt.ExitCode)
+				}
+				sw.Printf("Message:        %s (sent at %s, gas price %s, %s)\n", msg.Cid, msg.SentAt, msg.GasPrice, receipt)
+			}
+			if status.Error != "" {
+				sw.Printf("Error:          %s\n", status.Error)
+			}
+			return sw.Error()
+		}),
+	},
+}
@@ -11,7 +11,9 @@ import (
 	files "github.com/ipfs/go-ipfs-files"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/mining"
 	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -20,14 +22,16 @@ var miningCmd = &cmds.Command{
 		Tagline: "Manage all mining operations for a node",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"address":   miningAddrCmd,
-		"once":      miningOnceCmd,
-		"start":     miningStartCmd,
-		"status":    miningStatusCmd,
-		"stop":      miningStopCmd,
-		"setup":     miningSetupCmd,
-		"seal-now":  miningSealCmd,
-		"add-piece": miningAddPieceCmd,
+		"address":        miningAddrCmd,
+		"once":           miningOnceCmd,
+		"start":          miningStartCmd,
+		"status":         miningStatusCmd,
+		"stop":           miningStopCmd,
+		"setup":          miningSetupCmd,
+		"seal-now":       miningSealCmd,
+		"sealing-status": miningSealingStatusCmd,
+		"add-piece":      miningAddPieceCmd,
+		"storage":        miningStorageCmd,
 	},
 }
 
@@ -102,6 +106,7 @@ var miningStartCmd = &cmds.Command{
 // MiningStatusResult is the type returned when get mining status.
 type MiningStatusResult struct {
 	Active        bool                         `json:"active"`
+	Status        mining.Status                `json:"status"`
 	Miner         address.Address              `json:"minerAddress"`
 	Owner         address.Address              `json:"owner"`
 	Collateral    types.AttoFIL                `json:"collateral"`
@@ -115,6 +120,7 @@ var miningStatusCmd = &cmds.Command{
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		isMining := GetBlockAPI(env).MiningIsActive()
+		status := GetBlockAPI(env).MiningStatus()
 
 		// Get the Miner Address
 		minerAddress, err := GetBlockAPI(env).MinerAddress()
@@ -144,6 +150,7 @@ var miningStatusCmd = &cmds.Command{
 
 		return re.Emit(&MiningStatusResult{
 			Active:        isMining,
+			Status:        status,
 			Miner:         minerAddress,
 			Owner:         owner,
 			Collateral:    collateral,
@@ -158,8 +165,13 @@ var miningStatusCmd = &cmds.Command{
 			for p := range res.ProvingPeriod.ProvingSet {
 				pSet = append(pSet, p)
 			}
+			state := string(res.Status.State)
+			if res.Status.Reason != "" {
+				state = fmt.Sprintf("%s (%s)", state, res.Status.Reason)
+			}
 			_, err := fmt.Fprintf(w, `Mining Status
 Active:     %s
+State:      %s
 Address:    %s
 Owner:      %s
 Collateral: %s
@@ -171,6 +183,7 @@ End:           %s
 Proving Set:   %s
 
 `, strconv.FormatBool(res.Active),
+				state,
 				res.Miner.String(),
 				res.Owner.String(),
 				res.Collateral.String(),
@@ -208,6 +221,42 @@ var miningSealCmd = &cmds.Command{
 	Encoders: stringEncoderMap,
 }
 
+var miningSealingStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the sectors waiting to be sealed or being sealed",
+		ShortDescription: `
+Lists every sector that has not finished sealing. Sealing sectors are listed
+first, followed by queued sectors in the order they will be sealed and then
+sectors that are still accepting pieces. Priorities only apply to remote
+sealing, where each seal worker takes the queued sector with the lowest
+priority; the local sector builder seals all staged sectors at once.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		statuses, err := GetPorcelainAPI(env).SealingStatus(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(statuses)
+	},
+	Type: []sectorbuilder.SectorSealingStatus{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, statuses []sectorbuilder.SectorSealingStatus) error {
+			for _, status := range statuses {
+				priority := "none"
+				if status.Priority != sectorbuilder.DefaultSealPriority {
+					priority = strconv.FormatUint(status.Priority, 10)
+				}
+				_, err := fmt.Fprintf(w, "sector %d: %s, priority %s, %d pieces\n", status.SectorID, status.State, priority, status.Pieces)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
 var stringEncoderMap = cmds.EncoderMap{
 	cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, t string) error {
 		fmt.Fprintln(w, t) // nolint: errcheck
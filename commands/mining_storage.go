@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	"github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorstorage"
+)
+
+var miningStorageCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage the paths sector data is stored in",
+		ShortDescription: `
+Sector data may be stored in several named paths. The sector base root
+directory is always available as the path named "default". When the sector
+builder starts it stores new staged and sealed sectors in the paths allowing
+them with the most free space, scaled by their weight.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add":     miningStorageAddCmd,
+		"usage":   miningStorageUsageCmd,
+		"sectors": miningStorageSectorsCmd,
+		"move":    miningStorageMoveCmd,
+	},
+}
+
+var miningStorageAddCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Add a path sector data may be stored in",
+		ShortDescription: `
+Creates the staging and sealed directories of the path and records it in the
+config. The sector builder considers the path the next time it starts.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("name", true, false, "Name of the path"),
+		cmdkit.StringArg("path", true, false, "Directory to store sector data in"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("max-bytes", "Maximum number of bytes of sector data to store in the path, 0 for no limit").WithDefault(uint64(0)),
+		cmdkit.Uint64Option("weight", "Weight of the path when choosing where to store sectors").WithDefault(uint64(1)),
+		cmdkit.BoolOption("staging", "Store staged sectors in the path").WithDefault(true),
+		cmdkit.BoolOption("sealed", "Store sealed sectors in the path").WithDefault(true),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		p := config.StoragePathConfig{
+			Name:     req.Arguments[0],
+			Path:     req.Arguments[1],
+			MaxBytes: req.Options["max-bytes"].(uint64),
+			Weight:   req.Options["weight"].(uint64),
+			Staging:  req.Options["staging"].(bool),
+			Sealed:   req.Options["sealed"].(bool),
+		}
+		return GetPorcelainAPI(env).SectorStorageAddPath(p)
+	},
+}
+
+var miningStorageUsageCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the space used and available in each path",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		usages, err := GetPorcelainAPI(env).SectorStorageUsage()
+		if err != nil {
+			return err
+		}
+		return re.Emit(usages)
+	},
+	Type: []sectorstorage.PathUsage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, usages []sectorstorage.PathUsage) error {
+			for _, usage := range usages {
+				limit := "no limit"
+				if usage.MaxBytes > 0 {
+					limit = fmt.Sprintf("limit %d", usage.MaxBytes)
+				}
+				_, err := fmt.Fprintf(w, "%s (%s): %d bytes used, %d available, %s, weight %d, %s\n",
+					usage.Name, usage.Path, usage.Used, usage.Available, limit, usage.Weight, pathKinds(usage.StoragePathConfig))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var miningStorageSectorsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the sealed sector files in each path",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		sectors, err := GetPorcelainAPI(env).SectorStorageSealedSectors()
+		if err != nil {
+			return err
+		}
+		return re.Emit(sectors)
+	},
+	Type: []sectorstorage.SectorFile{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, sectors []sectorstorage.SectorFile) error {
+			for _, sector := range sectors {
+				if _, err := fmt.Fprintf(w, "%s\t%s\t%d\n", sector.Name, sector.Path, sector.Size); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var miningStorageMoveCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Move a sealed sector file to another path",
+		ShortDescription: `
+Copies the sector file to the path and replaces the location the sector builder
+knows it by with a link to the copy, so the sector stays readable while it is
+moved. Sector file names are listed by 'go-filecoin mining storage sectors'.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("sector", true, false, "Name of the sealed sector file"),
+		cmdkit.StringArg("path", true, false, "Name of the path to move the sector to"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return GetPorcelainAPI(env).SectorStorageMoveSealedSector(req.Context, req.Arguments[0], req.Arguments[1])
+	},
+}
+
+// pathKinds describes the kinds of sector data a path may store.
+func pathKinds(p config.StoragePathConfig) string {
+	switch {
+	case p.Staging && p.Sealed:
+		return "staging and sealed"
+	case p.Staging:
+		return "staging only"
+	default:
+		return "sealed only"
+	}
+}
@@ -139,6 +139,7 @@ var lsCmd = &cmds.Command{
 	Options: []cmdkit.Option{
 		cmdkit.StringOption("from", "Address for which message is sent"),
 		cmdkit.StringOption("payer", "Address for which to retrieve channels (defaults to from if omitted)"),
+		atOption,
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		fromAddr, err := fromAddrOrDefault(req, env)
@@ -152,7 +153,12 @@ var lsCmd = &cmds.Command{
 			return err
 		}
 
-		channels, err := GetPorcelainAPI(env).PaymentChannelLs(req.Context, fromAddr, payerAddr)
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+
+		channels, err := GetPorcelainAPI(env).PaymentChannelLsAt(req.Context, fromAddr, payerAddr, baseKey)
 		if err != nil {
 			return err
 		}
@@ -14,8 +14,15 @@ var protocolCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Show protocol parameter details",
 	},
+	Options: []cmdkit.Option{
+		atOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		params, err := GetPorcelainAPI(env).ProtocolParameters(env.Context())
+		baseKey, err := stateKeyFromOption(req, env)
+		if err != nil {
+			return err
+		}
+		params, err := GetPorcelainAPI(env).ProtocolParametersAt(req.Context, baseKey)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ipfs/go-ipfs-cmdkit"
+	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/protocol/sealworker"
+)
+
+var sealWorkerCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Seal sectors for a mining node",
+		ShortDescription: `
+Registers with the mining node at the given multiaddress and fake-seals the
+sectors it sends until the node closes the connection. The node must be
+started with --fake-proofs and have mining.remoteSealing set, and the token
+must match its mining.sealWorkerToken.
+`,
+		LongDescription: `
+Registers with the mining node at the given multiaddress, which must include
+the node's peer id, and seals the sectors it sends one at a time until the
+node closes the connection. The node must have mining.remoteSealing set, and
+the token must match its mining.sealWorkerToken.
+
+No proofs are run: the worker returns fake sealed sector metadata. The node
+generates proofs of spacetime itself and cannot read replicas sealed on
+another machine, so sealing real replicas remotely is not supported yet.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "multiaddress of the mining node, including its peer id"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("name", "name identifying the worker in the node's logs, defaults to the hostname"),
+		cmdkit.StringOption("token", "secret the node requires workers to present"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := ma.NewMultiaddr(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid miner multiaddress")
+		}
+		miner, err := peer.AddrInfoFromP2pAddr(addr)
+		if err != nil {
+			return errors.Wrap(err, "miner multiaddress must include a peer id")
+		}
+
+		name, _ := req.Options["name"].(string)
+		if name == "" {
+			if name, err = os.Hostname(); err != nil {
+				return errors.Wrap(err, "failed to get hostname, set --name")
+			}
+		}
+		token, _ := req.Options["token"].(string)
+
+		h, err := libp2p.New(req.Context)
+		if err != nil {
+			return errors.Wrap(err, "failed to create libp2p host")
+		}
+		defer h.Close() // nolint: errcheck
+
+		if err := re.Emit(fmt.Sprintf("sealing for miner %s as %s\n", miner.ID.Pretty(), name)); err != nil {
+			return err
+		}
+		return sealworker.NewWorker(h, &sealworker.FakeSealer{}, name, token).Run(req.Context, *miner)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.Encoders[cmds.Text],
+	},
+}
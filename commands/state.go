@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipfs-cmdkit"
+	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/plumbing/cst"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// atOption selects the tipset whose state a command reads.
+var atOption = cmdkit.StringOption("at", "Read the state of a tipset, given as a block height or as the comma separated CIDs of its blocks. Defaults to the chain head")
+
+// stateKeyFromOption returns the key of the tipset selected with the --at
+// option, or the key of the chain head without it. A height resolves to the
+// tipset at that height on the current chain.
+func stateKeyFromOption(req *cmds.Request, env cmds.Environment) (types.TipSetKey, error) {
+	at, _ := req.Options["at"].(string)
+	if at == "" {
+		return GetPorcelainAPI(env).ChainHeadKey(), nil
+	}
+
+	if height, err := strconv.ParseUint(at, 10, 64); err == nil {
+		ts, err := GetPorcelainAPI(env).ChainTipSetAtHeight(req.Context, height)
+		if err != nil {
+			return types.TipSetKey{}, err
+		}
+		return ts.Key(), nil
+	}
+
+	blockCids, err := cidsFromSlice(strings.Split(at, ","))
+	if err != nil {
+		return types.TipSetKey{}, errors.Wrapf(err, "invalid tipset %s", at)
+	}
+	return types.NewTipSetKey(blockCids...), nil
+}
+
+var stateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect state trees",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"diff": stateDiffCmd,
+	},
+}
+
+var stateDiffCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the actors that differ between two state trees",
+		ShortDescription: `
+Compares the state trees with the given roots and prints the actors that were
+added, removed or changed, with their balances and nonces. The state of builtin
+actors is decoded and compared field by field.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("root1", true, false, "CID of the state tree to compare from"),
+		cmdkit.StringArg("root2", true, false, "CID of the state tree to compare to"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		before, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrapf(err, "invalid state root %s", req.Arguments[0])
+		}
+		after, err := cid.Decode(req.Arguments[1])
+		if err != nil {
+			return errors.Wrapf(err, "invalid state root %s", req.Arguments[1])
+		}
+
+		diff, err := GetPorcelainAPI(env).StateDiff(req.Context, before, after)
+		if err != nil {
+			return err
+		}
+		return re.Emit(diff)
+	},
+	Type: &cst.StateDiff{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, diff *cst.StateDiff) error {
+			sw := NewSilentWriter(w)
+			for _, a := range diff.Actors {
+				switch {
+				case a.Before == nil:
+					sw.Printf("+ %s (%s)\n", a.Address, a.ActorType)
+					sw.Printf("    balance: %s\n", a.After.Balance)
+					sw.Printf("    nonce: %d\n", a.After.Nonce)
+				case a.After == nil:
+					sw.Printf("- %s (%s)\n", a.Address, a.ActorType)
+					sw.Printf("    balance: %s\n", a.Before.Balance)
+					sw.Printf("    nonce: %d\n", a.Before.Nonce)
+				default:
+					sw.Printf("~ %s (%s)\n", a.Address, a.ActorType)
+					if !a.Before.Code.Equals(a.After.Code) {
+						sw.Printf("    code: %s -> %s\n", a.Before.Code, a.After.Code)
+					}
+					if !a.Before.Head.Equals(a.After.Head) {
+						sw.Printf("    head: %s -> %s\n", a.Before.Head, a.After.Head)
+					}
+					if !a.Before.Balance.Equal(a.After.Balance) {
+						sw.Printf("    balance: %s -> %s\n", a.Before.Balance, a.After.Balance)
+					}
+					if a.Before.Nonce != a.After.Nonce {
+						sw.Printf("    nonce: %d -> %d\n", a.Before.Nonce, a.After.Nonce)
+					}
+				}
+				for _, s := range a.Storage {
+					sw.Printf("    %s: %s -> %s\n", s.Key, orNone(s.Before), orNone(s.After))
+				}
+			}
+			return sw.Error()
+		}),
+	},
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/plumbing/cst"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestStateDiff(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	d.RunSuccess("mining", "once")
+
+	var roots []string
+	chainLs := d.RunSuccess("chain", "ls", "--enc", "json").ReadStdoutTrimNewlines()
+	for _, line := range bytes.Split([]byte(chainLs), []byte{'\n'}) {
+		var blks []types.Block
+		require.NoError(t, json.Unmarshal(line, &blks))
+		roots = append(roots, blks[0].StateRoot.String())
+	}
+	require.Len(t, roots, 2)
+	head, genesis := roots[0], roots[1]
+
+	t.Run("reports changed actors", func(t *testing.T) {
+		out := d.RunSuccess("state", "diff", genesis, head, "--enc", "json").ReadStdoutTrimNewlines()
+		var diff cst.StateDiff
+		require.NoError(t, json.Unmarshal([]byte(out), &diff))
+		assert.Equal(t, genesis, diff.Before.String())
+		assert.Equal(t, head, diff.After.String())
+		assert.NotEmpty(t, diff.Actors)
+
+		text := d.RunSuccess("state", "diff", genesis, head).ReadStdout()
+		assert.Contains(t, text, "balance:")
+	})
+
+	t.Run("identical roots have no differences", func(t *testing.T) {
+		out := d.RunSuccess("state", "diff", head, head, "--enc", "json").ReadStdoutTrimNewlines()
+		var diff cst.StateDiff
+		require.NoError(t, json.Unmarshal([]byte(out), &diff))
+		assert.Empty(t, diff.Actors)
+	})
+
+	t.Run("rejects invalid roots", func(t *testing.T) {
+		d.RunFail("invalid state root notacid", "state", "diff", "notacid", head)
+	})
+}
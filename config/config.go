@@ -20,6 +20,7 @@ type Config struct {
 	API           *APIConfig           `json:"api"`
 	Bootstrap     *BootstrapConfig     `json:"bootstrap"`
 	Datastore     *DatastoreConfig     `json:"datastore"`
+	Explorer      *ExplorerConfig      `json:"explorer"`
 	Heartbeat     *HeartbeatConfig     `json:"heartbeat"`
 	Mining        *MiningConfig        `json:"mining"`
 	Mpool         *MessagePoolConfig   `json:"mpool"`
@@ -57,6 +58,22 @@ type DatastoreConfig struct {
 	Path string `json:"path"`
 }
 
+// ExplorerConfig holds all configuration options related to the read-only
+// block explorer HTTP API.
+type ExplorerConfig struct {
+	// Enabled serves the explorer API when true.
+	Enabled bool `json:"enabled"`
+	// Address is the multiaddress the explorer API listens on.
+	Address string `json:"address"`
+}
+
+func newDefaultExplorerConfig() *ExplorerConfig {
+	return &ExplorerConfig{
+		Enabled: false,
+		Address: "/ip4/127.0.0.1/tcp/3454",
+	}
+}
+
 // Validators hold the list of validation functions for each configuration
 // property. Validators must take a key and json string respectively as
 // arguments, and must return either an error or nil depending on whether or not
@@ -106,6 +123,26 @@ type MiningConfig struct {
 	MinerAddress            address.Address `json:"minerAddress"`
 	AutoSealIntervalSeconds uint            `json:"autoSealIntervalSeconds"`
 	StoragePrice            types.AttoFIL   `json:"storagePrice"`
+	// RemoteSealing hands sectors to registered seal workers instead of
+	// sealing them in the daemon. It is only supported with fake proofs,
+	// since workers only produce fake seals.
+	RemoteSealing bool `json:"remoteSealing"`
+	// SealWorkerToken is the secret seal workers must present to register.
+	// It must be set to use remote sealing.
+	SealWorkerToken string `json:"sealWorkerToken"`
+	// SectorHealthCheckIntervalSeconds is how often the miner checks that its
+	// sealed sectors are intact and declares faults for those that are not.
+	// Zero disables the checks.
+	SectorHealthCheckIntervalSeconds uint `json:"sectorHealthCheckIntervalSeconds"`
+	// MaxHeadLagRounds pauses mining while the chain head is more than this
+	// many rounds behind the height expected from the wall clock. Zero
+	// disables the check.
+	MaxHeadLagRounds uint `json:"maxHeadLagRounds"`
+	// MaxHeadAgeSeconds pauses mining while the chain head is older than this.
+	// Zero disables the check. Both checks also pause a miner that is mining
+	// null rounds on a network with no other miners, so leave them disabled
+	// on small networks.
+	MaxHeadAgeSeconds uint `json:"maxHeadAgeSeconds"`
 }
 
 func newDefaultMiningConfig() *MiningConfig {
@@ -113,6 +150,12 @@ func newDefaultMiningConfig() *MiningConfig {
 		MinerAddress:            address.Undef,
 		AutoSealIntervalSeconds: 120,
 		StoragePrice:            types.ZeroAttoFIL,
+		RemoteSealing:           false,
+		SealWorkerToken:         "",
+
+		SectorHealthCheckIntervalSeconds: 3600,
+		MaxHeadLagRounds:                 0,
+		MaxHeadAgeSeconds:                0,
 	}
 }
 
@@ -152,14 +195,16 @@ func newDefaultHeartbeatConfig() *HeartbeatConfig {
 
 // ObservabilityConfig is a container for configuration related to observables.
 type ObservabilityConfig struct {
-	Metrics *MetricsConfig `json:"metrics"`
-	Tracing *TraceConfig   `json:"tracing"`
+	Metrics         *MetricsConfig        `json:"metrics"`
+	Tracing         *TraceConfig          `json:"tracing"`
+	ExecutionTraces *ExecutionTraceConfig `json:"executionTraces"`
 }
 
 func newDefaultObservabilityConfig() *ObservabilityConfig {
 	return &ObservabilityConfig{
-		Metrics: newDefaultMetricsConfig(),
-		Tracing: newDefaultTraceConfig(),
+		Metrics:         newDefaultMetricsConfig(),
+		Tracing:         newDefaultTraceConfig(),
+		ExecutionTraces: newDefaultExecutionTraceConfig(),
 	}
 }
 
@@ -200,6 +245,20 @@ func newDefaultTraceConfig() *TraceConfig {
 	}
 }
 
+// ExecutionTraceConfig holds all configuration options related to the traces
+// of message execution in the VM.
+type ExecutionTraceConfig struct {
+	// Persist will store the execution trace of every message of the tipsets
+	// joining the chain when true. The tipsets are replayed to trace them.
+	Persist bool `json:"persist"`
+}
+
+func newDefaultExecutionTraceConfig() *ExecutionTraceConfig {
+	return &ExecutionTraceConfig{
+		Persist: false,
+	}
+}
+
 // MessagePoolConfig holds all configuration options related to nodes message pool (mpool).
 type MessagePoolConfig struct {
 	// MaxPoolSize is the maximum number of pending messages will will allow in the message pool at any time
@@ -221,11 +280,33 @@ type SectorBaseConfig struct {
 	// RootDir is the path to the root directory holding sector data.
 	// If empty the default of <homedir>/sectors is implied.
 	RootDir string `json:"rootdir"`
+	// StoragePaths are additional named locations sector data may be stored
+	// in. The root directory is always available as the path named "default".
+	StoragePaths []*StoragePathConfig `json:"storagePaths"`
+}
+
+// StoragePathConfig configures a named location for sector data.
+type StoragePathConfig struct {
+	// Name identifies the path in commands.
+	Name string `json:"name"`
+	// Path is the directory holding the sector data.
+	Path string `json:"path"`
+	// MaxBytes limits the sector data stored in the path. Zero means the
+	// path may fill its filesystem.
+	MaxBytes uint64 `json:"maxBytes"`
+	// Weight scales the free space of the path when choosing where to store
+	// sectors. Zero is treated as one.
+	Weight uint64 `json:"weight"`
+	// Staging allows staged sectors to be stored in the path.
+	Staging bool `json:"staging"`
+	// Sealed allows sealed sectors to be stored in the path.
+	Sealed bool `json:"sealed"`
 }
 
 func newDefaultSectorbaseConfig() *SectorBaseConfig {
 	return &SectorBaseConfig{
-		RootDir: "",
+		RootDir:      "",
+		StoragePaths: []*StoragePathConfig{},
 	}
 }
 
@@ -236,6 +317,7 @@ func NewDefaultConfig() *Config {
 		API:           newDefaultAPIConfig(),
 		Bootstrap:     newDefaultBootstrapConfig(),
 		Datastore:     newDefaultDatastoreConfig(),
+		Explorer:      newDefaultExplorerConfig(),
 		Swarm:         newDefaultSwarmConfig(),
 		Mining:        newDefaultMiningConfig(),
 		Wallet:        newDefaultWalletConfig(),
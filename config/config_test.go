@@ -66,6 +66,10 @@ func TestWriteFile(t *testing.T) {
 		"type": "badgerds",
 		"path": "badger"
 	},
+	"explorer": {
+		"enabled": false,
+		"address": "/ip4/127.0.0.1/tcp/3454"
+	},
 	"heartbeat": {
 		"beatTarget": "",
 		"beatPeriod": "3s",
@@ -75,7 +79,12 @@ func TestWriteFile(t *testing.T) {
 	"mining": {
 		"minerAddress": "empty",
 		"autoSealIntervalSeconds": 120,
-		"storagePrice": "0"
+		"storagePrice": "0",
+		"remoteSealing": false,
+		"sealWorkerToken": "",
+		"sectorHealthCheckIntervalSeconds": 3600,
+		"maxHeadLagRounds": 0,
+		"maxHeadAgeSeconds": 0
 	},
 	"mpool": {
 		"maxPoolSize": 10000,
@@ -91,10 +100,14 @@ func TestWriteFile(t *testing.T) {
 			"jaegerTracingEnabled": false,
 			"probabilitySampler": 1,
 			"jaegerEndpoint": "http://localhost:14268/api/traces"
+		},
+		"executionTraces": {
+			"persist": false
 		}
 	},
 	"sectorbase": {
-		"rootdir": ""
+		"rootdir": "",
+		"storagePaths": []
 	},
 	"swarm": {
 		"address": "/ip4/0.0.0.0/tcp/6000"
@@ -5,6 +5,7 @@ package consensus
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -37,7 +38,8 @@ func init() {
 }
 
 var (
-	// ErrStateRootMismatch is returned when the computed state root doesn't match the expected result.
+	// ErrStateRootMismatch describes a computed state root that doesn't match the expected result.
+	// Block validation reports it with a StateRootMismatchError.
 	ErrStateRootMismatch = errors.New("blocks state root does not match computed result")
 	// ErrInvalidBase is returned when the chain doesn't connect back to a known good block.
 	ErrInvalidBase = errors.New("block does not connect to a known good chain")
@@ -45,6 +47,18 @@ var (
 	ErrUnorderedTipSets = errors.New("trying to order two identical tipsets")
 )
 
+// StateRootMismatchError is returned when the state computed for a block
+// differs from the state root the block claims.
+type StateRootMismatchError struct {
+	Block    cid.Cid `json:"block"`
+	Claimed  cid.Cid `json:"claimed"`
+	Computed cid.Cid `json:"computed"`
+}
+
+func (e *StateRootMismatchError) Error() string {
+	return fmt.Sprintf("%s: block %s claims state root %s, computed %s", ErrStateRootMismatch, e.Block, e.Claimed, e.Computed)
+}
+
 // DefaultBlockTime is the estimated proving period time.
 // We define this so that we can fake mining in the current incomplete system.
 // We also use this to enforce a soft block validation.
@@ -272,7 +286,7 @@ func (c *Expected) runMessages(ctx context.Context, st state.Tree, vms vm.Storag
 		}
 
 		if !outCid.Equals(blk.StateRoot) {
-			return nil, ErrStateRootMismatch
+			return nil, &StateRootMismatchError{Block: blk.Cid(), Claimed: blk.StateRoot, Computed: outCid}
 		}
 	}
 	if ts.Len() <= 1 { // block validation state == aggregate parent state
@@ -14,6 +14,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/metrics"
 	"github.com/filecoin-project/go-filecoin/metrics/tracing"
+	"github.com/filecoin-project/go-filecoin/proofs/verification"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm"
@@ -56,11 +57,21 @@ type ProcessTipSetResponse struct {
 	Failures  map[cid.Cid]struct{}
 }
 
+// TraceRecorder receives the execution traces of the messages a processor
+// successfully applies.
+type TraceRecorder interface {
+	RecordTrace(ctx context.Context, msgCid cid.Cid, trace *vm.Trace)
+}
+
 // DefaultProcessor handles all block processing.
 type DefaultProcessor struct {
 	signedMessageValidator SignedMessageValidator
 	blockRewarder          BlockRewarder
 	actors                 builtin.Actors
+	// verifier verifies proofs, the proofs library is used if it is nil
+	verifier verification.Verifier
+	// traceRecorder records message traces, messages are not traced if it is nil
+	traceRecorder TraceRecorder
 }
 
 var _ Processor = (*DefaultProcessor)(nil)
@@ -83,6 +94,22 @@ func NewConfiguredProcessor(validator SignedMessageValidator, rewarder BlockRewa
 	}
 }
 
+// WithVerifier returns a copy of the processor that verifies proofs with the
+// given verifier instead of the proofs library.
+func (p *DefaultProcessor) WithVerifier(verifier verification.Verifier) *DefaultProcessor {
+	withVerifier := *p
+	withVerifier.verifier = verifier
+	return &withVerifier
+}
+
+// WithTraceRecorder returns a copy of the processor that traces the execution
+// of each message it applies and hands the traces to recorder.
+func (p *DefaultProcessor) WithTraceRecorder(recorder TraceRecorder) *DefaultProcessor {
+	withRecorder := *p
+	withRecorder.traceRecorder = recorder
+	return &withRecorder
+}
+
 // ProcessBlock is the entrypoint for validating the state transitions
 // of the messages in a block. When we receive a new block from the
 // network ProcessBlock applies the block's messages to the beginning
@@ -308,7 +335,12 @@ func (p *DefaultProcessor) ApplyMessage(ctx context.Context, st state.Tree, vms
 
 	cachedStateTree := state.NewCachedStateTree(st)
 
-	r, err := p.attemptApplyMessage(ctx, cachedStateTree, vms, msg, bh, gasTracker, ancestors)
+	var tracer *vm.Tracer
+	if p.traceRecorder != nil {
+		tracer = vm.NewTracer()
+	}
+
+	r, err := p.attemptApplyMessage(ctx, cachedStateTree, vms, msg, bh, gasTracker, ancestors, tracer)
 	if err == nil {
 		err = cachedStateTree.Commit(ctx)
 		if err != nil {
@@ -354,6 +386,10 @@ func (p *DefaultProcessor) ApplyMessage(ctx context.Context, st state.Tree, vms
 		return nil, errors.FaultErrorWrap(err, "could not set from actor after inc nonce")
 	}
 
+	if tracer != nil && tracer.Trace() != nil {
+		p.traceRecorder.RecordTrace(ctx, msgCid, tracer.Trace())
+	}
+
 	return &ApplicationResult{Receipt: r, ExecutionError: executionError}, nil
 }
 
@@ -378,48 +414,68 @@ var (
 // not make any changes to the state/blockchain and is useful for interrogating
 // actor state. Block height bh is optional; some methods will ignore it.
 func (p *DefaultProcessor) CallQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight) ([][]byte, uint8, error) {
-	toActor, err := st.GetActor(ctx, to)
+	vmCtx, err := p.queryContext(ctx, st, vms, to, method, params, from, optBh, nil)
 	if err != nil {
-		return nil, 1, errors.ApplyErrorPermanentWrapf(err, "failed to get To actor")
+		return nil, 1, err
 	}
 
-	// not committing or flushing storage structures guarantees changes won't make it to stored state tree or datastore
-	cachedSt := state.NewCachedStateTree(st)
+	ret, retCode, err := vm.Send(ctx, vmCtx)
+	return ret, retCode, err
+}
 
-	msg := &types.Message{
-		From:   from,
-		To:     to,
-		Nonce:  0,
-		Value:  types.ZeroAttoFIL,
-		Method: method,
-		Params: params,
+// PreviewQueryMethod estimates the amount of gas that will be used by a method
+// call. It accepts all the same arguments as CallQueryMethod.
+func (p *DefaultProcessor) PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight) (types.GasUnits, error) {
+	vmCtx, err := p.queryContext(ctx, st, vms, to, method, params, from, optBh, nil)
+	if err != nil {
+		return types.NewGasUnits(0), err
 	}
 
-	// Set the gas limit to the max because this message send should always succeed; it doesn't cost gas.
-	gasTracker := vm.NewGasTracker()
-	gasTracker.MsgGasLimit = types.BlockGasLimit
+	_, _, err = vm.Send(ctx, vmCtx)
+	return vmCtx.GasUnits(), err
+}
 
-	vmCtxParams := vm.NewContextParams{
-		To:          toActor,
-		Message:     msg,
-		State:       cachedSt,
-		StorageMap:  vms,
-		GasTracker:  gasTracker,
-		BlockHeight: optBh,
-		Actors:      p.actors,
+// TraceQueryMethod runs a method call like CallQueryMethod and returns the
+// trace of its execution, which holds its return values, exit code and the gas
+// it used. Failures of the call are recorded in the trace.
+func (p *DefaultProcessor) TraceQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight) (*vm.Trace, error) {
+	tracer := vm.NewTracer()
+	vmCtx, err := p.queryContext(ctx, st, vms, to, method, params, from, optBh, tracer)
+	if err != nil {
+		return nil, err
 	}
 
-	vmCtx := vm.NewVMContext(vmCtxParams)
-	ret, retCode, err := vm.Send(ctx, vmCtx)
-	return ret, retCode, err
+	_, _, err = vm.Send(ctx, vmCtx)
+	if errors.IsFault(err) {
+		return nil, err
+	}
+	return tracer.Trace(), nil
 }
 
-// PreviewQueryMethod estimates the amount of gas that will be used by a method
-// call. It accepts all the same arguments as CallQueryMethod.
-func (p *DefaultProcessor) PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight) (types.GasUnits, error) {
+// TraceTipSet applies the messages of a tipset to the state of its parent, as
+// ProcessTipSet does, and returns the traces of the messages it applied keyed
+// by message cid.
+func (p *DefaultProcessor) TraceTipSet(ctx context.Context, st state.Tree, vms vm.StorageMap, ts types.TipSet, tsMessages [][]*types.SignedMessage, ancestors []types.TipSet) (map[cid.Cid]*vm.Trace, error) {
+	traces := tipSetTraces{}
+	if _, err := p.WithTraceRecorder(traces).ProcessTipSet(ctx, st, vms, ts, tsMessages, ancestors); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// tipSetTraces collects the traces of the messages of a tipset.
+type tipSetTraces map[cid.Cid]*vm.Trace
+
+func (t tipSetTraces) RecordTrace(ctx context.Context, msgCid cid.Cid, trace *vm.Trace) {
+	t[msgCid] = trace
+}
+
+// queryContext creates the context of a read-only message from the given
+// sender. Changes made during the call are never committed to st.
+func (p *DefaultProcessor) queryContext(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight, tracer *vm.Tracer) (*vm.Context, error) {
 	toActor, err := st.GetActor(ctx, to)
 	if err != nil {
-		return types.NewGasUnits(0), errors.ApplyErrorPermanentWrapf(err, "failed to get To actor")
+		return nil, errors.ApplyErrorPermanentWrapf(err, "failed to get To actor")
 	}
 
 	// not committing or flushing storage structures guarantees changes won't make it to stored state tree or datastore
@@ -446,11 +502,10 @@ func (p *DefaultProcessor) PreviewQueryMethod(ctx context.Context, st state.Tree
 		GasTracker:  gasTracker,
 		BlockHeight: optBh,
 		Actors:      p.actors,
+		Verifier:    p.verifier,
+		Tracer:      tracer,
 	}
-	vmCtx := vm.NewVMContext(vmCtxParams)
-	_, _, err = vm.Send(ctx, vmCtx)
-
-	return vmCtx.GasUnits(), err
+	return vm.NewVMContext(vmCtxParams), nil
 }
 
 // attemptApplyMessage encapsulates the work of trying to apply the message in order
@@ -458,7 +513,7 @@ func (p *DefaultProcessor) PreviewQueryMethod(ctx context.Context, st state.Tree
 // should deal with trying to apply the message to the state tree whereas
 // ApplyMessage should deal with any side effects and how it should be presented
 // to the caller. attemptApplyMessage should only be called from ApplyMessage.
-func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.CachedTree, store vm.StorageMap, msg *types.SignedMessage, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet) (*types.MessageReceipt, error) {
+func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.CachedTree, store vm.StorageMap, msg *types.SignedMessage, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet, tracer *vm.Tracer) (*types.MessageReceipt, error) {
 	gasTracker.ResetForNewMessage(msg.MeteredMessage)
 	if err := blockGasLimitError(gasTracker); err != nil {
 		return &types.MessageReceipt{
@@ -513,6 +568,8 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 		BlockHeight: bh,
 		Ancestors:   ancestors,
 		Actors:      p.actors,
+		Verifier:    p.verifier,
+		Tracer:      tracer,
 	}
 	vmCtx := vm.NewVMContext(vmCtxParams)
 
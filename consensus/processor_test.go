@@ -2,6 +2,7 @@ package consensus_test
 
 import (
 	"context"
+	"math/big"
 	"testing"
 
 	"github.com/ipfs/go-cid"
@@ -786,6 +787,78 @@ func TestApplyQueryMessageWillNotAlterState(t *testing.T) {
 	assert.True(t, preCid.Equals(postCid))
 }
 
+type traceRecorder map[cid.Cid]*vm.Trace
+
+func (tr traceRecorder) RecordTrace(ctx context.Context, msgCid cid.Cid, trace *vm.Trace) {
+	tr[msgCid] = trace
+}
+
+func TestTraceNestedSends(t *testing.T) {
+	tf.UnitTest(t)
+
+	newAddress := address.NewForTestGetter()
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	vms := th.VMStorage()
+
+	// Install the fake actor so we can execute it.
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	actors := builtin.NewBuilder().
+		AddAll(builtin.DefaultActors).
+		Add(fakeActorCodeCid, 0, &actor.FakeActor{}).
+		Build()
+
+	addr0, addr1, addr2 := newAddress(), newAddress(), newAddress()
+	act0 := th.RequireNewAccountActor(t, types.NewAttoFILFromFIL(101))
+	act1 := th.RequireNewFakeActorWithTokens(t, vms, addr1, fakeActorCodeCid, types.NewAttoFILFromFIL(102))
+	act2 := th.RequireNewFakeActorWithTokens(t, vms, addr2, fakeActorCodeCid, types.NewAttoFILFromFIL(0))
+
+	_, st := th.RequireMakeStateTree(t, cst, map[address.Address]*actor.Actor{
+		addr0: act0,
+		addr1: act1,
+		addr2: act2,
+	})
+
+	params, err := abi.ToEncodedValues(addr2)
+	require.NoError(t, err)
+
+	processor := NewConfiguredProcessor(&th.FakeSignedMessageValidator{}, &th.FakeBlockRewarder{}, actors)
+
+	requireNestedTrace := func(t *testing.T, trace *vm.Trace) {
+		require.NotNil(t, trace)
+		assert.Equal(t, addr0, trace.From)
+		assert.Equal(t, addr1, trace.To)
+		assert.Equal(t, "nestedBalance", trace.Method)
+		assert.Equal(t, uint8(0), trace.ExitCode)
+
+		require.Len(t, trace.Calls, 1)
+		assert.Equal(t, addr1, trace.Calls[0].From)
+		assert.Equal(t, addr2, trace.Calls[0].To)
+		assert.Equal(t, types.NewAttoFILFromFIL(100), trace.Calls[0].Value)
+	}
+
+	t.Run("traces a query", func(t *testing.T) {
+		trace, err := processor.TraceQueryMethod(ctx, st, vms, addr1, "nestedBalance", params, addr0, types.NewBlockHeight(0))
+		require.NoError(t, err)
+		requireNestedTrace(t, trace)
+	})
+
+	t.Run("records the traces of applied messages", func(t *testing.T) {
+		msg := types.NewMessage(addr0, addr1, 0, types.ZeroAttoFIL, "nestedBalance", params)
+		smsg := &types.SignedMessage{MeteredMessage: *types.NewMeteredMessage(*msg, types.NewGasPrice(1), types.NewGasUnits(300))}
+		msgCid, err := smsg.Cid()
+		require.NoError(t, err)
+
+		recorder := traceRecorder{}
+		res, err := processor.WithTraceRecorder(recorder).ApplyMessage(ctx, st, vms, smsg, addr0, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
+		require.NoError(t, err)
+
+		trace := recorder[msgCid]
+		requireNestedTrace(t, trace)
+		assert.True(t, res.Receipt.GasAttoFIL.Equal(types.NewGasPrice(1).MulBigInt(big.NewInt(int64(trace.GasUsed)))))
+	})
+}
+
 func TestApplyMessageChargesGas(t *testing.T) {
 	tf.BadUnitTestWithSideEffects(t)
 
@@ -0,0 +1,422 @@
+// Package explorer implements a read-only HTTP API for block explorers,
+// serving tipsets, blocks, messages, actors and miners as JSON.
+package explorer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var log = logging.Logger("explorer")
+
+// API is the subset of the porcelain.API the explorer reads from.
+type API interface {
+	ChainHead() (types.TipSet, error)
+	ChainTipSet(key types.TipSetKey) (types.TipSet, error)
+	ChainTipSetByHeight(height uint64) (types.TipSet, error)
+	ChainGetFullBlock(ctx context.Context, id cid.Cid) (*types.FullBlock, error)
+	MessageFind(ctx context.Context, msgCid cid.Cid) (*msg.ChainMessage, bool, error)
+	ActorGetAt(ctx context.Context, baseKey types.TipSetKey, addr address.Address) (*actor.Actor, error)
+	ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error)
+	ActorDecodeState(ctx context.Context, a *actor.Actor) (map[string]string, error)
+	MinerGetPowerAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (porcelain.MinerPower, error)
+	MinerGetAsksAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) ([]minerActor.Ask, error)
+}
+
+// TipSet is a tipset and the headers of its blocks.
+type TipSet struct {
+	Key     types.TipSetKey `json:"key"`
+	Height  uint64          `json:"height"`
+	Parents types.TipSetKey `json:"parents"`
+	Blocks  []*types.Block  `json:"blocks"`
+}
+
+// Block is a block header with its messages and their receipts.
+type Block struct {
+	Cid      cid.Cid                 `json:"cid"`
+	Header   *types.Block            `json:"header"`
+	Messages []*Message              `json:"messages"`
+	Receipts []*types.MessageReceipt `json:"receipts"`
+}
+
+// Message is a signed message with its parameters decoded. When the message
+// was found on chain it includes the block it was found in, its receipt and
+// its decoded return values.
+type Message struct {
+	Cid     cid.Cid               `json:"cid"`
+	Message *types.SignedMessage  `json:"message"`
+	Params  []Value               `json:"params,omitempty"`
+	Block   *cid.Cid              `json:"block,omitempty"`
+	Height  uint64                `json:"height,omitempty"`
+	Receipt *types.MessageReceipt `json:"receipt,omitempty"`
+	Return  []Value               `json:"return,omitempty"`
+}
+
+// Value is a decoded method parameter or return value.
+type Value struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+	Text  string      `json:"text"`
+}
+
+// Actor is an actor with its state decoded when it is a builtin actor.
+type Actor struct {
+	Address   string            `json:"address"`
+	ActorType string            `json:"actorType"`
+	Code      cid.Cid           `json:"code"`
+	Head      cid.Cid           `json:"head"`
+	Nonce     uint64            `json:"nonce"`
+	Balance   types.AttoFIL     `json:"balance"`
+	State     map[string]string `json:"state,omitempty"`
+}
+
+// Error is the body of a failed request.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// errBadRequest marks errors caused by malformed requests.
+type errBadRequest struct {
+	error
+}
+
+// errNotFound marks errors for objects that do not exist.
+type errNotFound struct {
+	error
+}
+
+// Server serves the explorer API.
+type Server struct {
+	api API
+	mux *http.ServeMux
+}
+
+// NewServer returns a server reading from api.
+func NewServer(api API) *Server {
+	s := &Server{api: api, mux: http.NewServeMux()}
+	s.handle("/tipsets/", s.getTipSet)
+	s.handle("/blocks/", s.getBlock)
+	s.handle("/messages/", s.getMessage)
+	s.handle("/actors/", s.getActor)
+	s.handle("/miners/", s.getMiner)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers a handler for the paths under prefix. The handler is
+// passed the path segments following the prefix and returns the value to
+// write as JSON.
+func (s *Server) handle(prefix string, h func(r *http.Request, segments []string) (interface{}, error)) {
+	s.mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, &Error{Error: "only GET is supported"})
+			return
+		}
+
+		segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/"), "/")
+		res, err := h(r, segments)
+		switch errors.Cause(err).(type) {
+		case nil:
+			writeJSON(w, http.StatusOK, res)
+		case errBadRequest:
+			writeJSON(w, http.StatusBadRequest, &Error{Error: err.Error()})
+		case errNotFound:
+			writeJSON(w, http.StatusNotFound, &Error{Error: err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, &Error{Error: err.Error()})
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warningf("failed to write response: %s", err)
+	}
+}
+
+// getTipSet serves /tipsets/head, /tipsets/height/<height> and
+// /tipsets/key/<cid>,<cid>...
+func (s *Server) getTipSet(r *http.Request, segments []string) (interface{}, error) {
+	var ts types.TipSet
+	var err error
+	switch {
+	case len(segments) == 1 && segments[0] == "head":
+		ts, err = s.api.ChainHead()
+	case len(segments) == 2 && segments[0] == "height":
+		height, perr := strconv.ParseUint(segments[1], 10, 64)
+		if perr != nil {
+			return nil, errBadRequest{errors.Errorf("invalid height %s", segments[1])}
+		}
+		ts, err = s.api.ChainTipSetByHeight(height)
+		if err != nil {
+			err = errNotFound{err}
+		}
+	case len(segments) == 2 && segments[0] == "key":
+		key, kerr := parseTipSetKey(segments[1])
+		if kerr != nil {
+			return nil, kerr
+		}
+		ts, err = s.api.ChainTipSet(key)
+		if err != nil {
+			err = errNotFound{err}
+		}
+	default:
+		return nil, errNotFound{errors.New("expected /tipsets/head, /tipsets/height/<height> or /tipsets/key/<cids>")}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	parents, err := ts.Parents()
+	if err != nil {
+		return nil, err
+	}
+	return &TipSet{
+		Key:     ts.Key(),
+		Height:  height,
+		Parents: parents,
+		Blocks:  ts.ToSlice(),
+	}, nil
+}
+
+// getBlock serves /blocks/<cid>.
+func (s *Server) getBlock(r *http.Request, segments []string) (interface{}, error) {
+	if len(segments) != 1 {
+		return nil, errNotFound{errors.New("expected /blocks/<cid>")}
+	}
+	blockCid, err := cid.Decode(segments[0])
+	if err != nil {
+		return nil, errBadRequest{errors.Wrapf(err, "invalid block cid %s", segments[0])}
+	}
+
+	full, err := s.api.ChainGetFullBlock(r.Context(), blockCid)
+	if err != nil {
+		return nil, errNotFound{err}
+	}
+
+	out := &Block{
+		Cid:      blockCid,
+		Header:   full.Header,
+		Messages: make([]*Message, len(full.Messages)),
+		Receipts: full.Receipts,
+	}
+	for i, m := range full.Messages {
+		if out.Messages[i], err = s.message(r.Context(), m); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// getMessage serves /messages/<cid>.
+func (s *Server) getMessage(r *http.Request, segments []string) (interface{}, error) {
+	if len(segments) != 1 {
+		return nil, errNotFound{errors.New("expected /messages/<cid>")}
+	}
+	msgCid, err := cid.Decode(segments[0])
+	if err != nil {
+		return nil, errBadRequest{errors.Wrapf(err, "invalid message cid %s", segments[0])}
+	}
+
+	found, ok, err := s.api.MessageFind(r.Context(), msgCid)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNotFound{errors.Errorf("message %s not found on chain", msgCid)}
+	}
+
+	out, err := s.message(r.Context(), found.Message)
+	if err != nil {
+		return nil, err
+	}
+	blockCid := found.Block.Cid()
+	out.Block = &blockCid
+	out.Height = uint64(found.Block.Height)
+	out.Receipt = found.Receipt
+	if found.Receipt != nil {
+		out.Return = s.decodeReturn(r.Context(), found.Message, found.Receipt)
+	}
+	return out, nil
+}
+
+// getActor serves /actors/<address>, reading the state of the tipset given
+// by the optional `at` query parameter.
+func (s *Server) getActor(r *http.Request, segments []string) (interface{}, error) {
+	if len(segments) != 1 {
+		return nil, errNotFound{errors.New("expected /actors/<address>")}
+	}
+	addr, err := address.NewFromString(segments[0])
+	if err != nil {
+		return nil, errBadRequest{errors.Wrapf(err, "invalid address %s", segments[0])}
+	}
+	baseKey, err := s.stateKey(r)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.api.ActorGetAt(r.Context(), baseKey, addr)
+	if err != nil {
+		return nil, errNotFound{err}
+	}
+	st, err := s.api.ActorDecodeState(r.Context(), a)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode state of actor %s", addr)
+	}
+	return &Actor{
+		Address:   addr.String(),
+		ActorType: types.ActorCodeTypeName(a.Code),
+		Code:      a.Code,
+		Head:      a.Head,
+		Nonce:     uint64(a.Nonce),
+		Balance:   a.Balance,
+		State:     st,
+	}, nil
+}
+
+// getMiner serves /miners/<address>/power and /miners/<address>/asks,
+// reading the state of the tipset given by the optional `at` query parameter.
+func (s *Server) getMiner(r *http.Request, segments []string) (interface{}, error) {
+	if len(segments) != 2 {
+		return nil, errNotFound{errors.New("expected /miners/<address>/power or /miners/<address>/asks")}
+	}
+	addr, err := address.NewFromString(segments[0])
+	if err != nil {
+		return nil, errBadRequest{errors.Wrapf(err, "invalid address %s", segments[0])}
+	}
+	baseKey, err := s.stateKey(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch segments[1] {
+	case "power":
+		return s.api.MinerGetPowerAt(r.Context(), addr, baseKey)
+	case "asks":
+		return s.api.MinerGetAsksAt(r.Context(), addr, baseKey)
+	default:
+		return nil, errNotFound{errors.Errorf("unknown miner resource %s", segments[1])}
+	}
+}
+
+// message returns the view of a message with its parameters decoded.
+func (s *Server) message(ctx context.Context, m *types.SignedMessage) (*Message, error) {
+	msgCid, err := m.Cid()
+	if err != nil {
+		return nil, err
+	}
+	out := &Message{Cid: msgCid, Message: m}
+
+	sig, ok := s.signature(ctx, m)
+	if !ok {
+		return out, nil
+	}
+	params, err := abi.DecodeValues(m.Params, sig.Params)
+	if err != nil {
+		// Messages may carry parameters their method does not accept; they
+		// are still shown, undecoded.
+		return out, nil
+	}
+	out.Params = values(params)
+	return out, nil
+}
+
+// decodeReturn decodes the return values in a message's receipt, or returns
+// nil if they cannot be decoded.
+func (s *Server) decodeReturn(ctx context.Context, m *types.SignedMessage, receipt *types.MessageReceipt) []Value {
+	sig, ok := s.signature(ctx, m)
+	if !ok || len(sig.Return) != len(receipt.Return) {
+		return nil
+	}
+	vals := make([]*abi.Value, len(receipt.Return))
+	for i, ret := range receipt.Return {
+		v, err := abi.Deserialize(ret, sig.Return[i])
+		if err != nil {
+			return nil
+		}
+		vals[i] = v
+	}
+	return values(vals)
+}
+
+// signature looks up the signature of the method a message calls. Transfers
+// and messages to actors without an implementation have none.
+func (s *Server) signature(ctx context.Context, m *types.SignedMessage) (*exec.FunctionSignature, bool) {
+	if m.Method == "" {
+		return nil, false
+	}
+	sig, err := s.api.ActorGetSignature(ctx, m.To, m.Method)
+	if err != nil || sig == nil {
+		return nil, false
+	}
+	return sig, true
+}
+
+// stateKey returns the key of the tipset selected by the `at` query
+// parameter, given as a height or as comma separated block CIDs, or the key
+// of the chain head without it.
+func (s *Server) stateKey(r *http.Request) (types.TipSetKey, error) {
+	at := r.URL.Query().Get("at")
+	if at == "" {
+		head, err := s.api.ChainHead()
+		if err != nil {
+			return types.TipSetKey{}, err
+		}
+		return head.Key(), nil
+	}
+
+	if height, err := strconv.ParseUint(at, 10, 64); err == nil {
+		ts, err := s.api.ChainTipSetByHeight(height)
+		if err != nil {
+			return types.TipSetKey{}, errBadRequest{err}
+		}
+		return ts.Key(), nil
+	}
+	return parseTipSetKey(at)
+}
+
+func parseTipSetKey(s string) (types.TipSetKey, error) {
+	var cids []cid.Cid
+	for _, c := range strings.Split(s, ",") {
+		decoded, err := cid.Decode(c)
+		if err != nil {
+			return types.TipSetKey{}, errBadRequest{errors.Wrapf(err, "invalid tipset %s", s)}
+		}
+		cids = append(cids, decoded)
+	}
+	return types.NewTipSetKey(cids...), nil
+}
+
+func values(vals []*abi.Value) []Value {
+	out := make([]Value, len(vals))
+	for i, v := range vals {
+		out[i] = Value{Type: v.Type.String(), Value: v.Val, Text: v.String()}
+	}
+	return out
+}
@@ -0,0 +1,234 @@
+package explorer_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/explorer"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type fakeExplorerAPI struct {
+	builder  *chain.Builder
+	tipsets  []types.TipSet
+	blocks   map[cid.Cid]*types.FullBlock
+	messages map[cid.Cid]*msg.ChainMessage
+	actors   map[address.Address]*actor.Actor
+	asks     []minerActor.Ask
+	atKeys   []types.TipSetKey
+}
+
+func (api *fakeExplorerAPI) ChainHead() (types.TipSet, error) {
+	return api.tipsets[len(api.tipsets)-1], nil
+}
+
+func (api *fakeExplorerAPI) ChainTipSet(key types.TipSetKey) (types.TipSet, error) {
+	return api.builder.GetTipSet(key)
+}
+
+func (api *fakeExplorerAPI) ChainTipSetByHeight(height uint64) (types.TipSet, error) {
+	if height >= uint64(len(api.tipsets)) {
+		return types.UndefTipSet, fmt.Errorf("height %d is above the chain head", height)
+	}
+	return api.tipsets[height], nil
+}
+
+func (api *fakeExplorerAPI) ChainGetFullBlock(ctx context.Context, id cid.Cid) (*types.FullBlock, error) {
+	blk, ok := api.blocks[id]
+	if !ok {
+		return nil, fmt.Errorf("block %s not found", id)
+	}
+	return blk, nil
+}
+
+func (api *fakeExplorerAPI) MessageFind(ctx context.Context, msgCid cid.Cid) (*msg.ChainMessage, bool, error) {
+	found, ok := api.messages[msgCid]
+	return found, ok, nil
+}
+
+func (api *fakeExplorerAPI) ActorGetAt(ctx context.Context, baseKey types.TipSetKey, addr address.Address) (*actor.Actor, error) {
+	api.atKeys = append(api.atKeys, baseKey)
+	a, ok := api.actors[addr]
+	if !ok {
+		return nil, fmt.Errorf("actor %s not found", addr)
+	}
+	return a, nil
+}
+
+func (api *fakeExplorerAPI) ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
+	if method != "addAsk" {
+		return nil, fmt.Errorf("no method %s", method)
+	}
+	return &exec.FunctionSignature{
+		Params: []abi.Type{abi.AttoFIL, abi.BlockHeight},
+		Return: []abi.Type{abi.Integer},
+	}, nil
+}
+
+func (api *fakeExplorerAPI) ActorDecodeState(ctx context.Context, a *actor.Actor) (map[string]string, error) {
+	return map[string]string{"Power": "1024"}, nil
+}
+
+func (api *fakeExplorerAPI) MinerGetPowerAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (porcelain.MinerPower, error) {
+	api.atKeys = append(api.atKeys, baseKey)
+	return porcelain.MinerPower{Power: *types.NewBytesAmount(1024), Total: *types.NewBytesAmount(2048)}, nil
+}
+
+func (api *fakeExplorerAPI) MinerGetAsksAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) ([]minerActor.Ask, error) {
+	api.atKeys = append(api.atKeys, baseKey)
+	return api.asks, nil
+}
+
+func get(t *testing.T, server http.Handler, path string, out interface{}) int {
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	if out != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec.Code
+}
+
+func TestExplorer(t *testing.T) {
+	tf.UnitTest(t)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	genesis := builder.NewGenesis()
+	ts1 := builder.AppendOn(genesis, 2)
+	blk := ts1.At(0)
+
+	mockSigner, _ := types.NewMockSignersAndKeyInfo(1)
+	minerAddr := address.NewForTestGetter()()
+	params, err := abi.ToEncodedValues(types.NewAttoFILFromFIL(2), types.NewBlockHeight(5))
+	require.NoError(t, err)
+	addAsk, err := types.NewSignedMessage(*types.NewMessage(mockSigner.Addresses[0], minerAddr, 0, types.ZeroAttoFIL, "addAsk", params), &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	require.NoError(t, err)
+	transfer, err := types.NewSignedMessage(*types.NewMessage(mockSigner.Addresses[0], minerAddr, 1, types.NewAttoFILFromFIL(1), "", nil), &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	require.NoError(t, err)
+	addAskCid, err := addAsk.Cid()
+	require.NoError(t, err)
+	ret, err := (&abi.Value{Type: abi.Integer, Val: big.NewInt(7)}).Serialize()
+	require.NoError(t, err)
+	receipt := &types.MessageReceipt{ExitCode: 0, Return: [][]byte{ret}}
+
+	api := &fakeExplorerAPI{
+		builder: builder,
+		tipsets: []types.TipSet{genesis, ts1},
+		blocks: map[cid.Cid]*types.FullBlock{
+			blk.Cid(): {
+				Header:   blk,
+				Messages: []*types.SignedMessage{addAsk, transfer},
+				Receipts: []*types.MessageReceipt{receipt, {}},
+			},
+		},
+		messages: map[cid.Cid]*msg.ChainMessage{
+			addAskCid: {Message: addAsk, Block: blk, Receipt: receipt},
+		},
+		actors: map[address.Address]*actor.Actor{
+			minerAddr: minerActor.NewActor(),
+		},
+		asks: []minerActor.Ask{{Price: types.NewAttoFILFromFIL(2), Expiry: types.NewBlockHeight(5), ID: big.NewInt(0)}},
+	}
+	server := explorer.NewServer(api)
+
+	t.Run("tipsets", func(t *testing.T) {
+		var ts explorer.TipSet
+		assert.Equal(t, http.StatusOK, get(t, server, "/tipsets/head", &ts))
+		assert.Equal(t, ts1.Key(), ts.Key)
+		assert.Equal(t, uint64(1), ts.Height)
+		assert.Equal(t, genesis.Key(), ts.Parents)
+		assert.Len(t, ts.Blocks, 2)
+
+		assert.Equal(t, http.StatusOK, get(t, server, "/tipsets/height/0", &ts))
+		assert.Equal(t, genesis.Key(), ts.Key)
+
+		keyPath := fmt.Sprintf("/tipsets/key/%s,%s", ts1.At(0).Cid(), ts1.At(1).Cid())
+		assert.Equal(t, http.StatusOK, get(t, server, keyPath, &ts))
+		assert.Equal(t, ts1.Key(), ts.Key)
+
+		var apiErr explorer.Error
+		assert.Equal(t, http.StatusNotFound, get(t, server, "/tipsets/height/2", &apiErr))
+		assert.Contains(t, apiErr.Error, "above the chain head")
+		assert.Equal(t, http.StatusBadRequest, get(t, server, "/tipsets/height/x", &apiErr))
+		assert.Equal(t, http.StatusBadRequest, get(t, server, "/tipsets/key/notacid", &apiErr))
+	})
+
+	t.Run("blocks decode message params", func(t *testing.T) {
+		var out explorer.Block
+		assert.Equal(t, http.StatusOK, get(t, server, "/blocks/"+blk.Cid().String(), &out))
+		assert.Equal(t, blk.Cid(), out.Cid)
+		require.Len(t, out.Messages, 2)
+		assert.Equal(t, addAskCid, out.Messages[0].Cid)
+		require.Len(t, out.Messages[0].Params, 2)
+		assert.Equal(t, "2", out.Messages[0].Params[0].Text)
+		assert.Equal(t, "5", out.Messages[0].Params[1].Text)
+		// Transfers have no parameters to decode.
+		assert.Empty(t, out.Messages[1].Params)
+		assert.Len(t, out.Receipts, 2)
+
+		assert.Equal(t, http.StatusNotFound, get(t, server, "/blocks/"+addAskCid.String(), nil))
+	})
+
+	t.Run("messages include their block and decoded return", func(t *testing.T) {
+		var out explorer.Message
+		assert.Equal(t, http.StatusOK, get(t, server, "/messages/"+addAskCid.String(), &out))
+		require.NotNil(t, out.Block)
+		assert.Equal(t, blk.Cid(), *out.Block)
+		assert.Equal(t, uint64(1), out.Height)
+		require.Len(t, out.Return, 1)
+		assert.Equal(t, "7", out.Return[0].Text)
+
+		assert.Equal(t, http.StatusNotFound, get(t, server, "/messages/"+blk.Cid().String(), nil))
+	})
+
+	t.Run("actors include decoded state", func(t *testing.T) {
+		var out explorer.Actor
+		assert.Equal(t, http.StatusOK, get(t, server, "/actors/"+minerAddr.String(), &out))
+		assert.Equal(t, "MinerActor", out.ActorType)
+		assert.Equal(t, map[string]string{"Power": "1024"}, out.State)
+
+		api.atKeys = nil
+		assert.Equal(t, http.StatusOK, get(t, server, "/actors/"+minerAddr.String()+"?at=0", &out))
+		assert.Equal(t, []types.TipSetKey{genesis.Key()}, api.atKeys)
+
+		assert.Equal(t, http.StatusBadRequest, get(t, server, "/actors/notanaddress", nil))
+		assert.Equal(t, http.StatusNotFound, get(t, server, "/actors/"+address.TestAddress.String(), nil))
+	})
+
+	t.Run("miners", func(t *testing.T) {
+		var power porcelain.MinerPower
+		assert.Equal(t, http.StatusOK, get(t, server, "/miners/"+minerAddr.String()+"/power", &power))
+		assert.Equal(t, *types.NewBytesAmount(1024), power.Power)
+
+		var asks []minerActor.Ask
+		assert.Equal(t, http.StatusOK, get(t, server, "/miners/"+minerAddr.String()+"/asks", &asks))
+		require.Len(t, asks, 1)
+		assert.Equal(t, types.NewAttoFILFromFIL(2), asks[0].Price)
+
+		assert.Equal(t, http.StatusNotFound, get(t, server, "/miners/"+minerAddr.String()+"/deals", nil))
+	})
+
+	t.Run("only reads", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/tipsets/head", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
@@ -71,6 +71,7 @@ func main() {
 
 	jsonout := flag.Bool("json", false, "sets output to be json")
 	testProofsMode := flag.Bool("test-proofs-mode", false, "change sealing, sector packing, PoSt, etc. to be compatible with test environments (overrides proofs mode read from JSON)")
+	fakeProofsMode := flag.Bool("fake-proofs-mode", false, "skip the proofs entirely, for networks whose nodes run with --fake-proofs (overrides proofs mode read from JSON)")
 	keypath := flag.String("keypath", ".", "sets location to write key files to")
 	outJSON := flag.String("out-json", "", "enables json output and writes it to the given file")
 	outCar := flag.String("out-car", "", "writes the generated car file to the give path, instead of stdout")
@@ -87,7 +88,14 @@ func main() {
 		panic(err)
 	}
 
-	gengen.ApplyProofsModeDefaults(cfg, !*testProofsMode, isFlagPassed("test-proofs-mode"))
+	mode := types.LiveProofsMode
+	if *testProofsMode {
+		mode = types.TestProofsMode
+	}
+	if *fakeProofsMode {
+		mode = types.FakeProofsMode
+	}
+	gengen.ApplyProofsModeDefaults(cfg, mode, isFlagPassed("test-proofs-mode") || isFlagPassed("fake-proofs-mode"))
 
 	outfile := os.Stdout
 	if *outCar != "" {
@@ -420,22 +420,20 @@ func (ggs *signer) SignBytes(data []byte, addr address.Address) (types.Signature
 }
 
 // ApplyProofsModeDefaults mutates the given genesis configuration, setting the
-// appropriate proofs mode and corresponding storage miner sector size. If
-// force is true, proofs mode and sector size-values will be overridden with the
-// appropriate defaults for the selected proofs mode.
-func ApplyProofsModeDefaults(cfg *GenesisCfg, useLiveProofsMode bool, force bool) {
-	mode := types.TestProofsMode
-	sectorSize := types.OneKiBSectorSize
-
-	if useLiveProofsMode {
-		mode = types.LiveProofsMode
-		sectorSize = types.TwoHundredFiftySixMiBSectorSize
-	}
-
+// given proofs mode if the configuration has none and the storage miner
+// sector size that corresponds to the configuration's proofs mode. If force
+// is true, proofs mode and sector size-values will be overridden with the
+// appropriate defaults for the given proofs mode.
+func ApplyProofsModeDefaults(cfg *GenesisCfg, mode types.ProofsMode, force bool) {
 	if cfg.ProofsMode == types.UnsetProofsMode || force {
 		cfg.ProofsMode = mode
 	}
 
+	sectorSize := types.OneKiBSectorSize
+	if cfg.ProofsMode == types.LiveProofsMode {
+		sectorSize = types.TwoHundredFiftySixMiBSectorSize
+	}
+
 	for _, m := range cfg.Miners {
 		if m.SectorSize == 0 || force {
 			m.SectorSize = sectorSize.Uint64()
@@ -107,7 +107,36 @@ func (ob *Outbox) Send(ctx context.Context, from, to address.Address, value type
 		return cid.Undef, errors.Wrap(err, "failed to sign message")
 	}
 
-	err = ob.validator.Validate(ctx, signed, fromActor)
+	return ob.publish(ctx, head, signed, fromActor, bcast)
+}
+
+// SendSigned validates a message signed elsewhere and sends it, retaining it
+// in the outbound message queue. Its nonce must follow the nonces of the
+// messages from the same address already in the queue.
+func (ob *Outbox) SendSigned(ctx context.Context, signed *types.SignedMessage, bcast bool) (out cid.Cid, err error) {
+	defer func() {
+		if err != nil {
+			msgSendErrCt.Inc(ctx, 1)
+		}
+	}()
+
+	ob.nonceLock.Lock()
+	defer ob.nonceLock.Unlock()
+
+	head := ob.chains.GetHead()
+
+	fromActor, err := ob.actors.GetActorAt(ctx, head, signed.From)
+	if err != nil {
+		return cid.Undef, errors.Wrapf(err, "no actor at address %s", signed.From)
+	}
+
+	return ob.publish(ctx, head, signed, fromActor, bcast)
+}
+
+// publish validates, enqueues and publishes a signed message. The nonce lock
+// must be held.
+func (ob *Outbox) publish(ctx context.Context, head types.TipSetKey, signed *types.SignedMessage, fromActor *actor.Actor, bcast bool) (cid.Cid, error) {
+	err := ob.validator.Validate(ctx, signed, fromActor)
 	if err != nil {
 		return cid.Undef, errors.Wrap(err, "invalid message")
 	}
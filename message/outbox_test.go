@@ -147,4 +147,45 @@ func TestOutbox(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "account or empty")
 	})
+
+	t.Run("send signed message enqueues and publishes it", func(t *testing.T) {
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		toAddr := address.NewForTestGetter()()
+		queue := message.NewQueue()
+		publisher := &message.MockPublisher{}
+		provider := message.NewFakeProvider(t)
+
+		head := provider.BuildOneOn(types.UndefTipSet, func(b *chain.BlockBuilder) {
+			b.IncHeight(1000)
+		})
+		actr, _ := account.NewActor(types.ZeroAttoFIL)
+		actr.Nonce = 42
+		provider.SetHeadAndActor(t, head.Key(), sender, actr)
+
+		// The outbox has no signer for the sender, the message is signed elsewhere.
+		ob := message.NewOutbox(types.MockSigner{}, message.FakeValidator{}, queue, publisher, message.NullPolicy{}, provider, provider)
+
+		msg := types.NewMessage(sender, toAddr, 42, types.ZeroAttoFIL, "", nil)
+		signed, err := types.NewSignedMessage(*msg, w, types.NewGasPrice(1), types.NewGasUnits(0))
+		require.NoError(t, err)
+
+		c, err := ob.SendSigned(context.Background(), signed, true)
+		require.NoError(t, err)
+		expected, err := signed.Cid()
+		require.NoError(t, err)
+		assert.Equal(t, expected, c)
+		assert.Equal(t, signed, publisher.Message)
+		require.Len(t, queue.List(sender), 1)
+
+		t.Run("rejects a nonce not following the queue", func(t *testing.T) {
+			msg := types.NewMessage(sender, toAddr, 50, types.ZeroAttoFIL, "", nil)
+			signed, err := types.NewSignedMessage(*msg, w, types.NewGasPrice(1), types.NewGasUnits(0))
+			require.NoError(t, err)
+
+			_, err = ob.SendSigned(context.Background(), signed, true)
+			assert.Error(t, err)
+			assert.Len(t, queue.List(sender), 1)
+		})
+	})
 }
@@ -0,0 +1,113 @@
+package mining
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/clock"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// State describes whether the scheduler is mining, or why it is waiting.
+type State string
+
+const (
+	// StateStopped is the state of a scheduler that has not been started.
+	StateStopped = State("stopped")
+	// StateMining is the state of a scheduler mining on the chain head.
+	StateMining = State("mining")
+	// StateSyncing is the state of a scheduler waiting for the chain to sync.
+	StateSyncing = State("syncing")
+	// StateBehind is the state of a scheduler waiting because the chain head
+	// is too many rounds behind the height expected from the wall clock.
+	StateBehind = State("behind")
+	// StateStaleHead is the state of a scheduler waiting because the chain
+	// head is older than the configured threshold.
+	StateStaleHead = State("stale-head")
+)
+
+// Status is the state of a scheduler and, when it is waiting, the reason.
+type Status struct {
+	State  State  `json:"state"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// SafetyChecks are the conditions a chain head must satisfy before the
+// scheduler mines on it. Mining on a head the rest of the network has moved
+// past wastes work and risks mining a fork.
+//
+// Note that the wall clock checks hold back a miner that is not winning
+// blocks on a quiet network as well, since its head ages while it mines null
+// rounds. They are disabled when zero.
+type SafetyChecks struct {
+	// SyncStatus returns the status of the chain syncer. Mining waits while a
+	// trusted sync is in progress.
+	SyncStatus func() chain.Status
+	// Clock is the wall clock heads are checked against.
+	Clock clock.Clock
+	// BlockTime is the expected time between rounds.
+	BlockTime time.Duration
+	// MaxLagRounds is the number of rounds the head may be behind the height
+	// expected from the wall clock.
+	MaxLagRounds uint64
+	// MaxHeadAge is the maximum age of the head's timestamp.
+	MaxHeadAge time.Duration
+}
+
+// Check returns the status of mining on `head`. A nil SafetyChecks allows
+// mining on any head.
+func (c *SafetyChecks) Check(head types.TipSet) (Status, error) {
+	if c == nil {
+		return Status{State: StateMining}, nil
+	}
+
+	height, err := head.Height()
+	if err != nil {
+		return Status{}, err
+	}
+
+	// The height of a sync that completed or failed says nothing about the
+	// network, so it is only considered while a trusted sync is in progress.
+	if c.SyncStatus != nil {
+		syncStatus := c.SyncStatus()
+		if syncStatus.SyncingTrusted && !syncStatus.SyncingComplete {
+			return Status{
+				State:  StateSyncing,
+				Reason: fmt.Sprintf("syncing to height %d from height %d", syncStatus.SyncingHeight, height),
+			}, nil
+		}
+	}
+
+	if c.MaxLagRounds == 0 && c.MaxHeadAge == 0 {
+		return Status{State: StateMining}, nil
+	}
+
+	timestamp, err := head.MinTimestamp()
+	if err != nil {
+		return Status{}, err
+	}
+	age := c.Clock.Since(time.Unix(int64(timestamp), 0))
+
+	if c.MaxLagRounds > 0 {
+		expected := height
+		if age > 0 && c.BlockTime > 0 {
+			expected += uint64(age / c.BlockTime)
+		}
+		if expected-height > c.MaxLagRounds {
+			return Status{
+				State:  StateBehind,
+				Reason: fmt.Sprintf("head at height %d is %d rounds behind expected height %d", height, expected-height, expected),
+			}, nil
+		}
+	}
+
+	if c.MaxHeadAge > 0 && age > c.MaxHeadAge {
+		return Status{
+			State:  StateStaleHead,
+			Reason: fmt.Sprintf("head at height %d is %s old", height, age.Round(time.Second)),
+		}, nil
+	}
+
+	return Status{State: StateMining}, nil
+}
@@ -0,0 +1,150 @@
+package mining_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/clock"
+	. "github.com/filecoin-project/go-filecoin/mining"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func headMinedAgo(t *testing.T, height uint64, age time.Duration) types.TipSet {
+	blk := &types.Block{
+		Height:    types.Uint64(height),
+		Timestamp: types.Uint64(time.Now().Add(-age).Unix()),
+		StateRoot: types.CidFromString(t, "somecid"),
+	}
+	ts, err := types.NewTipSet(blk)
+	require.NoError(t, err)
+	return ts
+}
+
+func TestSafetyChecks(t *testing.T) {
+	tf.UnitTest(t)
+
+	synced := func() chain.Status { return chain.Status{SyncingComplete: true, SyncingHeight: 10} }
+
+	t.Run("nil checks allow mining", func(t *testing.T) {
+		var checks *SafetyChecks
+		status, err := checks.Check(headMinedAgo(t, 10, time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, StateMining, status.State)
+	})
+
+	t.Run("waits for syncing to complete", func(t *testing.T) {
+		checks := &SafetyChecks{
+			SyncStatus: func() chain.Status {
+				return chain.Status{SyncingTrusted: true, SyncingComplete: false, SyncingHeight: 20}
+			},
+			Clock: clock.NewSystemClock(),
+		}
+		status, err := checks.Check(headMinedAgo(t, 10, 0))
+		require.NoError(t, err)
+		assert.Equal(t, StateSyncing, status.State)
+		assert.Contains(t, status.Reason, "syncing to height 20")
+	})
+
+	t.Run("waits while the head is behind the wall clock", func(t *testing.T) {
+		checks := &SafetyChecks{
+			SyncStatus:   synced,
+			Clock:        clock.NewSystemClock(),
+			BlockTime:    time.Minute,
+			MaxLagRounds: 5,
+		}
+		status, err := checks.Check(headMinedAgo(t, 10, 3*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, StateMining, status.State)
+
+		status, err = checks.Check(headMinedAgo(t, 10, 10*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, StateBehind, status.State)
+		assert.Contains(t, status.Reason, "expected height 20")
+	})
+
+	t.Run("ignores the target of untrusted or finished syncs", func(t *testing.T) {
+		for _, syncStatus := range []chain.Status{
+			{SyncingTrusted: false, SyncingComplete: false, SyncingHeight: 30},
+			{SyncingTrusted: true, SyncingComplete: true, SyncingHeight: 30},
+		} {
+			syncStatus := syncStatus
+			checks := &SafetyChecks{
+				SyncStatus:   func() chain.Status { return syncStatus },
+				Clock:        clock.NewSystemClock(),
+				BlockTime:    time.Minute,
+				MaxLagRounds: 5,
+			}
+			status, err := checks.Check(headMinedAgo(t, 10, 0))
+			require.NoError(t, err)
+			assert.Equal(t, StateMining, status.State)
+		}
+	})
+
+	t.Run("waits while the head is stale", func(t *testing.T) {
+		checks := &SafetyChecks{
+			SyncStatus: synced,
+			Clock:      clock.NewSystemClock(),
+			BlockTime:  time.Minute,
+			MaxHeadAge: time.Hour,
+		}
+		status, err := checks.Check(headMinedAgo(t, 10, 30*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, StateMining, status.State)
+
+		status, err = checks.Check(headMinedAgo(t, 10, 2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, StateStaleHead, status.State)
+	})
+}
+
+func TestSchedulerPausesUntilSynced(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	head := headMinedAgo(t, 1, 0)
+	headFunc := func() (types.TipSet, error) {
+		return head, nil
+	}
+	syncStatus := make(chan chain.Status, 1)
+	syncStatus <- chain.Status{SyncingTrusted: true, SyncingComplete: false, SyncingHeight: 10}
+	checks := &SafetyChecks{
+		SyncStatus: func() chain.Status {
+			status := <-syncStatus
+			syncStatus <- status
+			return status
+		},
+		Clock: clock.NewSystemClock(),
+	}
+	mined := make(chan struct{}, 1)
+	worker := NewTestWorkerWithDeps(func(c context.Context, inTS types.TipSet, tArr []types.Ticket, outCh chan<- Output) (bool, types.Ticket) {
+		select {
+		case mined <- struct{}{}:
+		default:
+		}
+		return false, types.Ticket{}
+	})
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, checks)
+	assert.Equal(t, StateStopped, scheduler.Status().State)
+
+	scheduler.Start(ctx)
+	time.Sleep(3 * MineDelayTest)
+	assert.Equal(t, StateSyncing, scheduler.Status().State)
+	select {
+	case <-mined:
+		t.Fatal("mined while syncing")
+	default:
+	}
+
+	<-syncStatus
+	syncStatus <- chain.Status{SyncingTrusted: true, SyncingComplete: true, SyncingHeight: 1}
+	<-mined
+	assert.Equal(t, StateMining, scheduler.Status().State)
+}
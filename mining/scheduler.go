@@ -54,6 +54,7 @@ import (
 type Scheduler interface {
 	Start(miningCtx context.Context) (<-chan Output, *sync.WaitGroup)
 	IsStarted() bool
+	Status() Status
 }
 
 type timingScheduler struct {
@@ -64,8 +65,14 @@ type timingScheduler struct {
 	// pollHeadFunc is the function the scheduler uses to poll for the
 	// current heaviest tipset
 	pollHeadFunc func() (types.TipSet, error)
+	// checks decide whether the polled head is safe to mine on. Nil checks
+	// allow mining on any head.
+	checks *SafetyChecks
 
 	isStarted bool
+
+	statusLk sync.Mutex
+	status   Status
 }
 
 // MineDelayConversionFactor is the constant that divides the mining block time
@@ -92,6 +99,7 @@ func (s *timingScheduler) Start(miningCtx context.Context) (<-chan Output, *sync
 	doneWg.Add(1)
 
 	s.isStarted = true
+	s.setStatus(Status{State: StateMining})
 	go func() {
 		defer doneWg.Done()
 		ticketArray := []types.Ticket{}
@@ -102,6 +110,7 @@ func (s *timingScheduler) Start(miningCtx context.Context) (<-chan Output, *sync
 			select {
 			case <-miningCtx.Done():
 				s.isStarted = false
+				s.setStatus(Status{State: StateStopped})
 				return
 			default:
 			}
@@ -118,6 +127,16 @@ func (s *timingScheduler) Start(miningCtx context.Context) (<-chan Output, *sync
 				// TODO: investigate if there is a better way to handle this situation.
 				continue
 			}
+			// Wait, without mining, until the head is safe to mine on.
+			status, err := s.checks.Check(base)
+			if err != nil {
+				log.Warningf("failed to check mining base %s: %s", base.String(), err)
+				continue
+			}
+			s.setStatus(status)
+			if status.State != StateMining {
+				continue
+			}
 
 			// Determine how many null blocks we should mine with.
 			ticketArray = nextTicketArray(ticketArray, prevBase.Key(), base.Key())
@@ -147,6 +166,28 @@ func (s *timingScheduler) IsStarted() bool {
 	return s.isStarted
 }
 
+// Status returns whether the scheduler is mining or, if it is waiting for the
+// chain, why.
+func (s *timingScheduler) Status() Status {
+	s.statusLk.Lock()
+	defer s.statusLk.Unlock()
+	return s.status
+}
+
+// setStatus records the scheduler's status, logging when mining pauses or
+// resumes.
+func (s *timingScheduler) setStatus(status Status) {
+	s.statusLk.Lock()
+	defer s.statusLk.Unlock()
+	paused := func(state State) bool { return state != StateMining && state != StateStopped }
+	if paused(status.State) && status.State != s.status.State {
+		log.Infof("pausing mining: %s", status.Reason)
+	} else if status.State == StateMining && paused(s.status.State) {
+		log.Infof("resuming mining")
+	}
+	s.status = status
+}
+
 // nextTicketArray outputs the next ticket array for use in mining on top of
 // the current base tipset, curBase, given the previous base, prevBase and the
 // exisiting ticket array.
@@ -169,9 +210,10 @@ func nextTicketArray(prevTicketArray []types.Ticket, prevBase, curBase types.Tip
 }
 
 // NewScheduler returns a new timingScheduler to schedule mining work on the
-// input worker.
-func NewScheduler(w Worker, md time.Duration, f func() (types.TipSet, error)) Scheduler {
-	return &timingScheduler{worker: w, mineDelay: md, pollHeadFunc: f}
+// input worker. The scheduler only mines on heads that pass the safety
+// checks; nil checks allow mining on any head.
+func NewScheduler(w Worker, md time.Duration, f func() (types.TipSet, error), checks *SafetyChecks) Scheduler {
+	return &timingScheduler{worker: w, mineDelay: md, pollHeadFunc: f, checks: checks, status: Status{State: StateStopped}}
 }
 
 // MineOnce is a convenience function that presents a synchronous blocking
@@ -184,7 +226,7 @@ func MineOnce(ctx context.Context, w Worker, md time.Duration, ts types.TipSet)
 	pollHeadFunc := func() (types.TipSet, error) {
 		return ts, nil
 	}
-	s := NewScheduler(w, md, pollHeadFunc)
+	s := NewScheduler(w, md, pollHeadFunc, nil)
 	subCtx, subCtxCancel := context.WithCancel(ctx)
 	defer subCtxCancel()
 
@@ -118,7 +118,7 @@ func TestSchedulerPassesValue(t *testing.T) {
 		return head, nil
 	}
 	worker := NewTestWorkerWithDeps(checkValsMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, nil)
 	head = ts // set head so headFunc returns correctly
 	outCh, _ := scheduler.Start(ctx)
 	<-outCh
@@ -138,7 +138,7 @@ func TestSchedulerErrorsOnUnsetHead(t *testing.T) {
 		return types.UndefTipSet, nil
 	}
 	worker := NewTestWorkerWithDeps(nothingMine)
-	scheduler := NewScheduler(worker, MineDelayTest, nilHeadFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, nilHeadFunc, nil)
 	outCh, doneWg := scheduler.Start(ctx)
 	output := <-outCh
 	assert.Error(t, output.Err)
@@ -171,7 +171,7 @@ func TestSchedulerUpdatesTicketArray(t *testing.T) {
 		return head, nil
 	}
 	worker := NewTestWorkerWithDeps(checkTArrMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, nil)
 	head = ts
 	outCh, _ := scheduler.Start(ctx)
 	<-outCh
@@ -215,7 +215,7 @@ func TestSchedulerPassesManyValues(t *testing.T) {
 		return false, types.Ticket{}
 	}
 	worker := NewTestWorkerWithDeps(checkValsMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, nil)
 	checkTS = ts1
 	head = ts1
 	outCh, _ := scheduler.Start(ctx)
@@ -251,7 +251,7 @@ func TestSchedulerCollect(t *testing.T) {
 		return false, types.Ticket{}
 	}
 	worker := NewTestWorkerWithDeps(checkValsMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, nil)
 	head = ts1
 	outCh, _ := scheduler.Start(ctx)
 	// again this is racing on the assumption that mining delay is long
@@ -315,7 +315,7 @@ func TestSchedulerCancelMiningCtx(t *testing.T) {
 		return false, types.Ticket{}
 	}
 	worker := NewTestWorkerWithDeps(shouldCancelMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, nil)
 	head = ts
 	outCh, doneWg := scheduler.Start(miningCtx)
 	miningCtxCancel()
@@ -344,7 +344,7 @@ func TestSchedulerMultiRoundWithCollect(t *testing.T) {
 		return false, types.Ticket{}
 	}
 	worker := NewTestWorkerWithDeps(checkValsMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, nil)
 	checkTS = ts1
 	head = ts1
 	outCh, doneWg := scheduler.Start(ctx)
@@ -34,6 +34,14 @@ func (s *MockScheduler) IsStarted() bool {
 	return s.isStarted
 }
 
+// Status returns StateMining once the MockScheduler has been started.
+func (s *MockScheduler) Status() Status {
+	if s.isStarted {
+		return Status{State: StateMining}
+	}
+	return Status{State: StateStopped}
+}
+
 // TestWorker is a worker with a customizable work function to facilitate
 // easy testing.
 type TestWorker struct {
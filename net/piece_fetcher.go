@@ -0,0 +1,116 @@
+package net
+
+import (
+	"context"
+	"sync"
+
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	exchange "github.com/ipfs/go-ipfs-exchange-interface"
+	ipld "github.com/ipfs/go-ipld-format"
+	dag "github.com/ipfs/go-merkledag"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+)
+
+// PieceFetcher transfers the DAG of a storage deal's piece from the peer that
+// holds it into the local blockstore.
+type PieceFetcher interface {
+	// FetchPiece fetches every block of the DAG rooted at root that is not
+	// already stored locally from the given peer. progress is called with the
+	// total number of bytes of the DAG stored locally as the transfer advances.
+	FetchPiece(ctx context.Context, p peer.ID, root cid.Cid, progress func(bytesTransferred uint64)) error
+}
+
+// interface conformance check
+var _ PieceFetcher = (*ExchangePieceFetcher)(nil)
+
+// ExchangePieceFetcher pulls piece DAGs through a block exchange. Blocks
+// already in the local blockstore are not requested again, so a transfer that
+// is interrupted picks up where it left off when it is restarted.
+//
+// Pieces are unixfs (dag-pb) DAGs, which graphsync cannot traverse with the
+// codecs go-ipld-prime supports, so they are fetched through the exchange
+// rather than over graphsync.
+type ExchangePieceFetcher struct {
+	blockService bserv.BlockService
+	store        bstore.Blockstore
+}
+
+// NewExchangePieceFetcher returns an ExchangePieceFetcher that requests blocks
+// through exchange and stores them in blockstore.
+func NewExchangePieceFetcher(exchange exchange.Interface, blockstore bstore.Blockstore) *ExchangePieceFetcher {
+	return &ExchangePieceFetcher{
+		blockService: bserv.New(blockstore, exchange),
+		store:        blockstore,
+	}
+}
+
+// FetchPiece fetches the DAG rooted at root. The exchange asks the peers the
+// node is connected to, which include the client p that proposed the deal.
+// Blocks are requested in parallel within a single exchange session, as
+// FetchGraph does.
+func (pf *ExchangePieceFetcher) FetchPiece(ctx context.Context, p peer.ID, root cid.Cid, progress func(bytesTransferred uint64)) error {
+	var stored uint64
+	getter := &progressNodeGetter{
+		session: bserv.NewSession(ctx, pf.blockService),
+		store:   pf.store,
+		received: func(size uint64) {
+			stored += size
+			progress(stored)
+		},
+	}
+	if err := dag.EnumerateChildrenAsync(ctx, dag.GetLinksDirect(getter), root, cid.NewSet().Visit); err != nil {
+		return errors.Wrapf(err, "failed to fetch block of piece %s", root)
+	}
+	return nil
+}
+
+// progressNodeGetter gets nodes through an exchange session, storing the
+// blocks it fetches and reporting the size of every block of the DAG. It is
+// called concurrently.
+type progressNodeGetter struct {
+	session *bserv.Session
+	store   bstore.Blockstore
+
+	lk       sync.Mutex
+	received func(size uint64)
+}
+
+var _ ipld.NodeGetter = (*progressNodeGetter)(nil)
+
+// Get returns the node with the given cid, fetching it if it is not stored.
+func (g *progressNodeGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	has, err := g.store.Has(c)
+	if err != nil {
+		return nil, err
+	}
+	blk, err := g.session.GetBlock(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		// The exchange may or may not have stored the block itself.
+		if err := g.store.Put(blk); err != nil {
+			return nil, errors.Wrapf(err, "failed to store block %s", c)
+		}
+	}
+	g.lk.Lock()
+	g.received(uint64(len(blk.RawData())))
+	g.lk.Unlock()
+	return ipld.Decode(blk)
+}
+
+// GetMany returns the nodes with the given cids.
+func (g *progressNodeGetter) GetMany(ctx context.Context, cids []cid.Cid) <-chan *ipld.NodeOption {
+	out := make(chan *ipld.NodeOption, len(cids))
+	go func() {
+		defer close(out)
+		for _, c := range cids {
+			nd, err := g.Get(ctx, c)
+			out <- &ipld.NodeOption{Node: nd, Err: err}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,169 @@
+package net_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	format "github.com/ipfs/go-ipld-format"
+	dag "github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs/importer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/net"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestExchangePieceFetcher(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	pid := th.RequireIntPeerID(t, 0)
+
+	leaf1 := requireCborNode(t, map[string]interface{}{"data": "leaf one"})
+	leaf2 := requireCborNode(t, map[string]interface{}{"data": "leaf two"})
+	root := requireCborNode(t, map[string]interface{}{"children": []cid.Cid{leaf1.Cid(), leaf2.Cid()}})
+	piece := []format.Node{root, leaf1, leaf2}
+	pieceSize := uint64(len(root.RawData()) + len(leaf1.RawData()) + len(leaf2.RawData()))
+
+	t.Run("fetches a piece and reports its size", func(t *testing.T) {
+		bs := bstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
+		exchange := &fakeBlockFetcher{t: t, blocks: piece}
+		fetcher := net.NewExchangePieceFetcher(exchange, bs)
+
+		var transferred uint64
+		err := fetcher.FetchPiece(ctx, pid, root.Cid(), func(bytes uint64) { transferred = bytes })
+		require.NoError(t, err)
+
+		requireBlockstoreHasAll(t, bs, piece)
+		assert.Equal(t, 3, exchange.fetched)
+		assert.Equal(t, pieceSize, transferred)
+	})
+
+	t.Run("only requests the blocks of a piece that are missing", func(t *testing.T) {
+		bs := bstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
+		requireBlockStorePut(t, bs, root)
+		requireBlockStorePut(t, bs, leaf1)
+		exchange := &fakeBlockFetcher{t: t, blocks: piece}
+		fetcher := net.NewExchangePieceFetcher(exchange, bs)
+
+		var transferred uint64
+		err := fetcher.FetchPiece(ctx, pid, root.Cid(), func(bytes uint64) { transferred = bytes })
+		require.NoError(t, err)
+
+		requireBlockstoreHasAll(t, bs, piece)
+		assert.Equal(t, 1, exchange.fetched)
+		assert.Equal(t, pieceSize, transferred)
+	})
+
+	t.Run("fetches unixfs pieces", func(t *testing.T) {
+		source := dag.NewDAGService(bserv.New(bstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore())), nil))
+		data := make([]byte, 10000)
+		_, err := rand.New(rand.NewSource(0)).Read(data)
+		require.NoError(t, err)
+		fileRoot, err := importer.BuildDagFromReader(source, chunker.NewSizeSplitter(bytes.NewReader(data), 512))
+		require.NoError(t, err)
+
+		var file []format.Node
+		var fileSize uint64
+		queue := []cid.Cid{fileRoot.Cid()}
+		for len(queue) > 0 {
+			nd, err := source.Get(ctx, queue[0])
+			require.NoError(t, err)
+			queue = queue[1:]
+			file = append(file, nd)
+			fileSize += uint64(len(nd.RawData()))
+			for _, link := range nd.Links() {
+				queue = append(queue, link.Cid)
+			}
+		}
+		require.True(t, len(file) > 2)
+
+		bs := bstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
+		exchange := &fakeBlockFetcher{t: t, blocks: file}
+		fetcher := net.NewExchangePieceFetcher(exchange, bs)
+
+		var transferred uint64
+		err = fetcher.FetchPiece(ctx, pid, fileRoot.Cid(), func(bytes uint64) { transferred = bytes })
+		require.NoError(t, err)
+
+		requireBlockstoreHasAll(t, bs, file)
+		assert.Equal(t, len(file), exchange.fetched)
+		assert.Equal(t, fileSize, transferred)
+	})
+
+	t.Run("fails when the piece cannot be fetched", func(t *testing.T) {
+		bs := bstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
+		fetcher := net.NewExchangePieceFetcher(&fakeBlockFetcher{t: t}, bs)
+
+		err := fetcher.FetchPiece(ctx, pid, root.Cid(), func(uint64) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to fetch block")
+	})
+}
+
+func requireCborNode(t *testing.T, obj interface{}) format.Node {
+	nd, err := cbor.WrapObject(obj, types.DefaultHashFunction, -1)
+	require.NoError(t, err)
+	return nd
+}
+
+func requireBlockstoreHasAll(t *testing.T, bs bstore.Blockstore, nodes []format.Node) {
+	for _, nd := range nodes {
+		has, err := bs.Has(nd.Cid())
+		require.NoError(t, err)
+		require.True(t, has, "blockstore is missing %s", nd.Cid())
+	}
+}
+
+// fakeBlockFetcher is an exchange that serves blocks from a fixed set of
+// nodes.
+type fakeBlockFetcher struct {
+	t      *testing.T
+	blocks []format.Node
+
+	lk      sync.Mutex
+	fetched int
+}
+
+func (fbf *fakeBlockFetcher) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	for _, nd := range fbf.blocks {
+		if nd.Cid().Equals(c) {
+			fbf.lk.Lock()
+			fbf.fetched++
+			fbf.lk.Unlock()
+			return nd, nil
+		}
+	}
+	return nil, fmt.Errorf("block %s not found", c)
+}
+
+func (fbf *fakeBlockFetcher) GetBlocks(ctx context.Context, cids []cid.Cid) (<-chan blocks.Block, error) {
+	require.Fail(fbf.t, "unexpected GetBlocks call")
+	return nil, nil
+}
+
+func (fbf *fakeBlockFetcher) HasBlock(blocks.Block) error {
+	return nil
+}
+
+func (fbf *fakeBlockFetcher) IsOnline() bool {
+	return true
+}
+
+func (fbf *fakeBlockFetcher) Close() error {
+	return nil
+}
@@ -45,9 +45,11 @@ import (
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
 	"github.com/filecoin-project/go-filecoin/plumbing/cst"
 	"github.com/filecoin-project/go-filecoin/plumbing/dag"
+	"github.com/filecoin-project/go-filecoin/plumbing/evt"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/plumbing/strgdls"
 	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorstorage"
 	"github.com/filecoin-project/go-filecoin/proofs/verification"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/state"
@@ -62,6 +64,7 @@ type Builder struct {
 	BlockTime   time.Duration
 	Libp2pOpts  []libp2p.Option
 	OfflineMode bool
+	FakeProofs  bool
 	Verifier    verification.Verifier
 	Rewarder    consensus.BlockRewarder
 	Repo        repo.Repo
@@ -81,6 +84,16 @@ func OfflineMode(offlineMode bool) BuilderOpt {
 	}
 }
 
+// FakeProofs enables or disables fake proofs. With fake proofs the node seals
+// and proves sectors with a sector builder that needs neither the proofs
+// library nor its parameters, and accepts any proof.
+func FakeProofs(fakeProofs bool) BuilderOpt {
+	return func(c *Builder) error {
+		c.FakeProofs = fakeProofs
+		return nil
+	}
+}
+
 // IsRelay configures node to act as a libp2p relay.
 func IsRelay() BuilderOpt {
 	return func(c *Builder) error {
@@ -164,6 +177,14 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		b.Repo = repo.NewInMemoryRepo()
 	}
 
+	if b.FakeProofs && b.Verifier == nil {
+		b.Verifier = &verification.FakeVerifier{
+			VerifyPoStValid:                true,
+			VerifyPieceInclusionProofValid: true,
+			VerifySealValid:                true,
+		}
+	}
+
 	var err error
 
 	// fetch genesis block id
@@ -175,6 +196,7 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 	// create the node
 	nd := &Node{
 		OfflineMode: b.OfflineMode,
+		FakeProofs:  b.FakeProofs,
 		Clock:       b.Clock,
 		Repo:        b.Repo,
 	}
@@ -214,7 +236,7 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		return nil, errors.Wrap(err, "failed to build node.Messaging")
 	}
 
-	nd.StorageNetworking, err = b.buildStorgeNetworking(ctx, &nd.Network)
+	nd.StorageNetworking, err = b.buildStorgeNetworking(ctx, &nd.Network, &nd.Blockstore)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build node.StorageNetworking")
 	}
@@ -248,6 +270,8 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		return nil, errors.Wrap(err, "failed to build node.FaultSlasher")
 	}
 
+	msgWaiter := msg.NewWaiter(nd.Chain.ChainReader, nd.Chain.MessageStore, nd.Chain.MessageIndex, nd.Blockstore.Blockstore, nd.Blockstore.cborStore)
+
 	nd.PorcelainAPI = porcelain.New(plumbing.New(&plumbing.APIDeps{
 		Bitswap:       nd.Network.bitswap,
 		Chain:         nd.Chain.State,
@@ -255,14 +279,17 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		Config:        cfg.NewConfig(b.Repo),
 		DAG:           dag.NewDAG(merkledag.NewDAGService(nd.Blockservice.blockservice)),
 		Deals:         strgdls.New(b.Repo.DealsDatastore()),
+		Events:        evt.NewEvents(nd.Chain.ChainReader, msgWaiter),
 		Expected:      nd.Chain.Consensus,
 		MsgPool:       nd.Messaging.msgPool,
 		MsgPreviewer:  msg.NewPreviewer(nd.Chain.ChainReader, nd.Blockstore.cborStore, nd.Blockstore.Blockstore, nd.Chain.processor),
+		MsgTracer:     nd.Chain.tracer,
 		ActState:      nd.Chain.ActorState,
-		MsgWaiter:     msg.NewWaiter(nd.Chain.ChainReader, nd.Chain.MessageStore, nd.Blockstore.Blockstore, nd.Blockstore.cborStore),
+		MsgWaiter:     msgWaiter,
 		Network:       nd.Network.Network,
 		Outbox:        nd.Messaging.Outbox,
 		SectorBuilder: nd.SectorBuilder,
+		SectorStorage: nd.SectorStorage.Manager,
 		Wallet:        nd.Wallet.Wallet,
 	}))
 
@@ -342,6 +369,13 @@ func (b *Builder) buildNetwork(ctx context.Context, config *config.BootstrapConf
 	//nwork := bsnet.NewFromIpfsHost(innerHost, router)
 	bswap := bitswap.New(ctx, nwork, blockstore.Blockstore)
 
+	// set up graphsync
+	graphsyncNetwork := gsnet.NewFromLibp2pHost(peerHost)
+	bridge := ipldbridge.NewIPLDBridge()
+	loader := gsstoreutil.LoaderForBlockstore(blockstore.Blockstore)
+	storer := gsstoreutil.StorerForBlockstore(blockstore.Blockstore)
+	gsync := graphsync.New(ctx, graphsyncNetwork, bridge, loader, storer)
+
 	// set up pinger
 	pingService := ping.NewPingService(peerHost)
 
@@ -350,15 +384,16 @@ func (b *Builder) buildNetwork(ctx context.Context, config *config.BootstrapConf
 
 	// build the network submdule
 	return NetworkSubmodule{
-		NetworkName:  networkName,
-		host:         peerHost,
-		PeerHost:     peerHost,
-		Bootstrapper: bootstrapper,
-		PeerTracker:  peerTracker,
-		Router:       router,
-		fsub:         fsub,
-		bitswap:      bswap,
-		Network:      network,
+		NetworkName:   networkName,
+		host:          peerHost,
+		PeerHost:      peerHost,
+		Bootstrapper:  bootstrapper,
+		PeerTracker:   peerTracker,
+		Router:        router,
+		fsub:          fsub,
+		bitswap:       bswap,
+		GraphExchange: gsync,
+		Network:       network,
 	}, nil
 }
 
@@ -410,6 +445,11 @@ func (b *Builder) buildChain(ctx context.Context, blockstore *BlockstoreSubmodul
 	} else {
 		processor = consensus.NewConfiguredProcessor(consensus.NewDefaultMessageValidator(), b.Rewarder, builtin.DefaultActors)
 	}
+	// Nodes with fake proofs also accept any proof in messages. Other nodes
+	// verify them with the proofs library whatever verifier consensus uses.
+	if b.FakeProofs {
+		processor = processor.WithVerifier(b.Verifier)
+	}
 
 	// setup block validation
 	// TODO when #2961 is resolved do the needful here.
@@ -427,14 +467,17 @@ func (b *Builder) buildChain(ctx context.Context, blockstore *BlockstoreSubmodul
 	nodeChainSelector := consensus.NewChainSelector(blockstore.cborStore, actorState, b.genCid, pvt)
 
 	// setup fecher
-	graphsyncNetwork := gsnet.NewFromLibp2pHost(network.host)
-	bridge := ipldbridge.NewIPLDBridge()
-	loader := gsstoreutil.LoaderForBlockstore(blockstore.Blockstore)
-	storer := gsstoreutil.StorerForBlockstore(blockstore.Blockstore)
-	gsync := graphsync.New(ctx, graphsyncNetwork, bridge, loader, storer)
-	fetcher := net.NewGraphSyncFetcher(ctx, gsync, blockstore.Blockstore, blkValid, b.Clock, network.PeerTracker)
+	fetcher := net.NewGraphSyncFetcher(ctx, network.GraphExchange, blockstore.Blockstore, blkValid, b.Clock, network.PeerTracker)
 
 	messageStore := chain.NewMessageStore(blockstore.cborStore)
+	messageIndex := msg.NewIndex(chainStore, messageStore, b.Repo.ChainDatastore())
+
+	// persist the execution traces of tipsets joining the chain if configured to
+	var traceStore *msg.TraceStore
+	if b.Repo.Config().Observability.ExecutionTraces.Persist {
+		traceStore = msg.NewTraceStore(b.Repo.Datastore())
+	}
+	tracer := msg.NewTracer(chainStore, messageStore, messageIndex, blockstore.Blockstore, processor, traceStore)
 
 	// only the syncer gets the storage which is online connected
 	chainSyncer := chain.NewSyncer(nodeConsensus, nodeChainSelector, chainStore, messageStore, fetcher, chainStatusReporter, b.Clock)
@@ -447,6 +490,7 @@ func (b *Builder) buildChain(ctx context.Context, blockstore *BlockstoreSubmodul
 		ChainSelector: nodeChainSelector,
 		ChainReader:   chainStore,
 		MessageStore:  messageStore,
+		MessageIndex:  messageIndex,
 		Syncer:        chainSyncer,
 		ActorState:    actorState,
 		// HeaviestTipSetCh: nil,
@@ -456,6 +500,7 @@ func (b *Builder) buildChain(ctx context.Context, blockstore *BlockstoreSubmodul
 		State:       chainState,
 		validator:   blkValid,
 		processor:   processor,
+		tracer:      tracer,
 	}, nil
 }
 
@@ -475,6 +520,7 @@ func (b *Builder) buildBlockMining(ctx context.Context) (BlockMiningSubmodule, e
 func (b *Builder) buildSectorStorage(ctx context.Context) (SectorBuilderSubmodule, error) {
 	return SectorBuilderSubmodule{
 		// sectorBuilder: nil,
+		Manager: sectorstorage.NewManager(b.Repo),
 	}, nil
 }
 
@@ -510,9 +556,10 @@ func (b *Builder) buildWallet(ctx context.Context) (WalletSubmodule, error) {
 	}, nil
 }
 
-func (b *Builder) buildStorgeNetworking(ctx context.Context, network *NetworkSubmodule) (StorageNetworkingSubmodule, error) {
+func (b *Builder) buildStorgeNetworking(ctx context.Context, network *NetworkSubmodule, blockstore *BlockstoreSubmodule) (StorageNetworkingSubmodule, error) {
 	return StorageNetworkingSubmodule{
-		Exchange: network.bitswap,
+		Exchange:     network.bitswap,
+		PieceFetcher: net.NewExchangePieceFetcher(network.bitswap, blockstore.Blockstore),
 	}, nil
 }
 
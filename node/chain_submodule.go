@@ -8,6 +8,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/net"
 	"github.com/filecoin-project/go-filecoin/net/pubsub"
 	"github.com/filecoin-project/go-filecoin/plumbing/cst"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/util/moresync"
 )
 
@@ -18,6 +19,7 @@ type ChainSubmodule struct {
 	ChainSelector nodeChainSelector
 	ChainReader   nodeChainReader
 	MessageStore  *chain.MessageStore
+	MessageIndex  *msg.Index
 	Syncer        nodeChainSyncer
 	ActorState    *consensus.ActorStateStore
 
@@ -36,4 +38,5 @@ type ChainSubmodule struct {
 
 	validator consensus.BlockValidator
 	processor *consensus.DefaultProcessor
+	tracer    *msg.Tracer
 }
@@ -30,5 +30,8 @@ type NetworkSubmodule struct {
 	// TODO: split chain bitswap from storage bitswap (issue: ???)
 	bitswap exchange.Interface
 
+	// GraphExchange is the graphsync exchange used to fetch chain data.
+	GraphExchange net.GraphExchange
+
 	Network *net.Network
 }
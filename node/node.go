@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"time"
@@ -29,9 +30,12 @@ import (
 	"github.com/filecoin-project/go-filecoin/paths"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorstorage"
+	"github.com/filecoin-project/go-filecoin/proofs/verification"
 	"github.com/filecoin-project/go-filecoin/protocol/block"
 	"github.com/filecoin-project/go-filecoin/protocol/hello"
 	"github.com/filecoin-project/go-filecoin/protocol/retrieval"
+	"github.com/filecoin-project/go-filecoin/protocol/sealworker"
 	"github.com/filecoin-project/go-filecoin/protocol/storage"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/state"
@@ -52,6 +56,10 @@ type Node struct {
 	// OfflineMode, when true, disables libp2p.
 	OfflineMode bool
 
+	// FakeProofs, when true, replaces sealing and proving with fakes and
+	// accepts any proof.
+	FakeProofs bool
+
 	// Clock is a clock used by the node for time.
 	Clock clock.Clock
 
@@ -117,6 +125,10 @@ func (node *Node) Start(ctx context.Context) error {
 		return err
 	}
 
+	if err := node.checkProofsMode(ctx); err != nil {
+		return err
+	}
+
 	// Only set these up if there is a miner configured.
 	if _, err := node.MiningAddress(); err == nil {
 		if err := node.setupSectorBuilder(ctx); err != nil {
@@ -214,6 +226,9 @@ func (node *Node) Start(ctx context.Context) error {
 			return err
 		}
 
+		// Keep client deals up to date by polling their miners.
+		go node.StorageProtocol.StorageAPI.TrackDeals(syncCtx)
+
 		// Start heartbeats.
 		if err := node.setupHeartbeatServices(ctx); err != nil {
 			return errors.Wrap(err, "failed to start heartbeat services")
@@ -315,6 +330,10 @@ func (node *Node) handleNewChainHeads(ctx context.Context, prevHead types.TipSet
 	node.Chain.HeaviestTipSetCh = node.Chain.ChainReader.HeadEvents().Sub(chain.NewHeadTopic)
 	handler := message.NewHeadHandler(node.Messaging.Inbox, node.Messaging.Outbox, node.Chain.ChainReader, prevHead)
 
+	// Holds the latest head the indexer has not picked up yet.
+	indexHeads := make(chan types.TipSet, 1)
+	go node.indexNewChainHeads(ctx, indexHeads, prevHead)
+
 	for {
 		select {
 		case ts, ok := <-node.Chain.HeaviestTipSetCh:
@@ -330,6 +349,17 @@ func (node *Node) handleNewChainHeads(ctx context.Context, prevHead types.TipSet
 			if err := handler.HandleNewHead(ctx, newHead); err != nil {
 				log.Error(err)
 			}
+			select {
+			case indexHeads <- newHead:
+			default:
+				// The indexer is behind, replace the head it has not
+				// picked up yet. It catches up over the skipped heads.
+				select {
+				case <-indexHeads:
+				default:
+				}
+				indexHeads <- newHead
+			}
 
 			if node.StorageProtocol.StorageMiner != nil {
 				if _, err := node.StorageProtocol.StorageMiner.OnNewHeaviestTipSet(newHead); err != nil {
@@ -347,6 +377,27 @@ func (node *Node) handleNewChainHeads(ctx context.Context, prevHead types.TipSet
 	}
 }
 
+// indexNewChainHeads brings the message index and the recorded execution
+// traces up to date with each head received on heads. It runs apart from the
+// head loop so the storage miner and fault slasher are not held up by
+// indexing and tracing.
+func (node *Node) indexNewChainHeads(ctx context.Context, heads <-chan types.TipSet, prevHead types.TipSet) {
+	for {
+		select {
+		case newHead := <-heads:
+			if err := node.Chain.MessageIndex.Update(ctx); err != nil {
+				log.Errorf("failed to index messages: %s", err)
+			}
+			if err := node.Chain.tracer.RecordTraces(ctx, prevHead, newHead); err != nil {
+				log.Errorf("failed to record execution traces: %s", err)
+			}
+			prevHead = newHead
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (node *Node) cancelSubscriptions() {
 	if node.Chain.cancelChainSync != nil {
 		node.Chain.cancelChainSync()
@@ -455,11 +506,35 @@ func (node *Node) SetupMining(ctx context.Context) error {
 			return errors.Wrap(err, "failed to initialize storage miner")
 		}
 		node.StorageProtocol.StorageMiner = storageMiner
+
+		// pick up deals that were interrupted the last time the miner ran
+		if err := storageMiner.ResumeDeals(ctx); err != nil {
+			log.Errorf("failed to resume storage deals: %s", err)
+		}
 	}
 
 	return nil
 }
 
+// checkProofsMode returns an error if the network's proofs mode cannot be
+// used with the node's proofs. Nodes with fake proofs accept any proof, so
+// they would fork from nodes with real proofs on any other network, and nodes
+// with real proofs would reject the blocks of a network in fake proofs mode.
+func (node *Node) checkProofsMode(ctx context.Context) error {
+	params, err := node.PorcelainAPI.ProtocolParameters(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get protocol parameters")
+	}
+
+	if node.FakeProofs && params.ProofsMode != types.FakeProofsMode {
+		return errors.New("fake proofs can only be used on a network in fake proofs mode, create its genesis with gengen --fake-proofs-mode")
+	}
+	if !node.FakeProofs && params.ProofsMode == types.FakeProofsMode {
+		return errors.New("the network uses fake proofs, start the daemon with --fake-proofs")
+	}
+	return nil
+}
+
 // StartMining causes the node to start feeding blocks to the mining worker and initializes
 // the SectorBuilder for the mining address.
 func (node *Node) StartMining(ctx context.Context) error {
@@ -482,10 +557,18 @@ func (node *Node) StartMining(ctx context.Context) error {
 		return errors.Wrapf(err, "failed to get mining owner address for miner %s", minerAddr)
 	}
 
-	_, mineDelay := node.MiningTimes()
+	blockTime, mineDelay := node.MiningTimes()
 
 	if node.BlockMining.MiningScheduler == nil {
-		node.BlockMining.MiningScheduler = mining.NewScheduler(node.BlockMining.MiningWorker, mineDelay, node.PorcelainAPI.ChainHead)
+		miningConfig := node.Repo.Config().Mining
+		checks := &mining.SafetyChecks{
+			SyncStatus:   node.PorcelainAPI.ChainStatus,
+			Clock:        node.Clock,
+			BlockTime:    blockTime,
+			MaxLagRounds: uint64(miningConfig.MaxHeadLagRounds),
+			MaxHeadAge:   time.Duration(miningConfig.MaxHeadAgeSeconds) * time.Second,
+		}
+		node.BlockMining.MiningScheduler = mining.NewScheduler(node.BlockMining.MiningWorker, mineDelay, node.PorcelainAPI.ChainHead, checks)
 	} else if node.BlockMining.MiningScheduler.IsStarted() {
 		return fmt.Errorf("miner scheduler already started")
 	}
@@ -580,6 +663,17 @@ func (node *Node) StartMining(ctx context.Context) error {
 	} else {
 		log.Debug("auto-seal is disabled")
 	}
+
+	// periodically checks the sealed sectors and declares faults for those
+	// that cannot be read
+	_, canCheckSectors := node.SectorBuilder().(sectorbuilder.SectorChecker)
+	if interval := node.Repo.Config().Mining.SectorHealthCheckIntervalSeconds; interval == 0 {
+		log.Debug("sector health checks are disabled")
+	} else if !canCheckSectors {
+		log.Warning("sector health checks are disabled, the sector builder cannot check sealed sectors")
+	} else {
+		go node.StorageProtocol.StorageMiner.RunSectorHealthChecks(miningCtx, time.Duration(interval)*time.Second)
+	}
 	node.setIsMining(true)
 
 	return nil
@@ -601,6 +695,18 @@ func initSectorBuilderForNode(ctx context.Context, node *Node) (sectorbuilder.Se
 		return nil, errors.Wrapf(err, "failed to get last used sector id for miner w/address %s", minerAddr.String())
 	}
 
+	if node.Repo.Config().Mining.RemoteSealing {
+		return initRemoteSectorBuilderForNode(node, minerAddr, types.NewSectorClass(sectorSize), lastUsedSectorID)
+	}
+
+	if node.FakeProofs {
+		sb, err := sectorbuilder.NewFakeSectorBuilder(node.Repo.Datastore(), types.NewSectorClass(sectorSize), lastUsedSectorID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to initialize fake sector builder")
+		}
+		return sb, nil
+	}
+
 	// TODO: Currently, weconfigure the RustSectorBuilder to store its
 	// metadata in the staging directory, it should be in its own directory.
 	//
@@ -614,19 +720,27 @@ func initSectorBuilderForNode(ctx context.Context, node *Node) (sectorbuilder.Se
 		return nil, err
 	}
 
-	stagingDir, err := paths.StagingDir(sectorDir)
+	metadataDir, err := paths.StagingDir(sectorDir)
 	if err != nil {
 		return nil, err
 	}
 
-	sealedDir, err := paths.SealedDir(sectorDir)
+	// The sector builder stores new sectors in the storage paths with the
+	// most free space when it starts. The metadata stays in the root
+	// directory so it is found whatever paths are chosen.
+	stagingDir, err := node.SectorStorage.Manager.Dir(sectorstorage.Staging, sectorSize.Uint64())
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "failed to choose a staging directory")
+	}
+
+	sealedDir, err := node.SectorStorage.Manager.Dir(sectorstorage.Sealed, sectorSize.Uint64())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to choose a sealed sector directory")
 	}
 	cfg := sectorbuilder.RustSectorBuilderConfig{
 		BlockService:     node.Blockservice.blockservice,
 		LastUsedSectorID: lastUsedSectorID,
-		MetadataDir:      stagingDir,
+		MetadataDir:      metadataDir,
 		MinerAddr:        minerAddr,
 		SealedSectorDir:  sealedDir,
 		StagedSectorDir:  stagingDir,
@@ -641,6 +755,47 @@ func initSectorBuilderForNode(ctx context.Context, node *Node) (sectorbuilder.Se
 	return sb, nil
 }
 
+// initRemoteSectorBuilderForNode returns a sector builder that hands sectors
+// to seal workers. Workers only produce fake seals and PoSts are generated by
+// the node, so remote sealing is only supported with fake proofs. Staged data
+// is written to the storage paths that allow staged sectors.
+func initRemoteSectorBuilderForNode(node *Node, minerAddr address.Address, sectorClass types.SectorClass, lastUsedSectorID uint64) (sectorbuilder.SectorBuilder, error) {
+	if !node.FakeProofs {
+		return nil, errors.New("remote sealing requires fake proofs, the node cannot generate PoSts for sectors sealed by workers")
+	}
+
+	sectorSize := sectorClass.SectorSize().Uint64()
+	stagingDir := func() (string, error) {
+		dir, err := node.SectorStorage.Manager.Dir(sectorstorage.Staging, sectorSize)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "remote"), nil
+	}
+
+	prover, err := sectorbuilder.NewFakeSectorBuilder(node.Repo.Datastore(), sectorClass, lastUsedSectorID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize fake sector builder")
+	}
+	remote, err := sealworker.NewRemoteSectorBuilder(sealworker.RemoteSectorBuilderConfig{
+		Host:             node.Host(),
+		Datastore:        node.Repo.Datastore(),
+		StagingDir:       stagingDir,
+		MinerAddr:        minerAddr,
+		SectorClass:      sectorClass,
+		LastUsedSectorID: lastUsedSectorID,
+		Token:            node.Repo.Config().Mining.SealWorkerToken,
+		Prover:           prover,
+		// the verifier nodes with fake proofs use for consensus
+		Verifier: &verification.FakeVerifier{VerifySealValid: true},
+	})
+	if err != nil {
+		_ = prover.Close()
+		return nil, errors.Wrap(err, "failed to initialize remote sector builder")
+	}
+	return remote, nil
+}
+
 // initStorageMinerForNode initializes the storage miner, returning the miner, the miner owner address (to be
 // passed to storage fault slasher) and any error
 func initStorageMinerForNode(ctx context.Context, node *Node) (*storage.Miner, address.Address, error) {
@@ -726,6 +881,7 @@ func (node *Node) setupProtocols() error {
 		node.AddNewBlock,
 		node.Chain.ChainReader,
 		node.IsMining,
+		node.MiningStatus,
 		mineDelay,
 		node.SetupMining,
 		node.StartMining,
@@ -740,7 +896,7 @@ func (node *Node) setupProtocols() error {
 
 	// set up storage client and api
 	smc := storage.NewClient(node.Network.host, node.PorcelainAPI)
-	smcAPI := storage.NewAPI(smc)
+	smcAPI := storage.NewAPI(smc, node.GetStorageMiner)
 	node.StorageProtocol.StorageAPI = &smcAPI
 	return nil
 }
@@ -753,10 +909,20 @@ func (node *Node) GetMiningWorker(ctx context.Context) (mining.Worker, error) {
 	return node.BlockMining.MiningWorker, nil
 }
 
+// GetStorageMiner returns the storage miner. Unlike GetMiningWorker it does
+// not set up mining, so commands acting on a miner's deals have no side
+// effects on a node that is not mining.
+func (node *Node) GetStorageMiner(ctx context.Context) (*storage.Miner, error) {
+	if node.StorageProtocol.StorageMiner == nil {
+		return nil, errors.New("storage miner is not set up, run 'mining setup' or 'mining start' first")
+	}
+	return node.StorageProtocol.StorageMiner, nil
+}
+
 // CreateMiningWorker creates a mining.Worker for the node using the configured
 // getStateTree, getWeight, and getAncestors functions for the node
 func (node *Node) CreateMiningWorker(ctx context.Context) (mining.Worker, error) {
-	processor := consensus.NewDefaultProcessor()
+	processor := node.Chain.processor
 
 	minerAddr, err := node.MiningAddress()
 	if err != nil {
@@ -849,6 +1015,11 @@ func (node *Node) BlockService() bserv.BlockService {
 	return node.Blockservice.blockservice
 }
 
+// PieceFetcher returns the fetcher the node's miner uses to pull deal pieces.
+func (node *Node) PieceFetcher() net.PieceFetcher {
+	return node.StorageNetworking.PieceFetcher
+}
+
 // CborStore returns the nodes cborStore.
 func (node *Node) CborStore() *hamt.CborIpldStore {
 	return node.Blockstore.cborStore
@@ -860,3 +1031,12 @@ func (node *Node) IsMining() bool {
 	defer node.BlockMining.mining.Unlock()
 	return node.BlockMining.mining.isMining
 }
+
+// MiningStatus returns whether the node is mining or, if mining is paused
+// while the chain catches up, why.
+func (node *Node) MiningStatus() mining.Status {
+	if !node.IsMining() || node.BlockMining.MiningScheduler == nil {
+		return mining.Status{State: mining.StateStopped}
+	}
+	return node.BlockMining.MiningScheduler.Status()
+}
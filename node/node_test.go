@@ -117,6 +117,22 @@ func TestConnectsToBootstrapNodes(t *testing.T) {
 	})
 }
 
+func TestNodeStartsOnlineWithoutBlockTime(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	// Test nodes are built without a BlockTime option.
+	nd := node.MakeNodesUnstarted(t, 1, false)[0]
+	require.Equal(t, time.Duration(0), nd.PorcelainAPI.BlockTime())
+
+	require.NoError(t, nd.Start(ctx))
+	// Give the background services started with the node, such as client
+	// deal tracking, time to start before the node is stopped.
+	time.Sleep(100 * time.Millisecond)
+	nd.Stop(ctx)
+}
+
 func TestNodeInit(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -241,3 +257,39 @@ func repoConfig() node.BuilderOpt {
 		return nil
 	}
 }
+
+func TestNodeChecksProofsMode(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	t.Run("fake proofs are refused on a network with test proofs", func(t *testing.T) {
+		seed := node.MakeChainSeed(t, node.TestGenCfg)
+		nd := node.MakeNodeWithChainSeed(t, seed, []node.BuilderOpt{node.FakeProofs(true)})
+
+		err := nd.Start(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "fake proofs mode")
+	})
+
+	t.Run("real proofs are refused on a network with fake proofs", func(t *testing.T) {
+		cfg := *node.TestGenCfg
+		cfg.ProofsMode = types.FakeProofsMode
+		seed := node.MakeChainSeed(t, &cfg)
+		nd := node.MakeNodeWithChainSeed(t, seed, []node.BuilderOpt{})
+
+		err := nd.Start(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--fake-proofs")
+	})
+
+	t.Run("fake proofs are used on a network with fake proofs", func(t *testing.T) {
+		cfg := *node.TestGenCfg
+		cfg.ProofsMode = types.FakeProofsMode
+		seed := node.MakeChainSeed(t, &cfg)
+		nd := node.MakeNodeWithChainSeed(t, seed, []node.BuilderOpt{node.FakeProofs(true)})
+
+		require.NoError(t, nd.Start(ctx))
+		nd.Stop(ctx)
+	})
+}
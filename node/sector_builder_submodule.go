@@ -1,9 +1,15 @@
 package node
 
-import "github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+import (
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorstorage"
+)
 
 // SectorBuilderSubmodule enhances the `Node` with sector storage capabilities.
 type SectorBuilderSubmodule struct {
 	// SectorBuilder is used by the miner to fill and seal sectors.
 	sectorBuilder sectorbuilder.SectorBuilder
+
+	// Manager chooses and manages the paths sector data is stored in.
+	Manager *sectorstorage.Manager
 }
@@ -1,9 +1,16 @@
 package node
 
-import exchange "github.com/ipfs/go-ipfs-exchange-interface"
+import (
+	exchange "github.com/ipfs/go-ipfs-exchange-interface"
+
+	"github.com/filecoin-project/go-filecoin/net"
+)
 
 // StorageNetworkingSubmodule enhances the `Node` with data transfer capabilities.
 type StorageNetworkingSubmodule struct {
 	// Exchange is the interface for fetching data from other nodes.
 	Exchange exchange.Interface
+
+	// PieceFetcher transfers storage deal pieces from clients to this node's miner.
+	PieceFetcher net.PieceFetcher
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/message"
@@ -27,12 +28,15 @@ import (
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
 	"github.com/filecoin-project/go-filecoin/plumbing/cst"
 	"github.com/filecoin-project/go-filecoin/plumbing/dag"
+	"github.com/filecoin-project/go-filecoin/plumbing/evt"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/plumbing/strgdls"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorstorage"
 	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
 	"github.com/filecoin-project/go-filecoin/wallet"
 )
 
@@ -49,14 +53,17 @@ type API struct {
 	syncer        *cst.ChainSyncProvider
 	config        *cfg.Config
 	dag           *dag.DAG
+	events        *evt.Events
 	expected      consensus.Protocol
 	msgPool       *message.Pool
 	msgPreviewer  *msg.Previewer
+	msgTracer     *msg.Tracer
 	actorState    *consensus.ActorStateStore
 	msgWaiter     *msg.Waiter
 	network       *net.Network
 	outbox        *message.Outbox
 	sectorBuilder func() sectorbuilder.SectorBuilder
+	sectorStorage *sectorstorage.Manager
 	storagedeals  *strgdls.Store
 	wallet        *wallet.Wallet
 }
@@ -70,13 +77,16 @@ type APIDeps struct {
 	Config        *cfg.Config
 	DAG           *dag.DAG
 	Deals         *strgdls.Store
+	Events        *evt.Events
 	Expected      consensus.Protocol
 	MsgPool       *message.Pool
 	MsgPreviewer  *msg.Previewer
+	MsgTracer     *msg.Tracer
 	MsgWaiter     *msg.Waiter
 	Network       *net.Network
 	Outbox        *message.Outbox
 	SectorBuilder func() sectorbuilder.SectorBuilder
+	SectorStorage *sectorstorage.Manager
 	Wallet        *wallet.Wallet
 }
 
@@ -91,13 +101,16 @@ func New(deps *APIDeps) *API {
 		syncer:        deps.Sync,
 		config:        deps.Config,
 		dag:           deps.DAG,
+		events:        deps.Events,
 		expected:      deps.Expected,
 		msgPool:       deps.MsgPool,
 		msgPreviewer:  deps.MsgPreviewer,
+		msgTracer:     deps.MsgTracer,
 		msgWaiter:     deps.MsgWaiter,
 		network:       deps.Network,
 		outbox:        deps.Outbox,
 		sectorBuilder: deps.SectorBuilder,
+		sectorStorage: deps.SectorStorage,
 		storagedeals:  deps.Deals,
 		wallet:        deps.Wallet,
 	}
@@ -108,6 +121,11 @@ func (api *API) ActorGet(ctx context.Context, addr address.Address) (*actor.Acto
 	return api.chain.GetActor(ctx, addr)
 }
 
+// ActorGetAt returns an actor from the state of the tipset with the given key
+func (api *API) ActorGetAt(ctx context.Context, baseKey types.TipSetKey, addr address.Address) (*actor.Actor, error) {
+	return api.chain.GetActorAt(ctx, baseKey, addr)
+}
+
 // ActorGetSignature returns the signature of the given actor's given method.
 // The function signature is typically used to enable a caller to decode the
 // output of an actor method call (message).
@@ -115,11 +133,38 @@ func (api *API) ActorGetSignature(ctx context.Context, actorAddr address.Address
 	return api.chain.GetActorSignature(ctx, actorAddr, method)
 }
 
+// ActorGetExports returns the methods exported by the builtin actor with the
+// given code, with their signatures.
+func (api *API) ActorGetExports(ctx context.Context, code cid.Cid) (exec.Exports, error) {
+	return api.chain.GetActorExports(ctx, code)
+}
+
+// ActorDecodeState returns the decoded state of a builtin actor, keyed by
+// field name
+func (api *API) ActorDecodeState(ctx context.Context, a *actor.Actor) (map[string]string, error) {
+	return api.chain.DecodeActorState(ctx, a)
+}
+
 // ActorLs returns a channel with actors from the latest state on the chain
 func (api *API) ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, error) {
 	return api.chain.LsActors(ctx)
 }
 
+// ActorLsAt returns a channel with actors from the state of the tipset with
+// the given key
+func (api *API) ActorLsAt(ctx context.Context, baseKey types.TipSetKey) (<-chan state.GetAllActorsResult, error) {
+	return api.chain.LsActorsAt(ctx, baseKey)
+}
+
+// ActorWatchState invokes the apply callback when the actor at the given address
+// satisfies the predicate in the state of a tipset with at least `confidence`
+// rounds built on top of it, and the revert callback (which may be nil) if a
+// reorg later removes that tipset from the chain. Callbacks are invoked until
+// the context is canceled.
+func (api *API) ActorWatchState(ctx context.Context, addr address.Address, confidence uint64, pred evt.ActorPredicate, apply evt.ApplyFunc, revert evt.RevertFunc) error {
+	return api.events.OnActorState(ctx, addr, confidence, pred, apply, revert)
+}
+
 // BlockTime returns the block time used by the consensus protocol.
 func (api *API) BlockTime() time.Duration {
 	return api.expected.BlockTime()
@@ -171,6 +216,12 @@ func (api *API) ChainTipSet(key types.TipSetKey) (types.TipSet, error) {
 	return api.chain.GetTipSet(key)
 }
 
+// ChainTipSetByHeight returns the tipset at the given height on the chain
+// ending in the current head, or the closest tipset below it after null rounds
+func (api *API) ChainTipSetByHeight(height uint64) (types.TipSet, error) {
+	return api.chain.GetTipSetByHeight(height)
+}
+
 // ChainLs returns an iterator of tipsets from head to genesis
 func (api *API) ChainLs(ctx context.Context) (*chain.TipsetIterator, error) {
 	return api.chain.Ls(ctx)
@@ -194,6 +245,27 @@ func (api *API) ChainSyncHandleNewTipSet(ctx context.Context, ci *types.ChainInf
 	return api.syncer.HandleNewTipSet(ctx, ci, trusted)
 }
 
+// ChainWatchHeight invokes the apply callback when the chain reaches the given
+// height with at least `confidence` rounds built on top of it, and the revert
+// callback (which may be nil) if a reorg later removes the tipset at that
+// height from the chain. Callbacks are invoked until the context is canceled.
+func (api *API) ChainWatchHeight(ctx context.Context, height *types.BlockHeight, confidence uint64, apply evt.ApplyFunc, revert evt.RevertFunc) error {
+	return api.events.OnHeight(ctx, height, confidence, apply, revert)
+}
+
+// ChainReplay re-runs the state transition of a tipset on the state of its
+// parent and reports any state root mismatch.
+func (api *API) ChainReplay(ctx context.Context, key types.TipSetKey) (*chain.ReplayResult, error) {
+	return api.syncer.Replay(ctx, key)
+}
+
+// StateDiff compares two state trees and returns the actors that differ
+// between them, with the decoded state of builtin actors compared field by
+// field.
+func (api *API) StateDiff(ctx context.Context, before, after cid.Cid) (*cst.StateDiff, error) {
+	return api.chain.StateDiff(ctx, before, after)
+}
+
 // DealsIterator returns an iterator to access all deals
 func (api *API) DealsIterator() (*query.Results, error) {
 	return api.storagedeals.Iterator()
@@ -240,6 +312,18 @@ func (api *API) MessagePreview(ctx context.Context, from, to address.Address, me
 	return api.msgPreviewer.Preview(ctx, from, to, method, params...)
 }
 
+// MessagePreviewTrace runs a message locally on the client, like MessagePreview,
+// and returns the trace of its execution including the sends made by actors.
+func (api *API) MessagePreviewTrace(ctx context.Context, from, to address.Address, method string, params ...interface{}) (*vm.Trace, error) {
+	return api.msgPreviewer.Trace(ctx, from, to, method, params...)
+}
+
+// MessageTrace returns the execution trace of a message on chain, replaying
+// the message on the state it was applied to unless its trace was persisted.
+func (api *API) MessageTrace(ctx context.Context, msgCid cid.Cid) (*vm.Trace, error) {
+	return api.msgTracer.Trace(ctx, msgCid)
+}
+
 // MessageQuery calls an actor's method using the most recent chain state. It is read-only,
 // it does not change any state. It is use to interrogate actor state. The from address
 // is optional; if not provided, an address will be chosen from the node's wallet.
@@ -264,6 +348,12 @@ func (api *API) MessageSend(ctx context.Context, from, to address.Address, value
 	return api.outbox.Send(ctx, from, to, value, gasPrice, gasLimit, true, method, params...)
 }
 
+// MessageSendSigned sends a message signed elsewhere. Like MessageSend it enqueues the
+// message in the msg pool and broadcasts it to the network after validating it.
+func (api *API) MessageSendSigned(ctx context.Context, signed *types.SignedMessage) (cid.Cid, error) {
+	return api.outbox.SendSigned(ctx, signed, true)
+}
+
 // MessageFind returns a message and receipt from the blockchain, if it exists.
 func (api *API) MessageFind(ctx context.Context, msgCid cid.Cid) (*msg.ChainMessage, bool, error) {
 	return api.msgWaiter.Find(ctx, msgCid)
@@ -278,6 +368,15 @@ func (api *API) MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.
 	return api.msgWaiter.Wait(ctx, msgCid, cb)
 }
 
+// MessageWatch invokes the apply callback when the message with the given cid
+// is executed on chain with at least `confidence` rounds built on top of it,
+// and the revert callback (which may be nil) if a reorg later removes the
+// message's tipset from the chain. Callbacks are invoked until the context is
+// canceled.
+func (api *API) MessageWatch(ctx context.Context, msgCid cid.Cid, confidence uint64, apply evt.MessageApplyFunc, revert evt.RevertFunc) error {
+	return api.events.OnMessage(ctx, msgCid, confidence, apply, revert)
+}
+
 // PubSubSubscribe subscribes to a topic for notifications from the filecoin network
 func (api *API) PubSubSubscribe(topic string) (pubsub.Subscription, error) {
 	return api.network.Subscribe(topic)
@@ -400,3 +499,24 @@ func (api *API) BitswapGetStats(ctx context.Context) (*bitswap.Stat, error) {
 func (api *API) SectorBuilder() sectorbuilder.SectorBuilder {
 	return api.sectorBuilder()
 }
+
+// SectorStorageAddPath adds a named path sector data may be stored in.
+func (api *API) SectorStorageAddPath(p config.StoragePathConfig) error {
+	return api.sectorStorage.AddPath(p)
+}
+
+// SectorStorageUsage returns the space used and available in each path
+// sector data may be stored in.
+func (api *API) SectorStorageUsage() ([]sectorstorage.PathUsage, error) {
+	return api.sectorStorage.Usage()
+}
+
+// SectorStorageSealedSectors returns the sealed sector files in every path.
+func (api *API) SectorStorageSealedSectors() ([]sectorstorage.SectorFile, error) {
+	return api.sectorStorage.SealedSectors()
+}
+
+// SectorStorageMoveSealedSector moves a sealed sector file to the named path.
+func (api *API) SectorStorageMoveSealedSector(ctx context.Context, name string, to string) error {
+	return api.sectorStorage.MoveSealedSector(ctx, name, to)
+}
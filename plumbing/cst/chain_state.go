@@ -21,6 +21,7 @@ import (
 type chainReadWriter interface {
 	GetHead() types.TipSetKey
 	GetTipSet(types.TipSetKey) (types.TipSet, error)
+	GetTipSetByHeight(uint64) (types.TipSet, error)
 	GetTipSetState(context.Context, types.TipSetKey) (state.Tree, error)
 	SetHead(context.Context, types.TipSet) error
 }
@@ -65,6 +66,12 @@ func (chn *ChainStateReadWriter) GetTipSet(key types.TipSetKey) (types.TipSet, e
 	return chn.readWriter.GetTipSet(key)
 }
 
+// GetTipSetByHeight returns the tipset at the given height on the chain
+// ending in the head, or the closest tipset below it after null rounds.
+func (chn *ChainStateReadWriter) GetTipSetByHeight(height uint64) (types.TipSet, error) {
+	return chn.readWriter.GetTipSetByHeight(height)
+}
+
 // Ls returns an iterator over tipsets from head to genesis.
 func (chn *ChainStateReadWriter) Ls(ctx context.Context) (*chain.TipsetIterator, error) {
 	ts, err := chn.readWriter.GetTipSet(chn.readWriter.GetHead())
@@ -93,17 +100,15 @@ func (chn *ChainStateReadWriter) GetReceipts(ctx context.Context, id cid.Cid) ([
 
 // SampleRandomness samples randomness from the chain at the given height.
 func (chn *ChainStateReadWriter) SampleRandomness(ctx context.Context, sampleHeight *types.BlockHeight) ([]byte, error) {
-	head := chn.readWriter.GetHead()
-	headTipSet, err := chn.readWriter.GetTipSet(head)
-	if err != nil {
-		return nil, err
+	if sampleHeight.LessThan(types.NewBlockHeight(0)) {
+		return nil, errors.Errorf("can't sample chain at negative height %s", sampleHeight)
 	}
-	tipSetBuffer, err := chain.GetRecentAncestors(ctx, headTipSet, chn.readWriter, sampleHeight)
+	ts, err := chn.readWriter.GetTipSetByHeight(sampleHeight.AsBigInt().Uint64())
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get recent ancestors")
+		return nil, errors.Wrap(err, "failed to get tipset to sample")
 	}
 
-	return sampling.SampleChainRandomness(sampleHeight, tipSetBuffer)
+	return sampling.SampleChainRandomness(sampleHeight, []types.TipSet{ts})
 }
 
 // GetActor returns an actor from the latest state on the chain
@@ -115,7 +120,7 @@ func (chn *ChainStateReadWriter) GetActor(ctx context.Context, addr address.Addr
 func (chn *ChainStateReadWriter) GetActorAt(ctx context.Context, tipKey types.TipSetKey, addr address.Address) (*actor.Actor, error) {
 	st, err := chn.readWriter.GetTipSetState(ctx, tipKey)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load latest state")
+		return nil, errors.Wrap(err, "failed to load state")
 	}
 
 	actr, err := st.GetActor(ctx, addr)
@@ -127,7 +132,12 @@ func (chn *ChainStateReadWriter) GetActorAt(ctx context.Context, tipKey types.Ti
 
 // LsActors returns a channel with actors from the latest state on the chain
 func (chn *ChainStateReadWriter) LsActors(ctx context.Context) (<-chan state.GetAllActorsResult, error) {
-	st, err := chn.readWriter.GetTipSetState(ctx, chn.readWriter.GetHead())
+	return chn.LsActorsAt(ctx, chn.readWriter.GetHead())
+}
+
+// LsActorsAt returns a channel with actors from the state at a specified tipset key.
+func (chn *ChainStateReadWriter) LsActorsAt(ctx context.Context, tipKey types.TipSetKey) (<-chan state.GetAllActorsResult, error) {
+	st, err := chn.readWriter.GetTipSetState(ctx, tipKey)
 	if err != nil {
 		return nil, err
 	}
@@ -163,6 +173,17 @@ func (chn *ChainStateReadWriter) GetActorSignature(ctx context.Context, actorAdd
 	return export, nil
 }
 
+// GetActorExports returns the methods exported by the builtin actor with the
+// given code.
+func (chn *ChainStateReadWriter) GetActorExports(ctx context.Context, code cid.Cid) (exec.Exports, error) {
+	// TODO: use chain height to determine protocol version (#3360)
+	executable, err := chn.actors.GetActorCode(code, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load actor code")
+	}
+	return executable.Exports(), nil
+}
+
 // SetHead sets `key` as the new head of this chain iff it exists in the nodes chain store.
 func (chn *ChainStateReadWriter) SetHead(ctx context.Context, key types.TipSetKey) error {
 	headTs, err := chn.readWriter.GetTipSet(key)
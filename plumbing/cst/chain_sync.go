@@ -10,6 +10,7 @@ import (
 type chainSync interface {
 	HandleNewTipSet(context.Context, *types.ChainInfo, bool) error
 	Status() chain.Status
+	Replay(context.Context, types.TipSetKey) (*chain.ReplayResult, error)
 }
 
 // ChainSyncProvider provides access to chain sync operations and their status.
@@ -37,3 +38,9 @@ func (chs *ChainSyncProvider) Status() chain.Status {
 func (chs *ChainSyncProvider) HandleNewTipSet(ctx context.Context, ci *types.ChainInfo, trusted bool) error {
 	return chs.sync.HandleNewTipSet(ctx, ci, trusted)
 }
+
+// Replay re-runs the state transition of a tipset on the state of its parent
+// and reports whether it computes the stored state root.
+func (chs *ChainSyncProvider) Replay(ctx context.Context, key types.TipSetKey) (*chain.ReplayResult, error) {
+	return chs.sync.Replay(ctx, key)
+}
@@ -0,0 +1,272 @@
+package cst
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-hamt-ipld"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/initactor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// StateDiff lists the actors that differ between two state trees.
+type StateDiff struct {
+	Before cid.Cid      `json:"before"`
+	After  cid.Cid      `json:"after"`
+	Actors []*ActorDiff `json:"actors"`
+}
+
+// ActorDiff describes how an actor differs between two state trees. Before
+// is nil for an added actor and After is nil for a removed one.
+type ActorDiff struct {
+	Address   string        `json:"address"`
+	ActorType string        `json:"actorType"`
+	Before    *actor.Actor  `json:"before"`
+	After     *actor.Actor  `json:"after"`
+	Storage   []StorageDiff `json:"storage,omitempty"`
+}
+
+// StorageDiff is a difference in the decoded state of an actor. Key names a
+// field of the actor's state, or an entry of a collection it holds. Before or
+// After is empty when the key is absent from that side.
+type StorageDiff struct {
+	Key    string `json:"key"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// StateDiff compares the state trees with roots `before` and `after` and
+// returns the actors whose code, balance, nonce or state differ. The state of
+// builtin actors is decoded to report which fields changed.
+func (chn *ChainStateReadWriter) StateDiff(ctx context.Context, before, after cid.Cid) (*StateDiff, error) {
+	beforeActors, err := chn.loadActors(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	afterActors, err := chn.loadActors(ctx, after)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make(map[string]struct{})
+	for addr := range beforeActors {
+		addrs[addr] = struct{}{}
+	}
+	for addr := range afterActors {
+		addrs[addr] = struct{}{}
+	}
+
+	diff := &StateDiff{Before: before, After: after, Actors: []*ActorDiff{}}
+	for addr := range addrs {
+		b, a := beforeActors[addr], afterActors[addr]
+		if b != nil && a != nil && actorsEqual(b, a) {
+			continue
+		}
+
+		actorDiff := &ActorDiff{Address: addr, Before: b, After: a}
+		var beforeEntries, afterEntries map[string]string
+		if b != nil {
+			actorDiff.ActorType = types.ActorCodeTypeName(b.Code)
+			if beforeEntries, err = chn.storageEntries(ctx, b.Code, b.Head); err != nil {
+				return nil, errors.Wrapf(err, "failed to decode state of actor %s", addr)
+			}
+		}
+		if a != nil {
+			actorDiff.ActorType = types.ActorCodeTypeName(a.Code)
+			if afterEntries, err = chn.storageEntries(ctx, a.Code, a.Head); err != nil {
+				return nil, errors.Wrapf(err, "failed to decode state of actor %s", addr)
+			}
+		}
+		actorDiff.Storage = diffEntries(beforeEntries, afterEntries)
+		diff.Actors = append(diff.Actors, actorDiff)
+	}
+
+	sort.Slice(diff.Actors, func(i, j int) bool {
+		return diff.Actors[i].Address < diff.Actors[j].Address
+	})
+	return diff, nil
+}
+
+func (chn *ChainStateReadWriter) loadActors(ctx context.Context, root cid.Cid) (map[string]*actor.Actor, error) {
+	st, err := state.LoadStateTree(ctx, chn.cst, root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state tree %s", root)
+	}
+
+	actors := make(map[string]*actor.Actor)
+	for res := range state.GetAllActors(ctx, st) {
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		actors[res.Address] = res.Actor
+	}
+	return actors, nil
+}
+
+func actorsEqual(a, b *actor.Actor) bool {
+	return a.Code.Equals(b.Code) && a.Head.Equals(b.Head) && a.Nonce == b.Nonce && a.Balance.Equal(b.Balance)
+}
+
+// DecodeActorState decodes the state of a builtin actor into its fields, or
+// the entries of the collections it holds, rendered as strings. Actors without
+// state or of unknown code have no entries.
+func (chn *ChainStateReadWriter) DecodeActorState(ctx context.Context, a *actor.Actor) (map[string]string, error) {
+	return chn.storageEntries(ctx, a.Code, a.Head)
+}
+
+// storageEntries decodes the state of a builtin actor into named, printable
+// entries. Actors without state or of unknown code have no entries.
+func (chn *ChainStateReadWriter) storageEntries(ctx context.Context, code, head cid.Cid) (map[string]string, error) {
+	if !head.Defined() {
+		return nil, nil
+	}
+
+	switch {
+	case code.Equals(types.MinerActorCodeCid) || code.Equals(types.BootstrapMinerActorCodeCid):
+		var st miner.State
+		if err := chn.cst.Get(ctx, head, &st); err != nil {
+			return nil, err
+		}
+		return fieldEntries(st), nil
+	case code.Equals(types.StorageMarketActorCodeCid):
+		var st storagemarket.State
+		if err := chn.cst.Get(ctx, head, &st); err != nil {
+			return nil, err
+		}
+		entries := fieldEntries(st)
+		delete(entries, "Miners")
+		if err := chn.lookupEntries(ctx, st.Miners, "Miners/", entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	case code.Equals(types.InitActorCodeCid):
+		var st initactor.State
+		if err := chn.cst.Get(ctx, head, &st); err != nil {
+			return nil, err
+		}
+		return fieldEntries(st), nil
+	case code.Equals(types.PaymentBrokerActorCodeCid):
+		return chn.paymentChannelEntries(ctx, head)
+	default:
+		return nil, nil
+	}
+}
+
+// paymentChannelEntries flattens the payment broker's lookup of payers to
+// lookups of channels into entries keyed by payer and channel id.
+func (chn *ChainStateReadWriter) paymentChannelEntries(ctx context.Context, head cid.Cid) (map[string]string, error) {
+	byPayer, err := hamt.LoadNode(ctx, chn.cst, head, hamt.UseTreeBitWidth(actor.TreeBitWidth))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]string)
+	err = byPayer.ForEach(ctx, func(payer string, v interface{}) error {
+		var byChannelCid cid.Cid
+		if err := cbor.DecodeInto(v.(*cbg.Deferred).Raw, &byChannelCid); err != nil {
+			return err
+		}
+		byChannel, err := hamt.LoadNode(ctx, chn.cst, byChannelCid, hamt.UseTreeBitWidth(actor.TreeBitWidth))
+		if err != nil {
+			return err
+		}
+		return byChannel.ForEach(ctx, func(chid string, v interface{}) error {
+			var channel paymentbroker.PaymentChannel
+			if err := cbor.DecodeInto(v.(*cbg.Deferred).Raw, &channel); err != nil {
+				return err
+			}
+			entries[payer+"/"+chid] = renderValue(channel)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// lookupEntries adds an entry keyed by prefix and the entry's key for each
+// entry of the lookup with the given root, so a diff shows which entries of
+// the lookup changed rather than its root.
+func (chn *ChainStateReadWriter) lookupEntries(ctx context.Context, root cid.Cid, prefix string, entries map[string]string) error {
+	if !root.Defined() {
+		return nil
+	}
+	node, err := hamt.LoadNode(ctx, chn.cst, root, hamt.UseTreeBitWidth(actor.TreeBitWidth))
+	if err != nil {
+		return err
+	}
+	return node.ForEach(ctx, func(k string, v interface{}) error {
+		var value interface{}
+		if err := cbor.DecodeInto(v.(*cbg.Deferred).Raw, &value); err != nil {
+			return err
+		}
+		entries[prefix+k] = renderValue(value)
+		return nil
+	})
+}
+
+// fieldEntries renders each exported field of a state struct.
+func fieldEntries(st interface{}) map[string]string {
+	v := reflect.ValueOf(st)
+	entries := make(map[string]string)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		entries[field.Name] = renderValue(v.Field(i).Interface())
+	}
+	return entries
+}
+
+// renderValue prints a state value, preferring its String method. Values are
+// compared by their rendering since some, like types.IntSet, cannot be
+// compared structurally.
+func renderValue(val interface{}) string {
+	v := reflect.ValueOf(val)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return "null"
+	}
+	if s, ok := val.(fmt.Stringer); ok {
+		return s.String()
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+	return string(data)
+}
+
+// diffEntries returns the entries that differ between two decoded states,
+// sorted by key.
+func diffEntries(before, after map[string]string) []StorageDiff {
+	var diffs []StorageDiff
+	for k, b := range before {
+		if a, ok := after[k]; !ok || a != b {
+			diffs = append(diffs, StorageDiff{Key: k, Before: b, After: a})
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			diffs = append(diffs, StorageDiff{Key: k, After: a})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Key < diffs[j].Key
+	})
+	return diffs
+}
@@ -0,0 +1,130 @@
+package cst_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/cst"
+	"github.com/filecoin-project/go-filecoin/state"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestStateDiff(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	store := hamt.NewCborStore()
+	newAddr := address.NewForTestGetter()
+	minerAddr, accountAddr, removedAddr, addedAddr, unchangedAddr := newAddr(), newAddr(), newAddr(), newAddr(), newAddr()
+	marketAddr, otherMinerAddr := newAddr(), newAddr()
+
+	minerState := miner.NewState(newAddr(), newAddr(), peer.ID("peer"), types.OneKiBSectorSize)
+	beforeHead, err := store.Put(ctx, minerState)
+	require.NoError(t, err)
+	minerState.Power = types.NewBytesAmount(1024)
+	minerState.ProvingSet = types.NewIntSet(1, 2)
+	afterHead, err := store.Put(ctx, minerState)
+	require.NoError(t, err)
+
+	newMiner := func(head cid.Cid) *actor.Actor {
+		a := miner.NewActor()
+		a.Head = head
+		return a
+	}
+	newMarket := func(miners ...address.Address) *actor.Actor {
+		lookup := hamt.NewNode(store, hamt.UseTreeBitWidth(actor.TreeBitWidth))
+		for _, m := range miners {
+			require.NoError(t, lookup.Set(ctx, m.String(), true))
+		}
+		require.NoError(t, lookup.Flush(ctx))
+		minersCid, err := store.Put(ctx, lookup)
+		require.NoError(t, err)
+
+		head, err := store.Put(ctx, &storagemarket.State{Miners: minersCid, TotalCommittedStorage: types.NewBytesAmount(0)})
+		require.NoError(t, err)
+		a := storagemarket.NewActor()
+		a.Head = head
+		return a
+	}
+	newAccount := func(balance uint64, nonce types.Uint64) *actor.Actor {
+		a := actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(balance))
+		a.Nonce = nonce
+		return a
+	}
+
+	before := makeStateTree(ctx, t, store, map[address.Address]*actor.Actor{
+		minerAddr:     newMiner(beforeHead),
+		marketAddr:    newMarket(minerAddr),
+		accountAddr:   newAccount(10, 0),
+		removedAddr:   newAccount(1, 0),
+		unchangedAddr: newAccount(5, 3),
+	})
+	after := makeStateTree(ctx, t, store, map[address.Address]*actor.Actor{
+		minerAddr:     newMiner(afterHead),
+		marketAddr:    newMarket(minerAddr, otherMinerAddr),
+		accountAddr:   newAccount(20, 1),
+		addedAddr:     newAccount(2, 0),
+		unchangedAddr: newAccount(5, 3),
+	})
+
+	chn := cst.NewChainStateReadWriter(nil, nil, store, builtin.DefaultActors)
+	diff, err := chn.StateDiff(ctx, before, after)
+	require.NoError(t, err)
+	assert.Equal(t, before, diff.Before)
+	assert.Equal(t, after, diff.After)
+
+	byAddr := make(map[string]*cst.ActorDiff)
+	for _, a := range diff.Actors {
+		byAddr[a.Address] = a
+	}
+	require.Len(t, byAddr, 5)
+	assert.NotContains(t, byAddr, unchangedAddr.String())
+
+	added := byAddr[addedAddr.String()]
+	assert.Nil(t, added.Before)
+	assert.Equal(t, types.NewAttoFILFromFIL(2), added.After.Balance)
+
+	removed := byAddr[removedAddr.String()]
+	assert.Nil(t, removed.After)
+	assert.Equal(t, "AccountActor", removed.ActorType)
+
+	account := byAddr[accountAddr.String()]
+	assert.Equal(t, types.NewAttoFILFromFIL(10), account.Before.Balance)
+	assert.Equal(t, types.NewAttoFILFromFIL(20), account.After.Balance)
+	assert.Equal(t, types.Uint64(1), account.After.Nonce)
+	assert.Empty(t, account.Storage)
+
+	minerDiff := byAddr[minerAddr.String()]
+	assert.Equal(t, "MinerActor", minerDiff.ActorType)
+	assert.Equal(t, []cst.StorageDiff{
+		{Key: "Power", Before: "0", After: "1024"},
+		{Key: "ProvingSet", Before: "[]", After: "[1 2]"},
+	}, minerDiff.Storage)
+
+	marketDiff := byAddr[marketAddr.String()]
+	assert.Equal(t, []cst.StorageDiff{
+		{Key: "Miners/" + otherMinerAddr.String(), After: "true"},
+	}, marketDiff.Storage)
+}
+
+func makeStateTree(ctx context.Context, t *testing.T, store *hamt.CborIpldStore, actors map[address.Address]*actor.Actor) cid.Cid {
+	st := state.NewEmptyStateTree(store)
+	for addr, a := range actors {
+		require.NoError(t, st.SetActor(ctx, addr, a))
+	}
+	root, err := st.Flush(ctx)
+	require.NoError(t, err)
+	return root
+}
@@ -0,0 +1,434 @@
+package evt
+
+import (
+	"context"
+
+	"github.com/cskr/pubsub"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var log = logging.Logger("evt")
+
+// ApplyFunc is invoked when the condition of a registration holds on the
+// chain. The tipset is the earliest tipset at which the condition was
+// observed.
+type ApplyFunc func(ctx context.Context, ts types.TipSet) error
+
+// MessageApplyFunc is invoked when a watched message has been executed on
+// chain. The tipset is the one that includes the message.
+type MessageApplyFunc func(ctx context.Context, ts types.TipSet, chainMsg *msg.ChainMessage) error
+
+// RevertFunc is invoked when a reorg removes the tipset previously passed to
+// an apply function from the chain. The registration is re-armed afterwards
+// and may apply again on the new chain.
+type RevertFunc func(ctx context.Context, ts types.TipSet) error
+
+// ActorPredicate reports whether an actor satisfies a condition at the given
+// tipset. The actor is nil if no actor exists at the watched address.
+type ActorPredicate func(ctx context.Context, ts types.TipSet, act *actor.Actor) (bool, error)
+
+// Abstracts over a store of blockchain state.
+type eventsChainReader interface {
+	GetHead() types.TipSetKey
+	GetTipSet(types.TipSetKey) (types.TipSet, error)
+	GetTipSetState(context.Context, types.TipSetKey) (state.Tree, error)
+	HeadEvents() *pubsub.PubSub
+}
+
+// Abstracts over finding an executed message in a tipset.
+type messageFinder interface {
+	FindInTipSet(ctx context.Context, ts types.TipSet, msgCid cid.Cid) (*msg.ChainMessage, bool, error)
+}
+
+// Events invokes callbacks when conditions on the chain are met, and again
+// when a reorg undoes them. Each registration lives until its context is
+// canceled.
+type Events struct {
+	chainReader eventsChainReader
+	messages    messageFinder
+}
+
+// NewEvents returns a new Events.
+func NewEvents(chainReader eventsChainReader, messages messageFinder) *Events {
+	return &Events{
+		chainReader: chainReader,
+		messages:    messages,
+	}
+}
+
+// OnHeight registers callbacks for the chain reaching the given height with
+// at least `confidence` rounds built on top of it. The revert callback may be
+// nil.
+func (e *Events) OnHeight(ctx context.Context, height *types.BlockHeight, confidence uint64, apply ApplyFunc, revert RevertFunc) error {
+	return e.observe(ctx, confidence, &heightTrigger{
+		events:   e,
+		height:   height.AsBigInt().Uint64(),
+		onApply:  apply,
+		onRevert: revert,
+	})
+}
+
+// OnMessage registers callbacks for the message with the given cid being
+// executed on chain with at least `confidence` rounds built on top of it. The
+// revert callback may be nil.
+func (e *Events) OnMessage(ctx context.Context, msgCid cid.Cid, confidence uint64, apply MessageApplyFunc, revert RevertFunc) error {
+	return e.observe(ctx, confidence, &messageTrigger{
+		events:   e,
+		msgCid:   msgCid,
+		searched: types.UndefTipSet,
+		onApply:  apply,
+		onRevert: revert,
+	})
+}
+
+// OnActorState registers callbacks for the actor at the given address
+// satisfying the predicate in the state of a tipset with at least
+// `confidence` rounds built on top of it. The revert callback may be nil.
+func (e *Events) OnActorState(ctx context.Context, addr address.Address, confidence uint64, pred ActorPredicate, apply ApplyFunc, revert RevertFunc) error {
+	return e.observe(ctx, confidence, &actorStateTrigger{
+		events:   e,
+		addr:     addr,
+		pred:     pred,
+		onApply:  apply,
+		onRevert: revert,
+	})
+}
+
+// trigger is a condition on the chain with its callbacks.
+type trigger interface {
+	// match returns the tipset at which the condition holds on the chain
+	// ending in ts, or an undefined tipset if it does not hold yet.
+	match(ctx context.Context, ts types.TipSet) (types.TipSet, error)
+	apply(ctx context.Context, ts types.TipSet) error
+	revert(ctx context.Context, ts types.TipSet) error
+}
+
+// observer tracks a single registration across head changes.
+type observer struct {
+	events     *Events
+	trigger    trigger
+	confidence uint64
+	// applied is the tipset most recently passed to the apply callback, or
+	// undefined if the registration is armed.
+	applied types.TipSet
+	// verified is the latest head known to have applied on its chain, so
+	// that checking a new head only walks the tipsets added since.
+	verified types.TipSet
+}
+
+func (e *Events) observe(ctx context.Context, confidence uint64, t trigger) error {
+	ch := e.chainReader.HeadEvents().Sub(chain.NewHeadTopic)
+	head, err := e.chainReader.GetTipSet(e.chainReader.GetHead())
+	if err != nil {
+		e.chainReader.HeadEvents().Unsub(ch, chain.NewHeadTopic)
+		return errors.Wrap(err, "failed to load chain head")
+	}
+
+	obs := &observer{
+		events:     e,
+		trigger:    t,
+		confidence: confidence,
+		applied:    types.UndefTipSet,
+		verified:   types.UndefTipSet,
+	}
+
+	go func() {
+		defer e.chainReader.HeadEvents().Unsub(ch, chain.NewHeadTopic)
+
+		if err := obs.handleNewHead(ctx, head); err != nil {
+			log.Errorf("failed to handle chain head %s: %s", head.String(), err)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case raw, more := <-ch:
+				if !more {
+					return
+				}
+				newHead, ok := raw.(types.TipSet)
+				if !ok {
+					log.Warningf("non-tipset published on head events channel: %T", raw)
+					continue
+				}
+				if err := obs.handleNewHead(ctx, newHead); err != nil {
+					log.Errorf("failed to handle chain head %s: %s", newHead.String(), err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// handleNewHead reverts the registration if the applied tipset is no longer
+// on the chain, then applies it if the condition holds at the confident
+// tipset of the new head.
+func (obs *observer) handleNewHead(ctx context.Context, head types.TipSet) error {
+	if obs.applied.Defined() {
+		onChain, err := obs.events.isOnChain(ctx, head, obs.applied, obs.verified)
+		if err != nil {
+			return err
+		}
+		if onChain {
+			obs.verified = head
+			return nil
+		}
+
+		reverted := obs.applied
+		obs.applied = types.UndefTipSet
+		obs.verified = types.UndefTipSet
+		if err := obs.trigger.revert(ctx, reverted); err != nil {
+			return err
+		}
+	}
+
+	confident, err := obs.events.confidentTipSet(ctx, head, obs.confidence)
+	if err != nil {
+		return err
+	}
+	if !confident.Defined() {
+		return nil
+	}
+
+	ts, err := obs.trigger.match(ctx, confident)
+	if err != nil {
+		return err
+	}
+	if !ts.Defined() {
+		return nil
+	}
+
+	obs.applied = ts
+	obs.verified = head
+	return obs.trigger.apply(ctx, ts)
+}
+
+// confidentTipSet returns the latest tipset on the chain ending in head that
+// has at least `confidence` rounds on top of it, or an undefined tipset if
+// the chain is not yet long enough.
+func (e *Events) confidentTipSet(ctx context.Context, head types.TipSet, confidence uint64) (types.TipSet, error) {
+	headHeight, err := head.Height()
+	if err != nil {
+		return types.UndefTipSet, err
+	}
+	if headHeight < confidence {
+		return types.UndefTipSet, nil
+	}
+	return e.latestAtOrBelow(ctx, head, headHeight-confidence)
+}
+
+// isOnChain returns true if ts is an ancestor of (or equal to) head.
+// verified, if defined, is a head already known to have ts on its chain. The
+// walk from head stops at verified when head extends it, so it only visits
+// the tipsets added since, rather than every tipset back to ts.
+func (e *Events) isOnChain(ctx context.Context, head, ts, verified types.TipSet) (bool, error) {
+	h, err := ts.Height()
+	if err != nil {
+		return false, err
+	}
+
+	from := head
+	if verified.Defined() {
+		vh, err := verified.Height()
+		if err != nil {
+			return false, err
+		}
+		ancestor, err := e.latestAtOrBelow(ctx, head, vh)
+		if err != nil {
+			return false, err
+		}
+		if !ancestor.Defined() {
+			return false, nil
+		}
+		if ancestor.Equals(verified) {
+			return true, nil
+		}
+		// Head forked off below the verified head; keep walking down to ts.
+		from = ancestor
+	}
+
+	ancestor, err := e.latestAtOrBelow(ctx, from, h)
+	if err != nil {
+		return false, err
+	}
+	return ancestor.Defined() && ancestor.Key().Equals(ts.Key()), nil
+}
+
+// latestAtOrBelow returns the latest tipset on the chain ending in head with
+// a height less than or equal to h.
+func (e *Events) latestAtOrBelow(ctx context.Context, head types.TipSet, h uint64) (types.TipSet, error) {
+	var err error
+	for iterator := chain.IterAncestors(ctx, e.chainReader, head); !iterator.Complete(); err = iterator.Next() {
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		height, err := iterator.Value().Height()
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		if height <= h {
+			return iterator.Value(), nil
+		}
+	}
+	return types.UndefTipSet, err
+}
+
+// earliestAtOrAbove returns the earliest tipset on the chain ending in head
+// with a height greater than or equal to h, or an undefined tipset if head is
+// below h.
+func (e *Events) earliestAtOrAbove(ctx context.Context, head types.TipSet, h uint64) (types.TipSet, error) {
+	found := types.UndefTipSet
+	var err error
+	for iterator := chain.IterAncestors(ctx, e.chainReader, head); !iterator.Complete(); err = iterator.Next() {
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		height, err := iterator.Value().Height()
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		if height < h {
+			break
+		}
+		found = iterator.Value()
+	}
+	return found, err
+}
+
+type heightTrigger struct {
+	events   *Events
+	height   uint64
+	onApply  ApplyFunc
+	onRevert RevertFunc
+}
+
+func (t *heightTrigger) match(ctx context.Context, ts types.TipSet) (types.TipSet, error) {
+	return t.events.earliestAtOrAbove(ctx, ts, t.height)
+}
+
+func (t *heightTrigger) apply(ctx context.Context, ts types.TipSet) error {
+	return t.onApply(ctx, ts)
+}
+
+func (t *heightTrigger) revert(ctx context.Context, ts types.TipSet) error {
+	if t.onRevert == nil {
+		return nil
+	}
+	return t.onRevert(ctx, ts)
+}
+
+type messageTrigger struct {
+	events   *Events
+	msgCid   cid.Cid
+	onApply  MessageApplyFunc
+	onRevert RevertFunc
+
+	// searched is the latest tipset whose chain has been searched for the
+	// message, so that each new head only searches the tipsets it adds.
+	searched types.TipSet
+	// found is the message found by the last successful match.
+	found *msg.ChainMessage
+}
+
+func (t *messageTrigger) match(ctx context.Context, ts types.TipSet) (types.TipSet, error) {
+	var tipsets []types.TipSet
+	if t.searched.Defined() {
+		// Only the tipsets above the common ancestor are new, those below
+		// were searched on the previous chain.
+		_, newTips, err := chain.CollectTipsToCommonAncestor(ctx, t.events.chainReader, t.searched, ts)
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		tipsets = newTips
+	} else {
+		var err error
+		for iterator := chain.IterAncestors(ctx, t.events.chainReader, ts); !iterator.Complete(); err = iterator.Next() {
+			if err != nil {
+				return types.UndefTipSet, err
+			}
+			tipsets = append(tipsets, iterator.Value())
+		}
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+	}
+
+	for _, candidate := range tipsets {
+		chainMsg, found, err := t.events.messages.FindInTipSet(ctx, candidate, t.msgCid)
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		if found {
+			t.searched = candidate
+			t.found = chainMsg
+			return candidate, nil
+		}
+	}
+	t.searched = ts
+	return types.UndefTipSet, nil
+}
+
+func (t *messageTrigger) apply(ctx context.Context, ts types.TipSet) error {
+	return t.onApply(ctx, ts, t.found)
+}
+
+func (t *messageTrigger) revert(ctx context.Context, ts types.TipSet) error {
+	// The reverted tipset is the searched one, so the next match searches the
+	// new chain above its common ancestor with it.
+	t.found = nil
+
+	if t.onRevert == nil {
+		return nil
+	}
+	return t.onRevert(ctx, ts)
+}
+
+type actorStateTrigger struct {
+	events   *Events
+	addr     address.Address
+	pred     ActorPredicate
+	onApply  ApplyFunc
+	onRevert RevertFunc
+}
+
+func (t *actorStateTrigger) match(ctx context.Context, ts types.TipSet) (types.TipSet, error) {
+	st, err := t.events.chainReader.GetTipSetState(ctx, ts.Key())
+	if err != nil {
+		return types.UndefTipSet, errors.Wrapf(err, "failed to load state of tipset %s", ts.String())
+	}
+
+	act, err := st.GetActor(ctx, t.addr)
+	if err != nil {
+		if !state.IsActorNotFoundError(err) {
+			return types.UndefTipSet, errors.Wrapf(err, "failed to load actor %s", t.addr)
+		}
+		act = nil
+	}
+
+	ok, err := t.pred(ctx, ts, act)
+	if err != nil || !ok {
+		return types.UndefTipSet, err
+	}
+	return ts, nil
+}
+
+func (t *actorStateTrigger) apply(ctx context.Context, ts types.TipSet) error {
+	return t.onApply(ctx, ts)
+}
+
+func (t *actorStateTrigger) revert(ctx context.Context, ts types.TipSet) error {
+	if t.onRevert == nil {
+		return nil
+	}
+	return t.onRevert(ctx, ts)
+}
@@ -0,0 +1,285 @@
+package evt
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cskr/pubsub"
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/state"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestOnHeight(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("applies once the height is reached with confidence", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		builder := chain.NewBuilder(t, address.Undef)
+		reader := newFakeChainReader(builder, builder.NewGenesis())
+		events := NewEvents(reader, &fakeMessageFinder{})
+
+		applied := make(chan types.TipSet, 1)
+		err := events.OnHeight(ctx, types.NewBlockHeight(3), 1, func(ctx context.Context, ts types.TipSet) error {
+			applied <- ts
+			return nil
+		}, nil)
+		require.NoError(t, err)
+
+		ts3 := builder.AppendManyOn(3, reader.head())
+		reader.setHead(ts3)
+		assertNoTipSet(t, applied)
+
+		ts4 := builder.AppendOn(ts3, 1)
+		reader.setHead(ts4)
+		assert.Equal(t, ts3.Key(), requireTipSet(t, applied).Key())
+	})
+
+	t.Run("applies immediately if the chain is already past the height", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		builder := chain.NewBuilder(t, address.Undef)
+		gen := builder.NewGenesis()
+		ts2 := builder.AppendManyOn(2, gen)
+		reader := newFakeChainReader(builder, builder.AppendManyOn(3, ts2))
+		events := NewEvents(reader, &fakeMessageFinder{})
+
+		applied := make(chan types.TipSet, 1)
+		err := events.OnHeight(ctx, types.NewBlockHeight(2), 0, func(ctx context.Context, ts types.TipSet) error {
+			applied <- ts
+			return nil
+		}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ts2.Key(), requireTipSet(t, applied).Key())
+	})
+
+	t.Run("reverts and reapplies across a reorg", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		builder := chain.NewBuilder(t, address.Undef)
+		gen := builder.NewGenesis()
+		ts2 := builder.AppendManyOn(2, gen)
+		reader := newFakeChainReader(builder, ts2)
+		events := NewEvents(reader, &fakeMessageFinder{})
+
+		applied := make(chan types.TipSet, 2)
+		reverted := make(chan types.TipSet, 1)
+		err := events.OnHeight(ctx, types.NewBlockHeight(3), 0, func(ctx context.Context, ts types.TipSet) error {
+			applied <- ts
+			return nil
+		}, func(ctx context.Context, ts types.TipSet) error {
+			reverted <- ts
+			return nil
+		})
+		require.NoError(t, err)
+
+		oldTs3 := builder.AppendOn(ts2, 1)
+		reader.setHead(oldTs3)
+		assert.Equal(t, oldTs3.Key(), requireTipSet(t, applied).Key())
+
+		// A heavier fork from height 2 replaces the applied tipset.
+		newTs3 := builder.AppendOn(ts2, 2)
+		reader.setHead(builder.AppendOn(newTs3, 1))
+		assert.Equal(t, oldTs3.Key(), requireTipSet(t, reverted).Key())
+		assert.Equal(t, newTs3.Key(), requireTipSet(t, applied).Key())
+	})
+}
+
+func TestOnMessage(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	reader := newFakeChainReader(builder, gen)
+
+	msgTs := builder.AppendOn(gen, 1)
+	finder := &fakeMessageFinder{in: msgTs.Key()}
+	events := NewEvents(reader, finder)
+
+	applied := make(chan types.TipSet, 1)
+	err := events.OnMessage(ctx, types.CidFromString(t, "msg"), 2, func(ctx context.Context, ts types.TipSet, chainMsg *msg.ChainMessage) error {
+		assert.NotNil(t, chainMsg)
+		applied <- ts
+		return nil
+	}, nil)
+	require.NoError(t, err)
+
+	ts2 := builder.AppendOn(msgTs, 1)
+	reader.setHead(msgTs)
+	reader.setHead(ts2)
+	assertNoTipSet(t, applied)
+
+	reader.setHead(builder.AppendOn(ts2, 1))
+	assert.Equal(t, msgTs.Key(), requireTipSet(t, applied).Key())
+}
+
+func TestIsOnChainStopsAtVerifiedHead(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	applied := builder.AppendOn(gen, 1)
+	verified := builder.AppendManyOn(20, applied)
+	head := builder.AppendOn(verified, 1)
+	reader := &countingChainReader{fakeChainReader: newFakeChainReader(builder, head)}
+	events := NewEvents(reader, &fakeMessageFinder{})
+
+	onChain, err := events.isOnChain(ctx, head, applied, verified)
+	require.NoError(t, err)
+	assert.True(t, onChain)
+	// Only the parent of head is loaded, not the 20 tipsets below it.
+	assert.Equal(t, 1, reader.lookups)
+
+	// A fork below the verified head is still walked down to the applied
+	// tipset.
+	fork := builder.AppendOn(builder.AppendManyOn(5, applied), 2)
+	onChain, err = events.isOnChain(ctx, fork, applied, verified)
+	require.NoError(t, err)
+	assert.True(t, onChain)
+
+	onChain, err = events.isOnChain(ctx, builder.AppendOn(gen, 2), applied, verified)
+	require.NoError(t, err)
+	assert.False(t, onChain)
+}
+
+func TestMessageTriggerSearchesNewTipSetsOnly(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	ts5 := builder.AppendManyOn(5, gen)
+	reader := newFakeChainReader(builder, ts5)
+	finder := &countingMessageFinder{searches: make(map[string]int)}
+	trigger := &messageTrigger{events: NewEvents(reader, finder), msgCid: types.CidFromString(t, "msg"), searched: types.UndefTipSet}
+
+	found, err := trigger.match(ctx, ts5)
+	require.NoError(t, err)
+	assert.False(t, found.Defined())
+	assert.Len(t, finder.searches, 6)
+
+	// Extending the chain only searches the new tipsets, and a fork only
+	// those above its common ancestor with the searched chain.
+	ts7 := builder.AppendManyOn(2, ts5)
+	_, err = trigger.match(ctx, ts7)
+	require.NoError(t, err)
+	fork := builder.AppendOn(builder.AppendOn(ts5, 2), 1)
+	_, err = trigger.match(ctx, fork)
+	require.NoError(t, err)
+
+	assert.Len(t, finder.searches, 10)
+	for key, n := range finder.searches {
+		assert.Equal(t, 1, n, "tipset %s searched %d times", key, n)
+	}
+}
+
+func requireTipSet(t *testing.T, ch <-chan types.TipSet) types.TipSet {
+	select {
+	case ts := <-ch:
+		return ts
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for callback")
+		return types.UndefTipSet
+	}
+}
+
+func assertNoTipSet(t *testing.T, ch <-chan types.TipSet) {
+	select {
+	case ts := <-ch:
+		assert.Fail(t, "unexpected callback", "tipset %s", ts.String())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+type fakeChainReader struct {
+	*chain.Builder
+	events *pubsub.PubSub
+
+	mu      sync.Mutex
+	headSet types.TipSet
+}
+
+func newFakeChainReader(builder *chain.Builder, head types.TipSet) *fakeChainReader {
+	return &fakeChainReader{
+		Builder: builder,
+		events:  pubsub.New(128),
+		headSet: head,
+	}
+}
+
+func (f *fakeChainReader) GetHead() types.TipSetKey {
+	return f.head().Key()
+}
+
+func (f *fakeChainReader) GetTipSetState(ctx context.Context, key types.TipSetKey) (state.Tree, error) {
+	return nil, errors.New("no state in fake chain reader")
+}
+
+func (f *fakeChainReader) HeadEvents() *pubsub.PubSub {
+	return f.events
+}
+
+func (f *fakeChainReader) head() types.TipSet {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.headSet
+}
+
+func (f *fakeChainReader) setHead(ts types.TipSet) {
+	f.mu.Lock()
+	f.headSet = ts
+	f.mu.Unlock()
+	f.events.Pub(ts, chain.NewHeadTopic)
+}
+
+// countingChainReader counts the tipsets loaded through it.
+type countingChainReader struct {
+	*fakeChainReader
+	lookups int
+}
+
+func (c *countingChainReader) GetTipSet(key types.TipSetKey) (types.TipSet, error) {
+	c.lookups++
+	return c.fakeChainReader.GetTipSet(key)
+}
+
+// fakeMessageFinder finds a message in the tipset with key `in` only.
+type fakeMessageFinder struct {
+	in types.TipSetKey
+}
+
+func (f *fakeMessageFinder) FindInTipSet(ctx context.Context, ts types.TipSet, msgCid cid.Cid) (*msg.ChainMessage, bool, error) {
+	if ts.Key().Equals(f.in) {
+		return &msg.ChainMessage{Block: ts.At(0)}, true, nil
+	}
+	return nil, false, nil
+}
+
+// countingMessageFinder finds no messages and counts the searches of each
+// tipset.
+type countingMessageFinder struct {
+	searches map[string]int
+}
+
+func (f *countingMessageFinder) FindInTipSet(ctx context.Context, ts types.TipSet, msgCid cid.Cid) (*msg.ChainMessage, bool, error) {
+	f.searches[ts.String()]++
+	return nil, false, nil
+}
@@ -0,0 +1,219 @@
+package msg
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func init() {
+	cbor.RegisterCborType(indexEntry{})
+}
+
+// MessageIndexPrefix is the datastore prefix of the message index.
+const MessageIndexPrefix = "messageindex"
+
+// Abstracts over a store of tipsets.
+type indexChainReader interface {
+	GetHead() types.TipSetKey
+	GetTipSet(types.TipSetKey) (types.TipSet, error)
+	GetTipSetByHeight(height uint64) (types.TipSet, error)
+}
+
+// Index maps the messages on the chain to the tipsets including them, so a
+// message is found without traversing the chain. Entries are kept in a
+// datastore rather than in memory, and the index catches up with the chain
+// head on each update or lookup, visiting only the tipsets that joined the
+// chain since. The index records the head it is up to date with, so a
+// restarted node only indexes the tipsets that joined the chain meanwhile.
+// Entries of tipsets that left the chain are not removed: a lookup checks the
+// tipset of an entry against the chain's height index.
+type Index struct {
+	chainReader     indexChainReader
+	messageProvider chain.MessageProvider
+	ds              repo.Datastore
+
+	mu sync.Mutex
+	// head is the head the index is up to date with, undefined until the
+	// first update loads it from the datastore.
+	head types.TipSet
+}
+
+type indexEntry struct {
+	Key    types.TipSetKey
+	Height uint64
+}
+
+// NewIndex returns an Index of the chain in chainReader keeping its entries
+// in ds.
+func NewIndex(chainReader indexChainReader, messages chain.MessageProvider, ds repo.Datastore) *Index {
+	return &Index{
+		chainReader:     chainReader,
+		messageProvider: messages,
+		ds:              ds,
+	}
+}
+
+// Lookup returns the tipset on the chain that includes the message with the
+// given cid, and false if no tipset on the chain includes it.
+func (idx *Index) Lookup(ctx context.Context, msgCid cid.Cid) (types.TipSet, bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.update(ctx); err != nil {
+		return types.UndefTipSet, false, err
+	}
+
+	data, err := idx.ds.Get(messageIndexKey(msgCid))
+	if err == datastore.ErrNotFound {
+		return types.UndefTipSet, false, nil
+	}
+	if err != nil {
+		return types.UndefTipSet, false, errors.Wrap(err, "failed to read message index")
+	}
+	var entry indexEntry
+	if err := cbor.DecodeInto(data, &entry); err != nil {
+		return types.UndefTipSet, false, errors.Wrap(err, "failed to decode message index entry")
+	}
+
+	// A null round at the entry's height yields the tipset below it, which
+	// does not match the entry either.
+	ts, err := idx.chainReader.GetTipSetByHeight(entry.Height)
+	if errors.Cause(err) == chain.ErrNotFound {
+		return types.UndefTipSet, false, nil
+	}
+	if err != nil {
+		return types.UndefTipSet, false, err
+	}
+	if !ts.Key().Equals(entry.Key) {
+		return types.UndefTipSet, false, nil
+	}
+	return ts, true, nil
+}
+
+// Update indexes the tipsets that joined the chain since the last update or
+// lookup. Calling it as the head changes keeps the work off lookups.
+func (idx *Index) Update(ctx context.Context) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	return idx.update(ctx)
+}
+
+// update indexes the tipsets of the chain ending in the current head that are
+// not on the chain ending in the head the index is up to date with, or the
+// whole chain if the index is not up to date with any head the store knows.
+// The caller must hold idx.mu.
+func (idx *Index) update(ctx context.Context) error {
+	if !idx.head.Defined() {
+		if err := idx.loadHead(); err != nil {
+			return err
+		}
+	}
+
+	headKey := idx.chainReader.GetHead()
+	if idx.head.Defined() && headKey.Equals(idx.head.Key()) {
+		return nil
+	}
+	head, err := idx.chainReader.GetTipSet(headKey)
+	if err != nil {
+		return err
+	}
+
+	var newTips []types.TipSet
+	if idx.head.Defined() {
+		if _, newTips, err = chain.CollectTipsToCommonAncestor(ctx, idx.chainReader, idx.head, head); err != nil {
+			return err
+		}
+	} else {
+		for it := chain.IterAncestors(ctx, idx.chainReader, head); !it.Complete(); err = it.Next() {
+			if err != nil {
+				return err
+			}
+			newTips = append(newTips, it.Value())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// A failure leaves the head where it was, so the tipsets are indexed
+	// again on the next update.
+	for _, ts := range newTips {
+		if err := idx.indexTipSet(ctx, ts); err != nil {
+			return errors.Wrapf(err, "failed to index messages of tipset %s", ts.String())
+		}
+	}
+	data, err := cbor.DumpObject(head.Key())
+	if err != nil {
+		return err
+	}
+	if err := idx.ds.Put(messageIndexHeadKey, data); err != nil {
+		return errors.Wrap(err, "failed to write message index head")
+	}
+	idx.head = head
+	return nil
+}
+
+// loadHead loads the head the index was last brought up to date with, which
+// is left undefined if there is none or the store does not know it.
+func (idx *Index) loadHead() error {
+	data, err := idx.ds.Get(messageIndexHeadKey)
+	if err == datastore.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read message index head")
+	}
+	var key types.TipSetKey
+	if err := cbor.DecodeInto(data, &key); err != nil {
+		return errors.Wrap(err, "failed to decode message index head")
+	}
+	if head, err := idx.chainReader.GetTipSet(key); err == nil {
+		idx.head = head
+	}
+	return nil
+}
+
+// indexTipSet records the tipset as the one including each of its messages.
+func (idx *Index) indexTipSet(ctx context.Context, ts types.TipSet) error {
+	h, err := ts.Height()
+	if err != nil {
+		return err
+	}
+	data, err := cbor.DumpObject(indexEntry{Key: ts.Key(), Height: h})
+	if err != nil {
+		return err
+	}
+	for i := 0; i < ts.Len(); i++ {
+		msgs, err := idx.messageProvider.LoadMessages(ctx, ts.At(i).Messages)
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			c, err := msg.Cid()
+			if err != nil {
+				return err
+			}
+			if err := idx.ds.Put(messageIndexKey(c), data); err != nil {
+				return errors.Wrap(err, "failed to write message index")
+			}
+		}
+	}
+	return nil
+}
+
+// messageIndexHeadKey is the key of the head the index is up to date with.
+var messageIndexHeadKey = datastore.KeyWithNamespaces([]string{MessageIndexPrefix, "head"})
+
+func messageIndexKey(msgCid cid.Cid) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{MessageIndexPrefix, "messages", msgCid.String()})
+}
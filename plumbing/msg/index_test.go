@@ -0,0 +1,128 @@
+package msg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/repo"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// indexTestChain is a chain builder with a settable head.
+type indexTestChain struct {
+	*chain.Builder
+	head types.TipSetKey
+}
+
+func (c *indexTestChain) GetHead() types.TipSetKey {
+	return c.head
+}
+
+// GetTipSetByHeight returns the tipset at or closest below height on the
+// chain ending in the head, like the chain store's height index.
+func (c *indexTestChain) GetTipSetByHeight(height uint64) (types.TipSet, error) {
+	head, err := c.GetTipSet(c.head)
+	if err != nil {
+		return types.UndefTipSet, err
+	}
+	for it := chain.IterAncestors(context.Background(), c, head); !it.Complete(); err = it.Next() {
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		h, err := it.Value().Height()
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		if h <= height {
+			return it.Value(), nil
+		}
+	}
+	return types.UndefTipSet, chain.ErrNotFound
+}
+
+func TestIndexLookup(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	builder := chain.NewBuilder(t, address.Undef)
+	chn := &indexTestChain{Builder: builder}
+	ds := repo.NewInMemoryRepo().ChainDatastore()
+	index := NewIndex(chn, builder, ds)
+
+	withMessage := func(msg *types.SignedMessage) func(b *chain.BlockBuilder) {
+		return func(b *chain.BlockBuilder) {
+			b.AddMessages([]*types.SignedMessage{msg}, []*types.MessageReceipt{{}})
+		}
+	}
+	msgCid := func(msg *types.SignedMessage) cid.Cid {
+		c, err := msg.Cid()
+		require.NoError(t, err)
+		return c
+	}
+	requireLookupIn := func(index *Index, msg *types.SignedMessage, expected types.TipSet) {
+		ts, found, err := index.Lookup(ctx, msgCid(msg))
+		require.NoError(t, err)
+		if !expected.Defined() {
+			assert.False(t, found)
+			return
+		}
+		require.True(t, found)
+		assert.Equal(t, expected.Key(), ts.Key())
+	}
+	requireLookup := func(msg *types.SignedMessage, expected types.TipSet) {
+		requireLookupIn(index, msg, expected)
+	}
+
+	msgA, msgB, msgC, msgD := newSignedMessage(), newSignedMessage(), newSignedMessage(), newSignedMessage()
+
+	genesis := builder.NewGenesis()
+	a1 := builder.BuildOneOn(genesis, withMessage(msgA))
+	a2 := builder.AppendOn(a1, 1)
+	b1 := builder.BuildOneOn(genesis, withMessage(msgB))
+	b2 := builder.BuildOneOn(b1, withMessage(msgA))
+	c1 := builder.BuildOneOn(genesis, func(b *chain.BlockBuilder) {
+		b.IncHeight(2)
+		withMessage(msgC)(b)
+	})
+
+	t.Run("finds messages on the chain", func(t *testing.T) {
+		chn.head = a2.Key()
+		requireLookup(msgA, a1)
+		requireLookup(msgB, types.UndefTipSet)
+		requireLookup(msgC, types.UndefTipSet)
+	})
+
+	t.Run("follows reorgs", func(t *testing.T) {
+		chn.head = b2.Key()
+		requireLookup(msgA, b2)
+		requireLookup(msgB, b1)
+
+		chn.head = a2.Key()
+		requireLookup(msgA, a1)
+		requireLookup(msgB, types.UndefTipSet)
+	})
+
+	t.Run("forgets tipsets replaced by null rounds", func(t *testing.T) {
+		chn.head = c1.Key()
+		requireLookup(msgA, types.UndefTipSet)
+		requireLookup(msgB, types.UndefTipSet)
+		requireLookup(msgC, c1)
+	})
+
+	t.Run("catches up from the head it was left at", func(t *testing.T) {
+		c2 := builder.BuildOneOn(c1, withMessage(msgD))
+		chn.head = c2.Key()
+
+		restarted := NewIndex(chn, builder, ds)
+		requireLookupIn(restarted, msgC, c1)
+		requireLookupIn(restarted, msgD, c2)
+		requireLookupIn(restarted, msgA, types.UndefTipSet)
+	})
+}
@@ -24,6 +24,8 @@ type previewerChainReader interface {
 type messagePreviewer interface {
 	// PreviewQueryMethod estimates the amount of gas that will be used by a method
 	PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight) (types.GasUnits, error)
+	// TraceQueryMethod runs a method call and returns the trace of its execution
+	TraceQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight) (*vm.Trace, error)
 }
 
 // Previewer calculates the amount of Gas needed for a command
@@ -50,23 +52,53 @@ func (p *Previewer) Preview(ctx context.Context, optFrom, to address.Address, me
 		return types.NewGasUnits(0), errors.Wrap(err, "failed to encode message params")
 	}
 
-	st, err := p.chainReader.GetTipSetState(ctx, p.chainReader.GetHead())
+	st, h, err := p.headState(ctx)
 	if err != nil {
-		return types.NewGasUnits(0), errors.Wrap(err, "failed to load tree for latest state root")
+		return types.NewGasUnits(0), err
 	}
-	head, err := p.chainReader.GetTipSet(p.chainReader.GetHead())
+
+	vms := vm.NewStorageMap(p.bs)
+	usedGas, err := p.processor.PreviewQueryMethod(ctx, st, vms, to, method, encodedParams, optFrom, h)
 	if err != nil {
-		return types.NewGasUnits(0), errors.Wrap(err, "failed to get head tipset ")
+		return types.NewGasUnits(0), errors.Wrap(err, "query method returned an error")
 	}
-	h, err := head.Height()
+	return usedGas, nil
+}
+
+// Trace sends a read-only message to an actor and returns the trace of its
+// execution, including the sends the actor made.
+func (p *Previewer) Trace(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) (*vm.Trace, error) {
+	encodedParams, err := abi.ToEncodedValues(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode message params")
+	}
+
+	st, h, err := p.headState(ctx)
 	if err != nil {
-		return types.NewGasUnits(0), errors.Wrap(err, "failed to get head tipset height")
+		return nil, err
 	}
 
 	vms := vm.NewStorageMap(p.bs)
-	usedGas, err := p.processor.PreviewQueryMethod(ctx, st, vms, to, method, encodedParams, optFrom, types.NewBlockHeight(h))
+	trace, err := p.processor.TraceQueryMethod(ctx, st, vms, to, method, encodedParams, optFrom, h)
 	if err != nil {
-		return types.NewGasUnits(0), errors.Wrap(err, "query method returned an error")
+		return nil, errors.Wrap(err, "failed to trace query method")
 	}
-	return usedGas, nil
+	return trace, nil
+}
+
+// headState loads the state and height of the head of the chain.
+func (p *Previewer) headState(ctx context.Context) (state.Tree, *types.BlockHeight, error) {
+	st, err := p.chainReader.GetTipSetState(ctx, p.chainReader.GetHead())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load tree for latest state root")
+	}
+	head, err := p.chainReader.GetTipSet(p.chainReader.GetHead())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get head tipset ")
+	}
+	h, err := head.Height()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get head tipset height")
+	}
+	return st, types.NewBlockHeight(h), nil
 }
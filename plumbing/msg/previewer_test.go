@@ -55,5 +55,13 @@ func TestPreview(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, returnValue)
 		assert.Equal(t, types.NewGasUnits(100), returnValue)
+
+		trace, err := previewer.Trace(ctx, fromAddr, fakeActorAddr, "hasReturnValue")
+		require.NoError(t, err)
+		assert.Equal(t, fakeActorAddr, trace.To)
+		assert.Equal(t, "hasReturnValue", trace.Method)
+		assert.Equal(t, types.NewGasUnits(100), trace.GasUsed)
+		assert.Len(t, trace.Return, 1)
+		assert.Empty(t, trace.Calls)
 	})
 }
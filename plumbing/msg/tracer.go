@@ -0,0 +1,172 @@
+package msg
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+// ExecutionTracePrefix is the datastore prefix for message execution traces.
+const ExecutionTracePrefix = "executiontraces"
+
+// TraceStore keeps the execution traces of the messages applied to the
+// chain, keyed by the tipset that applied them and the message cid.
+type TraceStore struct {
+	ds repo.Datastore
+}
+
+// NewTraceStore returns a TraceStore keeping traces in ds.
+func NewTraceStore(ds repo.Datastore) *TraceStore {
+	return &TraceStore{ds: ds}
+}
+
+// Put stores the trace of a message applied by the tipset with key tsKey.
+func (ts *TraceStore) Put(tsKey types.TipSetKey, msgCid cid.Cid, trace *vm.Trace) error {
+	datum, err := cbor.DumpObject(trace)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal trace")
+	}
+	if err := ts.ds.Put(traceKey(tsKey, msgCid), datum); err != nil {
+		return errors.Wrap(err, "could not store trace")
+	}
+	return nil
+}
+
+// Get returns the stored trace of a message applied by the tipset with key
+// tsKey, if there is one.
+func (ts *TraceStore) Get(tsKey types.TipSetKey, msgCid cid.Cid) (*vm.Trace, bool, error) {
+	datum, err := ts.ds.Get(traceKey(tsKey, msgCid))
+	if err == datastore.ErrNotFound {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, errors.Wrap(err, "could not load trace")
+	}
+
+	var trace vm.Trace
+	if err := cbor.DecodeInto(datum, &trace); err != nil {
+		return nil, false, errors.Wrap(err, "could not unmarshal trace")
+	}
+	return &trace, true, nil
+}
+
+func traceKey(tsKey types.TipSetKey, msgCid cid.Cid) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{ExecutionTracePrefix, tsKey.String(), msgCid.String()})
+}
+
+// Abstracts over a store of blockchain state.
+type tracerChainReader interface {
+	GetTipSet(types.TipSetKey) (types.TipSet, error)
+	GetTipSetState(context.Context, types.TipSetKey) (state.Tree, error)
+}
+
+type messageTracer interface {
+	// TraceTipSet applies the messages of a tipset and returns their traces.
+	TraceTipSet(ctx context.Context, st state.Tree, vms vm.StorageMap, ts types.TipSet, tsMessages [][]*types.SignedMessage, ancestors []types.TipSet) (map[cid.Cid]*vm.Trace, error)
+}
+
+// Tracer produces the execution traces of messages on chain.
+type Tracer struct {
+	chainReader     tracerChainReader
+	messageProvider chain.MessageProvider
+	index           *Index
+	// For vm storage.
+	bs        bstore.Blockstore
+	processor messageTracer
+	// Traces recorded as tipsets joined the chain, may be nil.
+	store *TraceStore
+}
+
+// NewTracer constructs a Tracer. The store may be nil when traces are not
+// persisted.
+func NewTracer(chainReader tracerChainReader, messages chain.MessageProvider, index *Index, bs bstore.Blockstore, processor messageTracer, store *TraceStore) *Tracer {
+	return &Tracer{
+		chainReader:     chainReader,
+		messageProvider: messages,
+		index:           index,
+		bs:              bs,
+		processor:       processor,
+		store:           store,
+	}
+}
+
+// Trace returns the execution trace of a message on chain. The trace is
+// taken from the store when it was recorded as the tipset including the
+// message joined the chain, otherwise the tipset is replayed on the state of
+// its parent.
+func (t *Tracer) Trace(ctx context.Context, msgCid cid.Cid) (*vm.Trace, error) {
+	ts, found, err := t.index.Lookup(ctx, msgCid)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Errorf("message %s not found on chain", msgCid)
+	}
+
+	if t.store != nil {
+		trace, found, err := t.store.Get(ts.Key(), msgCid)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return trace, nil
+		}
+	}
+
+	traces, err := t.traceTipSet(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	trace, ok := traces[msgCid]
+	if !ok {
+		return nil, errors.Errorf("message %s was not executed in tipset %s", msgCid, ts.String())
+	}
+	return trace, nil
+}
+
+// RecordTraces stores the traces of the messages of the tipsets that joined
+// the chain when its head changed from oldHead to newHead. It does nothing
+// when traces are not persisted.
+func (t *Tracer) RecordTraces(ctx context.Context, oldHead, newHead types.TipSet) error {
+	if t.store == nil {
+		return nil
+	}
+
+	_, newTips, err := chain.CollectTipsToCommonAncestor(ctx, t.chainReader, oldHead, newHead)
+	if err != nil {
+		return err
+	}
+	// Replay from the lowest tipset up, so parent states are computed first.
+	for i := len(newTips) - 1; i >= 0; i-- {
+		ts := newTips[i]
+		traces, err := t.traceTipSet(ctx, ts)
+		if err != nil {
+			return errors.Wrapf(err, "failed to trace tipset %s", ts.String())
+		}
+		for msgCid, trace := range traces {
+			if err := t.store.Put(ts.Key(), msgCid, trace); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// traceTipSet replays a tipset on the state of its parent and returns the
+// traces of the messages it applied.
+func (t *Tracer) traceTipSet(ctx context.Context, ts types.TipSet) (map[cid.Cid]*vm.Trace, error) {
+	st, ancestors, tsMessages, err := loadTipSetInputs(ctx, t.chainReader, t.messageProvider, ts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load tipset for replay")
+	}
+	return t.processor.TraceTipSet(ctx, st, vm.NewStorageMap(t.bs), ts, tsMessages, ancestors)
+}
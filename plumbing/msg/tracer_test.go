@@ -0,0 +1,67 @@
+package msg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/repo"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+func TestTraceStoreRoundTrip(t *testing.T) {
+	tf.UnitTest(t)
+
+	newAddr := address.NewForTestGetter()
+	newCid := types.NewCidForTestGetter()
+	store := NewTraceStore(repo.NewInMemoryRepo().Datastore())
+
+	tsKey := types.NewTipSetKey(newCid())
+	msgCid := newCid()
+	_, found, err := store.Get(tsKey, msgCid)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	trace := &vm.Trace{
+		From:     newAddr(),
+		To:       newAddr(),
+		Method:   "outer",
+		Value:    types.NewAttoFILFromFIL(3),
+		Params:   []byte{1, 2},
+		Return:   [][]byte{[]byte("out")},
+		ExitCode: 1,
+		Error:    "failed",
+		GasUsed:  types.NewGasUnits(40),
+		Calls: []*vm.Trace{{
+			From:    newAddr(),
+			To:      newAddr(),
+			Method:  "inner",
+			Value:   types.ZeroAttoFIL,
+			Params:  []byte{3},
+			Return:  [][]byte{[]byte("ok")},
+			GasUsed: types.NewGasUnits(10),
+		}},
+	}
+	require.NoError(t, store.Put(tsKey, msgCid, trace))
+
+	_, found, err = store.Get(types.NewTipSetKey(newCid()), msgCid)
+	require.NoError(t, err)
+	assert.False(t, found, "traces are kept per tipset")
+
+	got, found, err := store.Get(tsKey, msgCid)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, trace.To, got.To)
+	assert.Equal(t, trace.Value, got.Value)
+	assert.Equal(t, trace.Return, got.Return)
+	assert.Equal(t, trace.Error, got.Error)
+	assert.Equal(t, trace.GasUsed, got.GasUsed)
+	require.Len(t, got.Calls, 1)
+	assert.Equal(t, "inner", got.Calls[0].Method)
+	assert.Equal(t, trace.Calls[0].Params, got.Calls[0].Params)
+	assert.Empty(t, got.Calls[0].Calls)
+}
@@ -28,10 +28,17 @@ type waiterChainReader interface {
 	HeadEvents() *pubsub.PubSub
 }
 
+// tipSetStateReader loads tipsets and the states they result in.
+type tipSetStateReader interface {
+	GetTipSet(types.TipSetKey) (types.TipSet, error)
+	GetTipSetState(context.Context, types.TipSetKey) (state.Tree, error)
+}
+
 // Waiter waits for a message to appear on chain.
 type Waiter struct {
 	chainReader     waiterChainReader
 	messageProvider chain.MessageProvider
+	index           *Index
 	cst             *hamt.CborIpldStore
 	bs              bstore.Blockstore
 }
@@ -44,22 +51,23 @@ type ChainMessage struct {
 }
 
 // NewWaiter returns a new Waiter.
-func NewWaiter(chainStore waiterChainReader, messages chain.MessageProvider, bs bstore.Blockstore, cst *hamt.CborIpldStore) *Waiter {
+func NewWaiter(chainStore waiterChainReader, messages chain.MessageProvider, index *Index, bs bstore.Blockstore, cst *hamt.CborIpldStore) *Waiter {
 	return &Waiter{
 		chainReader:     chainStore,
 		cst:             cst,
 		bs:              bs,
 		messageProvider: messages,
+		index:           index,
 	}
 }
 
-// Find searches the blockchain history for a message (but doesn't wait).
+// Find looks up a message in the message index of the chain (but doesn't wait).
 func (w *Waiter) Find(ctx context.Context, msgCid cid.Cid) (*ChainMessage, bool, error) {
-	headTipSet, err := w.chainReader.GetTipSet(w.chainReader.GetHead())
-	if err != nil {
+	ts, found, err := w.index.Lookup(ctx, msgCid)
+	if err != nil || !found {
 		return nil, false, err
 	}
-	return w.findMessage(ctx, headTipSet, msgCid)
+	return w.FindInTipSet(ctx, ts, msgCid)
 }
 
 // Wait invokes the callback when a message with the given cid appears on chain.
@@ -70,10 +78,6 @@ func (w *Waiter) Find(ctx context.Context, msgCid cid.Cid) (*ChainMessage, bool,
 // if in fact that's what it wants to do, using something like receiptFromTipset.
 // Something like receiptFromTipset is necessary because not every message in
 // a block will have a receipt in the tipset: it might be a duplicate message.
-//
-// TODO: This implementation will become prohibitively expensive since it
-// traverses the entire chain. We should use an index instead.
-// https://github.com/filecoin-project/go-filecoin/issues/1518
 func (w *Waiter) Wait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error {
 	ctx = log.Start(ctx, "Waiter.Wait")
 	defer log.Finish(ctx)
@@ -97,40 +101,6 @@ func (w *Waiter) Wait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block,
 	return err
 }
 
-// findMessage looks for a message CID in the chain and returns the message,
-// block and receipt, when it is found. Returns the found message/block or nil
-// if now block with the given CID exists in the chain.
-func (w *Waiter) findMessage(ctx context.Context, ts types.TipSet, msgCid cid.Cid) (*ChainMessage, bool, error) {
-	var err error
-	for iterator := chain.IterAncestors(ctx, w.chainReader, ts); !iterator.Complete(); err = iterator.Next() {
-		if err != nil {
-			log.Errorf("Waiter.Wait: %s", err)
-			return nil, false, err
-		}
-		for i := 0; i < iterator.Value().Len(); i++ {
-			blk := iterator.Value().At(i)
-			msgs, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
-			if err != nil {
-				return nil, false, err
-			}
-			for _, msg := range msgs {
-				c, err := msg.Cid()
-				if err != nil {
-					return nil, false, err
-				}
-				if c.Equals(msgCid) {
-					recpt, err := w.receiptFromTipSet(ctx, msgCid, iterator.Value())
-					if err != nil {
-						return nil, false, errors.Wrap(err, "error retrieving receipt from tipset")
-					}
-					return &ChainMessage{msg, blk, recpt}, true, nil
-				}
-			}
-		}
-	}
-	return nil, false, nil
-}
-
 // waitForMessage looks for a message CID in a channel of tipsets and returns
 // the message, block and receipt, when it is found. Reads until the channel is
 // closed or the context done. Returns the found message/block (or nil if the
@@ -150,25 +120,9 @@ func (w *Waiter) waitForMessage(ctx context.Context, ch <-chan interface{}, msgC
 				log.Errorf("Waiter.Wait: %s", e)
 				return nil, false, e
 			case types.TipSet:
-				for i := 0; i < raw.Len(); i++ {
-					blk := raw.At(i)
-					msgs, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
-					if err != nil {
-						return nil, false, err
-					}
-					for _, msg := range msgs {
-						c, err := msg.Cid()
-						if err != nil {
-							return nil, false, err
-						}
-						if c.Equals(msgCid) {
-							recpt, err := w.receiptFromTipSet(ctx, msgCid, raw)
-							if err != nil {
-								return nil, false, errors.Wrap(err, "error retrieving receipt from tipset")
-							}
-							return &ChainMessage{msg, blk, recpt}, true, nil
-						}
-					}
+				chainMsg, found, err := w.FindInTipSet(ctx, raw, msgCid)
+				if err != nil || found {
+					return chainMsg, found, err
 				}
 			default:
 				return nil, false, fmt.Errorf("unexpected type in channel: %T", raw)
@@ -177,6 +131,33 @@ func (w *Waiter) waitForMessage(ctx context.Context, ch <-chan interface{}, msgC
 	}
 }
 
+// FindInTipSet looks for a message CID in the blocks of a single tipset and
+// returns the message, block and receipt when it is found. It does not
+// traverse the tipset's ancestors.
+func (w *Waiter) FindInTipSet(ctx context.Context, ts types.TipSet, msgCid cid.Cid) (*ChainMessage, bool, error) {
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		msgs, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, msg := range msgs {
+			c, err := msg.Cid()
+			if err != nil {
+				return nil, false, err
+			}
+			if c.Equals(msgCid) {
+				recpt, err := w.receiptFromTipSet(ctx, msgCid, ts)
+				if err != nil {
+					return nil, false, errors.Wrap(err, "error retrieving receipt from tipset")
+				}
+				return &ChainMessage{msg, blk, recpt}, true, nil
+			}
+		}
+	}
+	return nil, false, nil
+}
+
 // receiptFromTipSet finds the receipt for the message with msgCid in the
 // input tipset.  This can differ from the message's receipt as stored in its
 // parent block in the case that the message is in conflict with another
@@ -206,39 +187,11 @@ func (w *Waiter) receiptFromTipSet(ctx context.Context, msgCid cid.Cid, ts types
 	}
 
 	// Apply all the tipset's messages to determine the correct receipts.
-	ids, err := ts.Parents()
-	if err != nil {
-		return nil, err
-	}
-	st, err := w.chainReader.GetTipSetState(ctx, ids)
-	if err != nil {
-		return nil, err
-	}
-
-	tsHeight, err := ts.Height()
-	if err != nil {
-		return nil, err
-	}
-	ancestorHeight := types.NewBlockHeight(tsHeight).Sub(types.NewBlockHeight(consensus.AncestorRoundsNeeded))
-	parentTs, err := w.chainReader.GetTipSet(ids)
-	if err != nil {
-		return nil, err
-	}
-	ancestors, err := chain.GetRecentAncestors(ctx, parentTs, w.chainReader, ancestorHeight)
+	st, ancestors, tsMessages, err := loadTipSetInputs(ctx, w.chainReader, w.messageProvider, ts)
 	if err != nil {
 		return nil, err
 	}
 
-	var tsMessages [][]*types.SignedMessage
-	for i := 0; i < ts.Len(); i++ {
-		blk := ts.At(i)
-		msgs, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
-		if err != nil {
-			return nil, err
-		}
-		tsMessages = append(tsMessages, msgs)
-	}
-
 	res, err := consensus.NewDefaultProcessor().ProcessTipSet(ctx, st, vm.NewStorageMap(w.bs), ts, tsMessages, ancestors)
 	if err != nil {
 		return nil, err
@@ -296,3 +249,42 @@ func (w *Waiter) msgIndexOfTipSet(ctx context.Context, msgCid cid.Cid, ts types.
 
 	return -1, fmt.Errorf("message cid %s not in tipset", msgCid.String())
 }
+
+// loadTipSetInputs loads what is needed to apply the messages of a tipset:
+// the state of its parent, its recent ancestors and the messages of each of
+// its blocks.
+func loadTipSetInputs(ctx context.Context, chainReader tipSetStateReader, messageProvider chain.MessageProvider, ts types.TipSet) (state.Tree, []types.TipSet, [][]*types.SignedMessage, error) {
+	ids, err := ts.Parents()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	st, err := chainReader.GetTipSetState(ctx, ids)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tsHeight, err := ts.Height()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ancestorHeight := types.NewBlockHeight(tsHeight).Sub(types.NewBlockHeight(consensus.AncestorRoundsNeeded))
+	parentTs, err := chainReader.GetTipSet(ids)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ancestors, err := chain.GetRecentAncestors(ctx, parentTs, chainReader, ancestorHeight)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var tsMessages [][]*types.SignedMessage
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		msgs, err := messageProvider.LoadMessages(ctx, blk.Messages)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		tsMessages = append(tsMessages, msgs)
+	}
+	return st, ancestors, tsMessages, nil
+}
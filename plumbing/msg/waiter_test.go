@@ -46,12 +46,12 @@ type smsgsSet [][]*types.SignedMessage
 
 func setupTest(t *testing.T) (*hamt.CborIpldStore, *chain.Store, *chain.MessageStore, *Waiter) {
 	d := requiredCommonDeps(t, th.DefaultGenesis)
-	return d.cst, d.chainStore, d.messages, NewWaiter(d.chainStore, d.messages, d.blockstore, d.cst)
+	return d.cst, d.chainStore, d.messages, NewWaiter(d.chainStore, d.messages, NewIndex(d.chainStore, d.messages, d.repo.ChainDatastore()), d.blockstore, d.cst)
 }
 
 func setupTestWithGif(t *testing.T, gif consensus.GenesisInitFunc) (*hamt.CborIpldStore, *chain.Store, *chain.MessageStore, *Waiter) {
 	d := requiredCommonDeps(t, gif)
-	return d.cst, d.chainStore, d.messages, NewWaiter(d.chainStore, d.messages, d.blockstore, d.cst)
+	return d.cst, d.chainStore, d.messages, NewWaiter(d.chainStore, d.messages, NewIndex(d.chainStore, d.messages, d.repo.ChainDatastore()), d.blockstore, d.cst)
 }
 
 func TestWait(t *testing.T) {
@@ -0,0 +1,122 @@
+package porcelain
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// actorSignaturePlumbing is the subset of the plumbing.API that
+// ActorParseParams uses.
+type actorSignaturePlumbing interface {
+	ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error)
+}
+
+// ActorParseParams converts the string representation of the parameters of
+// a method, as given on the command line, to the types of the method's
+// signature.
+func ActorParseParams(ctx context.Context, plumbing actorSignaturePlumbing, actorAddr address.Address, method string, args []string) ([]interface{}, error) {
+	if method == "" && len(args) == 0 {
+		return nil, nil
+	}
+
+	sig, err := plumbing.ActorGetSignature(ctx, actorAddr, method)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to acquire '%s' signature", method)
+	}
+
+	vals, err := abi.ParseValues(args, sig.Params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid parameters for '%s'", method)
+	}
+	return abi.FromValues(vals), nil
+}
+
+// actorCallPlumbing is the subset of the plumbing.API that ActorCall uses.
+type actorCallPlumbing interface {
+	ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
+}
+
+// ActorCall runs a read-only query of a method in the state of the tipset with
+// key baseKey and deserializes its return values according to the method's
+// signature.
+func ActorCall(ctx context.Context, plumbing actorCallPlumbing, optFrom, actorAddr address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([]*abi.Value, error) {
+	sig, err := plumbing.ActorGetSignature(ctx, actorAddr, method)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to acquire '%s' signature", method)
+	}
+
+	rets, err := plumbing.MessageQuery(ctx, optFrom, actorAddr, method, baseKey, params...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "'%s' query message failed", method)
+	}
+	if len(rets) != len(sig.Return) {
+		return nil, errors.Errorf("'%s' returned %d values, its signature has %d", method, len(rets), len(sig.Return))
+	}
+
+	vals := make([]*abi.Value, len(rets))
+	for i, ret := range rets {
+		vals[i], err = abi.Deserialize(ret, sig.Return[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to deserialize returned value")
+		}
+	}
+	return vals, nil
+}
+
+// ActorMethod is a method exported by an actor, with its signature.
+type ActorMethod struct {
+	Name   string
+	Params []abi.Type
+	Return []abi.Type
+}
+
+// actorMethodsPlumbing is the subset of the plumbing.API that ActorMethods
+// and ActorMethodsAt use.
+type actorMethodsPlumbing interface {
+	ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error)
+	ActorGetExports(ctx context.Context, code cid.Cid) (exec.Exports, error)
+}
+
+// ActorMethods lists the methods exported by the builtin actor with the given
+// code, ordered by name.
+func ActorMethods(ctx context.Context, plumbing actorMethodsPlumbing, code cid.Cid) ([]ActorMethod, error) {
+	exports, err := plumbing.ActorGetExports(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := make([]ActorMethod, 0, len(exports))
+	for name, sig := range exports {
+		methods = append(methods, ActorMethod{Name: name, Params: sig.Params, Return: sig.Return})
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+	return methods, nil
+}
+
+// ActorMethodsAt lists the methods exported by the actor at the given
+// address, along with the actor's code.
+func ActorMethodsAt(ctx context.Context, plumbing actorMethodsPlumbing, actorAddr address.Address) (cid.Cid, []ActorMethod, error) {
+	act, err := plumbing.ActorGet(ctx, actorAddr)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	if act.Empty() {
+		return cid.Undef, nil, errors.Errorf("actor at %s has no code", actorAddr)
+	}
+
+	methods, err := ActorMethods(ctx, plumbing, act.Code)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return act.Code, methods, nil
+}
@@ -0,0 +1,141 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	. "github.com/filecoin-project/go-filecoin/porcelain"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type actorCallPlumbing struct {
+	baseKey types.TipSetKey
+	params  []interface{}
+}
+
+func (acp *actorCallPlumbing) ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
+	if method != "getPeerAndSize" {
+		return nil, errors.New("no such method")
+	}
+	return &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address, abi.SectorID},
+		Return: []abi.Type{abi.String, abi.BytesAmount},
+	}, nil
+}
+
+func (acp *actorCallPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error) {
+	acp.baseKey = baseKey
+	acp.params = params
+	return [][]byte{[]byte("peer"), types.NewBytesAmount(1024).Bytes()}, nil
+}
+
+func TestActorParseParams(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	actorAddr := address.NewForTestGetter()()
+	plumbing := &actorCallPlumbing{}
+
+	t.Run("parses parameters per signature", func(t *testing.T) {
+		params, err := ActorParseParams(ctx, plumbing, actorAddr, "getPeerAndSize", []string{actorAddr.String(), "7"})
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{actorAddr, uint64(7)}, params)
+	})
+
+	t.Run("needs no signature without method", func(t *testing.T) {
+		params, err := ActorParseParams(ctx, plumbing, actorAddr, "", nil)
+		require.NoError(t, err)
+		assert.Nil(t, params)
+	})
+
+	t.Run("rejects parameters not matching the signature", func(t *testing.T) {
+		_, err := ActorParseParams(ctx, plumbing, actorAddr, "getPeerAndSize", []string{actorAddr.String()})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects an unknown method", func(t *testing.T) {
+		_, err := ActorParseParams(ctx, plumbing, actorAddr, "nope", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestActorCall(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	actorAddr := address.NewForTestGetter()()
+	plumbing := &actorCallPlumbing{}
+
+	baseKey := types.NewTipSetKey(types.NewCidForTestGetter()())
+	vals, err := ActorCall(ctx, plumbing, address.Undef, actorAddr, "getPeerAndSize", baseKey, actorAddr, uint64(7))
+	require.NoError(t, err)
+
+	assert.Equal(t, baseKey, plumbing.baseKey)
+	assert.Equal(t, []interface{}{actorAddr, uint64(7)}, plumbing.params)
+	require.Len(t, vals, 2)
+	assert.Equal(t, "peer", vals[0].Val)
+	assert.Equal(t, types.NewBytesAmount(1024), vals[1].Val)
+}
+
+type actorMethodsPlumbing struct {
+	actor *actor.Actor
+}
+
+func (amp *actorMethodsPlumbing) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+	return amp.actor, nil
+}
+
+func (amp *actorMethodsPlumbing) ActorGetExports(ctx context.Context, code cid.Cid) (exec.Exports, error) {
+	executable, err := builtin.DefaultActors.GetActorCode(code, 0)
+	if err != nil {
+		return nil, err
+	}
+	return executable.Exports(), nil
+}
+
+func TestActorMethods(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	t.Run("lists the exports of a code ordered by name", func(t *testing.T) {
+		methods, err := ActorMethods(ctx, &actorMethodsPlumbing{}, types.StorageMarketActorCodeCid)
+		require.NoError(t, err)
+		require.NotEmpty(t, methods)
+
+		for i := 1; i < len(methods); i++ {
+			assert.True(t, methods[i-1].Name < methods[i].Name)
+		}
+	})
+
+	t.Run("lists the exports of the actor at an address", func(t *testing.T) {
+		act := actor.NewActor(types.MinerActorCodeCid, types.ZeroAttoFIL)
+
+		code, methods, err := ActorMethodsAt(ctx, &actorMethodsPlumbing{actor: act}, address.NewForTestGetter()())
+		require.NoError(t, err)
+		assert.Equal(t, types.MinerActorCodeCid, code)
+		assert.Len(t, methods, len((&miner.Actor{}).Exports()))
+	})
+
+	t.Run("rejects an actor without code", func(t *testing.T) {
+		_, _, err := ActorMethodsAt(ctx, &actorMethodsPlumbing{actor: &actor.Actor{}}, address.NewForTestGetter()())
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects an unknown code", func(t *testing.T) {
+		_, err := ActorMethods(ctx, &actorMethodsPlumbing{}, types.EmptyMessagesCID)
+		assert.Error(t, err)
+	})
+}
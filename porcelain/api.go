@@ -10,10 +10,12 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/peer"
 
+	"github.com/filecoin-project/go-filecoin/abi"
 	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/plumbing"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
 	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
 	"github.com/filecoin-project/go-filecoin/types"
 )
@@ -46,6 +48,52 @@ func New(plumbing *plumbing.API) *API {
 	return &API{plumbing}
 }
 
+// ActorParseParams converts string parameters of a method to the types of its signature
+func (a *API) ActorParseParams(ctx context.Context, actorAddr address.Address, method string, args []string) ([]interface{}, error) {
+	return ActorParseParams(ctx, a, actorAddr, method, args)
+}
+
+// ActorCall runs a read-only query of a method and deserializes its return values
+func (a *API) ActorCall(ctx context.Context, optFrom, actorAddr address.Address, method string, params ...interface{}) ([]*abi.Value, error) {
+	return ActorCall(ctx, a, optFrom, actorAddr, method, a.ChainHeadKey(), params...)
+}
+
+// ActorCallAt runs a read-only query of a method in the state of the tipset
+// with the given key and deserializes its return values
+func (a *API) ActorCallAt(ctx context.Context, optFrom, actorAddr address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([]*abi.Value, error) {
+	return ActorCall(ctx, a, optFrom, actorAddr, method, baseKey, params...)
+}
+
+// ActorMethods lists the methods exported by the builtin actor with the given code
+func (a *API) ActorMethods(ctx context.Context, code cid.Cid) ([]ActorMethod, error) {
+	return ActorMethods(ctx, a, code)
+}
+
+// ActorMethodsAt lists the methods exported by the actor at an address
+func (a *API) ActorMethodsAt(ctx context.Context, actorAddr address.Address) (cid.Cid, []ActorMethod, error) {
+	return ActorMethodsAt(ctx, a, actorAddr)
+}
+
+// MessageNextNonce returns the nonce of the next message from an address
+func (a *API) MessageNextNonce(ctx context.Context, from address.Address) (uint64, error) {
+	return MessageNextNonce(ctx, a, from)
+}
+
+// MessageCreate builds an unsigned message
+func (a *API) MessageCreate(from, to address.Address, nonce uint64, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (*types.MeteredMessage, error) {
+	return MessageCreate(from, to, nonce, value, gasPrice, gasLimit, method, params...)
+}
+
+// MessageSign signs a message created with MessageCreate using the wallet
+func (a *API) MessageSign(msg *types.MeteredMessage) (*types.SignedMessage, error) {
+	return MessageSign(a, msg)
+}
+
+// MessageSubmit validates a message signed elsewhere and sends it
+func (a *API) MessageSubmit(ctx context.Context, signed *types.SignedMessage) (cid.Cid, error) {
+	return MessageSubmit(ctx, a, signed)
+}
+
 // ChainHead returns the current head tipset
 func (a *API) ChainHead() (types.TipSet, error) {
 	return ChainHead(a)
@@ -56,6 +104,18 @@ func (a *API) ChainGetFullBlock(ctx context.Context, id cid.Cid) (*types.FullBlo
 	return GetFullBlock(ctx, a, id)
 }
 
+// ChainTipSetAtHeight returns the tipset at the given height on the chain
+// ending in the current head
+func (a *API) ChainTipSetAtHeight(ctx context.Context, height uint64) (types.TipSet, error) {
+	return ChainTipSetAtHeight(ctx, a, height)
+}
+
+// ChainWaitForHeight blocks until the chain reaches the given height with at
+// least `confidence` rounds built on top of it
+func (a *API) ChainWaitForHeight(ctx context.Context, height *types.BlockHeight, confidence uint64) (types.TipSet, error) {
+	return ChainWaitForHeight(ctx, a, height, confidence)
+}
+
 // CreatePayments establishes a payment channel and create multiple payments against it
 func (a *API) CreatePayments(ctx context.Context, config CreatePaymentsParams) (*CreatePaymentsReturn, error) {
 	return CreatePayments(ctx, a, config)
@@ -119,12 +179,29 @@ func (a *API) MinerPreviewCreate(
 
 // MinerGetAsk queries for an ask of the given miner
 func (a *API) MinerGetAsk(ctx context.Context, minerAddr address.Address, askID uint64) (minerActor.Ask, error) {
-	return MinerGetAsk(ctx, a, minerAddr, askID)
+	return MinerGetAsk(ctx, a, minerAddr, askID, a.ChainHeadKey())
+}
+
+// MinerGetAsks queries for all the asks of the given miner
+func (a *API) MinerGetAsks(ctx context.Context, minerAddr address.Address) ([]minerActor.Ask, error) {
+	return MinerGetAsks(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetAsksAt queries for all the asks of the given miner in the state of
+// the tipset with key baseKey
+func (a *API) MinerGetAsksAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) ([]minerActor.Ask, error) {
+	return MinerGetAsks(ctx, a, minerAddr, baseKey)
 }
 
 // MinerGetOwnerAddress queries for the owner address of the given miner
 func (a *API) MinerGetOwnerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error) {
-	return MinerGetOwnerAddress(ctx, a, minerAddr)
+	return MinerGetOwnerAddress(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetOwnerAddressAt queries for the owner address of the given miner in
+// the state of the tipset with the given key
+func (a *API) MinerGetOwnerAddressAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (address.Address, error) {
+	return MinerGetOwnerAddress(ctx, a, minerAddr, baseKey)
 }
 
 // MinerGetWorkerAddress queries for the worker address of the given miner
@@ -134,22 +211,53 @@ func (a *API) MinerGetWorkerAddress(ctx context.Context, minerAddr address.Addre
 
 // MinerGetSectorSize queries for the sector size of the given miner.
 func (a *API) MinerGetSectorSize(ctx context.Context, minerAddr address.Address) (*types.BytesAmount, error) {
-	return MinerGetSectorSize(ctx, a, minerAddr)
+	return MinerGetSectorSize(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetSectorSizeAt queries for the sector size of the given miner in the
+// state of the tipset with the given key
+func (a *API) MinerGetSectorSizeAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (*types.BytesAmount, error) {
+	return MinerGetSectorSize(ctx, a, minerAddr, baseKey)
 }
 
 // MinerCalculateLateFee queries for the fee required for a PoSt submitted at some height.
 func (a *API) MinerCalculateLateFee(ctx context.Context, minerAddr address.Address, height *types.BlockHeight) (types.AttoFIL, error) {
-	return MinerCalculateLateFee(ctx, a, minerAddr, height)
+	return MinerCalculateLateFee(ctx, a, minerAddr, height, a.ChainHeadKey())
 }
 
-// MinerGetLastCommittedSectorID queries for the sector size of the given miner.
+// MinerCalculateLateFeeAt queries for the fee required for a PoSt submitted
+// at some height in the state of the tipset with the given key
+func (a *API) MinerCalculateLateFeeAt(ctx context.Context, minerAddr address.Address, height *types.BlockHeight, baseKey types.TipSetKey) (types.AttoFIL, error) {
+	return MinerCalculateLateFee(ctx, a, minerAddr, height, baseKey)
+}
+
+// MinerGetLastCommittedSectorID queries for the id of the last sector
+// committed by the given miner.
 func (a *API) MinerGetLastCommittedSectorID(ctx context.Context, minerAddr address.Address) (uint64, error) {
-	return MinerGetLastCommittedSectorID(ctx, a, minerAddr)
+	return MinerGetLastCommittedSectorID(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetLastCommittedSectorIDAt queries for the id of the last sector
+// committed by the given miner in the state of the tipset with the given key
+func (a *API) MinerGetLastCommittedSectorIDAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (uint64, error) {
+	return MinerGetLastCommittedSectorID(ctx, a, minerAddr, baseKey)
+}
+
+// MinerGetProposedOwnerAt queries for the proposed new owner of the given
+// miner in the state of the tipset with the given key
+func (a *API) MinerGetProposedOwnerAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (address.Address, error) {
+	return MinerGetProposedOwner(ctx, a, minerAddr, baseKey)
 }
 
 // MinerGetPeerID queries for the peer id of the given miner
 func (a *API) MinerGetPeerID(ctx context.Context, minerAddr address.Address) (peer.ID, error) {
-	return MinerGetPeerID(ctx, a, minerAddr)
+	return MinerGetPeerID(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetPeerIDAt queries for the peer id of the given miner in the state of
+// the tipset with the given key
+func (a *API) MinerGetPeerIDAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (peer.ID, error) {
+	return MinerGetPeerID(ctx, a, minerAddr, baseKey)
 }
 
 // MinerSetPrice configures the price of storage. See implementation for details.
@@ -159,17 +267,46 @@ func (a *API) MinerSetPrice(ctx context.Context, from address.Address, miner add
 
 // MinerGetPower queries for the power of the given miner
 func (a *API) MinerGetPower(ctx context.Context, minerAddr address.Address) (MinerPower, error) {
-	return MinerGetPower(ctx, a, minerAddr)
+	return MinerGetPower(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetPowerAt queries for the power of the given miner in the state of
+// the tipset with the given key
+func (a *API) MinerGetPowerAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (MinerPower, error) {
+	return MinerGetPower(ctx, a, minerAddr, baseKey)
 }
 
 // MinerGetProvingWindow queries for the proving period of the given miner
 func (a *API) MinerGetProvingWindow(ctx context.Context, minerAddr address.Address) (MinerProvingWindow, error) {
-	return MinerGetProvingWindow(ctx, a, minerAddr)
+	return MinerGetProvingWindow(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetProvingWindowAt queries for the proving period of the given miner
+// in the state of the tipset with the given key
+func (a *API) MinerGetProvingWindowAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (MinerProvingWindow, error) {
+	return MinerGetProvingWindow(ctx, a, minerAddr, baseKey)
 }
 
 // MinerGetCollateral queries for the proving period of the given miner
 func (a *API) MinerGetCollateral(ctx context.Context, minerAddr address.Address) (types.AttoFIL, error) {
-	return MinerGetCollateral(ctx, a, minerAddr)
+	return MinerGetCollateral(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetCollateralAt queries for the collateral of the given miner in the
+// state of the tipset with the given key
+func (a *API) MinerGetCollateralAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (types.AttoFIL, error) {
+	return MinerGetCollateral(ctx, a, minerAddr, baseKey)
+}
+
+// MinerGetBalance queries the balance of the given miner, broken down by what it is held for
+func (a *API) MinerGetBalance(ctx context.Context, minerAddr address.Address) (MinerBalance, error) {
+	return MinerGetBalance(ctx, a, minerAddr, a.ChainHeadKey())
+}
+
+// MinerGetBalanceAt queries the balance of the given miner in the state of the
+// tipset with the given key, broken down by what it is held for
+func (a *API) MinerGetBalanceAt(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (MinerBalance, error) {
+	return MinerGetBalance(ctx, a, minerAddr, baseKey)
 }
 
 // MinerPreviewSetPrice calculates the amount of Gas needed for a call to MinerSetPrice.
@@ -186,12 +323,24 @@ func (a *API) MinerPreviewSetPrice(
 
 // ProtocolParameters fetches the current protocol configuration parameters.
 func (a *API) ProtocolParameters(ctx context.Context) (*ProtocolParams, error) {
-	return ProtocolParameters(ctx, a)
+	return ProtocolParameters(ctx, a, a.ChainHeadKey())
+}
+
+// ProtocolParametersAt fetches the protocol configuration parameters in the
+// state of the tipset with the given key.
+func (a *API) ProtocolParametersAt(ctx context.Context, baseKey types.TipSetKey) (*ProtocolParams, error) {
+	return ProtocolParameters(ctx, a, baseKey)
 }
 
 // WalletBalance returns the current balance of the given wallet address.
 func (a *API) WalletBalance(ctx context.Context, address address.Address) (types.AttoFIL, error) {
-	return WalletBalance(ctx, a, address)
+	return WalletBalance(ctx, a, address, a.ChainHeadKey())
+}
+
+// WalletBalanceAt returns the balance of the given wallet address in the state
+// of the tipset with the given key.
+func (a *API) WalletBalanceAt(ctx context.Context, address address.Address, baseKey types.TipSetKey) (types.AttoFIL, error) {
+	return WalletBalance(ctx, a, address, baseKey)
 }
 
 // WalletDefaultAddress returns a default wallet address from the config.
@@ -206,7 +355,18 @@ func (a *API) PaymentChannelLs(
 	fromAddr address.Address,
 	payerAddr address.Address,
 ) (map[string]*paymentbroker.PaymentChannel, error) {
-	return PaymentChannelLs(ctx, a, fromAddr, payerAddr)
+	return PaymentChannelLs(ctx, a, fromAddr, payerAddr, a.ChainHeadKey())
+}
+
+// PaymentChannelLsAt lists payment channels for a given payer in the state of
+// the tipset with the given key
+func (a *API) PaymentChannelLsAt(
+	ctx context.Context,
+	fromAddr address.Address,
+	payerAddr address.Address,
+	baseKey types.TipSetKey,
+) (map[string]*paymentbroker.PaymentChannel, error) {
+	return PaymentChannelLs(ctx, a, fromAddr, payerAddr, baseKey)
 }
 
 // PaymentChannelVoucher returns a signed payment channel voucher
@@ -223,7 +383,13 @@ func (a *API) PaymentChannelVoucher(
 
 // ClientListAsks returns a channel with asks from the latest chain state
 func (a *API) ClientListAsks(ctx context.Context) <-chan Ask {
-	return ClientListAsks(ctx, a)
+	return ClientListAsks(ctx, a, a.ChainHeadKey())
+}
+
+// ClientListAsksAt returns a channel with asks from the state of the tipset
+// with the given key
+func (a *API) ClientListAsksAt(ctx context.Context, baseKey types.TipSetKey) <-chan Ask {
+	return ClientListAsks(ctx, a, baseKey)
 }
 
 // ClientValidateDeal checks to see that a storage deal is in the `Complete` state, and that its PIP is valid
@@ -232,8 +398,8 @@ func (a *API) ClientValidateDeal(ctx context.Context, proposalCid cid.Cid, proof
 }
 
 // CalculatePoSt invokes the sector builder to calculate a proof-of-spacetime.
-func (a *API) CalculatePoSt(ctx context.Context, sortedCommRs go_sectorbuilder.SortedSectorInfo, seed types.PoStChallengeSeed) (types.PoStProof, error) {
-	return CalculatePoSt(ctx, a, sortedCommRs, seed)
+func (a *API) CalculatePoSt(ctx context.Context, sortedCommRs go_sectorbuilder.SortedSectorInfo, seed types.PoStChallengeSeed, faults []uint64) (types.PoStProof, error) {
+	return CalculatePoSt(ctx, a, sortedCommRs, seed, faults)
 }
 
 // SealNow forces the sectorbuilder to seal the staged sectors it has
@@ -241,6 +407,11 @@ func (a *API) SealNow(ctx context.Context) error {
 	return SealNow(ctx, a)
 }
 
+// SealingStatus returns the status of the sectors the sectorbuilder has staged or is sealing
+func (a *API) SealingStatus(ctx context.Context) ([]sectorbuilder.SectorSealingStatus, error) {
+	return SealingStatus(ctx, a)
+}
+
 // AddPiece adds a piece to a staged sector
 func (a *API) AddPiece(ctx context.Context, reader io.Reader) (uint64, error) {
 	return AddPiece(ctx, a, reader)
@@ -260,3 +431,33 @@ func (a *API) PingMinerWithTimeout(
 func (a *API) MinerSetWorkerAddress(ctx context.Context, toAddr address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
 	return MinerSetWorkerAddress(ctx, a, toAddr, gasPrice, gasLimit)
 }
+
+// MinerChangeOwner proposes a new owner for the miner
+func (a *API) MinerChangeOwner(ctx context.Context, newOwner address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	return MinerChangeOwner(ctx, a, newOwner, gasPrice, gasLimit)
+}
+
+// MinerAcceptOwner accepts the ownership of a miner proposed to from
+func (a *API) MinerAcceptOwner(ctx context.Context, from, minerAddr address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	return MinerAcceptOwner(ctx, a, from, minerAddr, gasPrice, gasLimit)
+}
+
+// MinerTerminate terminates the miner
+func (a *API) MinerTerminate(ctx context.Context, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	return MinerTerminate(ctx, a, gasPrice, gasLimit)
+}
+
+// MinerRefundCollateral returns the collateral of the terminated miner to its owner
+func (a *API) MinerRefundCollateral(ctx context.Context, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	return MinerRefundCollateral(ctx, a, gasPrice, gasLimit)
+}
+
+// MinerPledge sends amount from the miner owner to the miner actor as collateral
+func (a *API) MinerPledge(ctx context.Context, amount types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	return MinerPledge(ctx, a, amount, gasPrice, gasLimit)
+}
+
+// MinerWithdraw withdraws amount from the miner actor to the miner owner
+func (a *API) MinerWithdraw(ctx context.Context, amount types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	return MinerWithdraw(ctx, a, amount, gasPrice, gasLimit)
+}
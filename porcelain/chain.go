@@ -5,6 +5,7 @@ import (
 
 	"github.com/ipfs/go-cid"
 
+	"github.com/filecoin-project/go-filecoin/plumbing/evt"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -18,6 +19,21 @@ func ChainHead(plumbing chainHeadPlumbing) (types.TipSet, error) {
 	return plumbing.ChainTipSet(plumbing.ChainHeadKey())
 }
 
+type chainHeightPlumbing interface {
+	ChainTipSetByHeight(height uint64) (types.TipSet, error)
+}
+
+// ChainTipSetAtHeight returns the tipset at `height` on the chain ending in
+// the current head, found in the chain's height index. When no tipset was
+// mined at `height` the closest tipset below it is returned, as its state is
+// the state at that height.
+func ChainTipSetAtHeight(ctx context.Context, plumbing chainHeightPlumbing, height uint64) (types.TipSet, error) {
+	if err := ctx.Err(); err != nil {
+		return types.UndefTipSet, err
+	}
+	return plumbing.ChainTipSetByHeight(height)
+}
+
 type fullBlockPlumbing interface {
 	ChainGetBlock(context.Context, cid.Cid) (*types.Block, error)
 	ChainGetMessages(context.Context, cid.Cid) ([]*types.SignedMessage, error)
@@ -46,3 +62,34 @@ func GetFullBlock(ctx context.Context, plumbing fullBlockPlumbing, id cid.Cid) (
 
 	return &out, nil
 }
+
+type chainWaitPlumbing interface {
+	ChainWatchHeight(ctx context.Context, height *types.BlockHeight, confidence uint64, apply evt.ApplyFunc, revert evt.RevertFunc) error
+}
+
+// ChainWaitForHeight blocks until the chain reaches the given height with at
+// least `confidence` rounds built on top of it, and returns the earliest
+// tipset at or above that height.
+func ChainWaitForHeight(ctx context.Context, plumbing chainWaitPlumbing, height *types.BlockHeight, confidence uint64) (types.TipSet, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reached := make(chan types.TipSet, 1)
+	err := plumbing.ChainWatchHeight(ctx, height, confidence, func(ctx context.Context, ts types.TipSet) error {
+		select {
+		case reached <- ts:
+		default:
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return types.UndefTipSet, err
+	}
+
+	select {
+	case ts := <-reached:
+		return ts, nil
+	case <-ctx.Done():
+		return types.UndefTipSet, ctx.Err()
+	}
+}
@@ -0,0 +1,87 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/plumbing/evt"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type fakeChainWaitPlumbing struct {
+	reached types.TipSet
+}
+
+func (p *fakeChainWaitPlumbing) ChainWatchHeight(ctx context.Context, height *types.BlockHeight, confidence uint64, apply evt.ApplyFunc, revert evt.RevertFunc) error {
+	if !p.reached.Defined() {
+		return nil
+	}
+	go func() {
+		_ = apply(ctx, p.reached)
+	}()
+	return nil
+}
+
+func TestChainWaitForHeight(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("returns the tipset once reached", func(t *testing.T) {
+		builder := chain.NewBuilder(t, address.Undef)
+		ts := builder.AppendManyOn(3, builder.NewGenesis())
+		plumbing := &fakeChainWaitPlumbing{reached: ts}
+
+		out, err := porcelain.ChainWaitForHeight(context.Background(), plumbing, types.NewBlockHeight(3), 0)
+		require.NoError(t, err)
+		assert.Equal(t, ts.Key(), out.Key())
+	})
+
+	t.Run("returns an error when the context is done first", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := porcelain.ChainWaitForHeight(ctx, &fakeChainWaitPlumbing{}, types.NewBlockHeight(3), 0)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
+
+type fakeChainHeightPlumbing struct {
+	tipsets map[uint64]types.TipSet
+}
+
+func (p *fakeChainHeightPlumbing) ChainTipSetByHeight(height uint64) (types.TipSet, error) {
+	ts, ok := p.tipsets[height]
+	if !ok {
+		return types.UndefTipSet, errors.Errorf("height %d is above the chain head", height)
+	}
+	return ts, nil
+}
+
+func TestChainTipSetAtHeight(t *testing.T) {
+	tf.UnitTest(t)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	genesis := builder.NewGenesis()
+	t1 := builder.AppendOn(genesis, 1)
+	plumbing := &fakeChainHeightPlumbing{tipsets: map[uint64]types.TipSet{0: genesis, 1: t1}}
+
+	ts, err := porcelain.ChainTipSetAtHeight(context.Background(), plumbing, 1)
+	require.NoError(t, err)
+	assert.Equal(t, t1.Key(), ts.Key())
+
+	_, err = porcelain.ChainTipSetAtHeight(context.Background(), plumbing, 2)
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = porcelain.ChainTipSetAtHeight(ctx, plumbing, 0)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -27,18 +27,18 @@ type Ask struct {
 }
 
 type claPlubming interface {
-	ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, error)
-	ChainHeadKey() types.TipSetKey
+	ActorLsAt(ctx context.Context, baseKey types.TipSetKey) (<-chan state.GetAllActorsResult, error)
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
 }
 
-// ClientListAsks returns a channel with asks from the latest chain state
-func ClientListAsks(ctx context.Context, plumbing claPlubming) <-chan Ask {
+// ClientListAsks returns a channel with asks from the state of the tipset with
+// key baseKey
+func ClientListAsks(ctx context.Context, plumbing claPlubming, baseKey types.TipSetKey) <-chan Ask {
 	out := make(chan Ask)
 
 	go func() {
 		defer close(out)
-		actorCh, err := plumbing.ActorLs(ctx)
+		actorCh, err := plumbing.ActorLsAt(ctx, baseKey)
 		if err != nil {
 			out <- Ask{
 				Error: err,
@@ -47,7 +47,7 @@ func ClientListAsks(ctx context.Context, plumbing claPlubming) <-chan Ask {
 		}
 
 		for actorResult := range actorCh {
-			err := listAsksFromActorResult(ctx, plumbing, actorResult, out)
+			err := listAsksFromActorResult(ctx, plumbing, actorResult, baseKey, out)
 			if err != nil {
 				out <- Ask{
 					Error: err,
@@ -60,7 +60,7 @@ func ClientListAsks(ctx context.Context, plumbing claPlubming) <-chan Ask {
 	return out
 }
 
-func listAsksFromActorResult(ctx context.Context, plumbing claPlubming, actorResult state.GetAllActorsResult, out chan Ask) error {
+func listAsksFromActorResult(ctx context.Context, plumbing claPlubming, actorResult state.GetAllActorsResult, baseKey types.TipSetKey, out chan Ask) error {
 	if actorResult.Error != nil {
 		return actorResult.Error
 	}
@@ -74,7 +74,7 @@ func listAsksFromActorResult(ctx context.Context, plumbing claPlubming, actorRes
 
 	// TODO: at some point, we will need to check that the miners are actually part of the storage market
 	// for now, its impossible for them not to be.
-	ret, err := plumbing.MessageQuery(ctx, address.Undef, addr, "getAsks", plumbing.ChainHeadKey())
+	ret, err := plumbing.MessageQuery(ctx, address.Undef, addr, "getAsks", baseKey)
 	if err != nil {
 		return err
 	}
@@ -85,7 +85,7 @@ func listAsksFromActorResult(ctx context.Context, plumbing claPlubming, actorRes
 	}
 
 	for _, id := range asksIds {
-		ask, err := getAskByID(ctx, plumbing, addr, id)
+		ask, err := getAskByID(ctx, plumbing, addr, id, baseKey)
 		if err != nil {
 			return err
 		}
@@ -128,8 +128,8 @@ func ClientVerifyStorageDeal(ctx context.Context, plumbing cvsdPlumbing, proposa
 	return nil
 }
 
-func getAskByID(ctx context.Context, plumbing claPlubming, addr address.Address, id uint64) (Ask, error) {
-	ret, err := plumbing.MessageQuery(ctx, address.Undef, addr, "getAsk", plumbing.ChainHeadKey(), big.NewInt(int64(id)))
+func getAskByID(ctx context.Context, plumbing claPlubming, addr address.Address, id uint64, baseKey types.TipSetKey) (Ask, error) {
+	ret, err := plumbing.MessageQuery(ctx, address.Undef, addr, "getAsk", baseKey, big.NewInt(int64(id)))
 	if err != nil {
 		return Ask{}, err
 	}
@@ -24,10 +24,12 @@ type claPlumbing struct {
 	messageFail bool
 
 	MinerAddress address.Address
+	keys         []types.TipSetKey
 }
 
-func (cla *claPlumbing) ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, error) {
+func (cla *claPlumbing) ActorLsAt(ctx context.Context, baseKey types.TipSetKey) (<-chan state.GetAllActorsResult, error) {
 	out := make(chan state.GetAllActorsResult)
+	cla.keys = append(cla.keys, baseKey)
 
 	if cla.actorFail {
 		return nil, errors.New("ACTOR FAILURE")
@@ -54,11 +56,8 @@ func (cla *claPlumbing) ActorLs(ctx context.Context) (<-chan state.GetAllActorsR
 	return out, nil
 }
 
-func (cla *claPlumbing) ChainHeadKey() types.TipSetKey {
-	return types.NewTipSetKey()
-}
-
-func (cla *claPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
+func (cla *claPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error) {
+	cla.keys = append(cla.keys, baseKey)
 	if cla.messageFail {
 		return nil, errors.New("MESSAGE FAILURE")
 	}
@@ -84,7 +83,7 @@ func TestClientListAsks(t *testing.T) {
 		ctx := context.Background()
 		plumbing := &claPlumbing{}
 
-		results := porcelain.ClientListAsks(ctx, plumbing)
+		results := porcelain.ClientListAsks(ctx, plumbing, types.NewTipSetKey())
 		result := <-results
 
 		expectedResult := porcelain.Ask{
@@ -97,13 +96,28 @@ func TestClientListAsks(t *testing.T) {
 		assert.Equal(t, expectedResult, result)
 	})
 
+	t.Run("reads the state of the given tipset", func(t *testing.T) {
+		ctx := context.Background()
+		plumbing := &claPlumbing{}
+		baseKey := types.NewTipSetKey(types.NewCidForTestGetter()())
+
+		for result := range porcelain.ClientListAsks(ctx, plumbing, baseKey) {
+			assert.NoError(t, result.Error)
+		}
+
+		assert.NotEmpty(t, plumbing.keys)
+		for _, key := range plumbing.keys {
+			assert.Equal(t, baseKey, key)
+		}
+	})
+
 	t.Run("failed actor ls", func(t *testing.T) {
 		ctx := context.Background()
 		plumbing := &claPlumbing{
 			actorFail: true,
 		}
 
-		results := porcelain.ClientListAsks(ctx, plumbing)
+		results := porcelain.ClientListAsks(ctx, plumbing, types.NewTipSetKey())
 		result := <-results
 
 		assert.Error(t, result.Error, "ACTOR FAILURE")
@@ -115,7 +129,7 @@ func TestClientListAsks(t *testing.T) {
 			actorChFail: true,
 		}
 
-		results := porcelain.ClientListAsks(ctx, plumbing)
+		results := porcelain.ClientListAsks(ctx, plumbing, types.NewTipSetKey())
 		result := <-results
 
 		assert.Error(t, result.Error, "ACTOR CHANNEL FAILURE")
@@ -127,7 +141,7 @@ func TestClientListAsks(t *testing.T) {
 			messageFail: true,
 		}
 
-		results := porcelain.ClientListAsks(ctx, plumbing)
+		results := porcelain.ClientListAsks(ctx, plumbing, types.NewTipSetKey())
 		result := <-results
 
 		assert.Error(t, result.Error, "MESSAGE FAILURE")
@@ -0,0 +1,79 @@
+package porcelain
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/message"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// ErrInvalidSignature is returned when a submitted message is not signed by its sender.
+var ErrInvalidSignature = errors.New("message signature is not valid for its sender")
+
+// mnnAPI is the subset of the plumbing.API that MessageNextNonce uses.
+type mnnAPI interface {
+	ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error)
+	OutboxQueueLs(sender address.Address) []*message.Queued
+}
+
+// MessageNextNonce returns the nonce of the next message from the given
+// address, accounting for the messages from it waiting in the outbox.
+func MessageNextNonce(ctx context.Context, plumbing mnnAPI, from address.Address) (uint64, error) {
+	act, err := plumbing.ActorGet(ctx, from)
+	if err != nil {
+		return 0, errors.Wrapf(err, "no actor at address %s", from)
+	}
+
+	nonce, err := actor.NextNonce(act)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, queued := range plumbing.OutboxQueueLs(from) {
+		if uint64(queued.Msg.Nonce) >= nonce {
+			nonce = uint64(queued.Msg.Nonce) + 1
+		}
+	}
+	return nonce, nil
+}
+
+// MessageCreate builds an unsigned message, to be signed with MessageSign.
+func MessageCreate(from, to address.Address, nonce uint64, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (*types.MeteredMessage, error) {
+	encodedParams, err := abi.ToEncodedValues(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid params")
+	}
+
+	msg := types.NewMessage(from, to, nonce, value, method, encodedParams)
+	return types.NewMeteredMessage(*msg, gasPrice, gasLimit), nil
+}
+
+// MessageSign signs a message created with MessageCreate with the key of its
+// sender held by signer.
+func MessageSign(signer types.Signer, msg *types.MeteredMessage) (*types.SignedMessage, error) {
+	signed, err := types.NewSignedMessage(msg.Message, signer, msg.GasPrice, msg.GasLimit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign message")
+	}
+	return signed, nil
+}
+
+// msAPI is the subset of the plumbing.API that MessageSubmit uses.
+type msAPI interface {
+	MessageSendSigned(ctx context.Context, signed *types.SignedMessage) (cid.Cid, error)
+}
+
+// MessageSubmit validates a message signed elsewhere, adds it to the message
+// pool and broadcasts it to the network.
+func MessageSubmit(ctx context.Context, plumbing msAPI, signed *types.SignedMessage) (cid.Cid, error) {
+	if !signed.VerifySignature() {
+		return cid.Undef, ErrInvalidSignature
+	}
+	return plumbing.MessageSendSigned(ctx, signed)
+}
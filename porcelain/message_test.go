@@ -0,0 +1,114 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/message"
+	. "github.com/filecoin-project/go-filecoin/porcelain"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type messageNextNoncePlumbing struct {
+	actor  *actor.Actor
+	queued []*message.Queued
+}
+
+func (mnnp *messageNextNoncePlumbing) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+	return mnnp.actor, nil
+}
+
+func (mnnp *messageNextNoncePlumbing) OutboxQueueLs(sender address.Address) []*message.Queued {
+	return mnnp.queued
+}
+
+type messageSubmitPlumbing struct {
+	sent *types.SignedMessage
+}
+
+func (msp *messageSubmitPlumbing) MessageSendSigned(ctx context.Context, signed *types.SignedMessage) (cid.Cid, error) {
+	msp.sent = signed
+	return signed.Cid()
+}
+
+func TestMessageNextNonce(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	from := address.NewForTestGetter()()
+
+	act, err := account.NewActor(types.ZeroAttoFIL)
+	require.NoError(t, err)
+	act.Nonce = 5
+
+	t.Run("uses the actor nonce", func(t *testing.T) {
+		nonce, err := MessageNextNonce(ctx, &messageNextNoncePlumbing{actor: act}, from)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), nonce)
+	})
+
+	t.Run("follows the outbox queue", func(t *testing.T) {
+		queued := &types.SignedMessage{}
+		queued.Nonce = 7
+		plumbing := &messageNextNoncePlumbing{actor: act, queued: []*message.Queued{{Msg: queued}}}
+
+		nonce, err := MessageNextNonce(ctx, plumbing, from)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(8), nonce)
+	})
+}
+
+func TestMessageCreateSignSubmit(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	signer, _ := types.NewMockSignersAndKeyInfo(2)
+	from := signer.Addresses[0]
+	to := address.NewForTestGetter()()
+
+	msg, err := MessageCreate(from, to, 3, types.NewAttoFILFromFIL(1), types.NewGasPrice(2), types.NewGasUnits(300), "someMethod", uint64(9))
+	require.NoError(t, err)
+	assert.Equal(t, types.Uint64(3), msg.Nonce)
+	assert.Equal(t, types.NewGasPrice(2), msg.GasPrice)
+	assert.Equal(t, abi.MustConvertParams(uint64(9)), msg.Params)
+
+	t.Run("submits a signed message", func(t *testing.T) {
+		signed, err := MessageSign(signer, msg)
+		require.NoError(t, err)
+		assert.Equal(t, *msg, signed.MeteredMessage)
+
+		plumbing := &messageSubmitPlumbing{}
+		c, err := MessageSubmit(ctx, plumbing, signed)
+		require.NoError(t, err)
+
+		expected, err := signed.Cid()
+		require.NoError(t, err)
+		assert.Equal(t, expected, c)
+		assert.Equal(t, signed, plumbing.sent)
+	})
+
+	t.Run("rejects a message signed by another key", func(t *testing.T) {
+		signed, err := MessageSign(signer, msg)
+		require.NoError(t, err)
+
+		otherMsg := *msg
+		otherMsg.From = signer.Addresses[1]
+		other, err := MessageSign(signer, &otherMsg)
+		require.NoError(t, err)
+		signed.Signature = other.Signature
+
+		plumbing := &messageSubmitPlumbing{}
+		_, err = MessageSubmit(ctx, plumbing, signed)
+		assert.Equal(t, ErrInvalidSignature, err)
+		assert.Nil(t, plumbing.sent)
+	})
+}
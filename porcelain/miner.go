@@ -13,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
 	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
@@ -267,14 +268,14 @@ func MinerPreviewSetPrice(ctx context.Context, plumbing mpspAPI, from address.Ad
 // minerQueryAndDeserialize is the subset of the plumbing.API that provides
 // support for sending query messages and getting method signatures.
 type minerQueryAndDeserialize interface {
-	ChainHeadKey() types.TipSetKey
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
 	ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error)
 }
 
-// MinerGetOwnerAddress queries for the owner address of the given miner
-func MinerGetOwnerAddress(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address) (address.Address, error) {
-	res, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getOwner", plumbing.ChainHeadKey())
+// MinerGetOwnerAddress queries for the owner address of the given miner in the
+// state of the tipset with key baseKey
+func MinerGetOwnerAddress(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address, baseKey types.TipSetKey) (address.Address, error) {
+	res, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getOwner", baseKey)
 	if err != nil {
 		return address.Undef, err
 	}
@@ -314,9 +315,10 @@ func queryAndDeserialize(ctx context.Context, plumbing minerQueryAndDeserialize,
 	return abiValue, nil
 }
 
-// MinerGetSectorSize queries for the sector size of the given miner.
-func MinerGetSectorSize(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address) (*types.BytesAmount, error) {
-	abiVal, err := queryAndDeserialize(ctx, plumbing, minerAddr, "getSectorSize", plumbing.ChainHeadKey())
+// MinerGetSectorSize queries for the sector size of the given miner in the
+// state of the tipset with key baseKey.
+func MinerGetSectorSize(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address, baseKey types.TipSetKey) (*types.BytesAmount, error) {
+	abiVal, err := queryAndDeserialize(ctx, plumbing, minerAddr, "getSectorSize", baseKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "query and deserialize failed")
 	}
@@ -329,9 +331,10 @@ func MinerGetSectorSize(ctx context.Context, plumbing minerQueryAndDeserialize,
 	return sectorSize, nil
 }
 
-// MinerCalculateLateFee calculates the fee due if a miner's PoSt were to be mined at `height`.
-func MinerCalculateLateFee(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address, height *types.BlockHeight) (types.AttoFIL, error) {
-	abiVal, err := queryAndDeserialize(ctx, plumbing, minerAddr, "calculateLateFee", plumbing.ChainHeadKey(), height)
+// MinerCalculateLateFee calculates the fee due if a miner's PoSt were to be
+// mined at `height`, in the state of the tipset with key baseKey.
+func MinerCalculateLateFee(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address, height *types.BlockHeight, baseKey types.TipSetKey) (types.AttoFIL, error) {
+	abiVal, err := queryAndDeserialize(ctx, plumbing, minerAddr, "calculateLateFee", baseKey, height)
 	if err != nil {
 		return types.ZeroAttoFIL, errors.Wrap(err, "query and deserialize failed")
 	}
@@ -345,9 +348,9 @@ func MinerCalculateLateFee(ctx context.Context, plumbing minerQueryAndDeserializ
 }
 
 // MinerGetLastCommittedSectorID queries for the id of the last sector committed
-// by the given miner.
-func MinerGetLastCommittedSectorID(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address) (uint64, error) {
-	abiVal, err := queryAndDeserialize(ctx, plumbing, minerAddr, "getLastUsedSectorID", plumbing.ChainHeadKey())
+// by the given miner in the state of the tipset with key baseKey.
+func MinerGetLastCommittedSectorID(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address, baseKey types.TipSetKey) (uint64, error) {
+	abiVal, err := queryAndDeserialize(ctx, plumbing, minerAddr, "getLastUsedSectorID", baseKey)
 	if err != nil {
 		return 0, errors.Wrap(err, "query and deserialize failed")
 	}
@@ -362,13 +365,13 @@ func MinerGetLastCommittedSectorID(ctx context.Context, plumbing minerQueryAndDe
 
 // mgaAPI is the subset of the plumbing.API that MinerGetAsk uses.
 type mgaAPI interface {
-	ChainHeadKey() types.TipSetKey
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
 }
 
-// MinerGetAsk queries for an ask of the given miner
-func MinerGetAsk(ctx context.Context, plumbing mgaAPI, minerAddr address.Address, askID uint64) (minerActor.Ask, error) {
-	ret, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getAsk", plumbing.ChainHeadKey(), big.NewInt(int64(askID)))
+// MinerGetAsk queries for an ask of the given miner in the state of the tipset
+// with key baseKey
+func MinerGetAsk(ctx context.Context, plumbing mgaAPI, minerAddr address.Address, askID uint64, baseKey types.TipSetKey) (minerActor.Ask, error) {
+	ret, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getAsk", baseKey, big.NewInt(int64(askID)))
 	if err != nil {
 		return minerActor.Ask{}, err
 	}
@@ -381,15 +384,38 @@ func MinerGetAsk(ctx context.Context, plumbing mgaAPI, minerAddr address.Address
 	return ask, nil
 }
 
+// MinerGetAsks queries for all the asks of the given miner in the state of the
+// tipset with key baseKey
+func MinerGetAsks(ctx context.Context, plumbing mgaAPI, minerAddr address.Address, baseKey types.TipSetKey) ([]minerActor.Ask, error) {
+	ret, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getAsks", baseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var askIDs []uint64
+	if err := cbor.DecodeInto(ret[0], &askIDs); err != nil {
+		return nil, err
+	}
+
+	asks := make([]minerActor.Ask, len(askIDs))
+	for i, id := range askIDs {
+		asks[i], err = MinerGetAsk(ctx, plumbing, minerAddr, id, baseKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return asks, nil
+}
+
 // mgpidAPI is the subset of the plumbing.API that MinerGetPeerID uses.
 type mgpidAPI interface {
-	ChainHeadKey() types.TipSetKey
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
 }
 
-// MinerGetPeerID queries for the peer id of the given miner
-func MinerGetPeerID(ctx context.Context, plumbing mgpidAPI, minerAddr address.Address) (peer.ID, error) {
-	res, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getPeerID", plumbing.ChainHeadKey())
+// MinerGetPeerID queries for the peer id of the given miner in the state of
+// the tipset with key baseKey
+func MinerGetPeerID(ctx context.Context, plumbing mgpidAPI, minerAddr address.Address, baseKey types.TipSetKey) (peer.ID, error) {
+	res, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getPeerID", baseKey)
 	if err != nil {
 		return "", err
 	}
@@ -409,14 +435,15 @@ type MinerProvingWindow struct {
 	ProvingSet map[string]types.Commitments
 }
 
-// MinerGetProvingWindow gets the proving period and commitments for miner `minerAddr`.
-func MinerGetProvingWindow(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address) (MinerProvingWindow, error) {
+// MinerGetProvingWindow gets the proving period and commitments for miner
+// `minerAddr` in the state of the tipset with key baseKey.
+func MinerGetProvingWindow(ctx context.Context, plumbing minerQueryAndDeserialize, minerAddr address.Address, baseKey types.TipSetKey) (MinerProvingWindow, error) {
 	res, err := plumbing.MessageQuery(
 		ctx,
 		address.Undef,
 		minerAddr,
 		"getProvingWindow",
-		plumbing.ChainHeadKey(),
+		baseKey,
 	)
 	if err != nil {
 		return MinerProvingWindow{}, errors.Wrap(err, "query ProvingPeriod method failed")
@@ -428,7 +455,7 @@ func MinerGetProvingWindow(ctx context.Context, plumbing minerQueryAndDeserializ
 		address.Undef,
 		minerAddr,
 		"getProvingSetCommitments",
-		plumbing.ChainHeadKey(),
+		baseKey,
 	)
 	if err != nil {
 		return MinerProvingWindow{}, errors.Wrap(err, "query SetCommitments method failed")
@@ -461,14 +488,15 @@ type MinerPower struct {
 	Total types.BytesAmount
 }
 
-// MinerGetPower queries the power of a given miner.
-func MinerGetPower(ctx context.Context, plumbing mgaAPI, minerAddr address.Address) (MinerPower, error) {
+// MinerGetPower queries the power of a given miner in the state of the tipset
+// with key baseKey.
+func MinerGetPower(ctx context.Context, plumbing mgaAPI, minerAddr address.Address, baseKey types.TipSetKey) (MinerPower, error) {
 	bytes, err := plumbing.MessageQuery(
 		ctx,
 		address.Undef,
 		minerAddr,
 		"getPower",
-		plumbing.ChainHeadKey(),
+		baseKey,
 	)
 	if err != nil {
 		return MinerPower{}, err
@@ -480,7 +508,7 @@ func MinerGetPower(ctx context.Context, plumbing mgaAPI, minerAddr address.Addre
 		address.Undef,
 		address.StorageMarketAddress,
 		"getTotalStorage",
-		plumbing.ChainHeadKey(),
+		baseKey,
 	)
 	if err != nil {
 		return MinerPower{}, err
@@ -493,14 +521,15 @@ func MinerGetPower(ctx context.Context, plumbing mgaAPI, minerAddr address.Addre
 	}, nil
 }
 
-// MinerGetCollateral queries the collateral of a given miner.
-func MinerGetCollateral(ctx context.Context, plumbing mgaAPI, minerAddr address.Address) (types.AttoFIL, error) {
+// MinerGetCollateral queries the collateral of a given miner in the state of
+// the tipset with key baseKey.
+func MinerGetCollateral(ctx context.Context, plumbing mgaAPI, minerAddr address.Address, baseKey types.TipSetKey) (types.AttoFIL, error) {
 	rets, err := plumbing.MessageQuery(
 		ctx,
 		address.Undef,
 		minerAddr,
 		"getActiveCollateral",
-		plumbing.ChainHeadKey(),
+		baseKey,
 	)
 	if err != nil {
 		return types.AttoFIL{}, err
@@ -525,19 +554,10 @@ func MinerSetWorkerAddress(
 	gasLimit types.GasUnits,
 ) (cid.Cid, error) {
 
-	retVal, err := plumbing.ConfigGet("mining.minerAddress")
+	minerAddr, minerOwnerAddr, err := configuredMinerOwner(ctx, plumbing)
 	if err != nil {
 		return cid.Undef, err
 	}
-	minerAddr, ok := retVal.(address.Address)
-	if !ok {
-		return cid.Undef, errors.New("problem converting miner address")
-	}
-
-	minerOwnerAddr, err := plumbing.MinerGetOwnerAddress(ctx, minerAddr)
-	if err != nil {
-		return cid.Undef, errors.Wrap(err, "could not get miner owner address")
-	}
 
 	return plumbing.MessageSend(
 		ctx,
@@ -549,3 +569,163 @@ func MinerSetWorkerAddress(
 		"changeWorker",
 		workerAddr)
 }
+
+// MinerPledge sends amount from the owner of the node's miner to the miner
+// actor, making it available as collateral.
+func MinerPledge(ctx context.Context, plumbing mwapi, amount types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	minerAddr, minerOwnerAddr, err := configuredMinerOwner(ctx, plumbing)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return plumbing.MessageSend(ctx, minerOwnerAddr, minerAddr, amount, gasPrice, gasLimit, "pledge")
+}
+
+// MinerWithdraw withdraws amount from the node's miner actor to its owner.
+// The miner actor rejects withdrawals of more than its available balance.
+func MinerWithdraw(ctx context.Context, plumbing mwapi, amount types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	minerAddr, minerOwnerAddr, err := configuredMinerOwner(ctx, plumbing)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return plumbing.MessageSend(ctx, minerOwnerAddr, minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "withdraw", amount)
+}
+
+// MinerChangeOwner proposes to hand the node's miner over to newOwner. The
+// owner changes once newOwner accepts with MinerAcceptOwner.
+func MinerChangeOwner(ctx context.Context, plumbing mwapi, newOwner address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	minerAddr, minerOwnerAddr, err := configuredMinerOwner(ctx, plumbing)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return plumbing.MessageSend(ctx, minerOwnerAddr, minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "changeOwner", newOwner)
+}
+
+// maoAPI is the subset of the plumbing.API that MinerAcceptOwner uses.
+type maoAPI interface {
+	MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
+	ChainHeadKey() types.TipSetKey
+}
+
+// MinerGetProposedOwner queries for the address the owner of the given miner
+// proposed to hand it over to, in the state of the tipset with key baseKey.
+// It returns address.Undef if no change of owner is proposed.
+func MinerGetProposedOwner(ctx context.Context, plumbing mgaAPI, minerAddr address.Address, baseKey types.TipSetKey) (address.Address, error) {
+	rets, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getProposedOwner", baseKey)
+	if err != nil {
+		return address.Undef, errors.Wrap(err, "could not get proposed owner")
+	}
+	if len(rets[0]) == 0 {
+		return address.Undef, nil
+	}
+	return address.NewFromBytes(rets[0])
+}
+
+// MinerAcceptOwner makes from the owner of minerAddr, accepting a change of
+// owner proposed by the current owner.
+func MinerAcceptOwner(ctx context.Context, plumbing maoAPI, from, minerAddr address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	proposed, err := MinerGetProposedOwner(ctx, plumbing, minerAddr, plumbing.ChainHeadKey())
+	if err != nil {
+		return cid.Undef, err
+	}
+	if proposed != from {
+		return cid.Undef, fmt.Errorf("%s is not the proposed owner of miner %s", from, minerAddr)
+	}
+
+	return plumbing.MessageSend(ctx, from, minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "acceptOwner")
+}
+
+// MinerTerminate terminates the node's miner. It must still submit the PoSt
+// of its current proving period, which removes its sectors and power. Its
+// collateral is held for a penalty window, after which MinerRefundCollateral
+// returns it to the owner.
+func MinerTerminate(ctx context.Context, plumbing mwapi, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	minerAddr, minerOwnerAddr, err := configuredMinerOwner(ctx, plumbing)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return plumbing.MessageSend(ctx, minerOwnerAddr, minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "terminate")
+}
+
+// MinerRefundCollateral returns the collateral of the node's terminated miner
+// to its owner once the penalty window has passed.
+func MinerRefundCollateral(ctx context.Context, plumbing mwapi, gasPrice types.AttoFIL, gasLimit types.GasUnits) (cid.Cid, error) {
+	minerAddr, minerOwnerAddr, err := configuredMinerOwner(ctx, plumbing)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return plumbing.MessageSend(ctx, minerOwnerAddr, minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "refundCollateral")
+}
+
+// configuredMinerOwner returns the address of the node's miner and of its owner.
+func configuredMinerOwner(ctx context.Context, plumbing mwapi) (address.Address, address.Address, error) {
+	retVal, err := plumbing.ConfigGet("mining.minerAddress")
+	if err != nil {
+		return address.Undef, address.Undef, err
+	}
+	minerAddr, ok := retVal.(address.Address)
+	if !ok {
+		return address.Undef, address.Undef, errors.New("problem converting miner address")
+	}
+
+	minerOwnerAddr, err := plumbing.MinerGetOwnerAddress(ctx, minerAddr)
+	if err != nil {
+		return address.Undef, address.Undef, errors.Wrap(err, "could not get miner owner address")
+	}
+	return minerAddr, minerOwnerAddr, nil
+}
+
+// MinerBalance breaks the balance of a miner actor down by what it is held for.
+type MinerBalance struct {
+	Total types.AttoFIL
+	// Pledged is the collateral required for the storage the miner committed.
+	Pledged types.AttoFIL
+	// Owed is the collateral reserved for the storage of slashed sectors.
+	Owed types.AttoFIL
+	// Available is the balance the owner may withdraw.
+	Available types.AttoFIL
+}
+
+// mgbAPI is the subset of the plumbing.API that MinerGetBalance uses.
+type mgbAPI interface {
+	ActorGetAt(ctx context.Context, baseKey types.TipSetKey, addr address.Address) (*actor.Actor, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
+}
+
+// MinerGetBalance queries the balance of a given miner in the state of the
+// tipset with key baseKey and breaks it down.
+func MinerGetBalance(ctx context.Context, plumbing mgbAPI, minerAddr address.Address, baseKey types.TipSetKey) (MinerBalance, error) {
+	act, err := plumbing.ActorGetAt(ctx, baseKey, minerAddr)
+	if err != nil {
+		return MinerBalance{}, errors.Wrap(err, "could not get miner actor")
+	}
+
+	rets, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getPledgeCollateralRequirement", baseKey)
+	if err != nil {
+		return MinerBalance{}, errors.Wrap(err, "could not get pledge collateral requirement")
+	}
+	pledged := types.NewAttoFILFromBytes(rets[0])
+
+	rets, err = plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getOwedStorageCollateral", baseKey)
+	if err != nil {
+		return MinerBalance{}, errors.Wrap(err, "could not get owed storage collateral")
+	}
+	owed := types.NewAttoFILFromBytes(rets[0])
+
+	available := act.Balance.Sub(pledged).Sub(owed)
+	if available.IsNegative() {
+		available = types.ZeroAttoFIL
+	}
+
+	return MinerBalance{
+		Total:     act.Balance,
+		Pledged:   pledged,
+		Owed:      owed,
+		Available: available,
+	}, nil
+}
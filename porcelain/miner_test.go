@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"testing"
 
 	"github.com/filecoin-project/go-leb128"
@@ -13,6 +14,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
@@ -447,7 +449,7 @@ func (mgop *minerQueryAndDeserializePlumbing) ActorGetSignature(ctx context.Cont
 func TestMinerGetOwnerAddress(t *testing.T) {
 	tf.UnitTest(t)
 
-	addr, err := MinerGetOwnerAddress(context.Background(), &minerQueryAndDeserializePlumbing{}, address.TestAddress2)
+	addr, err := MinerGetOwnerAddress(context.Background(), &minerQueryAndDeserializePlumbing{}, address.TestAddress2, types.NewTipSetKey())
 	assert.NoError(t, err)
 	assert.Equal(t, address.TestAddress, addr)
 }
@@ -463,7 +465,7 @@ func TestMinerGetWorkerAddress(t *testing.T) {
 func TestMinerGetPower(t *testing.T) {
 	tf.UnitTest(t)
 
-	power, err := MinerGetPower(context.Background(), &minerQueryAndDeserializePlumbing{}, address.TestAddress2)
+	power, err := MinerGetPower(context.Background(), &minerQueryAndDeserializePlumbing{}, address.TestAddress2, types.NewTipSetKey())
 	assert.NoError(t, err)
 	assert.Equal(t, "4", power.Total.String())
 	assert.Equal(t, "2", power.Power.String())
@@ -506,7 +508,7 @@ func (mpp *minerGetProvingPeriodPlumbing) ActorGetSignature(ctx context.Context,
 func TestMinerProvingPeriod(t *testing.T) {
 	tf.UnitTest(t)
 
-	pp, err := MinerGetProvingWindow(context.Background(), &minerGetProvingPeriodPlumbing{}, address.TestAddress2)
+	pp, err := MinerGetProvingWindow(context.Background(), &minerGetProvingPeriodPlumbing{}, address.TestAddress2, types.NewTipSetKey())
 	assert.NoError(t, err)
 	assert.Equal(t, "10", pp.Start.String())
 	assert.Equal(t, "20", pp.End.String())
@@ -515,10 +517,6 @@ func TestMinerProvingPeriod(t *testing.T) {
 
 type minerGetPeerIDPlumbing struct{}
 
-func (mgop *minerGetPeerIDPlumbing) ChainHeadKey() types.TipSetKey {
-	return types.NewTipSetKey()
-}
-
 func (mgop *minerGetPeerIDPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
 
 	peerID := requirePeerID()
@@ -528,7 +526,7 @@ func (mgop *minerGetPeerIDPlumbing) MessageQuery(ctx context.Context, optFrom, t
 func TestMinerGetPeerID(t *testing.T) {
 	tf.UnitTest(t)
 
-	id, err := MinerGetPeerID(context.Background(), &minerGetPeerIDPlumbing{}, address.TestAddress2)
+	id, err := MinerGetPeerID(context.Background(), &minerGetPeerIDPlumbing{}, address.TestAddress2, types.NewTipSetKey())
 	require.NoError(t, err)
 
 	expected := requirePeerID()
@@ -538,10 +536,6 @@ func TestMinerGetPeerID(t *testing.T) {
 
 type minerGetAskPlumbing struct{}
 
-func (mgop *minerGetAskPlumbing) ChainHeadKey() types.TipSetKey {
-	return types.NewTipSetKey()
-}
-
 func (mgop *minerGetAskPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
 	out, err := cbor.DumpObject(miner.Ask{
 		Price:  types.NewAttoFILFromFIL(32),
@@ -557,7 +551,7 @@ func (mgop *minerGetAskPlumbing) MessageQuery(ctx context.Context, optFrom, to a
 func TestMinerGetAsk(t *testing.T) {
 	tf.UnitTest(t)
 
-	ask, err := MinerGetAsk(context.Background(), &minerGetAskPlumbing{}, address.TestAddress2, 4)
+	ask, err := MinerGetAsk(context.Background(), &minerGetAskPlumbing{}, address.TestAddress2, 4, types.NewTipSetKey())
 	require.NoError(t, err)
 
 	assert.Equal(t, types.NewAttoFILFromFIL(32), ask.Price)
@@ -565,6 +559,48 @@ func TestMinerGetAsk(t *testing.T) {
 	assert.Equal(t, big.NewInt(4), ask.ID)
 }
 
+type minerGetAsksPlumbing struct {
+	asks map[uint64]miner.Ask
+}
+
+func (mgop *minerGetAsksPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
+	var out []byte
+	var err error
+	switch method {
+	case "getAsks":
+		var ids []uint64
+		for id := range mgop.asks {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		out, err = cbor.DumpObject(ids)
+	case "getAsk":
+		out, err = cbor.DumpObject(mgop.asks[params[0].(*big.Int).Uint64()])
+	default:
+		return nil, fmt.Errorf("unexpected method %s", method)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{out}, nil
+}
+
+func TestMinerGetAsks(t *testing.T) {
+	tf.UnitTest(t)
+
+	plumbing := &minerGetAsksPlumbing{asks: map[uint64]miner.Ask{
+		0: {Price: types.NewAttoFILFromFIL(2), Expiry: types.NewBlockHeight(10), ID: big.NewInt(0)},
+		1: {Price: types.NewAttoFILFromFIL(3), Expiry: types.NewBlockHeight(20), ID: big.NewInt(1)},
+	}}
+
+	asks, err := MinerGetAsks(context.Background(), plumbing, address.TestAddress2, types.NewTipSetKey())
+	require.NoError(t, err)
+	require.Len(t, asks, 2)
+	assert.Equal(t, types.NewAttoFILFromFIL(2), asks[0].Price)
+	assert.Equal(t, big.NewInt(1), asks[1].ID)
+	assert.Equal(t, types.NewBlockHeight(20), asks[1].Expiry)
+}
+
 func requirePeerID() peer.ID {
 	id, err := peer.IDB58Decode("QmWbMozPyW6Ecagtxq7SXBXXLY5BNdP1GwHB2WoZCKMvcb")
 	if err != nil {
@@ -573,13 +609,16 @@ func requirePeerID() peer.ID {
 	return id
 }
 
-type minerGetSectorSizePlumbing struct{}
+type minerGetSectorSizePlumbing struct {
+	keys []types.TipSetKey
+}
 
 func (minerGetSectorSizePlumbing) ChainHeadKey() types.TipSetKey {
 	return types.NewTipSetKey()
 }
 
-func (minerGetSectorSizePlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
+func (mgssp *minerGetSectorSizePlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error) {
+	mgssp.keys = append(mgssp.keys, baseKey)
 	return [][]byte{types.NewBytesAmount(1234).Bytes()}, nil
 }
 func (minerGetSectorSizePlumbing) ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
@@ -592,19 +631,25 @@ func (minerGetSectorSizePlumbing) ActorGetSignature(ctx context.Context, actorAd
 func TestMinerGetSectorSize(t *testing.T) {
 	tf.UnitTest(t)
 
-	sectorSize, err := MinerGetSectorSize(context.Background(), &minerGetSectorSizePlumbing{}, address.TestAddress2)
+	plumbing := &minerGetSectorSizePlumbing{}
+	baseKey := types.NewTipSetKey(types.NewCidForTestGetter()())
+	sectorSize, err := MinerGetSectorSize(context.Background(), plumbing, address.TestAddress2, baseKey)
 	require.NoError(t, err)
 
 	assert.Equal(t, int(sectorSize.Uint64()), 1234)
+	assert.Equal(t, []types.TipSetKey{baseKey}, plumbing.keys)
 }
 
-type minerGetLastCommittedSectorIDPlumbing struct{}
+type minerGetLastCommittedSectorIDPlumbing struct {
+	keys []types.TipSetKey
+}
 
 func (minerGetLastCommittedSectorIDPlumbing) ChainHeadKey() types.TipSetKey {
 	return types.NewTipSetKey()
 }
 
-func (minerGetLastCommittedSectorIDPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
+func (mglcsp *minerGetLastCommittedSectorIDPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error) {
+	mglcsp.keys = append(mglcsp.keys, baseKey)
 	return [][]byte{leb128.FromUInt64(5432)}, nil
 }
 func (minerGetLastCommittedSectorIDPlumbing) ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
@@ -617,10 +662,13 @@ func (minerGetLastCommittedSectorIDPlumbing) ActorGetSignature(ctx context.Conte
 func TestMinerGetLastCommittedSectorID(t *testing.T) {
 	tf.UnitTest(t)
 
-	lastCommittedSectorID, err := MinerGetLastCommittedSectorID(context.Background(), &minerGetLastCommittedSectorIDPlumbing{}, address.TestAddress2)
+	plumbing := &minerGetLastCommittedSectorIDPlumbing{}
+	baseKey := types.NewTipSetKey(types.NewCidForTestGetter()())
+	lastCommittedSectorID, err := MinerGetLastCommittedSectorID(context.Background(), plumbing, address.TestAddress2, baseKey)
 	require.NoError(t, err)
 
 	assert.Equal(t, int(lastCommittedSectorID), 5432)
+	assert.Equal(t, []types.TipSetKey{baseKey}, plumbing.keys)
 }
 
 type minerSetWorkerAddressPlumbing struct {
@@ -711,3 +759,189 @@ func TestMinerSetWorkerAddress(t *testing.T) {
 		})
 	}
 }
+
+type minerPledgePlumbing struct {
+	minerSetWorkerAddressPlumbing
+	from, to address.Address
+	value    types.AttoFIL
+	method   string
+	params   []interface{}
+}
+
+func (mpp *minerPledgePlumbing) MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+	mpp.from, mpp.to, mpp.value, mpp.method, mpp.params = from, to, value, method, params
+	return mpp.minerSetWorkerAddressPlumbing.MessageSend(ctx, from, to, value, gasPrice, gasLimit, method, params...)
+}
+
+func TestMinerPledgeAndWithdraw(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addrGetter := address.NewForTestGetter()
+	minerAddr, ownerAddr := addrGetter(), addrGetter()
+	amount := types.NewAttoFILFromFIL(5)
+
+	t.Run("pledges from the owner", func(t *testing.T) {
+		plumbing := &minerPledgePlumbing{minerSetWorkerAddressPlumbing: minerSetWorkerAddressPlumbing{minerAddr: minerAddr, ownerAddr: ownerAddr}}
+
+		_, err := MinerPledge(ctx, plumbing, amount, types.NewGasPrice(1), types.NewGasUnits(300))
+		require.NoError(t, err)
+		assert.Equal(t, ownerAddr, plumbing.from)
+		assert.Equal(t, minerAddr, plumbing.to)
+		assert.Equal(t, amount, plumbing.value)
+		assert.Equal(t, "pledge", plumbing.method)
+	})
+
+	t.Run("withdraws to the owner", func(t *testing.T) {
+		plumbing := &minerPledgePlumbing{minerSetWorkerAddressPlumbing: minerSetWorkerAddressPlumbing{minerAddr: minerAddr, ownerAddr: ownerAddr}}
+
+		_, err := MinerWithdraw(ctx, plumbing, amount, types.NewGasPrice(1), types.NewGasUnits(300))
+		require.NoError(t, err)
+		assert.Equal(t, ownerAddr, plumbing.from)
+		assert.Equal(t, types.ZeroAttoFIL, plumbing.value)
+		assert.Equal(t, "withdraw", plumbing.method)
+		assert.Equal(t, []interface{}{amount}, plumbing.params)
+	})
+
+	t.Run("fails without the owner address", func(t *testing.T) {
+		plumbing := &minerPledgePlumbing{minerSetWorkerAddressPlumbing: minerSetWorkerAddressPlumbing{getOwnerFail: true}}
+
+		_, err := MinerPledge(ctx, plumbing, amount, types.NewGasPrice(1), types.NewGasUnits(300))
+		assert.Error(t, err)
+		assert.Empty(t, plumbing.method)
+	})
+}
+
+type minerGetBalancePlumbing struct {
+	balance, pledged, owed types.AttoFIL
+	keys                   []types.TipSetKey
+}
+
+func (mgbp *minerGetBalancePlumbing) ActorGetAt(ctx context.Context, baseKey types.TipSetKey, addr address.Address) (*actor.Actor, error) {
+	mgbp.keys = append(mgbp.keys, baseKey)
+	return &actor.Actor{Balance: mgbp.balance}, nil
+}
+
+func (mgbp *minerGetBalancePlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error) {
+	mgbp.keys = append(mgbp.keys, baseKey)
+	switch method {
+	case "getPledgeCollateralRequirement":
+		return [][]byte{mgbp.pledged.Bytes()}, nil
+	case "getOwedStorageCollateral":
+		return [][]byte{mgbp.owed.Bytes()}, nil
+	}
+	return nil, fmt.Errorf("unsupported method: %s", method)
+}
+
+func TestMinerGetBalance(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	t.Run("breaks the balance down", func(t *testing.T) {
+		plumbing := &minerGetBalancePlumbing{
+			balance: types.NewAttoFILFromFIL(10),
+			pledged: types.NewAttoFILFromFIL(3),
+			owed:    types.NewAttoFILFromFIL(2),
+		}
+
+		balance, err := MinerGetBalance(ctx, plumbing, address.TestAddress, types.NewTipSetKey())
+		require.NoError(t, err)
+		assert.Equal(t, types.NewAttoFILFromFIL(10), balance.Total)
+		assert.Equal(t, types.NewAttoFILFromFIL(3), balance.Pledged)
+		assert.Equal(t, types.NewAttoFILFromFIL(2), balance.Owed)
+		assert.Equal(t, types.NewAttoFILFromFIL(5), balance.Available)
+	})
+
+	t.Run("has nothing available when the balance does not cover the collateral", func(t *testing.T) {
+		plumbing := &minerGetBalancePlumbing{
+			balance: types.NewAttoFILFromFIL(1),
+			pledged: types.NewAttoFILFromFIL(3),
+			owed:    types.ZeroAttoFIL,
+		}
+
+		balance, err := MinerGetBalance(ctx, plumbing, address.TestAddress, types.NewTipSetKey())
+		require.NoError(t, err)
+		assert.Equal(t, types.ZeroAttoFIL, balance.Available)
+	})
+
+	t.Run("reads the state of the given tipset", func(t *testing.T) {
+		plumbing := &minerGetBalancePlumbing{balance: types.NewAttoFILFromFIL(1)}
+		baseKey := types.NewTipSetKey(types.NewCidForTestGetter()())
+
+		_, err := MinerGetBalance(ctx, plumbing, address.TestAddress, baseKey)
+		require.NoError(t, err)
+		assert.Equal(t, []types.TipSetKey{baseKey, baseKey, baseKey}, plumbing.keys)
+	})
+}
+
+type minerAcceptOwnerPlumbing struct {
+	proposed address.Address
+	sent     string
+	keys     []types.TipSetKey
+}
+
+func (maop *minerAcceptOwnerPlumbing) MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+	maop.sent = method
+	return types.EmptyMessagesCID, nil
+}
+
+func (maop *minerAcceptOwnerPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error) {
+	maop.keys = append(maop.keys, baseKey)
+	if method == "getProposedOwner" {
+		return [][]byte{maop.proposed.Bytes()}, nil
+	}
+	return nil, fmt.Errorf("unsupported method: %s", method)
+}
+
+func (maop *minerAcceptOwnerPlumbing) ChainHeadKey() types.TipSetKey {
+	return types.NewTipSetKey()
+}
+
+func TestMinerAcceptOwner(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	minerAddr := address.NewForTestGetter()()
+
+	t.Run("accepts a proposed ownership", func(t *testing.T) {
+		plumbing := &minerAcceptOwnerPlumbing{proposed: address.TestAddress2}
+
+		_, err := MinerAcceptOwner(ctx, plumbing, address.TestAddress2, minerAddr, types.NewGasPrice(1), types.NewGasUnits(300))
+		require.NoError(t, err)
+		assert.Equal(t, "acceptOwner", plumbing.sent)
+	})
+
+	t.Run("refuses to accept an ownership proposed to another address", func(t *testing.T) {
+		plumbing := &minerAcceptOwnerPlumbing{proposed: address.TestAddress2}
+
+		_, err := MinerAcceptOwner(ctx, plumbing, address.TestAddress, minerAddr, types.NewGasPrice(1), types.NewGasUnits(300))
+		assert.Error(t, err)
+		assert.Empty(t, plumbing.sent)
+	})
+}
+
+func TestMinerGetProposedOwner(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	minerAddr := address.NewForTestGetter()()
+	baseKey := types.NewTipSetKey(types.NewCidForTestGetter()())
+
+	t.Run("reads the proposed owner in the state of the given tipset", func(t *testing.T) {
+		plumbing := &minerAcceptOwnerPlumbing{proposed: address.TestAddress2}
+
+		proposed, err := MinerGetProposedOwner(ctx, plumbing, minerAddr, baseKey)
+		require.NoError(t, err)
+		assert.Equal(t, address.TestAddress2, proposed)
+		assert.Equal(t, []types.TipSetKey{baseKey}, plumbing.keys)
+	})
+
+	t.Run("returns an undefined address without a proposal", func(t *testing.T) {
+		plumbing := &minerAcceptOwnerPlumbing{}
+
+		proposed, err := MinerGetProposedOwner(ctx, plumbing, minerAddr, baseKey)
+		require.NoError(t, err)
+		assert.Equal(t, address.Undef, proposed)
+	})
+}
@@ -11,17 +11,18 @@ import (
 )
 
 type pclPlumbing interface {
-	ChainHeadKey() types.TipSetKey
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
 	WalletDefaultAddress() (address.Address, error)
 }
 
-// PaymentChannelLs lists payments for a given payer
+// PaymentChannelLs lists payments for a given payer in the state of the tipset
+// with key baseKey
 func PaymentChannelLs(
 	ctx context.Context,
 	plumbing pclPlumbing,
 	fromAddr address.Address,
 	payerAddr address.Address,
+	baseKey types.TipSetKey,
 ) (channels map[string]*paymentbroker.PaymentChannel, err error) {
 	if fromAddr.Empty() {
 		fromAddr, err = plumbing.WalletDefaultAddress()
@@ -39,7 +40,7 @@ func PaymentChannelLs(
 		fromAddr,
 		address.PaymentBrokerAddress,
 		"ls",
-		plumbing.ChainHeadKey(),
+		baseKey,
 		payerAddr,
 	)
 	if err != nil {
@@ -21,10 +21,6 @@ type testPaymentChannelLsPlumbing struct {
 	channels map[string]*paymentbroker.PaymentChannel
 }
 
-func (p *testPaymentChannelLsPlumbing) ChainHeadKey() types.TipSetKey {
-	return types.NewTipSetKey()
-}
-
 func (p *testPaymentChannelLsPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
 	chnls, err := cbor.DumpObject(p.channels)
 	require.NoError(p.testing, err)
@@ -47,7 +43,7 @@ func TestPaymentChannelLs(t *testing.T) {
 		}
 		ctx := context.Background()
 
-		channels, err := porcelain.PaymentChannelLs(ctx, plumbing, address.Undef, address.Undef)
+		channels, err := porcelain.PaymentChannelLs(ctx, plumbing, address.Undef, address.Undef, types.NewTipSetKey())
 		require.NoError(t, err)
 		assert.Equal(t, expectedChannels, channels)
 	})
@@ -29,13 +29,13 @@ type ProtocolParams struct {
 
 type protocolParamsPlumbing interface {
 	ConfigGet(string) (interface{}, error)
-	ChainHeadKey() types.TipSetKey
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
 	BlockTime() time.Duration
 }
 
-// ProtocolParameters returns protocol parameter information about the node
-func ProtocolParameters(ctx context.Context, plumbing protocolParamsPlumbing) (*ProtocolParams, error) {
+// ProtocolParameters returns protocol parameter information about the node,
+// reading the network parameters from the state of the tipset with key baseKey
+func ProtocolParameters(ctx context.Context, plumbing protocolParamsPlumbing, baseKey types.TipSetKey) (*ProtocolParams, error) {
 	autoSealIntervalInterface, err := plumbing.ConfigGet("mining.autoSealIntervalSeconds")
 	if err != nil {
 		return nil, err
@@ -46,12 +46,12 @@ func ProtocolParameters(ctx context.Context, plumbing protocolParamsPlumbing) (*
 		return nil, errors.New("Failed to read autoSealInterval from config")
 	}
 
-	proofsMode, err := getProofsMode(ctx, plumbing)
+	proofsMode, err := getProofsMode(ctx, plumbing, baseKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not retrieve proofs mode")
 	}
 
-	networkName, err := getNetworkName(ctx, plumbing)
+	networkName, err := getNetworkName(ctx, plumbing, baseKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not retrieve network name")
 	}
@@ -88,9 +88,9 @@ func (pp *ProtocolParams) IsSupportedSectorSize(sectorSize *types.BytesAmount) b
 	return false
 }
 
-func getProofsMode(ctx context.Context, plumbing protocolParamsPlumbing) (types.ProofsMode, error) {
+func getProofsMode(ctx context.Context, plumbing protocolParamsPlumbing, baseKey types.TipSetKey) (types.ProofsMode, error) {
 	var proofsMode types.ProofsMode
-	values, err := plumbing.MessageQuery(ctx, address.Address{}, address.StorageMarketAddress, "getProofsMode", plumbing.ChainHeadKey())
+	values, err := plumbing.MessageQuery(ctx, address.Address{}, address.StorageMarketAddress, "getProofsMode", baseKey)
 	if err != nil {
 		return 0, errors.Wrap(err, "'getProofsMode' query message failed")
 	}
@@ -102,8 +102,8 @@ func getProofsMode(ctx context.Context, plumbing protocolParamsPlumbing) (types.
 	return proofsMode, nil
 }
 
-func getNetworkName(ctx context.Context, plumbing protocolParamsPlumbing) (string, error) {
-	nameBytes, err := plumbing.MessageQuery(ctx, address.Address{}, address.InitAddress, "getNetwork", plumbing.ChainHeadKey())
+func getNetworkName(ctx context.Context, plumbing protocolParamsPlumbing, baseKey types.TipSetKey) (string, error) {
+	nameBytes, err := plumbing.MessageQuery(ctx, address.Address{}, address.InitAddress, "getNetwork", baseKey)
 	if err != nil {
 		return "", errors.Wrap(err, "'getNetwork' query message failed")
 	}
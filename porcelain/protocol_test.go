@@ -20,6 +20,7 @@ const protocolTestParamBlockTime = time.Second
 type testProtocolParamsPlumbing struct {
 	testing          *testing.T
 	autoSealInterval uint
+	keys             []types.TipSetKey
 }
 
 func (tppp *testProtocolParamsPlumbing) ConfigGet(path string) (interface{}, error) {
@@ -27,11 +28,8 @@ func (tppp *testProtocolParamsPlumbing) ConfigGet(path string) (interface{}, err
 	return tppp.autoSealInterval, nil
 }
 
-func (tppp *testProtocolParamsPlumbing) ChainHeadKey() types.TipSetKey {
-	return types.NewTipSetKey()
-}
-
-func (tppp *testProtocolParamsPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
+func (tppp *testProtocolParamsPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error) {
+	tppp.keys = append(tppp.keys, baseKey)
 	if method == "getProofsMode" {
 		return [][]byte{{byte(types.TestProofsMode)}}, nil
 	} else if method == "getNetwork" {
@@ -66,9 +64,23 @@ func TestProtocolParams(t *testing.T) {
 			BlockTime:        protocolTestParamBlockTime,
 		}
 
-		out, err := porcelain.ProtocolParameters(context.TODO(), plumbing)
+		out, err := porcelain.ProtocolParameters(context.TODO(), plumbing, types.NewTipSetKey())
 		require.NoError(t, err)
 
 		assert.Equal(t, expected, out)
 	})
+
+	t.Run("reads the network parameters in the state of the given tipset", func(t *testing.T) {
+		t.Parallel()
+
+		plumbing := &testProtocolParamsPlumbing{
+			testing:          t,
+			autoSealInterval: 120,
+		}
+		baseKey := types.NewTipSetKey(types.NewCidForTestGetter()())
+
+		_, err := porcelain.ProtocolParameters(context.TODO(), plumbing, baseKey)
+		require.NoError(t, err)
+		assert.Equal(t, []types.TipSetKey{baseKey, baseKey}, plumbing.keys)
+	})
 }
@@ -3,6 +3,7 @@ package porcelain
 import (
 	"context"
 	"io"
+	"sort"
 
 	go_sectorbuilder "github.com/filecoin-project/go-sectorbuilder"
 	"github.com/ipfs/go-cid"
@@ -22,10 +23,11 @@ type sbPlumbing interface {
 }
 
 // CalculatePoSt invokes the sector builder to calculate a proof-of-spacetime.
-func CalculatePoSt(ctx context.Context, plumbing sbPlumbing, sortedCommRs go_sectorbuilder.SortedSectorInfo, seed types.PoStChallengeSeed) (types.PoStProof, error) {
+func CalculatePoSt(ctx context.Context, plumbing sbPlumbing, sortedCommRs go_sectorbuilder.SortedSectorInfo, seed types.PoStChallengeSeed, faults []uint64) (types.PoStProof, error) {
 	req := sectorbuilder.GeneratePoStRequest{
 		SortedSectorInfo: sortedCommRs,
 		ChallengeSeed:    seed,
+		Faults:           faults,
 	}
 	sb := plumbing.SectorBuilder()
 	if sb == nil {
@@ -76,3 +78,26 @@ func SealNow(ctx context.Context, plumbing sbPlumbing) error {
 	// start sealing on all existing staged sectors
 	return plumbing.SectorBuilder().SealAllStagedSectors(ctx)
 }
+
+// SealingStatus returns the status of the sectors the sectorbuilder has
+// staged or is sealing, sealing sectors first followed by queued sectors in
+// the order they will be sealed and then staged sectors.
+func SealingStatus(ctx context.Context, plumbing sbPlumbing) ([]sectorbuilder.SectorSealingStatus, error) {
+	if plumbing.SectorBuilder() == nil {
+		return nil, errors.New("must be mining to report sealing status")
+	}
+
+	statuses, err := plumbing.SectorBuilder().SealingStatus()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		if a.State != b.State {
+			// sealing, then queued, then staged
+			return a.State > b.State
+		}
+		return sectorbuilder.SealsBefore(a, b)
+	})
+	return statuses, nil
+}
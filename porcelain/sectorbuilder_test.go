@@ -47,6 +47,27 @@ func TestAddPiece(t *testing.T) {
 	})
 }
 
+func TestSealingStatus(t *testing.T) {
+	t.Run("lists sealing then queued then staged sectors", func(t *testing.T) {
+		p := newTestSectorBuilderPlumbing(0)
+		p.sectorbuilder.statuses = []sectorbuilder.SectorSealingStatus{
+			{SectorID: 1, State: sectorbuilder.SectorStaged, Priority: sectorbuilder.DefaultSealPriority},
+			{SectorID: 2, State: sectorbuilder.SectorQueued, Priority: sectorbuilder.DefaultSealPriority},
+			{SectorID: 3, State: sectorbuilder.SectorQueued, Priority: 7},
+			{SectorID: 4, State: sectorbuilder.SectorSealing, Priority: sectorbuilder.DefaultSealPriority},
+		}
+
+		statuses, err := SealingStatus(context.Background(), p)
+		require.NoError(t, err)
+
+		var ids []uint64
+		for _, status := range statuses {
+			ids = append(ids, status.SectorID)
+		}
+		assert.Equal(t, []uint64{4, 3, 2, 1}, ids)
+	})
+}
+
 func newTestSectorBuilderPlumbing(stagedSectors int) *testSectorBuilderPlumbing {
 	sb := &testSectorBuilder{numStagedSectors: stagedSectors}
 	return &testSectorBuilderPlumbing{
@@ -80,6 +101,7 @@ type testSectorBuilder struct {
 	addPieceCount       int
 	sealAllSectorsCount int
 	numStagedSectors    int
+	statuses            []sectorbuilder.SectorSealingStatus
 }
 
 func (tsb *testSectorBuilder) AddPiece(ctx context.Context, pieceRef cid.Cid, pieceSize uint64, pieceReader io.Reader) (sectorID uint64, err error) {
@@ -100,6 +122,10 @@ func (tsb *testSectorBuilder) GetAllStagedSectors() ([]go_sectorbuilder.StagedSe
 	return make([]go_sectorbuilder.StagedSectorMetadata, tsb.numStagedSectors), nil
 }
 
+func (tsb *testSectorBuilder) SealingStatus() ([]sectorbuilder.SectorSealingStatus, error) {
+	return tsb.statuses, nil
+}
+
 func (tsb *testSectorBuilder) SectorSealResults() <-chan sectorbuilder.SectorSealResult {
 	return nil
 }
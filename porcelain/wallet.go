@@ -14,12 +14,13 @@ import (
 var ErrNoDefaultFromAddress = errors.New("unable to determine a default wallet address")
 
 type wbPlumbing interface {
-	ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error)
+	ActorGetAt(ctx context.Context, baseKey types.TipSetKey, addr address.Address) (*actor.Actor, error)
 }
 
-// WalletBalance gets the current balance associated with an address
-func WalletBalance(ctx context.Context, plumbing wbPlumbing, addr address.Address) (types.AttoFIL, error) {
-	act, err := plumbing.ActorGet(ctx, addr)
+// WalletBalance gets the balance associated with an address in the state of
+// the tipset with key baseKey
+func WalletBalance(ctx context.Context, plumbing wbPlumbing, addr address.Address, baseKey types.TipSetKey) (types.AttoFIL, error) {
+	act, err := plumbing.ActorGetAt(ctx, baseKey, addr)
 	if err != nil {
 		if state.IsActorNotFoundError(err) {
 			// if the account doesn't exit, the balance should be zero
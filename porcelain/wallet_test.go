@@ -20,6 +20,7 @@ import (
 
 type wbTestPlumbing struct {
 	balance types.AttoFIL
+	baseKey types.TipSetKey
 }
 
 type wdaTestPlumbing struct {
@@ -37,7 +38,8 @@ func newWdaTestPlumbing(t *testing.T) *wdaTestPlumbing {
 	}
 }
 
-func (wbtp *wbTestPlumbing) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+func (wbtp *wbTestPlumbing) ActorGetAt(ctx context.Context, baseKey types.TipSetKey, addr address.Address) (*actor.Actor, error) {
+	wbtp.baseKey = baseKey
 	testActor := actor.NewActor(cid.Undef, wbtp.balance)
 	return testActor, nil
 }
@@ -68,10 +70,12 @@ func TestWalletBalance(t *testing.T) {
 		plumbing := &wbTestPlumbing{
 			balance: expectedBalance,
 		}
-		balance, err := porcelain.WalletBalance(ctx, plumbing, address.Undef)
+		baseKey := types.NewTipSetKey(types.NewCidForTestGetter()())
+		balance, err := porcelain.WalletBalance(ctx, plumbing, address.Undef, baseKey)
 		require.NoError(t, err)
 
 		assert.Equal(t, expectedBalance, balance)
+		assert.Equal(t, baseKey, plumbing.baseKey)
 	})
 }
 
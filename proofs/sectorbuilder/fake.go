@@ -0,0 +1,381 @@
+package sectorbuilder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+
+	"github.com/filecoin-project/go-sectorbuilder"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func init() {
+	cbor.RegisterCborType(fakeSector{})
+}
+
+const (
+	// fakeSectorsPrefix is the datastore namespace of the sectors of a
+	// FakeSectorBuilder.
+	fakeSectorsPrefix = "fakesectorbuilder/sectors"
+	// fakePiecesPrefix is the datastore namespace of the piece data of a
+	// FakeSectorBuilder.
+	fakePiecesPrefix = "fakesectorbuilder/pieces"
+)
+
+// FakeSeal produces sealed sector metadata for a sector holding data without
+// running the proofs. The commitments are derived from a hash of the data,
+// so sealing the same data always yields the same metadata. Its proof will
+// only be accepted by a verifier that accepts any proof.
+func FakeSeal(sectorID uint64, sectorClass types.SectorClass, pieces []*PieceInfo, data io.Reader) (*SealedSectorMetadata, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, data); err != nil {
+		return nil, errors.Wrap(err, "failed to read sector data")
+	}
+	digest := h.Sum(nil)
+
+	meta := &SealedSectorMetadata{
+		Pieces:   pieces,
+		Proof:    fakeProof(sectorClass.PoRepProofPartitions().Int(), digest),
+		SectorID: sectorID,
+	}
+	copy(meta.CommD[:], digest)
+	commR := sha256.Sum256(append([]byte("commR"), digest...))
+	copy(meta.CommR[:], commR[:])
+	commRStar := sha256.Sum256(append([]byte("commRStar"), digest...))
+	copy(meta.CommRStar[:], commRStar[:])
+	return meta, nil
+}
+
+// fakeProof returns a proof of the given number of partitions filled with
+// repetitions of digest.
+func fakeProof(partitions int, digest []byte) []byte {
+	proof := make([]byte, partitions*types.SinglePartitionProofLen)
+	for i := 0; i < len(proof); i += len(digest) {
+		copy(proof[i:], digest)
+	}
+	return proof
+}
+
+// FakeSectorBuilder is a SectorBuilder that needs neither the proofs library
+// nor its parameter files. Pieces are stored in a datastore, sectors are
+// sealed as soon as they are full or asked to be sealed, with FakeSeal, and
+// PoSts are made up. Its proofs will only be accepted by a verifier that
+// accepts any proof, like a verification.FakeVerifier.
+type FakeSectorBuilder struct {
+	ds           datastore.Datastore
+	sectorClass  types.SectorClass
+	maxUserBytes uint64
+
+	// lk protects staged and lastUsedSectorID, and serializes changes to the
+	// datastore
+	lk               sync.Mutex
+	staged           *fakeSector
+	lastUsedSectorID uint64
+
+	sectorSealResults chan SectorSealResult
+	stopCh            chan struct{}
+}
+
+var _ SectorBuilder = &FakeSectorBuilder{}
+var _ SectorChecker = &FakeSectorBuilder{}
+
+// NewFakeSectorBuilder returns a FakeSectorBuilder that stores its pieces and
+// sectors in ds. Sector ids are assigned after lastUsedSectorID.
+func NewFakeSectorBuilder(ds datastore.Datastore, sectorClass types.SectorClass, lastUsedSectorID uint64) (*FakeSectorBuilder, error) {
+	sb := &FakeSectorBuilder{
+		ds:                ds,
+		sectorClass:       sectorClass,
+		maxUserBytes:      go_sectorbuilder.GetMaxUserBytesPerStagedSector(sectorClass.SectorSize().Uint64()),
+		lastUsedSectorID:  lastUsedSectorID,
+		sectorSealResults: make(chan SectorSealResult),
+		stopCh:            make(chan struct{}),
+	}
+
+	sectors, err := sb.sectors()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load sectors")
+	}
+	for _, sector := range sectors {
+		if sector.SectorID > sb.lastUsedSectorID {
+			sb.lastUsedSectorID = sector.SectorID
+		}
+		if sector.Metadata == nil {
+			sb.staged = sector
+		}
+	}
+	return sb, nil
+}
+
+// AddPiece writes the given piece into the staged sector and returns the id
+// of that sector. The sector is sealed before AddPiece returns if the piece
+// fills it.
+func (sb *FakeSectorBuilder) AddPiece(ctx context.Context, pieceRef cid.Cid, pieceSize uint64, pieceReader io.Reader) (uint64, error) {
+	if pieceSize > sb.maxUserBytes {
+		return 0, errors.Errorf("piece of %d bytes does not fit in a sector of %d bytes", pieceSize, sb.maxUserBytes)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(pieceReader, int64(pieceSize)))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read piece")
+	}
+	if uint64(len(data)) != pieceSize {
+		return 0, errors.Errorf("expected to read %d bytes but read %d", pieceSize, len(data))
+	}
+
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+
+	if sb.staged != nil && sb.staged.Size+pieceSize > sb.maxUserBytes {
+		if err := sb.sealStaged(); err != nil {
+			return 0, err
+		}
+	}
+	if sb.staged == nil {
+		sb.lastUsedSectorID++
+		sb.staged = &fakeSector{SectorID: sb.lastUsedSectorID}
+	}
+
+	if err := sb.ds.Put(datastore.KeyWithNamespaces([]string{fakePiecesPrefix, pieceRef.String()}), data); err != nil {
+		return 0, errors.Wrapf(err, "failed to store piece %s", pieceRef.String())
+	}
+	sector := sb.staged
+	sector.Pieces = append(sector.Pieces, &PieceInfo{
+		Ref:            pieceRef,
+		Size:           pieceSize,
+		InclusionProof: fakeProof(1, pieceRef.Bytes()),
+	})
+	sector.Size += pieceSize
+
+	if sector.Size == sb.maxUserBytes {
+		return sector.SectorID, sb.sealStaged()
+	}
+	return sector.SectorID, sb.put(sector)
+}
+
+// ReadPieceFromSealedSector produces a Reader used to get original piece-bytes
+// from a sealed sector.
+func (sb *FakeSectorBuilder) ReadPieceFromSealedSector(pieceCid cid.Cid) (io.Reader, error) {
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+
+	if sb.staged != nil {
+		for _, piece := range sb.staged.Pieces {
+			if piece.Ref.Equals(pieceCid) {
+				return nil, errors.Errorf("piece %s is not in a sealed sector", pieceCid.String())
+			}
+		}
+	}
+
+	data, err := sb.ds.Get(datastore.KeyWithNamespaces([]string{fakePiecesPrefix, pieceCid.String()}))
+	if err == datastore.ErrNotFound {
+		return nil, errors.Errorf("piece %s is not in a sealed sector", pieceCid.String())
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load piece %s", pieceCid.String())
+	}
+	return bytes.NewReader(data), nil
+}
+
+// CheckSealedSector checks that the pieces of a sealed sector are stored. A
+// deep check also checks that they still hash to its data commitment.
+func (sb *FakeSectorBuilder) CheckSealedSector(ctx context.Context, sectorID uint64, deep bool) error {
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+
+	datum, err := sb.ds.Get(datastore.KeyWithNamespaces([]string{fakeSectorsPrefix, strconv.FormatUint(sectorID, 10)}))
+	if err == datastore.ErrNotFound {
+		return errors.Errorf("no sector %d", sectorID)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to load sector %d", sectorID)
+	}
+	var sector fakeSector
+	if err := cbor.DecodeInto(datum, &sector); err != nil {
+		return errors.Wrapf(err, "failed to decode sector %d", sectorID)
+	}
+	if sector.Metadata == nil {
+		return errors.Errorf("sector %d is not sealed", sectorID)
+	}
+
+	if !deep {
+		for _, piece := range sector.Pieces {
+			has, err := sb.ds.Has(datastore.KeyWithNamespaces([]string{fakePiecesPrefix, piece.Ref.String()}))
+			if err != nil {
+				return errors.Wrapf(err, "failed to look up piece %s", piece.Ref.String())
+			}
+			if !has {
+				return errors.Errorf("piece %s of sector %d is missing", piece.Ref.String(), sectorID)
+			}
+		}
+		return nil
+	}
+
+	data, err := sb.sectorData(&sector)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(data.Bytes()) != sector.Metadata.CommD {
+		return errors.Errorf("sector %d does not match its data commitment", sectorID)
+	}
+	return nil
+}
+
+// SealAllStagedSectors seals the staged sector if it holds any pieces.
+func (sb *FakeSectorBuilder) SealAllStagedSectors(ctx context.Context) error {
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+
+	if sb.staged == nil || len(sb.staged.Pieces) == 0 {
+		return nil
+	}
+	return sb.sealStaged()
+}
+
+// GetAllStagedSectors returns the staged sector, if there is one.
+func (sb *FakeSectorBuilder) GetAllStagedSectors() ([]go_sectorbuilder.StagedSectorMetadata, error) {
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+
+	if sb.staged == nil {
+		return nil, nil
+	}
+	return []go_sectorbuilder.StagedSectorMetadata{{SectorID: sb.staged.SectorID}}, nil
+}
+
+// SealingStatus returns the status of the staged sector, if there is one.
+// Sectors are sealed instantly so there are never any sealing sectors.
+func (sb *FakeSectorBuilder) SealingStatus() ([]SectorSealingStatus, error) {
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+
+	if sb.staged == nil {
+		return nil, nil
+	}
+	return []SectorSealingStatus{{
+		SectorID: sb.staged.SectorID,
+		State:    SectorStaged,
+		Priority: DefaultSealPriority,
+		Pieces:   len(sb.staged.Pieces),
+	}}, nil
+}
+
+// SectorSealResults returns an unbuffered channel that is sent a value whenever
+// sealing completes.
+func (sb *FakeSectorBuilder) SectorSealResults() <-chan SectorSealResult {
+	return sb.sectorSealResults
+}
+
+// GeneratePoSt produces a fake proof-of-spacetime derived from the challenge
+// seed and the provided replica commitments.
+func (sb *FakeSectorBuilder) GeneratePoSt(req GeneratePoStRequest) (GeneratePoStResponse, error) {
+	h := sha256.New()
+	h.Write(req.ChallengeSeed[:]) // nolint: errcheck
+	for _, info := range req.SortedSectorInfo.Values() {
+		h.Write(info.CommR[:]) // nolint: errcheck
+	}
+	return GeneratePoStResponse{
+		Proof: fakeProof(sb.sectorClass.PoStProofPartitions().Int(), h.Sum(nil)),
+	}, nil
+}
+
+// Close stops sending seal results.
+func (sb *FakeSectorBuilder) Close() error {
+	close(sb.stopCh)
+	return nil
+}
+
+// sealStaged seals the staged sector and sends its result. It must be called
+// with the lock held.
+func (sb *FakeSectorBuilder) sealStaged() error {
+	sector := sb.staged
+
+	data, err := sb.sectorData(sector)
+	if err != nil {
+		return err
+	}
+	meta, err := FakeSeal(sector.SectorID, sb.sectorClass, sector.Pieces, data)
+	if err != nil {
+		return err
+	}
+	sector.Metadata = meta
+	if err := sb.put(sector); err != nil {
+		return err
+	}
+	sb.staged = nil
+
+	go func() {
+		select {
+		case sb.sectorSealResults <- SectorSealResult{SectorID: meta.SectorID, SealingResult: meta}:
+		case <-sb.stopCh:
+		}
+	}()
+	return nil
+}
+
+// sectorData returns the concatenated data of the pieces of a sector.
+func (sb *FakeSectorBuilder) sectorData(sector *fakeSector) (*bytes.Buffer, error) {
+	var data bytes.Buffer
+	for _, piece := range sector.Pieces {
+		pieceData, err := sb.ds.Get(datastore.KeyWithNamespaces([]string{fakePiecesPrefix, piece.Ref.String()}))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load piece %s", piece.Ref.String())
+		}
+		data.Write(pieceData) // nolint: errcheck
+	}
+	return &data, nil
+}
+
+// sectors loads the sectors recorded in the datastore.
+func (sb *FakeSectorBuilder) sectors() ([]*fakeSector, error) {
+	results, err := sb.ds.Query(query.Query{Prefix: "/" + fakeSectorsPrefix})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+
+	sectors := make([]*fakeSector, len(entries))
+	for i, entry := range entries {
+		var sector fakeSector
+		if err := cbor.DecodeInto(entry.Value, &sector); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode sector at %s", entry.Key)
+		}
+		sectors[i] = &sector
+	}
+	return sectors, nil
+}
+
+// put records a sector in the datastore.
+func (sb *FakeSectorBuilder) put(sector *fakeSector) error {
+	datum, err := cbor.DumpObject(sector)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal sector %d", sector.SectorID)
+	}
+	key := datastore.KeyWithNamespaces([]string{fakeSectorsPrefix, strconv.FormatUint(sector.SectorID, 10)})
+	if err := sb.ds.Put(key, datum); err != nil {
+		return errors.Wrapf(err, "failed to store sector %d", sector.SectorID)
+	}
+	return nil
+}
+
+// fakeSector is the persisted state of a sector of a FakeSectorBuilder.
+type fakeSector struct {
+	SectorID uint64
+	Pieces   []*PieceInfo
+	// Size is the number of bytes of piece data in the sector.
+	Size uint64
+	// Metadata is set once the sector is sealed.
+	Metadata *SealedSectorMetadata
+}
@@ -0,0 +1,136 @@
+package sectorbuilder
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestFakeSectorBuilder(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	sectorClass := types.NewSectorClass(types.OneKiBSectorSize)
+
+	t.Run("seals staged sectors when asked to", func(t *testing.T) {
+		sb, err := NewFakeSectorBuilder(datastore.NewMapDatastore(), sectorClass, 0)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, sb.Close()) }()
+
+		data := bytes.Repeat([]byte{1}, 100)
+		pieceRef := types.CidFromString(t, "piece")
+		sectorID, err := sb.AddPiece(ctx, pieceRef, uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), sectorID)
+
+		_, err = sb.ReadPieceFromSealedSector(pieceRef)
+		assert.Error(t, err, "the piece is not sealed yet")
+
+		statuses, err := sb.SealingStatus()
+		require.NoError(t, err)
+		assert.Equal(t, []SectorSealingStatus{{SectorID: 1, State: SectorStaged, Priority: DefaultSealPriority, Pieces: 1}}, statuses)
+
+		require.NoError(t, sb.SealAllStagedSectors(ctx))
+		result := <-sb.SectorSealResults()
+		require.NoError(t, result.SealingErr)
+		assert.Equal(t, uint64(1), result.SectorID)
+		require.Len(t, result.SealingResult.Pieces, 1)
+		assert.Equal(t, pieceRef, result.SealingResult.Pieces[0].Ref)
+		assert.Len(t, result.SealingResult.Proof, sectorClass.PoRepProofPartitions().ProofLen())
+
+		r, err := sb.ReadPieceFromSealedSector(pieceRef)
+		require.NoError(t, err)
+		read, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, read)
+	})
+
+	t.Run("checks sealed sectors against their data commitment", func(t *testing.T) {
+		ds := datastore.NewMapDatastore()
+		sb, err := NewFakeSectorBuilder(ds, sectorClass, 0)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, sb.Close()) }()
+
+		data := bytes.Repeat([]byte{1}, 100)
+		pieceRef := types.CidFromString(t, "piece")
+		sectorID, err := sb.AddPiece(ctx, pieceRef, uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Error(t, sb.CheckSealedSector(ctx, sectorID, false), "the sector is not sealed")
+
+		require.NoError(t, sb.SealAllStagedSectors(ctx))
+		<-sb.SectorSealResults()
+		require.NoError(t, sb.CheckSealedSector(ctx, sectorID, false))
+		require.NoError(t, sb.CheckSealedSector(ctx, sectorID, true))
+
+		pieceKey := datastore.KeyWithNamespaces([]string{fakePiecesPrefix, pieceRef.String()})
+		require.NoError(t, ds.Put(pieceKey, []byte("corrupt")))
+		assert.NoError(t, sb.CheckSealedSector(ctx, sectorID, false), "a quick check does not read the data")
+		assert.Error(t, sb.CheckSealedSector(ctx, sectorID, true))
+
+		require.NoError(t, ds.Delete(pieceKey))
+		assert.Error(t, sb.CheckSealedSector(ctx, sectorID, false))
+	})
+
+	t.Run("seals sectors as soon as they are full", func(t *testing.T) {
+		sb, err := NewFakeSectorBuilder(datastore.NewMapDatastore(), sectorClass, 0)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, sb.Close()) }()
+
+		data := bytes.Repeat([]byte{1}, int(sb.maxUserBytes))
+		sectorID, err := sb.AddPiece(ctx, types.CidFromString(t, "piece"), uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+
+		result := <-sb.SectorSealResults()
+		assert.Equal(t, sectorID, result.SectorID)
+
+		staged, err := sb.GetAllStagedSectors()
+		require.NoError(t, err)
+		assert.Empty(t, staged)
+	})
+
+	t.Run("seals the same data into the same commitments", func(t *testing.T) {
+		data := []byte("sector data")
+		first, err := FakeSeal(1, sectorClass, nil, bytes.NewReader(data))
+		require.NoError(t, err)
+		second, err := FakeSeal(1, sectorClass, nil, bytes.NewReader(data))
+		require.NoError(t, err)
+		other, err := FakeSeal(1, sectorClass, nil, bytes.NewReader([]byte("other data")))
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.NotEqual(t, first.CommR, other.CommR)
+	})
+
+	t.Run("restores the staged sector after a restart", func(t *testing.T) {
+		ds := datastore.NewMapDatastore()
+		sb, err := NewFakeSectorBuilder(ds, sectorClass, 4)
+		require.NoError(t, err)
+
+		data := bytes.Repeat([]byte{1}, 100)
+		sectorID, err := sb.AddPiece(ctx, types.CidFromString(t, "first"), uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), sectorID)
+		require.NoError(t, sb.Close())
+
+		sb, err = NewFakeSectorBuilder(ds, sectorClass, 0)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, sb.Close()) }()
+
+		sectorID, err = sb.AddPiece(ctx, types.CidFromString(t, "second"), uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), sectorID)
+
+		statuses, err := sb.SealingStatus()
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		assert.Equal(t, 2, statuses[0].Pieces)
+	})
+}
@@ -13,6 +13,7 @@ import (
 
 func init() {
 	cbor.RegisterCborType(PieceInfo{})
+	cbor.RegisterCborType(SealedSectorMetadata{})
 }
 
 // SectorBuilder provides an interface through which user piece-bytes can be
@@ -36,6 +37,10 @@ type SectorBuilder interface {
 	// GetAllStagedSectors returns a slice of all staged sector metadata for the sector builder, or an error.
 	GetAllStagedSectors() ([]go_sectorbuilder.StagedSectorMetadata, error)
 
+	// SealingStatus returns the status of every sector that is staged or
+	// being sealed.
+	SealingStatus() ([]SectorSealingStatus, error)
+
 	// SectorSealResults returns an unbuffered channel that is sent a value
 	// whenever sealing completes. All calls to SectorSealResults will get the
 	// same channel. Values will be either a *SealedSectorMetadata or an error.
@@ -56,6 +61,17 @@ type SectorBuilder interface {
 	Close() error
 }
 
+// SectorChecker is implemented by sector builders that can check the sealed
+// replicas they store.
+type SectorChecker interface {
+	// CheckSealedSector returns an error if the sealed sector cannot be read
+	// or no longer holds the data it was sealed with. A quick check is cheap
+	// enough to run on every sector periodically, and looks at the sector's
+	// replica where the builder stores one. A deep check also reads back a
+	// sample of the sector's data, which may mean unsealing it.
+	CheckSealedSector(ctx context.Context, sectorID uint64, deep bool) error
+}
+
 // SectorSealResult represents the outcome of a sector's sealing.
 type SectorSealResult struct {
 	SectorID uint64
@@ -91,6 +107,8 @@ type SealedSectorMetadata struct {
 type GeneratePoStRequest struct {
 	SortedSectorInfo go_sectorbuilder.SortedSectorInfo
 	ChallengeSeed    types.PoStChallengeSeed
+	// Faults are the ids of sectors left out of the proof.
+	Faults []uint64
 }
 
 // GeneratePoStResponse contains PoST proof.
@@ -87,6 +87,19 @@ func (p *sealStatusPoller) addSectorID(sectorID uint64) {
 	p.sectorsAwaitingSeal[sectorID] = struct{}{}
 }
 
+// sectorIDs returns the ids of the sectors whose sealing status is being
+// polled for.
+func (p *sealStatusPoller) sectorIDs() []uint64 {
+	p.sectorsAwaitingSealLk.Lock()
+	defer p.sectorsAwaitingSealLk.Unlock()
+
+	ids := make([]uint64, 0, len(p.sectorsAwaitingSeal))
+	for id := range p.sectorsAwaitingSeal {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 // stop causes the sealStatusPoller to stop polling. The poller cannot be
 // restarted after this method is called.
 func (p *sealStatusPoller) stop() {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"math/rand"
 	"unsafe"
 
 	bserv "github.com/ipfs/go-blockservice"
@@ -12,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder/bytesink"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-sectorbuilder"
@@ -36,12 +38,16 @@ type RustSectorBuilder struct {
 	// knows about.
 	sealStatusPoller *sealStatusPoller
 
+	// sealedFiles records the replica file of each sector, for health checks.
+	sealedFiles *sealedFileIndex
+
 	// SectorClass configures behavior of sector_builder_ffi, including sector
 	// packing, sector sizes, sealing and PoSt generation performance.
 	SectorClass types.SectorClass
 }
 
 var _ SectorBuilder = &RustSectorBuilder{}
+var _ SectorChecker = &RustSectorBuilder{}
 
 // RustSectorBuilderConfig is a configuration object used when instantiating a
 // Rust-backed SectorBuilder through the FFI. All fields are required.
@@ -57,6 +63,11 @@ type RustSectorBuilderConfig struct {
 
 // NewRustSectorBuilder instantiates a SectorBuilder through the FFI.
 func NewRustSectorBuilder(cfg RustSectorBuilderConfig) (*RustSectorBuilder, error) {
+	sealedFiles, err := loadSealedFileIndex(cfg.MetadataDir, cfg.SealedSectorDir)
+	if err != nil {
+		return nil, err
+	}
+
 	ptr, err := go_sectorbuilder.InitSectorBuilder(cfg.SectorClass.SectorSize().Uint64(), uint8(cfg.SectorClass.PoRepProofPartitions().Int()), uint8(cfg.SectorClass.PoStProofPartitions().Int()), cfg.LastUsedSectorID, cfg.MetadataDir, AddressToProverID(cfg.MinerAddr), cfg.SealedSectorDir, cfg.StagedSectorDir, MaxNumStagedSectors)
 	if err != nil {
 		return nil, err
@@ -66,6 +77,7 @@ func NewRustSectorBuilder(cfg RustSectorBuilderConfig) (*RustSectorBuilder, erro
 		blockService:      cfg.BlockService,
 		ptr:               ptr,
 		sectorSealResults: make(chan SectorSealResult),
+		sealedFiles:       sealedFiles,
 		SectorClass:       cfg.SectorClass,
 	}
 
@@ -176,6 +188,12 @@ func (sb *RustSectorBuilder) findSealedSectorMetadata(sectorID uint64) (*SealedS
 		return nil, err
 	}
 
+	if status.SealStatusCode == 0 || status.SealStatusCode == 3 {
+		if err := sb.sealedFiles.observe(sectorID, status.SealStatusCode == 0); err != nil {
+			log.Warningf("failed to record replica file of sector %d: %s", sectorID, err)
+		}
+	}
+
 	if status.SealStatusCode == 0 {
 		info := make([]*PieceInfo, len(status.Pieces))
 		for idx, pieceMetadata := range status.Pieces {
@@ -216,6 +234,44 @@ func (sb *RustSectorBuilder) findSealedSectorMetadata(sectorID uint64) (*SealedS
 	}
 }
 
+// CheckSealedSector checks that the sector builder's metadata records the
+// sector as sealed, and that the sector's replica file is still there with
+// the size it was sealed with and can be read, see sealedFileIndex. A deep
+// check also unseals a randomly chosen piece of the sector and checks that
+// its commitment matches the one computed when the piece was added.
+func (sb *RustSectorBuilder) CheckSealedSector(ctx context.Context, sectorID uint64, deep bool) error {
+	status, err := go_sectorbuilder.GetSectorSealingStatusByID(sb.ptr, sectorID)
+	if err != nil {
+		return err
+	}
+	if status.SealStatusCode != 0 {
+		return errors.Errorf("sector %d is not sealed", sectorID)
+	}
+	if err := sb.sealedFiles.check(sectorID); err != nil {
+		return err
+	}
+	if !deep || len(status.Pieces) == 0 {
+		return nil
+	}
+
+	piece := status.Pieces[rand.Intn(len(status.Pieces))]
+	data, err := go_sectorbuilder.ReadPieceFromSealedSector(sb.ptr, piece.Key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read piece %s", piece.Key)
+	}
+	res, err := proofs.GeneratePieceCommitment(proofs.GeneratePieceCommitmentRequest{
+		PieceReader: bytes.NewReader(data),
+		PieceSize:   types.NewBytesAmount(piece.Size),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to read piece %s", piece.Key)
+	}
+	if res.CommP != types.CommP(piece.CommP) {
+		return errors.Errorf("piece %s does not match its commitment", piece.Key)
+	}
+	return nil
+}
+
 // ReadPieceFromSealedSector produces a Reader used to get original piece-bytes
 // from a sealed sector.
 func (sb *RustSectorBuilder) ReadPieceFromSealedSector(pieceCid cid.Cid) (io.Reader, error) {
@@ -237,6 +293,38 @@ func (sb *RustSectorBuilder) GetAllStagedSectors() ([]go_sectorbuilder.StagedSec
 	return go_sectorbuilder.GetAllStagedSectors(sb.ptr)
 }
 
+// SealingStatus returns the status of every sector that is staged or being
+// sealed. The sector builder seals sectors in the order they fill up, so all
+// sectors have the default priority.
+func (sb *RustSectorBuilder) SealingStatus() ([]SectorSealingStatus, error) {
+	metadata, err := sb.GetAllStagedSectors()
+	if err != nil {
+		return nil, err
+	}
+
+	staged := make(map[uint64]bool, len(metadata))
+	var statuses []SectorSealingStatus
+	for _, m := range metadata {
+		staged[m.SectorID] = true
+		statuses = append(statuses, SectorSealingStatus{
+			SectorID: m.SectorID,
+			State:    SectorStaged,
+			Priority: DefaultSealPriority,
+		})
+	}
+
+	for _, id := range sb.sealStatusPoller.sectorIDs() {
+		if !staged[id] {
+			statuses = append(statuses, SectorSealingStatus{
+				SectorID: id,
+				State:    SectorSealing,
+				Priority: DefaultSealPriority,
+			})
+		}
+	}
+	return statuses, nil
+}
+
 // SectorSealResults returns an unbuffered channel that is sent a value whenever
 // sealing completes.
 func (sb *RustSectorBuilder) SectorSealResults() <-chan SectorSealResult {
@@ -255,7 +343,7 @@ func (sb *RustSectorBuilder) Close() error {
 
 // GeneratePoSt produces a proof-of-spacetime for the provided replica commitments.
 func (sb *RustSectorBuilder) GeneratePoSt(req GeneratePoStRequest) (GeneratePoStResponse, error) {
-	proof, err := go_sectorbuilder.GeneratePoSt(sb.ptr, req.SortedSectorInfo, req.ChallengeSeed, []uint64{})
+	proof, err := go_sectorbuilder.GeneratePoSt(sb.ptr, req.SortedSectorInfo, req.ChallengeSeed, req.Faults)
 	if err != nil {
 		return GeneratePoStResponse{}, err
 	}
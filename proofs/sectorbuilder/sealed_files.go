@@ -0,0 +1,217 @@
+package sectorbuilder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// sealedFileIndexName is the name of the file, in the sector builder's
+// metadata directory, that records the replica file of each sealed sector.
+const sealedFileIndexName = "sealed-sector-files.json"
+
+// sampleReadSize is the number of bytes a quick check reads from a replica.
+const sampleReadSize = 32
+
+// sealedFile is the replica file a sector was sealed into.
+type sealedFile struct {
+	Path string `json:"path"`
+	// Size is the size of the replica once sealing completed, zero while the
+	// sector is being sealed.
+	Size uint64 `json:"size"`
+}
+
+// sealedFileIndex records the replica file the proofs library sealed each
+// sector into, which the library does not expose. The library creates a new
+// file in the sealed sector directory when a sector starts sealing, and the
+// node keeps a single staged sector open, so sectors start sealing one at a
+// time: the file of a sealing sector is the only one in the directory not
+// attributed to another sector. If sectors start sealing between two polls
+// their files cannot be told apart and they are not recorded.
+type sealedFileIndex struct {
+	sealedDir string
+	indexPath string
+
+	lk sync.Mutex
+	sealedFileIndexState
+}
+
+// sealedFileIndexState is the persisted state of a sealedFileIndex.
+type sealedFileIndexState struct {
+	// Files maps sector ids to their replica file.
+	Files map[uint64]*sealedFile `json:"files"`
+	// Unknown are files that were in a sealed sector directory when the node
+	// started and that are not attributed to a sector.
+	Unknown map[string]bool `json:"unknown"`
+}
+
+// loadSealedFileIndex loads the index kept in metadataDir, for sectors sealed
+// into sealedDir. Files already in sealedDir that are not attributed to a
+// sector, such as replicas sealed before files were recorded or by a seal
+// interrupted by the restart, are set aside as unknown.
+func loadSealedFileIndex(metadataDir string, sealedDir string) (*sealedFileIndex, error) {
+	idx := &sealedFileIndex{
+		sealedDir: sealedDir,
+		indexPath: filepath.Join(metadataDir, sealedFileIndexName),
+		sealedFileIndexState: sealedFileIndexState{
+			Files:   make(map[uint64]*sealedFile),
+			Unknown: make(map[string]bool),
+		},
+	}
+
+	if err := os.MkdirAll(metadataDir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", metadataDir)
+	}
+	data, err := ioutil.ReadFile(idx.indexPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read sealed sector file index")
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &idx.sealedFileIndexState); err != nil {
+			return nil, errors.Wrap(err, "failed to decode sealed sector file index")
+		}
+	}
+
+	paths, err := idx.unattributedFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		idx.Unknown[path] = true
+	}
+	return idx, idx.save()
+}
+
+// observe records the replica file of a sector that is being sealed, or has
+// been sealed, and the file's size once it is sealed.
+func (idx *sealedFileIndex) observe(sectorID uint64, sealed bool) error {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+
+	file, ok := idx.Files[sectorID]
+	if !ok {
+		paths, err := idx.unattributedFiles()
+		if err != nil || len(paths) != 1 {
+			return err
+		}
+		file = &sealedFile{Path: paths[0]}
+		idx.Files[sectorID] = file
+	} else if !sealed || file.Size != 0 {
+		return nil
+	}
+
+	if sealed {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to stat replica of sector %d", sectorID)
+		}
+		file.Size = uint64(info.Size())
+	}
+	return idx.save()
+}
+
+// check stats the replica file of a sealed sector, checks that it still has
+// the size it was sealed with and reads a sample of it. Sectors whose file is
+// not known, because they were sealed before files were recorded or their
+// file could not be told apart from another, are not checked.
+func (idx *sealedFileIndex) check(sectorID uint64) error {
+	idx.lk.Lock()
+	file, ok := idx.Files[sectorID]
+	idx.lk.Unlock()
+	if !ok || file.Size == 0 {
+		log.Debugf("replica file of sector %d is not known, not checking it", sectorID)
+		return nil
+	}
+
+	// Stat follows the link left behind when the sector storage manager moves
+	// a replica, so a replica moved to a path that went away is missing.
+	info, err := os.Stat(file.Path)
+	if os.IsNotExist(err) {
+		return errors.Errorf("replica of sector %d is missing from %s", sectorID, file.Path)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat replica of sector %d", sectorID)
+	}
+	if uint64(info.Size()) != file.Size {
+		return errors.Errorf("replica of sector %d is %d bytes, it was sealed with %d", sectorID, info.Size(), file.Size)
+	}
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open replica of sector %d", sectorID)
+	}
+	defer f.Close() // nolint: errcheck
+
+	buf := make([]byte, sampleReadSize)
+	offset := int64(0)
+	if file.Size > sampleReadSize {
+		offset = rand.Int63n(int64(file.Size - sampleReadSize))
+	}
+	if _, err := f.ReadAt(buf[:min(sampleReadSize, file.Size)], offset); err != nil {
+		return errors.Wrapf(err, "failed to read replica of sector %d", sectorID)
+	}
+	return nil
+}
+
+// unattributedFiles returns the files in the sealed sector directory that
+// are neither attributed to a sector nor unknown. It must be called with the
+// lock held, or before the index is shared.
+func (idx *sealedFileIndex) unattributedFiles() ([]string, error) {
+	infos, err := ioutil.ReadDir(idx.sealedDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list sealed sector directory")
+	}
+
+	// A replica moved into the directory by the sector storage manager is
+	// attributed through the link left where it was sealed.
+	attributed := make(map[string]bool, len(idx.Files))
+	for _, file := range idx.Files {
+		attributed[file.Path] = true
+		if target, err := filepath.EvalSymlinks(file.Path); err == nil {
+			attributed[target] = true
+		}
+	}
+
+	var paths []string
+	for _, info := range infos {
+		path := filepath.Join(idx.sealedDir, info.Name())
+		if info.IsDir() || attributed[path] || idx.Unknown[path] {
+			continue
+		}
+		if target, err := filepath.EvalSymlinks(path); err == nil && attributed[target] {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// save writes the index through a temporary file, so a crash leaves either
+// the old or the new index. It must be called with the lock held, or before
+// the index is shared.
+func (idx *sealedFileIndex) save() error {
+	data, err := json.Marshal(idx.sealedFileIndexState)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode sealed sector file index")
+	}
+	tmp := idx.indexPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "failed to write sealed sector file index")
+	}
+	return os.Rename(tmp, idx.indexPath)
+}
+
+func min(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
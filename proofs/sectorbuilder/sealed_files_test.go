@@ -0,0 +1,109 @@
+package sectorbuilder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestSealedFileIndex(t *testing.T) {
+	tf.UnitTest(t)
+
+	setup := func(t *testing.T) (string, string, func()) {
+		root, err := ioutil.TempDir("", "sealed-files")
+		require.NoError(t, err)
+		sealedDir := filepath.Join(root, "sealed")
+		require.NoError(t, os.MkdirAll(sealedDir, 0700))
+		return filepath.Join(root, "metadata"), sealedDir, func() { require.NoError(t, os.RemoveAll(root)) }
+	}
+
+	writeReplica := func(t *testing.T, path string, size int) {
+		require.NoError(t, ioutil.WriteFile(path, make([]byte, size), 0600))
+	}
+
+	t.Run("checks the replica a sector was sealed into", func(t *testing.T) {
+		metadataDir, sealedDir, cleanup := setup(t)
+		defer cleanup()
+		idx, err := loadSealedFileIndex(metadataDir, sealedDir)
+		require.NoError(t, err)
+
+		replica := filepath.Join(sealedDir, "replica")
+		writeReplica(t, replica, 1024)
+		require.NoError(t, idx.observe(1, false))
+		require.NoError(t, idx.observe(1, true))
+		require.NoError(t, idx.check(1))
+
+		require.NoError(t, os.Truncate(replica, 512))
+		err = idx.check(1)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "it was sealed with 1024")
+
+		require.NoError(t, os.Remove(replica))
+		err = idx.check(1)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is missing")
+	})
+
+	t.Run("follows links left by moved replicas", func(t *testing.T) {
+		metadataDir, sealedDir, cleanup := setup(t)
+		defer cleanup()
+		idx, err := loadSealedFileIndex(metadataDir, sealedDir)
+		require.NoError(t, err)
+
+		replica := filepath.Join(sealedDir, "replica")
+		writeReplica(t, replica, 1024)
+		require.NoError(t, idx.observe(1, true))
+
+		moved := filepath.Join(filepath.Dir(sealedDir), "moved")
+		require.NoError(t, os.Rename(replica, moved))
+		require.NoError(t, os.Symlink(moved, replica))
+		require.NoError(t, idx.check(1))
+
+		// the moved replica is not taken for the replica of a new sector
+		writeReplica(t, filepath.Join(sealedDir, "next"), 1024)
+		require.NoError(t, os.Rename(moved, filepath.Join(sealedDir, "moved")))
+		require.NoError(t, os.Remove(replica))
+		moved = filepath.Join(sealedDir, "moved")
+		require.NoError(t, os.Symlink(moved, replica))
+		require.NoError(t, idx.observe(2, true))
+		assert.Equal(t, filepath.Join(sealedDir, "next"), idx.Files[2].Path)
+
+		require.NoError(t, os.Remove(moved))
+		assert.Error(t, idx.check(1))
+	})
+
+	t.Run("attributes files once and persists them", func(t *testing.T) {
+		metadataDir, sealedDir, cleanup := setup(t)
+		defer cleanup()
+		writeReplica(t, filepath.Join(sealedDir, "old"), 1024)
+
+		idx, err := loadSealedFileIndex(metadataDir, sealedDir)
+		require.NoError(t, err)
+
+		// files present at startup are not attributed to new sectors
+		writeReplica(t, filepath.Join(sealedDir, "first"), 1024)
+		require.NoError(t, idx.observe(1, true))
+
+		// two sectors that started sealing between polls are ambiguous
+		writeReplica(t, filepath.Join(sealedDir, "second"), 1024)
+		writeReplica(t, filepath.Join(sealedDir, "third"), 1024)
+		require.NoError(t, idx.observe(2, false))
+		require.NoError(t, idx.observe(3, false))
+
+		idx, err = loadSealedFileIndex(metadataDir, sealedDir)
+		require.NoError(t, err)
+		require.Contains(t, idx.Files, uint64(1))
+		assert.Equal(t, &sealedFile{Path: filepath.Join(sealedDir, "first"), Size: 1024}, idx.Files[1])
+		assert.NotContains(t, idx.Files, uint64(2))
+		assert.NotContains(t, idx.Files, uint64(3))
+
+		// unknown sectors are not checked
+		assert.NoError(t, idx.check(2))
+	})
+}
@@ -0,0 +1,89 @@
+package sectorbuilder
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultSealPriority is the priority of a sector none of whose pieces were
+// added with a priority. Such sectors are sealed after all others.
+const DefaultSealPriority = math.MaxUint64
+
+// SectorSealState is the state of a sector that has not finished sealing.
+type SectorSealState int
+
+const (
+	// SectorStaged sectors are accepting pieces.
+	SectorStaged SectorSealState = iota
+	// SectorQueued sectors are waiting to be sealed.
+	SectorQueued
+	// SectorSealing sectors are being sealed.
+	SectorSealing
+)
+
+func (s SectorSealState) String() string {
+	switch s {
+	case SectorStaged:
+		return "staged"
+	case SectorQueued:
+		return "queued"
+	case SectorSealing:
+		return "sealing"
+	default:
+		return fmt.Sprintf("<unrecognized %d>", s)
+	}
+}
+
+// MarshalText encodes the state as its name.
+func (s SectorSealState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state from its name.
+func (s *SectorSealState) UnmarshalText(text []byte) error {
+	for _, state := range []SectorSealState{SectorStaged, SectorQueued, SectorSealing} {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return errors.Errorf("unknown sector seal state %q", text)
+}
+
+// SectorSealingStatus reports the progress of a sector towards being sealed.
+type SectorSealingStatus struct {
+	SectorID uint64          `json:"sectorId"`
+	State    SectorSealState `json:"state"`
+	// Priority orders queued sectors, lower values are sealed first.
+	Priority uint64 `json:"priority"`
+	// Pieces is the number of pieces added to the sector.
+	Pieces int `json:"pieces"`
+}
+
+// SealsBefore returns true if sector a should be sealed before sector b.
+func SealsBefore(a, b SectorSealingStatus) bool {
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	return a.SectorID < b.SectorID
+}
+
+type sealPriorityKey struct{}
+
+// WithSealPriority returns a context that makes AddPiece record the given
+// priority for the piece's sector. A sector takes the lowest priority of its
+// pieces. Builders that choose which queued sector to seal next seal lower
+// priorities first; the RustSectorBuilder seals every staged sector at once
+// and ignores priorities.
+func WithSealPriority(ctx context.Context, priority uint64) context.Context {
+	return context.WithValue(ctx, sealPriorityKey{}, priority)
+}
+
+// SealPriorityFromContext returns the priority set on ctx by WithSealPriority.
+func SealPriorityFromContext(ctx context.Context) (uint64, bool) {
+	priority, ok := ctx.Value(sealPriorityKey{}).(uint64)
+	return priority, ok
+}
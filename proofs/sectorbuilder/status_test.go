@@ -0,0 +1,41 @@
+package sectorbuilder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestSectorSealingStatus(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("encodes states as text", func(t *testing.T) {
+		text, err := SectorQueued.MarshalText()
+		require.NoError(t, err)
+		assert.Equal(t, "queued", string(text))
+
+		var state SectorSealState
+		require.NoError(t, state.UnmarshalText([]byte("sealing")))
+		assert.Equal(t, SectorSealing, state)
+		assert.Error(t, state.UnmarshalText([]byte("unknown")))
+	})
+
+	t.Run("orders sectors by priority then id", func(t *testing.T) {
+		assert.True(t, SealsBefore(SectorSealingStatus{SectorID: 2, Priority: 5}, SectorSealingStatus{SectorID: 1, Priority: DefaultSealPriority}))
+		assert.True(t, SealsBefore(SectorSealingStatus{SectorID: 1, Priority: 5}, SectorSealingStatus{SectorID: 2, Priority: 5}))
+		assert.False(t, SealsBefore(SectorSealingStatus{SectorID: 2, Priority: 5}, SectorSealingStatus{SectorID: 1, Priority: 5}))
+	})
+
+	t.Run("carries the seal priority in the context", func(t *testing.T) {
+		_, ok := SealPriorityFromContext(context.Background())
+		assert.False(t, ok)
+
+		priority, ok := SealPriorityFromContext(WithSealPriority(context.Background(), 10))
+		require.True(t, ok)
+		assert.Equal(t, uint64(10), priority)
+	})
+}
@@ -0,0 +1,445 @@
+package sectorstorage
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/paths"
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+// DefaultPathName is the name of the path backed by the sector base root
+// directory.
+const DefaultPathName = "default"
+
+// Kind is a kind of sector data.
+type Kind int
+
+const (
+	// Staging is the data of sectors receiving pieces.
+	Staging = Kind(iota)
+	// Sealed is the data of sealed sectors.
+	Sealed
+)
+
+// String returns the name of the directory holding the kind of sector data.
+func (k Kind) String() string {
+	if k == Staging {
+		return "staging"
+	}
+	return "sealed"
+}
+
+// PathUsage describes how much space a storage path uses and has left.
+type PathUsage struct {
+	config.StoragePathConfig
+	// Used is the number of bytes of sector data stored in the path.
+	Used uint64
+	// Available is the number of bytes that may still be stored in the path,
+	// limited by both its filesystem and its MaxBytes.
+	Available uint64
+}
+
+// SectorFile is a sealed sector file stored in a path.
+type SectorFile struct {
+	Name string
+	Path string
+	Size uint64
+}
+
+// Manager manages the named paths sector data is stored in. Paths are read
+// from the repo config on every call so changes made through the config
+// command are picked up.
+type Manager struct {
+	repo repo.Repo
+
+	// lk serializes changes to the paths and to the files in them.
+	lk sync.Mutex
+}
+
+// NewManager returns a Manager for the paths configured in r.
+func NewManager(r repo.Repo) *Manager {
+	return &Manager{repo: r}
+}
+
+// Paths returns the configured paths, starting with the default path.
+func (m *Manager) Paths() ([]config.StoragePathConfig, error) {
+	repoPath, err := m.repo.Path()
+	if err != nil {
+		return nil, err
+	}
+	sectorBase := m.repo.Config().SectorBase
+	root, err := paths.GetSectorPath(sectorBase.RootDir, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	storagePaths := []config.StoragePathConfig{{
+		Name:    DefaultPathName,
+		Path:    root,
+		Weight:  1,
+		Staging: true,
+		Sealed:  true,
+	}}
+	for _, p := range sectorBase.StoragePaths {
+		storagePaths = append(storagePaths, *p)
+	}
+	return storagePaths, nil
+}
+
+// AddPath creates the directories of a new path and records it in the repo
+// config.
+func (m *Manager) AddPath(p config.StoragePathConfig) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if p.Name == "" {
+		return errors.New("storage paths must have a name")
+	}
+	if !p.Staging && !p.Sealed {
+		return errors.New("storage paths must allow staged or sealed sectors")
+	}
+	dir, err := homedir.Expand(p.Path)
+	if err != nil {
+		return err
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	p.Path = dir
+
+	existing, err := m.Paths()
+	if err != nil {
+		return err
+	}
+	for _, e := range existing {
+		if e.Name == p.Name {
+			return errors.Errorf("storage path %s already exists", p.Name)
+		}
+		if e.Path == p.Path {
+			return errors.Errorf("%s is already storage path %s", p.Path, e.Name)
+		}
+	}
+
+	for _, kind := range []Kind{Staging, Sealed} {
+		if err := os.MkdirAll(filepath.Join(p.Path, kind.String()), 0755); err != nil {
+			return errors.Wrapf(err, "failed to create %s directory", kind)
+		}
+	}
+
+	cfg := m.repo.Config()
+	cfg.SectorBase.StoragePaths = append(cfg.SectorBase.StoragePaths, &p)
+	return m.repo.ReplaceConfig(cfg)
+}
+
+// Usage returns the usage of every path.
+func (m *Manager) Usage() ([]PathUsage, error) {
+	storagePaths, err := m.Paths()
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make([]PathUsage, len(storagePaths))
+	for i, p := range storagePaths {
+		usage, err := pathUsage(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get usage of storage path %s", p.Name)
+		}
+		usages[i] = usage
+	}
+	return usages, nil
+}
+
+// Dir returns the directory holding sector data of the given kind in the
+// path allowing that kind with the most weighted free space. The chosen path
+// must have room for at least minBytes.
+func (m *Manager) Dir(kind Kind, minBytes uint64) (string, error) {
+	usages, err := m.Usage()
+	if err != nil {
+		return "", err
+	}
+
+	var best *PathUsage
+	var bestScore float64
+	for i, usage := range usages {
+		if (kind == Staging && !usage.Staging) || (kind == Sealed && !usage.Sealed) {
+			continue
+		}
+		if usage.Available < minBytes {
+			continue
+		}
+		weight := usage.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		score := float64(usage.Available) * float64(weight)
+		if best == nil || score > bestScore {
+			best, bestScore = &usages[i], score
+		}
+	}
+	if best == nil {
+		return "", errors.Errorf("no storage path has room for %d bytes of %s sectors", minBytes, kind)
+	}
+
+	dir := filepath.Join(best.Path, kind.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to create %s directory", kind)
+	}
+	return dir, nil
+}
+
+// SealedSectors returns the sealed sector files stored in every path.
+// Sectors moved out of a path are only listed in the path they were moved to.
+func (m *Manager) SealedSectors() ([]SectorFile, error) {
+	storagePaths, err := m.Paths()
+	if err != nil {
+		return nil, err
+	}
+
+	var sectors []SectorFile
+	for _, p := range storagePaths {
+		infos, err := ioutil.ReadDir(filepath.Join(p.Path, Sealed.String()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if !info.Mode().IsRegular() || isTemp(info.Name()) {
+				continue
+			}
+			sectors = append(sectors, SectorFile{Name: info.Name(), Path: p.Name, Size: uint64(info.Size())})
+		}
+	}
+	sort.Slice(sectors, func(i, j int) bool { return sectors[i].Name < sectors[j].Name })
+	return sectors, nil
+}
+
+// MoveSealedSector moves a sealed sector file to the path named to. The sector
+// builder records where it wrote each sector, so that location is left with a
+// symlink to the sector's new location. The file is copied before anything
+// refers to it and every link is replaced atomically, so the sector can be read
+// throughout the move and a failed move leaves it where it was.
+func (m *Manager) MoveSealedSector(ctx context.Context, name string, to string) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if name != filepath.Base(name) || isTemp(name) {
+		return errors.Errorf("invalid sector file name %s", name)
+	}
+
+	storagePaths, err := m.Paths()
+	if err != nil {
+		return err
+	}
+
+	var src string
+	var dest *config.StoragePathConfig
+	var links []string
+	for i, p := range storagePaths {
+		file := filepath.Join(p.Path, Sealed.String(), name)
+		if p.Name == to {
+			dest = &storagePaths[i]
+		}
+		info, err := os.Lstat(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			links = append(links, file)
+		} else if info.Mode().IsRegular() {
+			src = file
+		}
+	}
+	if src == "" {
+		return errors.Errorf("no sealed sector file named %s", name)
+	}
+	if dest == nil {
+		return errors.Errorf("no storage path named %s", to)
+	}
+	if !dest.Sealed {
+		return errors.Errorf("storage path %s does not allow sealed sectors", to)
+	}
+	destFile := filepath.Join(dest.Path, Sealed.String(), name)
+	if destFile == src {
+		return errors.Errorf("%s is already stored in %s", name, to)
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	usage, err := pathUsage(*dest)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get usage of storage path %s", to)
+	}
+	if usage.Available < uint64(info.Size()) {
+		return errors.Errorf("storage path %s has %d bytes available but the sector needs %d", to, usage.Available, info.Size())
+	}
+
+	if err := copyFile(ctx, src, destFile); err != nil {
+		return errors.Wrapf(err, "failed to copy %s to %s", name, to)
+	}
+
+	var origin string
+	for _, link := range links {
+		if link == destFile {
+			// the sector moved back to its origin, which holds the file
+			// itself again
+			return os.Remove(src)
+		}
+		origin = link
+	}
+	if origin == "" {
+		// the sector has not been moved before, so it is at its origin
+		return replaceWithSymlink(src, destFile)
+	}
+	if err := replaceWithSymlink(origin, destFile); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// pathUsage returns the usage of a path.
+func pathUsage(p config.StoragePathConfig) (PathUsage, error) {
+	usage := PathUsage{StoragePathConfig: p}
+
+	for _, kind := range []Kind{Staging, Sealed} {
+		infos, err := ioutil.ReadDir(filepath.Join(p.Path, kind.String()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return PathUsage{}, err
+		}
+		for _, info := range infos {
+			if info.Mode().IsRegular() {
+				usage.Used += uint64(info.Size())
+			}
+		}
+	}
+
+	free, err := freeSpace(p.Path)
+	if err != nil {
+		return PathUsage{}, err
+	}
+	usage.Available = free
+	if p.MaxBytes > 0 {
+		if usage.Used >= p.MaxBytes {
+			usage.Available = 0
+		} else if p.MaxBytes-usage.Used < usage.Available {
+			usage.Available = p.MaxBytes - usage.Used
+		}
+	}
+	return usage, nil
+}
+
+// freeSpace returns the bytes available to unprivileged users in the
+// filesystem holding dir, or its closest existing parent.
+func freeSpace(dir string) (uint64, error) {
+	for {
+		var st syscall.Statfs_t
+		err := syscall.Statfs(dir, &st)
+		if err == nil {
+			return uint64(st.Bavail) * uint64(st.Bsize), nil // nolint: unconvert
+		}
+		parent := filepath.Dir(dir)
+		if !os.IsNotExist(err) || parent == dir {
+			return 0, err
+		}
+		dir = parent
+	}
+}
+
+const tempPrefix = ".moving-"
+
+func isTemp(name string) bool {
+	return len(name) >= len(tempPrefix) && name[:len(tempPrefix)] == tempPrefix
+}
+
+// copyFile copies src to dest through a temporary file, so dest only appears
+// once it holds all of the data.
+func copyFile(ctx context.Context, src, dest string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+
+	tmp := filepath.Join(filepath.Dir(dest), tempPrefix+filepath.Base(dest))
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if _, err = io.Copy(out, &ctxReader{ctx: ctx, r: in}); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	srcInfo, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmpInfo, err := os.Stat(tmp)
+	if err != nil {
+		return err
+	}
+	if srcInfo.Size() != tmpInfo.Size() {
+		return errors.Errorf("copied %d of %d bytes", tmpInfo.Size(), srcInfo.Size())
+	}
+	return os.Rename(tmp, dest)
+}
+
+// replaceWithSymlink atomically replaces file with a symlink to target.
+func replaceWithSymlink(file, target string) error {
+	tmp := filepath.Join(filepath.Dir(file), tempPrefix+filepath.Base(file))
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// ctxReader stops reading once its context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
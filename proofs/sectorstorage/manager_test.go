@@ -0,0 +1,130 @@
+package sectorstorage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/repo"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestManager(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	t.Run("adds paths to the config", func(t *testing.T) {
+		m, dir, cleanup := requireManager(t)
+		defer cleanup()
+
+		extra := filepath.Join(dir, "extra")
+		require.NoError(t, m.AddPath(config.StoragePathConfig{Name: "extra", Path: extra, Weight: 2, Sealed: true}))
+		assert.DirExists(t, filepath.Join(extra, "sealed"))
+
+		storagePaths, err := m.Paths()
+		require.NoError(t, err)
+		require.Len(t, storagePaths, 2)
+		assert.Equal(t, DefaultPathName, storagePaths[0].Name)
+		assert.Equal(t, config.StoragePathConfig{Name: "extra", Path: extra, Weight: 2, Sealed: true}, storagePaths[1])
+
+		assert.Error(t, m.AddPath(config.StoragePathConfig{Name: "extra", Path: filepath.Join(dir, "other"), Sealed: true}), "names are unique")
+		assert.Error(t, m.AddPath(config.StoragePathConfig{Name: "other", Path: extra, Sealed: true}), "paths are unique")
+		assert.Error(t, m.AddPath(config.StoragePathConfig{Name: "none", Path: filepath.Join(dir, "none")}), "paths store some kind of sector")
+	})
+
+	t.Run("limits the available space of a path", func(t *testing.T) {
+		m, dir, cleanup := requireManager(t)
+		defer cleanup()
+
+		limited := filepath.Join(dir, "limited")
+		require.NoError(t, m.AddPath(config.StoragePathConfig{Name: "limited", Path: limited, MaxBytes: 100, Sealed: true}))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(limited, "sealed", "sector"), make([]byte, 40), 0644))
+
+		usages, err := m.Usage()
+		require.NoError(t, err)
+		require.Len(t, usages, 2)
+		assert.Equal(t, uint64(40), usages[1].Used)
+		assert.Equal(t, uint64(60), usages[1].Available)
+	})
+
+	t.Run("chooses the path with the most weighted free space", func(t *testing.T) {
+		m, dir, cleanup := requireManager(t)
+		defer cleanup()
+
+		heavy := filepath.Join(dir, "heavy")
+		require.NoError(t, m.AddPath(config.StoragePathConfig{Name: "heavy", Path: heavy, Weight: 1000, Sealed: true}))
+
+		sealedDir, err := m.Dir(Sealed, 1)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(heavy, "sealed"), sealedDir)
+
+		stagingDir, err := m.Dir(Staging, 1)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "root", "staging"), stagingDir, "only the default path allows staged sectors")
+
+		_, err = m.Dir(Sealed, ^uint64(0))
+		assert.Error(t, err)
+	})
+
+	t.Run("moves sealed sectors leaving a link at their origin", func(t *testing.T) {
+		m, dir, cleanup := requireManager(t)
+		defer cleanup()
+
+		for _, name := range []string{"a", "b"} {
+			require.NoError(t, m.AddPath(config.StoragePathConfig{Name: name, Path: filepath.Join(dir, name), Sealed: true}))
+		}
+		origin := filepath.Join(dir, "root", "sealed", "sector")
+		require.NoError(t, os.MkdirAll(filepath.Dir(origin), 0755))
+		require.NoError(t, ioutil.WriteFile(origin, []byte("sealed data"), 0644))
+
+		require.NoError(t, m.MoveSealedSector(ctx, "sector", "a"))
+		requireSector(t, m, "a")
+		requireData(t, origin)
+
+		require.NoError(t, m.MoveSealedSector(ctx, "sector", "b"))
+		requireSector(t, m, "b")
+		requireData(t, origin)
+		_, err := os.Lstat(filepath.Join(dir, "a", "sealed", "sector"))
+		assert.True(t, os.IsNotExist(err))
+
+		require.NoError(t, m.MoveSealedSector(ctx, "sector", DefaultPathName))
+		requireSector(t, m, DefaultPathName)
+		info, err := os.Lstat(origin)
+		require.NoError(t, err)
+		assert.True(t, info.Mode().IsRegular())
+		requireData(t, origin)
+
+		assert.Error(t, m.MoveSealedSector(ctx, "sector", DefaultPathName), "the sector is already there")
+		assert.Error(t, m.MoveSealedSector(ctx, "missing", "a"))
+		assert.Error(t, m.MoveSealedSector(ctx, "sector", "missing"))
+	})
+}
+
+func requireManager(t *testing.T) (*Manager, string, func()) {
+	dir, err := ioutil.TempDir("", "sectorstorage")
+	require.NoError(t, err)
+
+	r := repo.NewInMemoryRepo()
+	r.Config().SectorBase.RootDir = filepath.Join(dir, "root")
+	return NewManager(r), dir, func() { require.NoError(t, os.RemoveAll(dir)) }
+}
+
+func requireSector(t *testing.T, m *Manager, path string) {
+	sectors, err := m.SealedSectors()
+	require.NoError(t, err)
+	require.Len(t, sectors, 1)
+	assert.Equal(t, SectorFile{Name: "sector", Path: path, Size: uint64(len("sealed data"))}, sectors[0])
+}
+
+func requireData(t *testing.T, file string) {
+	data, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "sealed data", string(data))
+}
@@ -21,6 +21,7 @@ type MiningAPI struct {
 	addNewBlockFunc func(context.Context, *types.Block) (err error)
 	chainReader     miningChainReader
 	isMiningFunc    func() bool
+	statusFunc      func() mining.Status
 	mineDelay       time.Duration
 	setupMiningFunc func(context.Context) error
 	startMiningFunc func(context.Context) error
@@ -34,6 +35,7 @@ func New(
 	addNewBlockFunc func(context.Context, *types.Block) (err error),
 	chainReader miningChainReader,
 	isMiningFunc func() bool,
+	statusFunc func() mining.Status,
 	blockMineDelay time.Duration,
 	setupMiningFunc func(ctx context.Context) error,
 	startMiningFunc func(context.Context) error,
@@ -45,6 +47,7 @@ func New(
 		addNewBlockFunc: addNewBlockFunc,
 		chainReader:     chainReader,
 		isMiningFunc:    isMiningFunc,
+		statusFunc:      statusFunc,
 		mineDelay:       blockMineDelay,
 		setupMiningFunc: setupMiningFunc,
 		startMiningFunc: startMiningFunc,
@@ -64,6 +67,12 @@ func (a *MiningAPI) MiningIsActive() bool {
 	return a.isMiningFunc()
 }
 
+// MiningStatus returns whether the node is mining or, if mining is paused,
+// why.
+func (a *MiningAPI) MiningStatus() mining.Status {
+	return a.statusFunc()
+}
+
 // MiningOnce mines a single block in the given context, and returns the new block.
 func (a *MiningAPI) MiningOnce(ctx context.Context) (*types.Block, error) {
 	if a.isMiningFunc() {
@@ -0,0 +1,18 @@
+// Package sealworker implements a protocol through which a mining node hands
+// sealing off to worker processes, usually running on other machines:
+//
+// 1. WORKER opens a /fil/seal-worker/0.0.1 stream to MINER
+// 2. WORKER sends MINER a Registration carrying the token MINER is configured with
+// 3. MINER sends WORKER a RegistrationResponse, and closes the stream if it rejected the registration
+// 4. MINER sends WORKER a SealJob for a staged sector followed by SealJobChunks holding the sector's staged data
+// 5. WORKER seals the sector and sends MINER a SealJobResult, whose seal proof MINER verifies
+// 6. Steps 4 and 5 repeat until either side closes the stream
+//
+// Sectors whose worker disconnects before returning a result are handed to
+// the next worker.
+//
+// Workers only produce fake seals for now. The mining node generates proofs
+// of spacetime over the replicas it stores, and replicas sealed by a worker
+// never reach it, so remote sealing is limited to nodes running with fake
+// proofs.
+package sealworker
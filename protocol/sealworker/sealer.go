@@ -0,0 +1,52 @@
+package sealworker
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// Sealer seals the staged data of a SealJob.
+type Sealer interface {
+	// Seal seals a sector holding the given staged data, which is the
+	// concatenation of the job's pieces.
+	Seal(ctx context.Context, job *SealJob, data io.Reader) (*sectorbuilder.SealedSectorMetadata, error)
+}
+
+// FakeSealer produces sealed sector metadata without running the proofs, see
+// sectorbuilder.FakeSeal. It is only useful with nodes that run with fake
+// proofs, and is the only Sealer: the node generates PoSts itself and cannot
+// read replicas sealed on another machine.
+type FakeSealer struct{}
+
+var _ Sealer = &FakeSealer{}
+
+// Seal returns fake sealed sector metadata for the job.
+func (fs *FakeSealer) Seal(ctx context.Context, job *SealJob, data io.Reader) (*sectorbuilder.SealedSectorMetadata, error) {
+	counter := &countingReader{r: data}
+	sectorClass := types.NewSectorClass(types.NewBytesAmount(job.SectorSize))
+	meta, err := sectorbuilder.FakeSeal(job.SectorID, sectorClass, job.Pieces, counter)
+	if err != nil {
+		return nil, err
+	}
+	if counter.n != job.DataSize {
+		return nil, errors.Errorf("read %d bytes of staged data, expected %d", counter.n, job.DataSize)
+	}
+	return meta, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += uint64(n)
+	return n, err
+}
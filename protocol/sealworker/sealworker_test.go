@@ -0,0 +1,288 @@
+package sealworker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/proofs/verification"
+	"github.com/filecoin-project/go-filecoin/repo"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestRemoteSealing(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("seals sectors with registered workers", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		miner, worker := requireHosts(ctx, t)
+		stagingDir, cleanup := requireStagingDir(t)
+		defer cleanup()
+		verifier := &verification.FakeVerifier{VerifySealValid: true}
+		b := requireRemoteSectorBuilderWithVerifier(t, miner, repo.NewInMemoryRepo().Datastore(), stagingDir, verifier)
+		defer func() { require.NoError(t, b.Close()) }()
+
+		data := bytes.Repeat([]byte{1}, 100)
+		pieceRef := types.CidFromString(t, "piece")
+		sectorID, err := b.AddPiece(ctx, pieceRef, uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), sectorID)
+		require.NoError(t, b.SealAllStagedSectors(ctx))
+
+		go func() {
+			_ = NewWorker(worker, &FakeSealer{}, "worker", "secret").Run(ctx, peer.AddrInfo{ID: miner.ID(), Addrs: miner.Addrs()})
+		}()
+
+		result := <-b.SectorSealResults()
+		require.NoError(t, result.SealingErr)
+		assert.Equal(t, uint64(1), result.SectorID)
+		require.NotNil(t, result.SealingResult)
+		assert.Equal(t, uint64(1), result.SealingResult.SectorID)
+		require.Len(t, result.SealingResult.Pieces, 1)
+		assert.Equal(t, pieceRef, result.SealingResult.Pieces[0].Ref)
+		require.NotNil(t, verifier.LastReceivedVerifySealRequest)
+		assert.Equal(t, result.SealingResult.CommR, verifier.LastReceivedVerifySealRequest.CommR)
+
+		statuses, err := b.SealingStatus()
+		require.NoError(t, err)
+		assert.Empty(t, statuses)
+
+		r, err := b.ReadPieceFromSealedSector(pieceRef)
+		require.NoError(t, err)
+		read, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, read)
+	})
+
+	t.Run("seals queued sectors in priority order", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		miner, worker := requireHosts(ctx, t)
+		stagingDir, cleanup := requireStagingDir(t)
+		defer cleanup()
+		b := requireRemoteSectorBuilder(t, miner, repo.NewInMemoryRepo().Datastore(), stagingDir)
+		defer func() { require.NoError(t, b.Close()) }()
+
+		data := bytes.Repeat([]byte{1}, 100)
+		for i, priorityCtx := range []context.Context{ctx, sectorbuilder.WithSealPriority(ctx, 5), ctx} {
+			_, err := b.AddPiece(priorityCtx, types.CidFromString(t, fmt.Sprintf("piece-%d", i)), uint64(len(data)), bytes.NewReader(data))
+			require.NoError(t, err)
+			require.NoError(t, b.SealAllStagedSectors(ctx))
+		}
+
+		statuses, err := b.SealingStatus()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []sectorbuilder.SectorSealingStatus{
+			{SectorID: 1, State: sectorbuilder.SectorQueued, Priority: sectorbuilder.DefaultSealPriority, Pieces: 1},
+			{SectorID: 2, State: sectorbuilder.SectorQueued, Priority: 5, Pieces: 1},
+			{SectorID: 3, State: sectorbuilder.SectorQueued, Priority: sectorbuilder.DefaultSealPriority, Pieces: 1},
+		}, statuses)
+
+		go func() {
+			_ = NewWorker(worker, &FakeSealer{}, "worker", "secret").Run(ctx, peer.AddrInfo{ID: miner.ID(), Addrs: miner.Addrs()})
+		}()
+
+		for _, sectorID := range []uint64{2, 1, 3} {
+			result := <-b.SectorSealResults()
+			require.NoError(t, result.SealingErr)
+			assert.Equal(t, sectorID, result.SectorID)
+		}
+	})
+
+	t.Run("rejects workers with an invalid token", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		miner, worker := requireHosts(ctx, t)
+		stagingDir, cleanup := requireStagingDir(t)
+		defer cleanup()
+		b := requireRemoteSectorBuilder(t, miner, repo.NewInMemoryRepo().Datastore(), stagingDir)
+		defer func() { require.NoError(t, b.Close()) }()
+
+		err := NewWorker(worker, &FakeSealer{}, "worker", "guess").Run(ctx, peer.AddrInfo{ID: miner.ID(), Addrs: miner.Addrs()})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid token")
+	})
+
+	t.Run("fails sectors whose seal proof is invalid", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		miner, worker := requireHosts(ctx, t)
+		stagingDir, cleanup := requireStagingDir(t)
+		defer cleanup()
+		verifier := &verification.FakeVerifier{VerifySealValid: false}
+		b := requireRemoteSectorBuilderWithVerifier(t, miner, repo.NewInMemoryRepo().Datastore(), stagingDir, verifier)
+		defer func() { require.NoError(t, b.Close()) }()
+
+		data := bytes.Repeat([]byte{1}, 100)
+		_, err := b.AddPiece(ctx, types.CidFromString(t, "piece"), uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		require.NoError(t, b.SealAllStagedSectors(ctx))
+
+		go func() {
+			_ = NewWorker(worker, &FakeSealer{}, "worker", "secret").Run(ctx, peer.AddrInfo{ID: miner.ID(), Addrs: miner.Addrs()})
+		}()
+
+		result := <-b.SectorSealResults()
+		require.Error(t, result.SealingErr)
+		assert.Contains(t, result.SealingErr.Error(), "invalid seal proof")
+		assert.Nil(t, result.SealingResult)
+	})
+
+	t.Run("requires a token", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		miner, _ := requireHosts(ctx, t)
+		stagingDir, cleanup := requireStagingDir(t)
+		defer cleanup()
+		minerAddr, err := address.NewActorAddress([]byte("miner"))
+		require.NoError(t, err)
+
+		_, err = NewRemoteSectorBuilder(RemoteSectorBuilderConfig{
+			Host:        miner,
+			Datastore:   repo.NewInMemoryRepo().Datastore(),
+			StagingDir:  stagingDirFunc(stagingDir),
+			MinerAddr:   minerAddr,
+			SectorClass: types.NewSectorClass(types.OneKiBSectorSize),
+			Verifier:    &verification.FakeVerifier{VerifySealValid: true},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "token is required")
+	})
+
+	t.Run("queues the staged sector when a piece does not fit", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		miner, _ := requireHosts(ctx, t)
+		stagingDir, cleanup := requireStagingDir(t)
+		defer cleanup()
+		b := requireRemoteSectorBuilder(t, miner, repo.NewInMemoryRepo().Datastore(), stagingDir)
+		defer func() { require.NoError(t, b.Close()) }()
+
+		data := bytes.Repeat([]byte{1}, 600)
+		first, err := b.AddPiece(ctx, types.CidFromString(t, "first"), uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		second, err := b.AddPiece(ctx, types.CidFromString(t, "second"), uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, first+1, second)
+
+		statuses, err := b.SealingStatus()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []sectorbuilder.SectorSealingStatus{
+			{SectorID: first, State: sectorbuilder.SectorQueued, Priority: sectorbuilder.DefaultSealPriority, Pieces: 1},
+			{SectorID: second, State: sectorbuilder.SectorStaged, Priority: sectorbuilder.DefaultSealPriority, Pieces: 1},
+		}, statuses)
+	})
+
+	t.Run("restores sectors after a restart", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		miner, _ := requireHosts(ctx, t)
+		ds := repo.NewInMemoryRepo().Datastore()
+		stagingDir, cleanup := requireStagingDir(t)
+		defer cleanup()
+
+		b := requireRemoteSectorBuilder(t, miner, ds, stagingDir)
+		data := bytes.Repeat([]byte{1}, 100)
+		_, err := b.AddPiece(ctx, types.CidFromString(t, "piece"), uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		require.NoError(t, b.SealAllStagedSectors(ctx))
+		require.NoError(t, b.Close())
+
+		b = requireRemoteSectorBuilder(t, miner, ds, stagingDir)
+		defer func() { require.NoError(t, b.Close()) }()
+
+		statuses, err := b.SealingStatus()
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		assert.Equal(t, sectorbuilder.SectorQueued, statuses[0].State)
+
+		sectorID, err := b.AddPiece(ctx, types.CidFromString(t, "next"), uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), sectorID)
+	})
+
+	t.Run("keeps staged data where it was written when the staging directory changes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		miner, worker := requireHosts(ctx, t)
+		ds := repo.NewInMemoryRepo().Datastore()
+		firstDir, cleanupFirst := requireStagingDir(t)
+		defer cleanupFirst()
+		secondDir, cleanupSecond := requireStagingDir(t)
+		defer cleanupSecond()
+
+		b := requireRemoteSectorBuilder(t, miner, ds, firstDir)
+		data := bytes.Repeat([]byte{1}, 100)
+		pieceRef := types.CidFromString(t, "piece")
+		_, err := b.AddPiece(ctx, pieceRef, uint64(len(data)), bytes.NewReader(data))
+		require.NoError(t, err)
+		require.NoError(t, b.SealAllStagedSectors(ctx))
+		require.NoError(t, b.Close())
+
+		b = requireRemoteSectorBuilder(t, miner, ds, secondDir)
+		defer func() { require.NoError(t, b.Close()) }()
+
+		go func() {
+			_ = NewWorker(worker, &FakeSealer{}, "worker", "secret").Run(ctx, peer.AddrInfo{ID: miner.ID(), Addrs: miner.Addrs()})
+		}()
+
+		result := <-b.SectorSealResults()
+		require.NoError(t, result.SealingErr)
+
+		r, err := b.ReadPieceFromSealedSector(pieceRef)
+		require.NoError(t, err)
+		read, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, read)
+	})
+}
+
+func requireHosts(ctx context.Context, t *testing.T) (host.Host, host.Host) {
+	mn, err := mocknet.FullMeshLinked(ctx, 2)
+	require.NoError(t, err)
+	return mn.Hosts()[0], mn.Hosts()[1]
+}
+
+func requireStagingDir(t *testing.T) (string, func()) {
+	stagingDir, err := ioutil.TempDir("", "sealworker")
+	require.NoError(t, err)
+	return stagingDir, func() { require.NoError(t, os.RemoveAll(stagingDir)) }
+}
+
+func stagingDirFunc(dir string) func() (string, error) {
+	return func() (string, error) { return dir, nil }
+}
+
+func requireRemoteSectorBuilder(t *testing.T, h host.Host, ds repo.Datastore, stagingDir string) *RemoteSectorBuilder {
+	return requireRemoteSectorBuilderWithVerifier(t, h, ds, stagingDir, &verification.FakeVerifier{VerifySealValid: true})
+}
+
+func requireRemoteSectorBuilderWithVerifier(t *testing.T, h host.Host, ds repo.Datastore, stagingDir string, verifier verification.Verifier) *RemoteSectorBuilder {
+	minerAddr, err := address.NewActorAddress([]byte("miner"))
+	require.NoError(t, err)
+
+	b, err := NewRemoteSectorBuilder(RemoteSectorBuilderConfig{
+		Host:        h,
+		Datastore:   ds,
+		StagingDir:  stagingDirFunc(stagingDir),
+		MinerAddr:   minerAddr,
+		SectorClass: types.NewSectorClass(types.OneKiBSectorSize),
+		Token:       "secret",
+		Verifier:    verifier,
+	})
+	require.NoError(t, err)
+	return b
+}
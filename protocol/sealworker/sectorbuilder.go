@@ -0,0 +1,657 @@
+package sealworker
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/filecoin-project/go-sectorbuilder"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/libp2p/go-libp2p-core/host"
+	inet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/proofs/verification"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func init() {
+	cbor.RegisterCborType(sectorRecord{})
+}
+
+// sectorsPrefix is the datastore namespace of the sectors staged for workers.
+const sectorsPrefix = "sealworker/sectors"
+
+// Prover generates the proofs of spacetime of a miner.
+type Prover interface {
+	GeneratePoSt(sectorbuilder.GeneratePoStRequest) (sectorbuilder.GeneratePoStResponse, error)
+}
+
+// RemoteSectorBuilderConfig configures a RemoteSectorBuilder. All fields are
+// required.
+type RemoteSectorBuilderConfig struct {
+	// Host accepts worker registrations.
+	Host host.Host
+	// Datastore persists the state of staged and sealed sectors.
+	Datastore repo.Datastore
+	// StagingDir returns the directory to write the staged data of a new
+	// sector to. The directory is recorded with the sector, and its data is
+	// kept after the sector is sealed to serve reads of its pieces.
+	StagingDir       func() (string, error)
+	MinerAddr        address.Address
+	SectorClass      types.SectorClass
+	LastUsedSectorID uint64
+	// Token is the secret workers must present to register.
+	Token string
+	// Prover generates PoSts. It is closed with the builder if it is an
+	// io.Closer.
+	Prover Prover
+	// Verifier checks the seal proofs returned by workers.
+	Verifier verification.Verifier
+}
+
+// RemoteSectorBuilder is a SectorBuilder that stages pieces locally and hands
+// sectors to registered workers to be sealed. Each worker seals one sector at
+// a time, so the number of sectors sealing at once is the number of workers.
+// Queued sectors are handed out in priority order, see
+// sectorbuilder.WithSealPriority.
+// The seal proof of each sector is verified before its result is reported.
+//
+// Workers only produce fake seals, see FakeSealer, and proofs of spacetime
+// are generated by the configured Prover, so remote sealing is only useful
+// with fake proofs. For the same reason the builder cannot check the health
+// of sealed sectors.
+type RemoteSectorBuilder struct {
+	host         host.Host
+	ds           repo.Datastore
+	stagingDir   func() (string, error)
+	minerAddr    address.Address
+	sectorSize   uint64
+	maxUserBytes uint64
+	token        string
+	prover       Prover
+	verifier     verification.Verifier
+
+	// addLk serializes changes to the staged sector's data
+	addLk sync.Mutex
+
+	// lk protects the fields below
+	lk               sync.Mutex
+	sectors          map[uint64]*sectorRecord
+	staged           *sectorRecord
+	lastUsedSectorID uint64
+	queue            []uint64
+
+	// jobQueued is signaled when a sector is queued for a worker
+	jobQueued         chan struct{}
+	sectorSealResults chan sectorbuilder.SectorSealResult
+	stopCh            chan struct{}
+}
+
+var _ sectorbuilder.SectorBuilder = &RemoteSectorBuilder{}
+
+// NewRemoteSectorBuilder returns a RemoteSectorBuilder that accepts workers
+// on the config's host. Sectors that were queued or sealing when the builder
+// last stopped are queued again.
+func NewRemoteSectorBuilder(cfg RemoteSectorBuilderConfig) (*RemoteSectorBuilder, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("a seal worker token is required")
+	}
+
+	sectorSize := cfg.SectorClass.SectorSize().Uint64()
+	b := &RemoteSectorBuilder{
+		host:              cfg.Host,
+		ds:                cfg.Datastore,
+		stagingDir:        cfg.StagingDir,
+		minerAddr:         cfg.MinerAddr,
+		sectorSize:        sectorSize,
+		maxUserBytes:      go_sectorbuilder.GetMaxUserBytesPerStagedSector(sectorSize),
+		token:             cfg.Token,
+		prover:            cfg.Prover,
+		verifier:          cfg.Verifier,
+		sectors:           make(map[uint64]*sectorRecord),
+		lastUsedSectorID:  cfg.LastUsedSectorID,
+		jobQueued:         make(chan struct{}, 1),
+		sectorSealResults: make(chan sectorbuilder.SectorSealResult),
+		stopCh:            make(chan struct{}),
+	}
+
+	if err := b.load(); err != nil {
+		return nil, errors.Wrap(err, "failed to load sectors")
+	}
+
+	b.host.SetStreamHandler(ProtocolID, b.handleWorker)
+	return b, nil
+}
+
+// AddPiece writes the given piece into the staged sector and returns the id
+// of that sector. A new sector is staged if the piece does not fit, and a
+// sector is queued for sealing as soon as it is full. The sector takes the
+// priority set on ctx if it is lower than its own.
+func (b *RemoteSectorBuilder) AddPiece(ctx context.Context, pieceRef cid.Cid, pieceSize uint64, pieceReader io.Reader) (uint64, error) {
+	if pieceSize > b.maxUserBytes {
+		return 0, errors.Errorf("piece of %d bytes does not fit in a sector of %d bytes", pieceSize, b.maxUserBytes)
+	}
+
+	b.addLk.Lock()
+	defer b.addLk.Unlock()
+
+	b.lk.Lock()
+	if b.staged != nil && b.staged.Size+pieceSize > b.maxUserBytes {
+		if err := b.queueStaged(); err != nil {
+			b.lk.Unlock()
+			return 0, err
+		}
+	}
+	if b.staged == nil {
+		dir, err := b.stagingDir()
+		if err != nil {
+			b.lk.Unlock()
+			return 0, errors.Wrap(err, "failed to choose a staging directory")
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			b.lk.Unlock()
+			return 0, errors.Wrapf(err, "failed to create staging directory %s", dir)
+		}
+		b.lastUsedSectorID++
+		b.staged = &sectorRecord{
+			SectorID:    b.lastUsedSectorID,
+			State:       sectorStaged,
+			Priority:    sectorbuilder.DefaultSealPriority,
+			StagingPath: filepath.Join(dir, strconv.FormatUint(b.lastUsedSectorID, 10)),
+		}
+		b.sectors[b.staged.SectorID] = b.staged
+	}
+	sector := b.staged
+	offset := sector.Size
+	b.lk.Unlock()
+
+	if err := b.writePiece(sector.SectorID, offset, pieceSize, pieceReader); err != nil {
+		return 0, err
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	sector.Pieces = append(sector.Pieces, &sectorbuilder.PieceInfo{Ref: pieceRef, Size: pieceSize})
+	sector.Size += pieceSize
+	if priority, ok := sectorbuilder.SealPriorityFromContext(ctx); ok && priority < sector.Priority {
+		sector.Priority = priority
+	}
+	if sector.Size == b.maxUserBytes {
+		return sector.SectorID, b.queueStaged()
+	}
+	return sector.SectorID, b.put(sector)
+}
+
+// ReadPieceFromSealedSector produces a Reader used to get original piece-bytes
+// from a sealed sector.
+func (b *RemoteSectorBuilder) ReadPieceFromSealedSector(pieceCid cid.Cid) (io.Reader, error) {
+	b.lk.Lock()
+	var sectorID, offset, size uint64
+	found := false
+	for _, sector := range b.sectors {
+		if sector.State != sectorSealed {
+			continue
+		}
+		offset = 0
+		for _, piece := range sector.Pieces {
+			if piece.Ref.Equals(pieceCid) {
+				sectorID, size, found = sector.SectorID, piece.Size, true
+				break
+			}
+			offset += piece.Size
+		}
+		if found {
+			break
+		}
+	}
+	b.lk.Unlock()
+
+	if !found {
+		return nil, errors.Errorf("piece %s is not in a sealed sector", pieceCid.String())
+	}
+
+	f, err := os.Open(b.stagingPath(sectorID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open data of sector %d", sectorID)
+	}
+	defer f.Close() // nolint: errcheck
+
+	buf := make([]byte, size)
+	if _, err := f.ReadAt(buf, int64(offset)); err != nil {
+		return nil, errors.Wrapf(err, "failed to read piece %s", pieceCid.String())
+	}
+	return bytes.NewReader(buf), nil
+}
+
+// SealAllStagedSectors queues the staged sector for sealing if it holds any
+// pieces.
+func (b *RemoteSectorBuilder) SealAllStagedSectors(ctx context.Context) error {
+	b.addLk.Lock()
+	defer b.addLk.Unlock()
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if b.staged == nil || len(b.staged.Pieces) == 0 {
+		return nil
+	}
+	return b.queueStaged()
+}
+
+// GetAllStagedSectors returns a slice of all sectors that have not finished
+// sealing.
+func (b *RemoteSectorBuilder) GetAllStagedSectors() ([]go_sectorbuilder.StagedSectorMetadata, error) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	var metadata []go_sectorbuilder.StagedSectorMetadata
+	for _, sector := range b.sectors {
+		if sector.State.unsealed() {
+			metadata = append(metadata, go_sectorbuilder.StagedSectorMetadata{SectorID: sector.SectorID})
+		}
+	}
+	return metadata, nil
+}
+
+// SealingStatus returns the status of every sector that has not finished
+// sealing.
+func (b *RemoteSectorBuilder) SealingStatus() ([]sectorbuilder.SectorSealingStatus, error) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	var statuses []sectorbuilder.SectorSealingStatus
+	for _, sector := range b.sectors {
+		if !sector.State.unsealed() {
+			continue
+		}
+		status := sectorbuilder.SectorSealingStatus{
+			SectorID: sector.SectorID,
+			State:    sectorbuilder.SectorStaged,
+			Priority: sector.Priority,
+			Pieces:   len(sector.Pieces),
+		}
+		if sector.State == sectorQueued {
+			status.State = sectorbuilder.SectorQueued
+		} else if sector.State == sectorSealing {
+			status.State = sectorbuilder.SectorSealing
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// SectorSealResults returns an unbuffered channel that is sent a value whenever
+// a worker finishes sealing a sector.
+func (b *RemoteSectorBuilder) SectorSealResults() <-chan sectorbuilder.SectorSealResult {
+	return b.sectorSealResults
+}
+
+// GeneratePoSt produces a proof-of-spacetime for the provided replica commitments.
+func (b *RemoteSectorBuilder) GeneratePoSt(req sectorbuilder.GeneratePoStRequest) (sectorbuilder.GeneratePoStResponse, error) {
+	return b.prover.GeneratePoSt(req)
+}
+
+// Close stops accepting workers and closes the prover.
+func (b *RemoteSectorBuilder) Close() error {
+	b.host.RemoveStreamHandler(ProtocolID)
+	close(b.stopCh)
+	if closer, ok := b.prover.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// handleWorker registers a worker and sends it queued sectors until the
+// builder is closed or the worker goes away.
+func (b *RemoteSectorBuilder) handleWorker(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	reader := cbu.NewMsgReader(s)
+	writer := cbu.NewMsgWriter(s)
+	worker := s.Conn().RemotePeer().Pretty()
+
+	var reg Registration
+	if err := reader.ReadMsg(&reg); err != nil {
+		log.Warningf("failed to read registration from %s: %s", worker, err)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(reg.Token), []byte(b.token)) != 1 {
+		log.Warningf("rejected seal worker %s (%s) with an invalid token", reg.Name, worker)
+		if err := writer.WriteMsg(&RegistrationResponse{ErrorMessage: "invalid token"}); err != nil {
+			log.Warningf("failed to write registration response to %s: %s", worker, err)
+		}
+		return
+	}
+	if err := writer.WriteMsg(&RegistrationResponse{Accepted: true}); err != nil {
+		log.Warningf("failed to write registration response to %s: %s", worker, err)
+		return
+	}
+	log.Infof("seal worker %s (%s) registered", reg.Name, worker)
+
+	for {
+		job, ok := b.nextJob()
+		if !ok {
+			return
+		}
+
+		result, err := b.runJob(reader, writer, job)
+		if err != nil {
+			log.Warningf("seal worker %s (%s) failed to seal sector %d, queueing it again: %s", reg.Name, worker, job.SectorID, err)
+			b.requeue(job.SectorID)
+			return
+		}
+		b.finishJob(job, result)
+	}
+}
+
+// nextJob waits for a sector to be queued, takes the queued sector with the
+// lowest priority and marks it as sealing. It returns false if the builder is
+// closed.
+func (b *RemoteSectorBuilder) nextJob() (*SealJob, bool) {
+	for {
+		b.lk.Lock()
+		if len(b.queue) > 0 {
+			next := 0
+			for i := range b.queue[1:] {
+				if sealsBefore(b.sectors[b.queue[i+1]], b.sectors[b.queue[next]]) {
+					next = i + 1
+				}
+			}
+			sector := b.sectors[b.queue[next]]
+			b.queue = append(b.queue[:next], b.queue[next+1:]...)
+			sector.State = sectorSealing
+			if err := b.put(sector); err != nil {
+				log.Errorf("failed to record sector %d as sealing: %s", sector.SectorID, err)
+			}
+			if len(b.queue) > 0 {
+				b.signalJobQueued()
+			}
+			job := &SealJob{
+				SectorID:   sector.SectorID,
+				MinerAddr:  b.minerAddr,
+				SectorSize: b.sectorSize,
+				Pieces:     sector.Pieces,
+				DataSize:   sector.Size,
+			}
+			b.lk.Unlock()
+			return job, true
+		}
+		b.lk.Unlock()
+
+		select {
+		case <-b.jobQueued:
+		case <-b.stopCh:
+			return nil, false
+		}
+	}
+}
+
+// runJob sends a job and its staged data to a worker and waits for the result.
+func (b *RemoteSectorBuilder) runJob(reader *cbu.MsgReader, writer *cbu.MsgWriter, job *SealJob) (*SealJobResult, error) {
+	if err := writer.WriteMsg(job); err != nil {
+		return nil, errors.Wrap(err, "failed to send job")
+	}
+
+	f, err := os.Open(b.stagingPath(job.SectorID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open staged data")
+	}
+	defer f.Close() // nolint: errcheck
+
+	buf := make([]byte, SealJobChunkSize)
+	for sent := uint64(0); sent < job.DataSize; {
+		n, err := io.ReadFull(f, buf[:min(SealJobChunkSize, job.DataSize-sent)])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read staged data")
+		}
+		if err := writer.WriteMsg(&SealJobChunk{Data: buf[:n]}); err != nil {
+			return nil, errors.Wrap(err, "failed to send staged data")
+		}
+		sent += uint64(n)
+	}
+
+	var result SealJobResult
+	if err := reader.ReadMsg(&result); err != nil {
+		return nil, errors.Wrap(err, "failed to read result")
+	}
+	if result.SectorID != job.SectorID {
+		return nil, errors.Errorf("received result for sector %d", result.SectorID)
+	}
+	return &result, nil
+}
+
+// finishJob records the outcome of sealing a sector and reports it.
+func (b *RemoteSectorBuilder) finishJob(job *SealJob, result *SealJobResult) {
+	sealResult := sectorbuilder.SectorSealResult{SectorID: result.SectorID}
+	if result.ErrorMessage != "" {
+		sealResult.SealingErr = errors.New(result.ErrorMessage)
+	} else if err := b.verifyResult(job, result.Metadata); err != nil {
+		sealResult.SealingErr = errors.Wrapf(err, "worker returned invalid metadata for sector %d", result.SectorID)
+	} else {
+		sealResult.SealingResult = result.Metadata
+	}
+
+	b.lk.Lock()
+	sector := b.sectors[result.SectorID]
+	if sealResult.SealingErr != nil {
+		sector.State = sectorFailed
+	} else {
+		sector.State = sectorSealed
+		sector.Metadata = result.Metadata
+	}
+	if err := b.put(sector); err != nil {
+		log.Errorf("failed to record result for sector %d: %s", sector.SectorID, err)
+	}
+	b.lk.Unlock()
+
+	select {
+	case b.sectorSealResults <- sealResult:
+	case <-b.stopCh:
+	}
+}
+
+// verifyResult checks that a worker's sealed sector metadata holds the job's
+// pieces and that its seal proof is valid.
+func (b *RemoteSectorBuilder) verifyResult(job *SealJob, meta *sectorbuilder.SealedSectorMetadata) error {
+	if meta == nil {
+		return errors.New("missing metadata")
+	}
+	if meta.SectorID != job.SectorID {
+		return errors.Errorf("metadata is for sector %d", meta.SectorID)
+	}
+	if len(meta.Pieces) != len(job.Pieces) {
+		return errors.Errorf("metadata has %d pieces, expected %d", len(meta.Pieces), len(job.Pieces))
+	}
+	for i, piece := range job.Pieces {
+		if !meta.Pieces[i].Ref.Equals(piece.Ref) || meta.Pieces[i].Size != piece.Size {
+			return errors.Errorf("piece %d does not match piece %s", i, piece.Ref.String())
+		}
+	}
+
+	res, err := b.verifier.VerifySeal(verification.VerifySealRequest{
+		CommD:      meta.CommD,
+		CommR:      meta.CommR,
+		CommRStar:  meta.CommRStar,
+		Proof:      meta.Proof,
+		ProverID:   sectorbuilder.AddressToProverID(b.minerAddr),
+		SectorID:   meta.SectorID,
+		SectorSize: types.NewBytesAmount(b.sectorSize),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to verify seal proof")
+	}
+	if !res.IsValid {
+		return errors.New("invalid seal proof")
+	}
+	return nil
+}
+
+// requeue puts a sector whose worker went away back in the queue.
+func (b *RemoteSectorBuilder) requeue(sectorID uint64) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	sector := b.sectors[sectorID]
+	sector.State = sectorQueued
+	if err := b.put(sector); err != nil {
+		log.Errorf("failed to record sector %d as queued: %s", sectorID, err)
+	}
+	b.queue = append(b.queue, sectorID)
+	b.signalJobQueued()
+}
+
+// queueStaged queues the staged sector for a worker. It must be called with
+// the lock held.
+func (b *RemoteSectorBuilder) queueStaged() error {
+	sector := b.staged
+	sector.State = sectorQueued
+	if err := b.put(sector); err != nil {
+		return err
+	}
+	b.staged = nil
+	b.queue = append(b.queue, sector.SectorID)
+	b.signalJobQueued()
+	return nil
+}
+
+func (b *RemoteSectorBuilder) signalJobQueued() {
+	select {
+	case b.jobQueued <- struct{}{}:
+	default:
+	}
+}
+
+// writePiece writes a piece to a sector's staged data at offset, overwriting
+// anything a failed write left there.
+func (b *RemoteSectorBuilder) writePiece(sectorID uint64, offset uint64, size uint64, r io.Reader) error {
+	f, err := os.OpenFile(b.stagingPath(sectorID), os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open data of sector %d", sectorID)
+	}
+	defer f.Close() // nolint: errcheck
+
+	if err := f.Truncate(int64(offset)); err != nil {
+		return errors.Wrapf(err, "failed to truncate data of sector %d", sectorID)
+	}
+	if _, err := f.Seek(int64(offset), io.SeekStart); err != nil {
+		return errors.Wrapf(err, "failed to seek in data of sector %d", sectorID)
+	}
+	if _, err := io.CopyN(f, r, int64(size)); err != nil {
+		return errors.Wrap(err, "failed to write piece")
+	}
+	return f.Sync()
+}
+
+// stagingPath returns the file holding a sector's staged data.
+func (b *RemoteSectorBuilder) stagingPath(sectorID uint64) string {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	return b.sectors[sectorID].StagingPath
+}
+
+// load restores the sectors recorded in the datastore.
+func (b *RemoteSectorBuilder) load() error {
+	results, err := b.ds.Query(query.Query{Prefix: "/" + sectorsPrefix})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		var sector sectorRecord
+		if err := cbor.DecodeInto(entry.Value, &sector); err != nil {
+			return errors.Wrapf(err, "failed to decode sector at %s", entry.Key)
+		}
+		b.sectors[sector.SectorID] = &sector
+		if sector.SectorID > b.lastUsedSectorID {
+			b.lastUsedSectorID = sector.SectorID
+		}
+
+		switch sector.State {
+		case sectorStaged:
+			b.staged = &sector
+		case sectorQueued, sectorSealing:
+			sector.State = sectorQueued
+			b.queue = append(b.queue, sector.SectorID)
+		}
+	}
+	if len(b.queue) > 0 {
+		b.signalJobQueued()
+	}
+	return nil
+}
+
+// put records a sector in the datastore.
+func (b *RemoteSectorBuilder) put(sector *sectorRecord) error {
+	datum, err := cbor.DumpObject(sector)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal sector %d", sector.SectorID)
+	}
+	key := datastore.KeyWithNamespaces([]string{sectorsPrefix, strconv.FormatUint(sector.SectorID, 10)})
+	if err := b.ds.Put(key, datum); err != nil {
+		return errors.Wrapf(err, "failed to store sector %d", sector.SectorID)
+	}
+	return nil
+}
+
+// sectorState is the progress of a sector handled by a RemoteSectorBuilder.
+type sectorState int
+
+const (
+	sectorStaged = sectorState(iota)
+	sectorQueued
+	sectorSealing
+	sectorSealed
+	sectorFailed
+)
+
+// unsealed returns true for the states of sectors that have not finished
+// sealing.
+func (s sectorState) unsealed() bool {
+	return s == sectorStaged || s == sectorQueued || s == sectorSealing
+}
+
+// sectorRecord is the persisted state of a sector.
+type sectorRecord struct {
+	SectorID uint64
+	State    sectorState
+	Pieces   []*sectorbuilder.PieceInfo
+	// Size is the number of bytes of staged data.
+	Size     uint64
+	Metadata *sectorbuilder.SealedSectorMetadata
+	// Priority is the lowest seal priority of the sector's pieces.
+	Priority uint64
+	// StagingPath is the file holding the sector's staged data.
+	StagingPath string
+}
+
+// sealsBefore returns true if sector a should be sealed before sector b.
+func sealsBefore(a, b *sectorRecord) bool {
+	return sectorbuilder.SealsBefore(
+		sectorbuilder.SectorSealingStatus{SectorID: a.SectorID, Priority: a.Priority},
+		sectorbuilder.SectorSealingStatus{SectorID: b.SectorID, Priority: b.Priority},
+	)
+}
+
+func min(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,63 @@
+package sealworker
+
+import (
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/libp2p/go-libp2p-core/protocol"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+)
+
+func init() {
+	cbor.RegisterCborType(Registration{})
+	cbor.RegisterCborType(RegistrationResponse{})
+	cbor.RegisterCborType(SealJob{})
+	cbor.RegisterCborType(SealJobChunk{})
+	cbor.RegisterCborType(SealJobResult{})
+}
+
+// ProtocolID is the protocol workers use to register with a mining node.
+const ProtocolID = protocol.ID("/fil/seal-worker/0.0.1")
+
+// SealJobChunkSize is the maximum number of bytes of staged data sent in a
+// single SealJobChunk.
+const SealJobChunkSize = 128 << 10
+
+// Registration is sent by a worker to offer to seal sectors for a miner.
+type Registration struct {
+	// Name identifies the worker in the miner's logs.
+	Name string
+	// Token must match the token the miner is configured with.
+	Token string
+}
+
+// RegistrationResponse tells a worker whether the miner will send it jobs.
+type RegistrationResponse struct {
+	Accepted     bool
+	ErrorMessage string
+}
+
+// SealJob asks a worker to seal a staged sector. It is followed by
+// SealJobChunks holding DataSize bytes of staged data, the concatenation of
+// the sector's pieces.
+type SealJob struct {
+	SectorID   uint64
+	MinerAddr  address.Address
+	SectorSize uint64
+	Pieces     []*sectorbuilder.PieceInfo
+	DataSize   uint64
+}
+
+// SealJobChunk is a subset of the bytes of a sector being sealed.
+type SealJobChunk struct {
+	Data []byte
+}
+
+// SealJobResult reports the outcome of a SealJob.
+type SealJobResult struct {
+	SectorID uint64
+	// Metadata is set if the sector was sealed.
+	Metadata *sectorbuilder.SealedSectorMetadata
+	// ErrorMessage is set if sealing failed.
+	ErrorMessage string
+}
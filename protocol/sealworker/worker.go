@@ -0,0 +1,142 @@
+package sealworker
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+)
+
+var log = logging.Logger("/fil/seal-worker")
+
+// Worker registers with a mining node and seals the sectors it is sent.
+type Worker struct {
+	host   host.Host
+	sealer Sealer
+	name   string
+	token  string
+}
+
+// NewWorker returns a Worker that seals sectors with sealer. The worker
+// registers under name, presenting token to the miner.
+func NewWorker(h host.Host, sealer Sealer, name string, token string) *Worker {
+	return &Worker{
+		host:   h,
+		sealer: sealer,
+		name:   name,
+		token:  token,
+	}
+}
+
+// Run registers with the miner and seals the sectors it sends one at a time.
+// It returns when the context is canceled or the miner closes the stream.
+func (w *Worker) Run(ctx context.Context, miner peer.AddrInfo) error {
+	if err := w.host.Connect(ctx, miner); err != nil {
+		return errors.Wrapf(err, "failed to connect to miner %s", miner.ID.Pretty())
+	}
+
+	s, err := w.host.NewStream(ctx, miner.ID, ProtocolID)
+	if err != nil {
+		return errors.Wrap(err, "failed to open seal worker stream")
+	}
+	defer s.Close() // nolint: errcheck
+
+	// Unblock reads and writes on the stream when the worker is stopped.
+	go func() {
+		<-ctx.Done()
+		_ = s.Reset()
+	}()
+
+	reader := cbu.NewMsgReader(s)
+	writer := cbu.NewMsgWriter(s)
+
+	if err := writer.WriteMsg(&Registration{Name: w.name, Token: w.token}); err != nil {
+		return errors.Wrap(err, "failed to register with miner")
+	}
+	var resp RegistrationResponse
+	if err := reader.ReadMsg(&resp); err != nil {
+		return errors.Wrap(err, "failed to read registration response")
+	}
+	if !resp.Accepted {
+		return errors.Errorf("miner rejected registration: %s", resp.ErrorMessage)
+	}
+	log.Infof("registered with miner %s as %s", miner.ID.Pretty(), w.name)
+
+	for {
+		var job SealJob
+		if err := reader.ReadMsg(&job); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "failed to read seal job")
+		}
+
+		result, err := w.seal(ctx, reader, &job)
+		if err != nil {
+			return err
+		}
+		if err := writer.WriteMsg(result); err != nil {
+			return errors.Wrapf(err, "failed to send result for sector %d", job.SectorID)
+		}
+	}
+}
+
+// seal seals the sector of a job whose staged data is read from reader. It
+// only returns an error if the stream can no longer be used, sealing errors
+// are reported in the result.
+func (w *Worker) seal(ctx context.Context, reader *cbu.MsgReader, job *SealJob) (*SealJobResult, error) {
+	log.Infof("sealing sector %d (%d bytes)", job.SectorID, job.DataSize)
+
+	data := &chunkReader{reader: reader, remaining: job.DataSize}
+	meta, sealErr := w.sealer.Seal(ctx, job, data)
+
+	// Consume the data the sealer did not read so the next message can be read.
+	if _, err := io.Copy(ioutil.Discard, data); err != nil {
+		return nil, errors.Wrapf(err, "failed to read staged data for sector %d", job.SectorID)
+	}
+
+	if sealErr != nil {
+		log.Errorf("failed to seal sector %d: %s", job.SectorID, sealErr)
+		return &SealJobResult{SectorID: job.SectorID, ErrorMessage: sealErr.Error()}, nil
+	}
+	log.Infof("sealed sector %d", job.SectorID)
+	return &SealJobResult{SectorID: job.SectorID, Metadata: meta}, nil
+}
+
+// chunkReader reads the staged data of a job from the SealJobChunks that
+// follow it on the stream.
+type chunkReader struct {
+	reader    *cbu.MsgReader
+	remaining uint64
+	buf       []byte
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	for len(cr.buf) == 0 {
+		if cr.remaining == 0 {
+			return 0, io.EOF
+		}
+		var chunk SealJobChunk
+		if err := cr.reader.ReadMsg(&chunk); err != nil {
+			if err == io.EOF {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if uint64(len(chunk.Data)) > cr.remaining {
+			return 0, errors.New("received more staged data than announced")
+		}
+		cr.remaining -= uint64(len(chunk.Data))
+		cr.buf = chunk.Data
+	}
+
+	n := copy(p, cr.buf)
+	cr.buf = cr.buf[n:]
+	return n, nil
+}
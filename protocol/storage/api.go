@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"io"
 
 	"github.com/ipfs/go-cid"
 
@@ -10,21 +11,23 @@ import (
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
-// API here is the API for a storage client.
+// API here is the API for a storage client and miner.
 type API struct {
-	sc *Client
+	sc       *Client
+	getMiner func(ctx context.Context) (*Miner, error)
 }
 
-// NewAPI creates a new API for a storage client.
-func NewAPI(storageClient *Client) API {
-	return API{sc: storageClient}
+// NewAPI creates a new API for a storage client and miner. getMiner returns
+// the node's storage miner, setting it up if necessary.
+func NewAPI(storageClient *Client, getMiner func(ctx context.Context) (*Miner, error)) API {
+	return API{sc: storageClient, getMiner: getMiner}
 }
 
 // ProposeStorageDeal calls the storage client ProposeDeal function
 func (a *API) ProposeStorageDeal(ctx context.Context, data cid.Cid, miner address.Address,
-	askid uint64, duration uint64, allowDuplicates bool) (*storagedeal.SignedResponse, error) {
+	askid uint64, duration uint64, allowDuplicates bool, manual bool) (*storagedeal.SignedResponse, error) {
 
-	return a.sc.ProposeDeal(ctx, miner, data, askid, duration, allowDuplicates)
+	return a.sc.ProposeDeal(ctx, miner, data, askid, duration, allowDuplicates, manual)
 }
 
 // QueryStorageDeal calls the storage client QueryDeal function
@@ -32,7 +35,56 @@ func (a *API) QueryStorageDeal(ctx context.Context, prop cid.Cid) (*storagedeal.
 	return a.sc.QueryDeal(ctx, prop)
 }
 
+// TrackDeals calls the storage client TrackDeals function
+func (a *API) TrackDeals(ctx context.Context) {
+	a.sc.TrackDeals(ctx)
+}
+
 // Payments calls the storage client LoadVouchersForDeal function
 func (a *API) Payments(ctx context.Context, dealCid cid.Cid) ([]*types.PaymentVoucher, error) {
 	return a.sc.LoadVouchersForDeal(ctx, dealCid)
 }
+
+// RetryDeal calls the storage miner RetryDeal function
+func (a *API) RetryDeal(ctx context.Context, proposalCid cid.Cid) error {
+	miner, err := a.getMiner(ctx)
+	if err != nil {
+		return err
+	}
+	return miner.RetryDeal(ctx, proposalCid)
+}
+
+// ImportDealData calls the storage miner ImportDealData function
+func (a *API) ImportDealData(ctx context.Context, proposalCid cid.Cid, data io.Reader) error {
+	miner, err := a.getMiner(ctx)
+	if err != nil {
+		return err
+	}
+	return miner.ImportDealData(ctx, proposalCid, data)
+}
+
+// SectorFaults returns the unhealthy sectors found by the storage miner's
+// sector health checks. If check is set the sectors are checked first, and
+// deeply if deep is set.
+func (a *API) SectorFaults(ctx context.Context, check bool, deep bool) ([]SectorFault, error) {
+	miner, err := a.getMiner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if check || deep {
+		if err := miner.CheckSectorHealth(ctx, deep); err != nil {
+			return nil, err
+		}
+	}
+	return miner.SectorFaults(), nil
+}
+
+// PoStStatus returns the progress of the storage miner's PoSt of its latest
+// proving period, nil if it has not started proving one.
+func (a *API) PoStStatus(ctx context.Context) (*PoStStatus, error) {
+	miner, err := a.getMiner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return miner.PoStStatus()
+}
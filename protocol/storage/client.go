@@ -56,6 +56,8 @@ type clientPorcelainAPI interface {
 	BlockTime() time.Duration
 	ChainHeadKey() types.TipSetKey
 	ChainTipSet(types.TipSetKey) (types.TipSet, error)
+	ClientValidateDeal(ctx context.Context, proposalCid cid.Cid, proofInfo *storagedeal.ProofInfo) error
+	ConfigGet(dottedPath string) (interface{}, error)
 	CreatePayments(ctx context.Context, config porcelain.CreatePaymentsParams) (*porcelain.CreatePaymentsReturn, error)
 	DealGet(context.Context, cid.Cid) (*storagedeal.Deal, error)
 	DAGGetFileSize(context.Context, cid.Cid) (uint64, error)
@@ -92,8 +94,9 @@ func NewClient(host host.Host, api clientPorcelainAPI) *Client {
 }
 
 // ProposeDeal proposes a storage deal to a miner.  Pass allowDuplicates = true to
-// allow duplicate proposals without error.
-func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data cid.Cid, askID uint64, duration uint64, allowDuplicates bool) (*storagedeal.SignedResponse, error) {
+// allow duplicate proposals without error. Pass manual = true to deliver the data
+// to the miner out of band rather than have the miner fetch it from this node.
+func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data cid.Cid, askID uint64, duration uint64, allowDuplicates bool, manual bool) (*storagedeal.SignedResponse, error) {
 	pid, err := smc.api.MinerGetPeerID(ctx, miner)
 	if err != nil {
 		return nil, err
@@ -176,6 +179,7 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 		TotalPrice:   totalPrice,
 		Duration:     duration,
 		MinerAddress: miner,
+		Manual:       manual,
 	}
 
 	if smc.isMaybeDupDeal(ctx, proposal) && !allowDuplicates {
@@ -237,7 +241,8 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 		return nil, errors.Wrap(err, "response check failed")
 	}
 
-	// Note: currently the miner requests the data out of band
+	// Note: the miner pulls the data from this node, unless the deal is manual
+	// in which case it is delivered out of band.
 
 	if err := smc.recordResponse(ctx, &response, miner, signedProposal, pieceCommitmentResponse.CommP); err != nil {
 		return nil, errors.Wrap(err, "failed to track response")
@@ -321,6 +326,11 @@ func (smc *Client) QueryDeal(ctx context.Context, proposalCid cid.Cid) (*storage
 	q := storagedeal.QueryRequest{Cid: proposalCid}
 	var resp storagedeal.SignedResponse
 	err = smc.ProtocolRequestFunc(ctx, queryDealProtocol, minerpid, smc.host, q, &resp)
+	if errors.Cause(err) == errProtocolNotSupported {
+		// Miners that predate the current query protocol don't report transfer progress
+		resp = storagedeal.SignedResponse{}
+		err = smc.ProtocolRequestFunc(ctx, queryDealProtocolV1, minerpid, smc.host, q, &resp)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "error querying deal")
 	}
@@ -358,13 +368,17 @@ func (smc *Client) LoadVouchersForDeal(ctx context.Context, dealCid cid.Cid) ([]
 	return storageDeal.Proposal.Payment.Vouchers, nil
 }
 
+// errProtocolNotSupported is returned by MakeProtocolRequest when the peer does
+// not support the requested protocol.
+var errProtocolNotSupported = errors.New("peer does not support protocol")
+
 // MakeProtocolRequest makes a request and expects a response from the host using the given protocol.
 func MakeProtocolRequest(ctx context.Context, protocol protocol.ID, peer peer.ID,
 	host host.Host, request interface{}, response interface{}) error {
 	s, err := host.NewStream(ctx, peer, protocol)
 	if err != nil {
 		if err == multistream.ErrNotSupported {
-			return errors.New("could not establish connection with peer. Peer does not support protocol")
+			return errors.Wrap(errProtocolNotSupported, "could not establish connection with peer")
 		}
 
 		return errors.Wrap(err, "failed to establish connection with the peer")
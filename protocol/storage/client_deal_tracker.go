@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+)
+
+// dealQueryTimeout bounds how long the client waits for a miner to answer a
+// deal query while tracking deals.
+const dealQueryTimeout = 30 * time.Second
+
+// TrackDeals polls the miners of this node's client deals once per block time
+// until the context is done, recording each deal's progress. See UpdateDeals.
+func (smc *Client) TrackDeals(ctx context.Context) {
+	ticker := time.NewTicker(smc.dealPollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := smc.UpdateDeals(ctx); err != nil {
+				smc.log.Errorf("failed to update client deals: %s", err)
+			}
+		}
+	}
+}
+
+// dealPollInterval returns how often TrackDeals polls miners. Nodes built
+// without a block time poll at the default block time.
+func (smc *Client) dealPollInterval() time.Duration {
+	if blockTime := smc.api.BlockTime(); blockTime > 0 {
+		return blockTime
+	}
+	return consensus.DefaultBlockTime
+}
+
+// UpdateDeals queries the miner of every client deal that is still in
+// progress and records any change to the deal's state or proof info. Once a
+// deal is complete, the client verifies on chain that the miner sealed the
+// deal's piece and marks the deal verified. Deals made with this node's own
+// miner are skipped; the miner keeps those up to date itself.
+func (smc *Client) UpdateDeals(ctx context.Context) error {
+	ownMiner, err := smc.ownMinerAddress()
+	if err != nil {
+		return err
+	}
+
+	dealsCh, err := smc.api.DealsLs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list deals")
+	}
+
+	var tracked []storagedeal.Deal
+	for result := range dealsCh {
+		if result.Err != nil {
+			return errors.Wrap(result.Err, "failed to list deals")
+		}
+		if result.Deal.Miner == ownMiner || !isTrackedClientDeal(&result.Deal) {
+			continue
+		}
+		tracked = append(tracked, result.Deal)
+	}
+
+	for i := range tracked {
+		if err := smc.updateDeal(ctx, &tracked[i]); err != nil {
+			smc.log.Warningf("failed to update deal %s: %s", tracked[i].Response.ProposalCid.String(), err)
+		}
+	}
+	return nil
+}
+
+// updateDeal refreshes a single client deal from its miner.
+func (smc *Client) updateDeal(ctx context.Context, deal *storagedeal.Deal) error {
+	proposalCid := deal.Response.ProposalCid
+
+	if deal.Response.State != storagedeal.Complete {
+		queryCtx, cancel := context.WithTimeout(ctx, dealQueryTimeout)
+		resp, err := smc.QueryDeal(queryCtx, proposalCid)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !resp.ProposalCid.Equals(proposalCid) {
+			return errors.Errorf("miner responded for proposal %s", resp.ProposalCid.String())
+		}
+
+		if resp.State != deal.Response.State {
+			smc.log.Infof("deal %s changed state from %s to %s", proposalCid.String(), deal.Response.State, resp.State)
+		}
+		deal.Response = resp
+		if resp.BytesTransferred > deal.BytesTransferred {
+			deal.BytesTransferred = resp.BytesTransferred
+		}
+		if err := smc.api.DealPut(deal); err != nil {
+			return errors.Wrap(err, "failed to store updated deal")
+		}
+	}
+
+	if deal.Response.State != storagedeal.Complete || deal.Response.ProofInfo == nil {
+		return nil
+	}
+
+	// The miner reports a deal complete as soon as it sends the commitSector message, so
+	// verification fails until that message is mined. It is retried on the next update.
+	if err := smc.api.ClientValidateDeal(ctx, proposalCid, deal.Response.ProofInfo); err != nil {
+		return errors.Wrap(err, "failed to verify completed deal")
+	}
+
+	deal.Verified = true
+	if err := smc.api.DealPut(deal); err != nil {
+		return errors.Wrap(err, "failed to store verified deal")
+	}
+	smc.log.Infof("verified deal %s is sealed in sector %d", proposalCid.String(), deal.Response.ProofInfo.SectorID)
+	return nil
+}
+
+// ownMinerAddress returns the address of this node's miner, or the empty
+// address if it has none.
+func (smc *Client) ownMinerAddress() (address.Address, error) {
+	val, err := smc.api.ConfigGet("mining.minerAddress")
+	if err != nil {
+		return address.Undef, errors.Wrap(err, "failed to get miner address")
+	}
+	minerAddr, ok := val.(address.Address)
+	if !ok {
+		return address.Undef, errors.New("failed to get miner address")
+	}
+	return minerAddr, nil
+}
+
+// isTrackedClientDeal returns true if the client has more to learn about a deal.
+func isTrackedClientDeal(deal *storagedeal.Deal) bool {
+	switch deal.Response.State {
+	case storagedeal.Accepted, storagedeal.Started, storagedeal.Staged:
+		return true
+	case storagedeal.Complete:
+		return !deal.Verified
+	default:
+		return false
+	}
+}
@@ -0,0 +1,144 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	. "github.com/filecoin-project/go-filecoin/protocol/storage"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestClientUpdateDeals(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addressCreator := address.NewForTestGetter()
+	minerAddr := addressCreator()
+
+	proofInfo := &storagedeal.ProofInfo{SectorID: 7, CommitmentMessage: types.CidFromString(t, "commit")}
+
+	t.Run("records miner progress and verifies completed deals", func(t *testing.T) {
+		testAPI := newTestClientAPI(t, nil, 0)
+		proposalCid := putClientTestDeal(t, testAPI, minerAddr, "deal", storagedeal.Accepted)
+
+		client := NewClient(th.NewFakeHost(), testAPI)
+		client.ProtocolRequestFunc = newTestClientNode(dealQueryResponder(t, testAPI, storagedeal.Staged, nil)).MakeTestProtocolRequest
+
+		require.NoError(t, client.UpdateDeals(ctx))
+		assert.Equal(t, storagedeal.Staged, testAPI.deals[proposalCid].Response.State)
+		assert.Empty(t, testAPI.validated)
+
+		client.ProtocolRequestFunc = newTestClientNode(dealQueryResponder(t, testAPI, storagedeal.Complete, proofInfo)).MakeTestProtocolRequest
+
+		require.NoError(t, client.UpdateDeals(ctx))
+		deal := testAPI.deals[proposalCid]
+		assert.Equal(t, storagedeal.Complete, deal.Response.State)
+		assert.Equal(t, proofInfo, deal.Response.ProofInfo)
+		assert.Equal(t, []cid.Cid{proposalCid}, testAPI.validated)
+		assert.True(t, deal.Verified)
+	})
+
+	t.Run("records bytes transferred reported by the miner", func(t *testing.T) {
+		testAPI := newTestClientAPI(t, nil, 0)
+		proposalCid := putClientTestDeal(t, testAPI, minerAddr, "deal", storagedeal.Started)
+
+		respond := dealQueryResponder(t, testAPI, storagedeal.Started, nil)
+		client := NewClient(th.NewFakeHost(), testAPI)
+		client.ProtocolRequestFunc = newTestClientNode(func(request interface{}) (interface{}, error) {
+			res, err := respond(request)
+			require.NoError(t, err)
+			resp := res.(*storagedeal.SignedResponse)
+			resp.BytesTransferred = 1024
+			require.NoError(t, resp.Sign(testAPI.signer, testAPI.worker))
+			return resp, nil
+		}).MakeTestProtocolRequest
+
+		require.NoError(t, client.UpdateDeals(ctx))
+		assert.Equal(t, uint64(1024), testAPI.deals[proposalCid].BytesTransferred)
+	})
+
+	t.Run("retries verification until it succeeds", func(t *testing.T) {
+		testAPI := newTestClientAPI(t, nil, 0)
+		testAPI.validateErr = errors.New("commitment not on chain")
+		proposalCid := putClientTestDeal(t, testAPI, minerAddr, "deal", storagedeal.Staged)
+
+		client := NewClient(th.NewFakeHost(), testAPI)
+		client.ProtocolRequestFunc = newTestClientNode(dealQueryResponder(t, testAPI, storagedeal.Complete, proofInfo)).MakeTestProtocolRequest
+
+		require.NoError(t, client.UpdateDeals(ctx))
+		assert.False(t, testAPI.deals[proposalCid].Verified)
+
+		// once complete the miner is not queried again
+		client.ProtocolRequestFunc = newTestClientNode(failingResponder(t)).MakeTestProtocolRequest
+		testAPI.validateErr = nil
+
+		require.NoError(t, client.UpdateDeals(ctx))
+		assert.True(t, testAPI.deals[proposalCid].Verified)
+		assert.Len(t, testAPI.validated, 2)
+	})
+
+	t.Run("skips finished deals and deals with this node's miner", func(t *testing.T) {
+		testAPI := newTestClientAPI(t, nil, 0)
+		testAPI.minerAddress = addressCreator()
+		putClientTestDeal(t, testAPI, minerAddr, "failed", storagedeal.Failed)
+		putClientTestDeal(t, testAPI, minerAddr, "rejected", storagedeal.Rejected)
+		putClientTestDeal(t, testAPI, testAPI.minerAddress, "own", storagedeal.Accepted)
+		verifiedCid := putClientTestDeal(t, testAPI, minerAddr, "verified", storagedeal.Complete)
+		testAPI.deals[verifiedCid].Verified = true
+
+		client := NewClient(th.NewFakeHost(), testAPI)
+		client.ProtocolRequestFunc = newTestClientNode(failingResponder(t)).MakeTestProtocolRequest
+
+		require.NoError(t, client.UpdateDeals(ctx))
+		assert.Empty(t, testAPI.validated)
+	})
+}
+
+func putClientTestDeal(t *testing.T, testAPI *clientTestAPI, minerAddr address.Address, name string, state storagedeal.State) cid.Cid {
+	proposalCid := types.CidFromString(t, name)
+	require.NoError(t, testAPI.DealPut(&storagedeal.Deal{
+		Miner:    minerAddr,
+		Proposal: &storagedeal.SignedProposal{},
+		Response: &storagedeal.SignedResponse{
+			Response: storagedeal.Response{
+				State:       state,
+				ProposalCid: proposalCid,
+			},
+		},
+	}))
+	return proposalCid
+}
+
+// dealQueryResponder answers deal queries with a signed response in the given state.
+func dealQueryResponder(t *testing.T, testAPI *clientTestAPI, state storagedeal.State, proofInfo *storagedeal.ProofInfo) func(request interface{}) (interface{}, error) {
+	return func(request interface{}) (interface{}, error) {
+		q, ok := request.(storagedeal.QueryRequest)
+		require.True(t, ok)
+
+		resp := &storagedeal.SignedResponse{
+			Response: storagedeal.Response{
+				State:       state,
+				ProposalCid: q.Cid,
+				ProofInfo:   proofInfo,
+			},
+		}
+		require.NoError(t, resp.Sign(testAPI.signer, testAPI.worker))
+		return resp, nil
+	}
+}
+
+func failingResponder(t *testing.T) func(request interface{}) (interface{}, error) {
+	return func(request interface{}) (interface{}, error) {
+		assert.Fail(t, "miner should not have been queried")
+		return nil, errors.New("unexpected query")
+	}
+}
@@ -65,7 +65,7 @@ func TestProposeDeal(t *testing.T) {
 	minerAddr := addressCreator()
 	askID := uint64(67)
 	duration := uint64(10000)
-	dealResponse, err := client.ProposeDeal(ctx, minerAddr, dataCid, askID, duration, false)
+	dealResponse, err := client.ProposeDeal(ctx, minerAddr, dataCid, askID, duration, false, false)
 	require.NoError(t, err)
 
 	t.Run("and creates proposal from parameters", func(t *testing.T) {
@@ -162,7 +162,7 @@ func TestProposeZeroPriceDeal(t *testing.T) {
 	})
 	client.ProtocolRequestFunc = testNode.MakeTestProtocolRequest
 
-	_, err := client.ProposeDeal(ctx, addressCreator(), types.CidFromString(t, "somecid"), uint64(67), uint64(10000), false)
+	_, err := client.ProposeDeal(ctx, addressCreator(), types.CidFromString(t, "somecid"), uint64(67), uint64(10000), false, false)
 	require.NoError(t, err)
 
 	// ensure client did not attempt to create a payment channel
@@ -203,9 +203,9 @@ func TestProposeDealFailsWhenADealAlreadyExists(t *testing.T) {
 	minerAddr := addressCreator()
 	askID := uint64(67)
 	duration := uint64(10000)
-	_, err := client.ProposeDeal(ctx, minerAddr, dataCid, askID, duration, false)
+	_, err := client.ProposeDeal(ctx, minerAddr, dataCid, askID, duration, false, false)
 	require.NoError(t, err)
-	_, err = client.ProposeDeal(ctx, minerAddr, dataCid, askID, duration, false)
+	_, err = client.ProposeDeal(ctx, minerAddr, dataCid, askID, duration, false, false)
 	assert.Error(t, err)
 }
 
@@ -247,7 +247,7 @@ func TestProposeDealFailsWhenSignatureIsInvalid(t *testing.T) {
 	minerAddr := addressCreator()
 	askID := uint64(67)
 	duration := uint64(10000)
-	_, err := client.ProposeDeal(ctx, minerAddr, dataCid, askID, duration, false)
+	_, err := client.ProposeDeal(ctx, minerAddr, dataCid, askID, duration, false, false)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "signature is invalid")
 }
@@ -267,6 +267,9 @@ type clientTestAPI struct {
 	pieceReader    io.Reader
 	pieceSize      uint64
 	signer         types.Signer
+	minerAddress   address.Address
+	validateErr    error
+	validated      []cid.Cid
 }
 
 func newTestClientAPI(t *testing.T, pieceReader io.Reader, pieceSize uint64) *clientTestAPI {
@@ -306,6 +309,15 @@ func (ctp *clientTestAPI) ChainTipSet(_ types.TipSetKey) (types.TipSet, error) {
 	return types.NewTipSet(&types.Block{Height: types.Uint64(ctp.blockHeight)})
 }
 
+func (ctp *clientTestAPI) ClientValidateDeal(ctx context.Context, proposalCid cid.Cid, proofInfo *storagedeal.ProofInfo) error {
+	ctp.validated = append(ctp.validated, proposalCid)
+	return ctp.validateErr
+}
+
+func (ctp *clientTestAPI) ConfigGet(dottedPath string) (interface{}, error) {
+	return ctp.minerAddress, nil
+}
+
 func (ctp *clientTestAPI) CreatePayments(ctx context.Context, config porcelain.CreatePaymentsParams) (*porcelain.CreatePaymentsReturn, error) {
 	ctp.createdPayment = true
 	resp := &porcelain.CreatePaymentsReturn{
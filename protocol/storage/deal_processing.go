@@ -0,0 +1,458 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	dag "github.com/ipfs/go-merkledag"
+	uio "github.com/ipfs/go-unixfs/io"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+)
+
+const (
+	// defaultDealRetryDelay is how long the miner waits before retrying a deal
+	// after its first transient failure. The delay doubles with each attempt.
+	defaultDealRetryDelay = 10 * time.Second
+
+	// maxDealRetryDelay caps the delay between attempts to process a deal.
+	maxDealRetryDelay = 10 * time.Minute
+
+	// maxDealAttempts is the number of consecutive times a deal transition may
+	// fail transiently before the deal is moved to the Failed state.
+	maxDealAttempts = 8
+
+	// dealProgressInterval is the minimum time between records of how much of
+	// a deal's piece has been transferred.
+	dealProgressInterval = 5 * time.Second
+)
+
+// dealError is an error encountered while processing a deal. Its message is
+// recorded on the deal response if the deal fails because of it.
+type dealError struct {
+	message   string
+	cause     error
+	transient bool
+}
+
+func (e *dealError) Error() string {
+	return fmt.Sprintf("%s: %s", e.message, e.cause)
+}
+
+// transientDealError returns an error for a failure that may succeed if retried.
+func transientDealError(message string, cause error) *dealError {
+	return &dealError{message: message, cause: cause, transient: true}
+}
+
+// permanentDealError returns an error for a failure that fails the deal outright.
+func permanentDealError(message string, cause error) *dealError {
+	return &dealError{message: message, cause: cause}
+}
+
+// processStorageDeal drives a deal through the miner side of the deal state
+// machine:
+//
+//	Accepted -> Started -> Staged -> Complete
+//
+// Each transition is recorded in the deals datastore before the next step
+// begins, so a deal interrupted by a restart is picked up from its last recorded
+// state by ResumeDeals. Transient failures are retried with exponential backoff.
+// A deal that fails permanently, or too many times in a row, is moved to Failed.
+func processStorageDeal(ctx context.Context, sm *Miner, proposalCid cid.Cid) {
+	log.Debugf("Miner.processStorageDeal(%s)", proposalCid.String())
+
+	retryNow, ok := sm.startProcessingDeal(proposalCid)
+	if !ok {
+		log.Debugf("deal %s is already being processed", proposalCid.String())
+		return
+	}
+
+	var failure *dealError
+	defer func() {
+		sm.stopProcessingDeal(ctx, proposalCid, retryNow, failure)
+	}()
+
+	attempts := 0
+	for {
+		d, err := sm.porcelainAPI.DealGet(ctx, proposalCid)
+		if err != nil {
+			log.Errorf("could not retrieve deal with proposal CID %s: %s", proposalCid.String(), err)
+			return
+		}
+
+		var dealErr *dealError
+		switch d.Response.State {
+		case storagedeal.Accepted:
+			dealErr = sm.startDeal(ctx, d)
+		case storagedeal.Started:
+			if d.Proposal.Manual && !sm.hasPieceData(d) {
+				// ImportDealData resumes the deal once its data has been imported.
+				return
+			}
+			dealErr = sm.stageDeal(ctx, d)
+		case storagedeal.Staged:
+			// From here on dealsAwaitingSeal moves the deal to Complete or Failed
+			// when its sector's commitment is sent.
+			if dealErr = sm.awaitSeal(ctx, d); dealErr == nil {
+				return
+			}
+		default:
+			// the deal is in a terminal state, there is nothing left to do
+			return
+		}
+		if dealErr == nil {
+			attempts = 0
+			continue
+		}
+
+		attempts++
+		if !dealErr.transient || attempts >= maxDealAttempts {
+			log.Errorf("deal %s failed in state %s: %s", proposalCid.String(), d.Response.State, dealErr)
+			failure = dealErr
+			return
+		}
+
+		delay := sm.dealRetryBackoff(attempts)
+		log.Warningf("deal %s failed in state %s (attempt %d), retrying in %s: %s", proposalCid.String(), d.Response.State, attempts, delay, dealErr)
+		select {
+		case <-time.After(delay):
+		case <-retryNow:
+			attempts = 0
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// startDeal records that the miner has begun work on an accepted deal.
+func (sm *Miner) startDeal(ctx context.Context, d *storagedeal.Deal) *dealError {
+	message := ""
+	if d.Proposal.Manual {
+		message = awaitingImportMessage
+	}
+	err := sm.updateDealResponse(ctx, d.Response.ProposalCid, func(resp *storagedeal.Response) {
+		resp.State = storagedeal.Started
+		resp.Message = message
+	})
+	if err != nil {
+		return transientDealError("internal error", err)
+	}
+	return nil
+}
+
+// stageDeal stages a started deal's piece into a sector, then records the
+// sector and moves the deal to Staged in a single update.
+func (sm *Miner) stageDeal(ctx context.Context, d *storagedeal.Deal) *dealError {
+	sectorID, err := sm.pieceStager(ctx, sm, d)
+	if err != nil {
+		if dealErr, ok := err.(*dealError); ok {
+			return dealErr
+		}
+		return transientDealError("internal error", err)
+	}
+
+	// If this update fails the piece is staged again on retry. That wastes some
+	// sector space but cannot lose the deal.
+	err = sm.updateDeal(ctx, d.Response.ProposalCid, func(deal *storagedeal.Deal) {
+		deal.SectorID = sectorID
+		deal.Response.State = storagedeal.Staged
+		deal.Response.Message = ""
+	})
+	if err != nil {
+		return transientDealError("internal error", err)
+	}
+	return nil
+}
+
+// awaitSeal attaches a staged deal to its sector so the deal is updated when
+// the sector's commitment is sent.
+func (sm *Miner) awaitSeal(ctx context.Context, d *storagedeal.Deal) *dealError {
+	// Careful: this might update state to success or failure so it should go after
+	// updating state to Staged.
+	sm.dealsAwaitingSeal.attachDealToSector(ctx, d.SectorID, d.Response.ProposalCid)
+	if err := sm.saveDealsAwaitingSeal(); err != nil {
+		return transientDealError("internal error", err)
+	}
+	return nil
+}
+
+// stageDealPiece fetches the deal's data, confirms the client's payment
+// conditions match its piece commitment and adds it to a sector, returning
+// the sector id.
+func stageDealPiece(ctx context.Context, sm *Miner, d *storagedeal.Deal) (uint64, error) {
+	// 'Receive' the data, this could also be a truck full of hard drives.
+	// TODO: this needs to be fetched into a staging area for miners to prepare and seal in data
+	if d.ClientPeer != "" && !d.Proposal.Manual {
+		if err := sm.fetchPiece(ctx, d); err != nil {
+			return 0, transientDealError("Transfer failed", err)
+		}
+	}
+
+	// Once the piece has been pulled from the client or imported this only walks
+	// the local blockstore. Deals that do not record their client fetch over bitswap.
+	log.Debug("Miner.processStorageDeal - FetchGraph")
+	dagService := dag.NewDAGService(sm.node.BlockService())
+	if err := dag.FetchGraph(ctx, d.Proposal.PieceRef, dagService); err != nil {
+		return 0, transientDealError("Transfer failed", err)
+	}
+
+	rootIpldNode, err := dagService.Get(ctx, d.Proposal.PieceRef)
+	if err != nil {
+		return 0, transientDealError("internal error", errors.Wrap(err, "failed to add piece"))
+	}
+
+	// Before adding piece, confirm that client has generated payment conditions correctly now that
+	// we can compute CommP
+	if err := sm.validatePieceCommitments(ctx, d, rootIpldNode, dagService); err != nil {
+		return 0, permanentDealError("payment error", errors.Wrap(err, "failed to add piece"))
+	}
+
+	r, err := uio.NewDagReader(ctx, rootIpldNode, dagService)
+	if err != nil {
+		return 0, transientDealError("internal error", errors.Wrap(err, "failed to add piece"))
+	}
+
+	// There is a race here that requires us to use dealsAwaitingSeal. If the
+	// sector gets sealed and OnCommitmentSent is called right after
+	// AddPiece returns but before we record the sector/deal mapping we might
+	// miss it. Hence, dealsAwaitingSeal. I'm told that sealing in practice is
+	// so slow that the race only exists in tests, but tests were flaky so
+	// we fixed it with dealsAwaitingSeal.
+	//
+	// Also, this pattern of not being able to set up book-keeping ahead of
+	// the call is inelegant.
+	if priority, ok := dealSealPriority(d); ok {
+		ctx = sectorbuilder.WithSealPriority(ctx, priority)
+	}
+	sectorID, err := sm.porcelainAPI.SectorBuilder().AddPiece(ctx, d.Proposal.PieceRef, d.Proposal.Size.Uint64(), r)
+	if err != nil {
+		return 0, transientDealError("failed to add piece to sector", err)
+	}
+	return sectorID, nil
+}
+
+// fetchPiece pulls a deal's piece from the client that proposed it, recording
+// the number of bytes transferred on the deal as the transfer advances. The
+// progress is stored next to the deal's signed response rather than in it, so
+// recording it does not sign the response again. Blocks fetched by an earlier
+// attempt are not transferred again.
+func (sm *Miner) fetchPiece(ctx context.Context, d *storagedeal.Deal) error {
+	proposalCid := d.Response.ProposalCid
+
+	var transferred uint64
+	var lastRecorded time.Time
+	recordProgress := func() {
+		err := sm.updateDeal(ctx, proposalCid, func(deal *storagedeal.Deal) {
+			deal.BytesTransferred = transferred
+		})
+		if err != nil {
+			log.Warningf("failed to record transfer progress of deal %s: %s", proposalCid.String(), err)
+		}
+		lastRecorded = time.Now()
+	}
+
+	err := sm.node.PieceFetcher().FetchPiece(ctx, d.ClientPeer, d.Proposal.PieceRef, func(bytesTransferred uint64) {
+		transferred = bytesTransferred
+		if time.Since(lastRecorded) >= dealProgressInterval {
+			recordProgress()
+		}
+	})
+	recordProgress()
+	return err
+}
+
+// dealRetryBackoff returns how long to wait before the given attempt to
+// process a deal.
+func (sm *Miner) dealRetryBackoff(attempt int) time.Duration {
+	delay := sm.dealRetryDelay
+	for i := 1; i < attempt && delay < maxDealRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDealRetryDelay {
+		delay = maxDealRetryDelay
+	}
+	return delay
+}
+
+// startProcessingDeal registers a processor for a deal. It returns false if
+// the deal is already being processed, otherwise a channel that is signalled
+// when the deal should be retried without waiting out its backoff.
+func (sm *Miner) startProcessingDeal(proposalCid cid.Cid) (<-chan struct{}, bool) {
+	sm.dealsInProcessLk.Lock()
+	defer sm.dealsInProcessLk.Unlock()
+
+	if sm.dealsInProcess == nil {
+		sm.dealsInProcess = make(map[cid.Cid]chan struct{})
+	}
+	if _, ok := sm.dealsInProcess[proposalCid]; ok {
+		return nil, false
+	}
+	retryNow := make(chan struct{}, 1)
+	sm.dealsInProcess[proposalCid] = retryNow
+	return retryNow, true
+}
+
+// stopProcessingDeal moves the deal to Failed if processing failed, then
+// unregisters its processor. A retry requested after the failure was decided
+// but before the processor was unregistered is run once the processor is gone,
+// so it is not lost.
+func (sm *Miner) stopProcessingDeal(ctx context.Context, proposalCid cid.Cid, retryNow <-chan struct{}, failure *dealError) {
+	if failure != nil {
+		err := sm.updateDealResponse(ctx, proposalCid, func(resp *storagedeal.Response) {
+			resp.Message = failure.message
+			resp.State = storagedeal.Failed
+		})
+		if err != nil {
+			log.Errorf("could not update deal to 'Failed' state: %s", err)
+		}
+	}
+
+	sm.dealsInProcessLk.Lock()
+	delete(sm.dealsInProcess, proposalCid)
+	sm.dealsInProcessLk.Unlock()
+
+	if failure == nil {
+		return
+	}
+	select {
+	case <-retryNow:
+		if err := sm.RetryDeal(ctx, proposalCid); err != nil {
+			log.Errorf("failed to retry deal %s: %s", proposalCid.String(), err)
+		}
+	default:
+	}
+}
+
+// ResumeDeals restarts processing of every deal made with this miner that has
+// not reached a terminal state, so deals interrupted by a restart are not
+// stranded.
+func (sm *Miner) ResumeDeals(ctx context.Context) error {
+	dealsCh, err := sm.porcelainAPI.DealsLs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list deals")
+	}
+
+	for result := range dealsCh {
+		if result.Err != nil {
+			return errors.Wrap(result.Err, "failed to list deals")
+		}
+		deal := result.Deal
+		if deal.Miner != sm.minerAddr || !isResumableDealState(deal.Response.State) {
+			continue
+		}
+
+		log.Infof("resuming deal %s in state %s", deal.Response.ProposalCid.String(), deal.Response.State)
+		go sm.proposalProcessor(context.Background(), sm, deal.Response.ProposalCid)
+	}
+	return nil
+}
+
+// RetryDeal retries a deal made with this miner. A deal waiting out a retry
+// backoff is retried immediately and a deal with no processor is resumed. A
+// failed deal whose piece is still staged in a sector is restarted from the
+// Staged state, any other failed deal from the Accepted state.
+func (sm *Miner) RetryDeal(ctx context.Context, proposalCid cid.Cid) error {
+	if !sm.claimDealForRetry(proposalCid) {
+		return nil
+	}
+	// No processor starts while the deal is claimed, so the deal can be reset
+	// without holding dealsInProcessLk.
+	err := sm.resetDealForRetry(ctx, proposalCid)
+	sm.releaseDealClaim(proposalCid)
+	if err != nil {
+		return err
+	}
+
+	go sm.proposalProcessor(context.Background(), sm, proposalCid)
+	return nil
+}
+
+// resetDealForRetry moves a failed deal back to the state it is retried from.
+func (sm *Miner) resetDealForRetry(ctx context.Context, proposalCid cid.Cid) error {
+	deal, err := sm.porcelainAPI.DealGet(ctx, proposalCid)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get deal with proposal CID %s", proposalCid.String())
+	}
+	if deal.Miner != sm.minerAddr {
+		return errors.Errorf("deal %s was not made with miner %s", proposalCid.String(), sm.minerAddr.String())
+	}
+
+	switch state := deal.Response.State; {
+	case state == storagedeal.Failed:
+		err := sm.updateDeal(ctx, proposalCid, func(deal *storagedeal.Deal) {
+			// onCommitFail clears the sector of a deal whose sector failed,
+			// so a remaining sector still holds the deal's piece.
+			if deal.SectorID != 0 {
+				deal.Response.State = storagedeal.Staged
+			} else {
+				deal.Response.State = storagedeal.Accepted
+			}
+			deal.Response.Message = ""
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to reset failed deal")
+		}
+	case !isResumableDealState(state):
+		return errors.Errorf("cannot retry deal in state %s", state)
+	}
+	return nil
+}
+
+// claimDealForRetry signals the processor of a deal to retry it without
+// waiting out its backoff and returns false. If the deal has no processor it
+// is claimed, so no processor starts until the claim is released, and true is
+// returned.
+func (sm *Miner) claimDealForRetry(proposalCid cid.Cid) bool {
+	sm.dealsInProcessLk.Lock()
+	defer sm.dealsInProcessLk.Unlock()
+
+	if retryNow, ok := sm.dealsInProcess[proposalCid]; ok {
+		select {
+		case retryNow <- struct{}{}:
+		default:
+		}
+		return false
+	}
+	if sm.dealsInProcess == nil {
+		sm.dealsInProcess = make(map[cid.Cid]chan struct{})
+	}
+	sm.dealsInProcess[proposalCid] = make(chan struct{}, 1)
+	return true
+}
+
+// releaseDealClaim releases a claim taken by claimDealForRetry.
+func (sm *Miner) releaseDealClaim(proposalCid cid.Cid) {
+	sm.dealsInProcessLk.Lock()
+	defer sm.dealsInProcessLk.Unlock()
+	delete(sm.dealsInProcess, proposalCid)
+}
+
+// isResumableDealState returns true for the states a miner deal passes through
+// while it is being processed.
+func isResumableDealState(state storagedeal.State) bool {
+	return state == storagedeal.Accepted || state == storagedeal.Started || state == storagedeal.Staged
+}
+
+// dealSealPriority returns the priority with which a deal's sector should be
+// sealed. Deals whose first payment becomes valid earliest start earliest, so
+// their sectors are sealed first by builders that order their seal queue.
+func dealSealPriority(d *storagedeal.Deal) (uint64, bool) {
+	vouchers := d.Proposal.Payment.Vouchers
+	if len(vouchers) == 0 {
+		return 0, false
+	}
+	start := vouchers[0].ValidAt.AsBigInt()
+	for _, v := range vouchers[1:] {
+		if validAt := v.ValidAt.AsBigInt(); validAt.Cmp(start) < 0 {
+			start = validAt
+		}
+	}
+	if !start.IsUint64() {
+		return 0, false
+	}
+	return start.Uint64(), true
+}
@@ -0,0 +1,382 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/net"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	"github.com/filecoin-project/go-filecoin/repo"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestProcessStorageDeal(t *testing.T) {
+	tf.UnitTest(t)
+
+	sectorID := uint64(42)
+
+	t.Run("moves an accepted deal to staged and attaches it to its sector", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Accepted)
+		miner.pieceStager = fakePieceStager(sectorID)
+
+		processStorageDeal(context.Background(), miner, proposalCid)
+
+		deal := porcelainAPI.deals[proposalCid]
+		assert.Equal(t, storagedeal.Staged, deal.Response.State)
+		assert.Equal(t, sectorID, deal.SectorID)
+		assert.Equal(t, []cid.Cid{proposalCid}, miner.dealsAwaitingSeal.SectorsToDeals[sectorID])
+
+		valid, err := deal.Response.VerifySignature(porcelainAPI.workerAddress)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("resumes a started deal", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Started)
+		miner.pieceStager = fakePieceStager(sectorID)
+
+		processStorageDeal(context.Background(), miner, proposalCid)
+
+		assert.Equal(t, storagedeal.Staged, porcelainAPI.deals[proposalCid].Response.State)
+	})
+
+	t.Run("re-attaches a staged deal without staging it again", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Staged)
+		porcelainAPI.deals[proposalCid].SectorID = sectorID
+		miner.pieceStager = func(context.Context, *Miner, *storagedeal.Deal) (uint64, error) {
+			require.Fail(t, "staged deal should not be staged again")
+			return 0, nil
+		}
+
+		processStorageDeal(context.Background(), miner, proposalCid)
+
+		assert.Equal(t, []cid.Cid{proposalCid}, miner.dealsAwaitingSeal.SectorsToDeals[sectorID])
+	})
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Accepted)
+		miner.pieceStager = fakePieceStager(sectorID,
+			transientDealError("Transfer failed", errors.New("boom")),
+			transientDealError("Transfer failed", errors.New("boom")),
+		)
+
+		processStorageDeal(context.Background(), miner, proposalCid)
+
+		assert.Equal(t, storagedeal.Staged, porcelainAPI.deals[proposalCid].Response.State)
+	})
+
+	t.Run("fails after too many transient failures", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Accepted)
+		attempts := 0
+		miner.pieceStager = func(context.Context, *Miner, *storagedeal.Deal) (uint64, error) {
+			attempts++
+			return 0, transientDealError("Transfer failed", errors.New("boom"))
+		}
+
+		processStorageDeal(context.Background(), miner, proposalCid)
+
+		assert.Equal(t, maxDealAttempts, attempts)
+		assert.Equal(t, storagedeal.Failed, porcelainAPI.deals[proposalCid].Response.State)
+		assert.Equal(t, "Transfer failed", porcelainAPI.deals[proposalCid].Response.Message)
+	})
+
+	t.Run("fails immediately on a permanent failure", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Accepted)
+		miner.pieceStager = fakePieceStager(sectorID, permanentDealError("payment error", errors.New("bad commP")))
+
+		processStorageDeal(context.Background(), miner, proposalCid)
+
+		assert.Equal(t, storagedeal.Failed, porcelainAPI.deals[proposalCid].Response.State)
+		assert.Equal(t, "payment error", porcelainAPI.deals[proposalCid].Response.Message)
+	})
+
+	t.Run("does nothing for a deal in a terminal state", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Complete)
+		miner.pieceStager = fakePieceStager(sectorID)
+
+		processStorageDeal(context.Background(), miner, proposalCid)
+
+		assert.Equal(t, storagedeal.Complete, porcelainAPI.deals[proposalCid].Response.State)
+	})
+}
+
+func TestResumeDeals(t *testing.T) {
+	tf.UnitTest(t)
+
+	porcelainAPI, miner, startedCid := minerWithDealTestSetup(t, storagedeal.Started)
+	addTestDeal(t, porcelainAPI, miner.minerAddr, storagedeal.Complete)
+	addTestDeal(t, porcelainAPI, porcelainAPI.targetAddress, storagedeal.Accepted)
+
+	resumed := make(chan cid.Cid, 3)
+	miner.proposalProcessor = func(ctx context.Context, m *Miner, proposalCid cid.Cid) {
+		resumed <- proposalCid
+	}
+
+	require.NoError(t, miner.ResumeDeals(context.Background()))
+
+	assert.Equal(t, startedCid, <-resumed)
+	assert.Len(t, resumed, 0)
+}
+
+func TestRetryDeal(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("restarts a failed deal that was not staged from accepted", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Failed)
+
+		retried := make(chan cid.Cid, 1)
+		miner.proposalProcessor = func(ctx context.Context, m *Miner, proposalCid cid.Cid) {
+			retried <- proposalCid
+		}
+
+		require.NoError(t, miner.RetryDeal(context.Background(), proposalCid))
+
+		assert.Equal(t, proposalCid, <-retried)
+		assert.Equal(t, storagedeal.Accepted, porcelainAPI.deals[proposalCid].Response.State)
+	})
+
+	t.Run("restarts a failed deal whose piece is staged without staging it again", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Failed)
+		porcelainAPI.deals[proposalCid].SectorID = 42
+		miner.proposalProcessor = func(ctx context.Context, m *Miner, proposalCid cid.Cid) {}
+
+		require.NoError(t, miner.RetryDeal(context.Background(), proposalCid))
+		deal := porcelainAPI.deals[proposalCid]
+		assert.Equal(t, storagedeal.Staged, deal.Response.State)
+		assert.Equal(t, uint64(42), deal.SectorID)
+	})
+
+	t.Run("restages a deal whose sector failed", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Staged)
+		porcelainAPI.deals[proposalCid].SectorID = 42
+		miner.dealsAwaitingSeal.attachDealToSector(context.Background(), 42, proposalCid)
+		miner.dealsAwaitingSeal.onSealFail(context.Background(), 42, "failed sealing sector: 42")
+
+		deal := porcelainAPI.deals[proposalCid]
+		require.Equal(t, storagedeal.Failed, deal.Response.State)
+		assert.Equal(t, uint64(0), deal.SectorID)
+
+		miner.proposalProcessor = func(ctx context.Context, m *Miner, proposalCid cid.Cid) {}
+		require.NoError(t, miner.RetryDeal(context.Background(), proposalCid))
+		assert.Equal(t, storagedeal.Accepted, porcelainAPI.deals[proposalCid].Response.State)
+	})
+
+	t.Run("runs a retry requested while the deal was failing", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Accepted)
+		retryNow, ok := miner.startProcessingDeal(proposalCid)
+		require.True(t, ok)
+
+		// The retry finds the failing processor still registered.
+		require.NoError(t, miner.RetryDeal(context.Background(), proposalCid))
+
+		retried := make(chan cid.Cid, 1)
+		miner.proposalProcessor = func(ctx context.Context, m *Miner, proposalCid cid.Cid) {
+			retried <- proposalCid
+		}
+		miner.stopProcessingDeal(context.Background(), proposalCid, retryNow, permanentDealError("payment error", errors.New("bad commP")))
+
+		assert.Equal(t, proposalCid, <-retried)
+		assert.Equal(t, storagedeal.Accepted, porcelainAPI.deals[proposalCid].Response.State)
+	})
+
+	t.Run("cuts short the backoff of a deal being processed", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Accepted)
+		miner.dealRetryDelay = maxDealRetryDelay
+
+		failed := make(chan struct{})
+		miner.pieceStager = func(context.Context, *Miner, *storagedeal.Deal) (uint64, error) {
+			select {
+			case <-failed:
+				return 42, nil
+			default:
+				close(failed)
+				return 0, transientDealError("Transfer failed", errors.New("boom"))
+			}
+		}
+
+		done := make(chan struct{})
+		go func() {
+			processStorageDeal(context.Background(), miner, proposalCid)
+			close(done)
+		}()
+
+		<-failed
+		require.NoError(t, miner.RetryDeal(context.Background(), proposalCid))
+		<-done
+
+		assert.Equal(t, storagedeal.Staged, porcelainAPI.deals[proposalCid].Response.State)
+	})
+
+	t.Run("rejects a completed deal", func(t *testing.T) {
+		_, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Complete)
+
+		err := miner.RetryDeal(context.Background(), proposalCid)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot retry deal in state complete")
+	})
+
+	t.Run("rejects a deal made with another miner", func(t *testing.T) {
+		porcelainAPI, miner, _ := minerWithDealTestSetup(t, storagedeal.Failed)
+		proposalCid := addTestDeal(t, porcelainAPI, porcelainAPI.targetAddress, storagedeal.Failed)
+
+		err := miner.RetryDeal(context.Background(), proposalCid)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "was not made with miner")
+	})
+}
+
+func TestFetchPiece(t *testing.T) {
+	tf.UnitTest(t)
+
+	clientPeer := th.RequireIntPeerID(t, 0)
+
+	t.Run("pulls the piece from the client and records bytes transferred", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Started)
+		porcelainAPI.deals[proposalCid].ClientPeer = clientPeer
+		fetcher := &fakePieceFetcher{progress: []uint64{100, 200}}
+		miner.node = &fakeMinerNode{pieceFetcher: fetcher}
+
+		deal := porcelainAPI.deals[proposalCid]
+		require.NoError(t, miner.fetchPiece(context.Background(), deal))
+
+		assert.Equal(t, clientPeer, fetcher.peer)
+		assert.Equal(t, deal.Proposal.PieceRef, fetcher.root)
+
+		deal = porcelainAPI.deals[proposalCid]
+		assert.Equal(t, uint64(200), deal.BytesTransferred)
+		assert.Equal(t, uint64(0), deal.Response.BytesTransferred)
+		valid, err := deal.Response.VerifySignature(porcelainAPI.workerAddress)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("records progress of a running transfer at most once per interval", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Started)
+		porcelainAPI.deals[proposalCid].ClientPeer = clientPeer
+		fetcher := &fakePieceFetcher{progress: []uint64{100, 200}}
+		fetcher.during = func() {
+			assert.Equal(t, uint64(100), porcelainAPI.deals[proposalCid].BytesTransferred)
+		}
+		miner.node = &fakeMinerNode{pieceFetcher: fetcher}
+
+		require.NoError(t, miner.fetchPiece(context.Background(), porcelainAPI.deals[proposalCid]))
+
+		assert.Equal(t, uint64(200), porcelainAPI.deals[proposalCid].BytesTransferred)
+	})
+
+	t.Run("query reports recorded progress in a signed response", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Started)
+		porcelainAPI.deals[proposalCid].ClientPeer = clientPeer
+		miner.node = &fakeMinerNode{pieceFetcher: &fakePieceFetcher{progress: []uint64{100, 200}}}
+
+		require.NoError(t, miner.fetchPiece(context.Background(), porcelainAPI.deals[proposalCid]))
+
+		resp := miner.Query(context.Background(), proposalCid)
+		assert.Equal(t, uint64(200), resp.BytesTransferred)
+		valid, err := resp.VerifySignature(porcelainAPI.workerAddress)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		assert.Equal(t, uint64(0), miner.queryV1(context.Background(), proposalCid).BytesTransferred)
+		assert.Equal(t, uint64(0), porcelainAPI.deals[proposalCid].Response.BytesTransferred)
+	})
+
+	t.Run("records progress of a failed transfer", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, storagedeal.Started)
+		porcelainAPI.deals[proposalCid].ClientPeer = clientPeer
+		miner.node = &fakeMinerNode{pieceFetcher: &fakePieceFetcher{progress: []uint64{100}, err: errors.New("stream reset")}}
+
+		err := miner.fetchPiece(context.Background(), porcelainAPI.deals[proposalCid])
+		require.Error(t, err)
+
+		assert.Equal(t, uint64(100), porcelainAPI.deals[proposalCid].BytesTransferred)
+	})
+}
+
+// minerWithDealTestSetup creates a miner that has stored a deal in the given state.
+func minerWithDealTestSetup(t *testing.T, state storagedeal.State) (*minerTestPorcelain, *Miner, cid.Cid) {
+	porcelainAPI, miner, _ := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
+
+	miner.dealsAwaitingSealDs = repo.NewInMemoryRepo().DealsDs
+	require.NoError(t, miner.loadDealsAwaitingSeal())
+	miner.dealsAwaitingSeal.onSuccess = miner.onCommitSuccess
+	miner.dealsAwaitingSeal.onFail = miner.onCommitFail
+
+	return porcelainAPI, miner, addTestDeal(t, porcelainAPI, miner.minerAddr, state)
+}
+
+// addTestDeal stores a new deal with the given miner in the given state and returns its proposal cid.
+func addTestDeal(t *testing.T, porcelainAPI *minerTestPorcelain, minerAddr address.Address, state storagedeal.State) cid.Cid {
+	proposal := testSignedDealProposal(porcelainAPI, testPaymentVouchers(porcelainAPI, VoucherInterval, defaultAmountInc), defaultPieceSize)
+	proposalCid := types.CidFromString(t, fmt.Sprintf("deal-%d", len(porcelainAPI.deals)))
+
+	require.NoError(t, porcelainAPI.DealPut(&storagedeal.Deal{
+		Miner:    minerAddr,
+		Proposal: proposal,
+		Response: &storagedeal.SignedResponse{
+			Response: storagedeal.Response{
+				State:       state,
+				ProposalCid: proposalCid,
+			},
+		},
+	}))
+	return proposalCid
+}
+
+// fakePieceStager returns a piece stager that fails with each of errs in turn
+// and then stages the piece into sectorID.
+func fakePieceStager(sectorID uint64, errs ...error) func(context.Context, *Miner, *storagedeal.Deal) (uint64, error) {
+	return func(context.Context, *Miner, *storagedeal.Deal) (uint64, error) {
+		if len(errs) > 0 {
+			err := errs[0]
+			errs = errs[1:]
+			return 0, err
+		}
+		return sectorID, nil
+	}
+}
+
+// fakeMinerNode is a miner node without a host.
+type fakeMinerNode struct {
+	blockService bserv.BlockService
+	pieceFetcher net.PieceFetcher
+}
+
+func (n *fakeMinerNode) BlockService() bserv.BlockService { return n.blockService }
+func (n *fakeMinerNode) Host() host.Host                  { return nil }
+func (n *fakeMinerNode) PieceFetcher() net.PieceFetcher   { return n.pieceFetcher }
+
+// fakePieceFetcher reports each of progress in turn and then returns err.
+type fakePieceFetcher struct {
+	progress []uint64
+	err      error
+	// during is called after progress has been reported.
+	during func()
+
+	peer peer.ID
+	root cid.Cid
+}
+
+func (f *fakePieceFetcher) FetchPiece(ctx context.Context, p peer.ID, root cid.Cid, progress func(uint64)) error {
+	f.peer = p
+	f.root = root
+	for _, bytes := range f.progress {
+		progress(bytes)
+	}
+	if f.during != nil {
+		f.during()
+	}
+	return f.err
+}
@@ -57,7 +57,7 @@ func newDealsAwaitingSeal() *dealsAwaitingSeal {
 
 // attachDealToSector checks the list of sealed sectors to see if a sector has been sealed. If sealing of this sector is done,
 // onSuccess or onFailure will be called immediately, otherwise, add it to SectorsToDeals so we can respond
-// when sealing completes.
+// when sealing completes. Attaching a deal that is already awaiting the sector is a no-op.
 func (dealsAwaitingSeal *dealsAwaitingSeal) attachDealToSector(ctx context.Context, sectorID uint64, dealCid cid.Cid) {
 	dealsAwaitingSeal.l.Lock()
 	defer dealsAwaitingSeal.l.Unlock()
@@ -66,12 +66,13 @@ func (dealsAwaitingSeal *dealsAwaitingSeal) attachDealToSector(ctx context.Conte
 
 	// if sector sealing hasn't succeed or failed yet, just add to SectorToDeals and exit
 	if !ok {
-		deals, ok := dealsAwaitingSeal.SectorsToDeals[sectorID]
-		if ok {
-			dealsAwaitingSeal.SectorsToDeals[sectorID] = append(deals, dealCid)
-		} else {
-			dealsAwaitingSeal.SectorsToDeals[sectorID] = []cid.Cid{dealCid}
+		// a resumed deal may already have been attached before a restart
+		for _, c := range dealsAwaitingSeal.SectorsToDeals[sectorID] {
+			if c.Equals(dealCid) {
+				return
+			}
 		}
+		dealsAwaitingSeal.SectorsToDeals[sectorID] = append(dealsAwaitingSeal.SectorsToDeals[sectorID], dealCid)
 		return
 	}
 
@@ -46,6 +46,24 @@ func TestDealsAwaitingSealAdd(t *testing.T) {
 		assert.Len(t, gotCids, 2, "onSuccess should've been called twice")
 	})
 
+	t.Run("attachDealToSector is idempotent", func(t *testing.T) {
+		dealsAwaitingSeal := newDealsAwaitingSeal()
+		gotCids := []cid.Cid{}
+		dealsAwaitingSeal.onSuccess = func(_ context.Context, dealCid cid.Cid, sector *sectorbuilder.SealedSectorMetadata) {
+			gotCids = append(gotCids, dealCid)
+		}
+		dealsAwaitingSeal.onFail = func(_ context.Context, dealCid cid.Cid, message string) {
+			require.Fail(t, "onFail should not have been called")
+		}
+
+		dealsAwaitingSeal.attachDealToSector(context.Background(), wantSectorID, cid0)
+		dealsAwaitingSeal.attachDealToSector(context.Background(), wantSectorID, cid0)
+		assert.Len(t, dealsAwaitingSeal.SectorsToDeals[wantSectorID], 1)
+
+		dealsAwaitingSeal.onSealSuccess(context.Background(), wantSector, commitSectorCid)
+		assert.Equal(t, []cid.Cid{cid0}, gotCids, "onSuccess should've been called once")
+	})
+
 	t.Run("attachDealToSector after onSealSuccess", func(t *testing.T) {
 		dealsAwaitingSeal := newDealsAwaitingSeal()
 		gotCids := []cid.Cid{}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	dag "github.com/ipfs/go-merkledag"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+)
+
+// awaitingImportMessage is the message of a started manual deal until its data
+// has been imported.
+const awaitingImportMessage = "waiting for piece data to be imported"
+
+// ImportDealData imports the piece of a manual deal, whose client delivered
+// the data out of band. The data must have the piece cid of the deal's
+// proposal and satisfy its payment conditions, otherwise the deal fails. Once
+// the data is imported the deal is staged and sealed like any other.
+func (sm *Miner) ImportDealData(ctx context.Context, proposalCid cid.Cid, data io.Reader) error {
+	deal, err := sm.porcelainAPI.DealGet(ctx, proposalCid)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get deal with proposal CID %s", proposalCid.String())
+	}
+	if deal.Miner != sm.minerAddr {
+		return errors.Errorf("deal %s was not made with miner %s", proposalCid.String(), sm.minerAddr.String())
+	}
+	if !deal.Proposal.Manual {
+		return errors.Errorf("deal %s is not a manual deal, its data is fetched from the client", proposalCid.String())
+	}
+	if state := deal.Response.State; state != storagedeal.Accepted && state != storagedeal.Started {
+		return errors.Errorf("cannot import data for deal in state %s", state)
+	}
+
+	// Hold off the deal's processor while the data is checked, so it does not
+	// stage data that turns out not to match the proposal.
+	retryNow, ok := sm.startProcessingDeal(proposalCid)
+	if !ok {
+		return errors.Errorf("deal %s is already being processed", proposalCid.String())
+	}
+
+	failure, err := sm.importDealData(ctx, deal, data)
+	sm.stopProcessingDeal(ctx, proposalCid, retryNow, failure)
+	if err != nil {
+		return err
+	}
+
+	go sm.proposalProcessor(context.Background(), sm, proposalCid)
+	return nil
+}
+
+// importDealData adds data to the DAG and checks it is the deal's piece. It
+// returns a deal error if the deal must fail because of the data.
+func (sm *Miner) importDealData(ctx context.Context, deal *storagedeal.Deal, data io.Reader) (*dealError, error) {
+	root, err := sm.porcelainAPI.DAGImportData(ctx, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to import data")
+	}
+	if !root.Cid().Equals(deal.Proposal.PieceRef) {
+		return nil, errors.Errorf("imported data has CID %s but the deal is for piece %s", root.Cid().String(), deal.Proposal.PieceRef.String())
+	}
+
+	// The data is the piece the client proposed, so a mismatch here means the
+	// client generated its payment conditions incorrectly.
+	dagService := dag.NewDAGService(sm.node.BlockService())
+	if err := sm.validatePieceCommitments(ctx, deal, root, dagService); err != nil {
+		err = errors.Wrap(err, "imported data does not match the deal's payment conditions")
+		return permanentDealError("payment error", err), err
+	}
+	return nil, nil
+}
+
+// hasPieceData returns true if the root of a deal's piece is stored locally.
+func (sm *Miner) hasPieceData(d *storagedeal.Deal) bool {
+	has, err := sm.node.BlockService().Blockstore().Has(d.Proposal.PieceRef)
+	if err != nil {
+		log.Errorf("failed to check for piece %s: %s", d.Proposal.PieceRef.String(), err)
+		return false
+	}
+	return has
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/plumbing/dag"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestImportDealData(t *testing.T) {
+	tf.UnitTest(t)
+
+	data := []byte("the contents of a drive shipped to the miner")
+
+	t.Run("imports the data and resumes the deal", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := manualDealTestSetup(t, storagedeal.Started, data)
+		porcelainAPI.deals[proposalCid].Proposal.Payment.Vouchers = nil
+
+		resumed := make(chan cid.Cid, 1)
+		miner.proposalProcessor = func(ctx context.Context, m *Miner, proposalCid cid.Cid) {
+			resumed <- proposalCid
+		}
+
+		require.NoError(t, miner.ImportDealData(context.Background(), proposalCid, bytes.NewReader(data)))
+
+		assert.Equal(t, proposalCid, <-resumed)
+		assert.True(t, miner.hasPieceData(porcelainAPI.deals[proposalCid]))
+	})
+
+	t.Run("rejects data that is not the deal's piece", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := manualDealTestSetup(t, storagedeal.Started, data)
+
+		err := miner.ImportDealData(context.Background(), proposalCid, bytes.NewReader([]byte("something else")))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "imported data has CID")
+		assert.Equal(t, storagedeal.Started, porcelainAPI.deals[proposalCid].Response.State)
+	})
+
+	t.Run("fails the deal if the data does not match its payment conditions", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := manualDealTestSetup(t, storagedeal.Started, data)
+		porcelainAPI.deals[proposalCid].Proposal.Payment.Vouchers[0].Condition = &types.Predicate{To: miner.minerAddr, Method: "notVerifyPieceInclusion"}
+
+		err := miner.ImportDealData(context.Background(), proposalCid, bytes.NewReader(data))
+		require.Error(t, err)
+
+		deal := porcelainAPI.deals[proposalCid]
+		assert.Equal(t, storagedeal.Failed, deal.Response.State)
+		assert.Equal(t, "payment error", deal.Response.Message)
+	})
+
+	t.Run("releases the deal after failing it", func(t *testing.T) {
+		_, miner, proposalCid := manualDealTestSetup(t, storagedeal.Started, data)
+
+		require.Error(t, miner.ImportDealData(context.Background(), proposalCid, bytes.NewReader([]byte("something else"))))
+
+		_, ok := miner.startProcessingDeal(proposalCid)
+		assert.True(t, ok)
+	})
+
+	t.Run("rejects deals that are being processed", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := manualDealTestSetup(t, storagedeal.Started, data)
+		retryNow, ok := miner.startProcessingDeal(proposalCid)
+		require.True(t, ok)
+
+		err := miner.ImportDealData(context.Background(), proposalCid, bytes.NewReader(data))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is already being processed")
+		assert.False(t, miner.hasPieceData(porcelainAPI.deals[proposalCid]))
+
+		miner.stopProcessingDeal(context.Background(), proposalCid, retryNow, nil)
+	})
+
+	t.Run("rejects deals whose data is fetched from the client", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid := manualDealTestSetup(t, storagedeal.Started, data)
+		porcelainAPI.deals[proposalCid].Proposal.Manual = false
+
+		err := miner.ImportDealData(context.Background(), proposalCid, bytes.NewReader(data))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not a manual deal")
+	})
+
+	t.Run("rejects deals that are already staged", func(t *testing.T) {
+		_, miner, proposalCid := manualDealTestSetup(t, storagedeal.Staged, data)
+
+		err := miner.ImportDealData(context.Background(), proposalCid, bytes.NewReader(data))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot import data for deal in state staged")
+	})
+}
+
+func TestProcessManualDeal(t *testing.T) {
+	tf.UnitTest(t)
+
+	porcelainAPI, miner, proposalCid := manualDealTestSetup(t, storagedeal.Accepted, []byte("data"))
+	miner.pieceStager = func(context.Context, *Miner, *storagedeal.Deal) (uint64, error) {
+		require.Fail(t, "deal should not be staged before its data is imported")
+		return 0, nil
+	}
+
+	processStorageDeal(context.Background(), miner, proposalCid)
+
+	deal := porcelainAPI.deals[proposalCid]
+	assert.Equal(t, storagedeal.Started, deal.Response.State)
+	assert.Equal(t, awaitingImportMessage, deal.Response.Message)
+}
+
+// manualDealTestSetup creates a miner that has stored a manual deal in the
+// given state for a piece holding data.
+func manualDealTestSetup(t *testing.T, state storagedeal.State, data []byte) (*minerTestPorcelain, *Miner, cid.Cid) {
+	porcelainAPI, miner, proposalCid := minerWithDealTestSetup(t, state)
+	miner.node = &fakeMinerNode{blockService: porcelainAPI.blockService}
+
+	// import the data elsewhere to learn its cid without storing it with the miner
+	bs := bstore.NewBlockstore(datastore.NewMapDatastore())
+	piece, err := dag.NewDAG(merkledag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))).ImportData(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+
+	proposal := porcelainAPI.deals[proposalCid].Proposal
+	proposal.Manual = true
+	proposal.PieceRef = piece.Cid()
+	proposal.Size = types.NewBytesAmount(uint64(len(data)))
+
+	return porcelainAPI, miner, proposalCid
+}
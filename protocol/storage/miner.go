@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/big"
 	"strconv"
 	"sync"
@@ -15,10 +16,10 @@ import (
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/ipfs/go-ipld-format"
 	logging "github.com/ipfs/go-log"
-	dag "github.com/ipfs/go-merkledag"
 	uio "github.com/ipfs/go-unixfs/io"
 	"github.com/libp2p/go-libp2p-core/host"
 	inet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/protocol"
 	"github.com/pkg/errors"
 
@@ -27,6 +28,9 @@ import (
 	"github.com/filecoin-project/go-filecoin/address"
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/message"
+	"github.com/filecoin-project/go-filecoin/net"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
@@ -42,7 +46,10 @@ var log = logging.Logger("/fil/storage")
 
 const (
 	makeDealProtocol  = protocol.ID("/fil/storage/mk/1.0.0")
-	queryDealProtocol = protocol.ID("/fil/storage/qry/1.0.0")
+	queryDealProtocol = protocol.ID("/fil/storage/qry/1.1.0")
+	// queryDealProtocolV1 is the query protocol of nodes that predate the
+	// transfer progress in query responses. Its responses leave it out.
+	queryDealProtocolV1 = protocol.ID("/fil/storage/qry/1.0.0")
 
 	// TODO: replace this with a queries to pick reasonable gas price and limits.
 	submitPostGasPrice = 1
@@ -64,8 +71,13 @@ type Miner struct {
 
 	dealsAwaitingSealDs repo.Datastore
 
+	// postInProcess is the end of the proving period whose proof is being
+	// computed or sent. postAlertedEnd and postAlerted record the alerts
+	// logged about the period. All are guarded by postInProcessLk.
 	postInProcessLk sync.Mutex
 	postInProcess   *types.BlockHeight
+	postAlertedEnd  *types.BlockHeight
+	postAlerted     int
 
 	dealsAwaitingSeal *dealsAwaitingSeal
 
@@ -76,6 +88,18 @@ type Miner struct {
 	node         node
 
 	proposalProcessor func(context.Context, *Miner, cid.Cid)
+	pieceStager       func(context.Context, *Miner, *storagedeal.Deal) (uint64, error)
+
+	// dealsInProcess maps the proposal cids of deals with a running processor
+	// to a channel used to cut short a retry backoff.
+	dealsInProcessLk sync.Mutex
+	dealsInProcess   map[cid.Cid]chan struct{}
+	dealRetryDelay   time.Duration
+
+	// faults are the unhealthy sectors found by sector health checks that
+	// are still in the proving set.
+	faultsLk sync.Mutex
+	faults   map[uint64]*SectorFault
 }
 
 // minerPorcelain is the subset of the porcelain API that storage.Miner needs.
@@ -86,22 +110,27 @@ type minerPorcelain interface {
 	ChainTipSet(types.TipSetKey) (types.TipSet, error)
 	ConfigGet(dottedPath string) (interface{}, error)
 
+	DAGImportData(context.Context, io.Reader) (format.Node, error)
+
 	DealGet(context.Context, cid.Cid) (*storagedeal.Deal, error)
 	DealPut(*storagedeal.Deal) error
+	DealsLs(context.Context) (<-chan *porcelain.StorageDealLsResult, error)
 
 	ValidatePaymentVoucherCondition(ctx context.Context, condition *types.Predicate, minerAddr address.Address, commP types.CommP, pieceSize *types.BytesAmount) error
 
 	MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageFind(ctx context.Context, msgCid cid.Cid) (*msg.ChainMessage, bool, error)
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey types.TipSetKey, params ...interface{}) ([][]byte, error)
 	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
 	MinerGetWorkerAddress(ctx context.Context, minerAddr address.Address, baseKey types.TipSetKey) (address.Address, error)
+	OutboxQueueLs(sender address.Address) []*message.Queued
 	SectorBuilder() sectorbuilder.SectorBuilder
 	types.Signer
 }
 
 // prover computes PoSts for submission by a miner.
 type prover interface {
-	CalculatePoSt(ctx context.Context, start, end *types.BlockHeight, inputs []PoStInputs) (*PoStSubmission, error)
+	CalculatePoSt(ctx context.Context, start, end *types.BlockHeight, inputs []PoStInputs, faults types.FaultSet) (*PoStSubmission, error)
 }
 
 // node is subset of node on which this protocol depends. These deps
@@ -110,6 +139,7 @@ type prover interface {
 type node interface {
 	BlockService() bserv.BlockService
 	Host() host.Host
+	PieceFetcher() net.PieceFetcher
 }
 
 // NewMiner is for construction of a new storage miner.
@@ -123,6 +153,10 @@ func NewMiner(minerAddr, ownerAddr address.Address, prover prover, sectorSize *t
 		sectorSize:          sectorSize,
 		node:                nd,
 		proposalProcessor:   processStorageDeal,
+		pieceStager:         stageDealPiece,
+		dealsInProcess:      make(map[cid.Cid]chan struct{}),
+		dealRetryDelay:      defaultDealRetryDelay,
+		faults:              make(map[uint64]*SectorFault),
 	}
 
 	if err := sm.loadDealsAwaitingSeal(); err != nil {
@@ -133,6 +167,7 @@ func NewMiner(minerAddr, ownerAddr address.Address, prover prover, sectorSize *t
 
 	nd.Host().SetStreamHandler(makeDealProtocol, sm.handleMakeDeal)
 	nd.Host().SetStreamHandler(queryDealProtocol, sm.handleQueryDeal)
+	nd.Host().SetStreamHandler(queryDealProtocolV1, sm.handleQueryDealV1)
 
 	return sm, nil
 }
@@ -147,7 +182,7 @@ func (sm *Miner) handleMakeDeal(s inet.Stream) {
 	}
 
 	ctx := context.Background()
-	resp, err := sm.receiveStorageProposal(ctx, &signedProposal)
+	resp, err := sm.receiveStorageProposal(ctx, &signedProposal, s.Conn().RemotePeer())
 	if err != nil {
 		log.Errorf("failed to process proposal: %s", err)
 		return
@@ -158,8 +193,9 @@ func (sm *Miner) handleMakeDeal(s inet.Stream) {
 	}
 }
 
-// receiveStorageProposal is the entry point for the miner storage protocol
-func (sm *Miner) receiveStorageProposal(ctx context.Context, sp *storagedeal.SignedProposal) (*storagedeal.SignedResponse, error) {
+// receiveStorageProposal is the entry point for the miner storage protocol. The
+// deal's piece is later pulled from clientPeer.
+func (sm *Miner) receiveStorageProposal(ctx context.Context, sp *storagedeal.SignedProposal, clientPeer peer.ID) (*storagedeal.SignedResponse, error) {
 	// Validate deal signature
 	bdp, err := sp.Proposal.Marshal()
 	if err != nil {
@@ -189,7 +225,7 @@ func (sm *Miner) receiveStorageProposal(ctx context.Context, sp *storagedeal.Sig
 	}
 
 	// Payment is valid, everything else checks out, let's accept this proposal
-	return sm.acceptProposal(ctx, sp)
+	return sm.acceptProposal(ctx, sp, clientPeer)
 }
 
 func (sm *Miner) validateDealPayment(ctx context.Context, p *storagedeal.SignedProposal, price types.AttoFIL) error {
@@ -330,7 +366,7 @@ func (sm *Miner) getPaymentChannel(ctx context.Context, p *storagedeal.SignedPro
 	return channel, nil
 }
 
-func (sm *Miner) acceptProposal(ctx context.Context, p *storagedeal.SignedProposal) (*storagedeal.SignedResponse, error) {
+func (sm *Miner) acceptProposal(ctx context.Context, p *storagedeal.SignedProposal, clientPeer peer.ID) (*storagedeal.SignedResponse, error) {
 	if sm.porcelainAPI.SectorBuilder() == nil {
 		return nil, errors.New("Mining disabled, can not process proposal")
 	}
@@ -348,9 +384,10 @@ func (sm *Miner) acceptProposal(ctx context.Context, p *storagedeal.SignedPropos
 	}
 
 	storageDeal := &storagedeal.Deal{
-		Miner:    sm.minerAddr,
-		Proposal: p,
-		Response: signed,
+		Miner:      sm.minerAddr,
+		Proposal:   p,
+		Response:   signed,
+		ClientPeer: clientPeer,
 	}
 
 	if err := sm.porcelainAPI.DealPut(storageDeal); err != nil {
@@ -394,12 +431,19 @@ func (sm *Miner) rejectProposal(ctx context.Context, p *storagedeal.SignedPropos
 
 // updateDealResponse retrieves a deal, operates on its response with a provided callback then signs the deal and stores it.
 func (sm *Miner) updateDealResponse(ctx context.Context, proposalCid cid.Cid, callback func(*storagedeal.Response)) error {
+	return sm.updateDeal(ctx, proposalCid, func(deal *storagedeal.Deal) {
+		callback(&deal.Response.Response)
+	})
+}
+
+// updateDeal retrieves a deal, operates on it with a provided callback then signs the deal response and stores it.
+func (sm *Miner) updateDeal(ctx context.Context, proposalCid cid.Cid, callback func(*storagedeal.Deal)) error {
 	deal, err := sm.porcelainAPI.DealGet(ctx, proposalCid)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get retrieve deal with proposal CID %s", proposalCid.String())
 	}
 
-	callback(&deal.Response.Response)
+	callback(deal)
 
 	if err := sm.addSignature(ctx, deal.Response); err != nil {
 		return errors.Wrap(err, "could not sign deal response")
@@ -414,99 +458,6 @@ func (sm *Miner) updateDealResponse(ctx context.Context, proposalCid cid.Cid, ca
 	return nil
 }
 
-func processStorageDeal(ctx context.Context, sm *Miner, proposalCid cid.Cid) {
-	log.Debugf("Miner.processStorageDeal(%s)", proposalCid.String())
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	d, err := sm.porcelainAPI.DealGet(ctx, proposalCid)
-	if err != nil {
-		log.Errorf("could not retrieve deal with proposal CID %s: %s", proposalCid.String(), err)
-	}
-	if d.Response.State != storagedeal.Accepted {
-		// TODO: handle resumption of deal processing across miner restarts
-		log.Error("attempted to process an already started deal")
-		return
-	}
-
-	// 'Receive' the data, this could also be a truck full of hard drives. (TODO: proper abstraction)
-	// TODO: this is not a great way to do this. At least use a session
-	// Also, this needs to be fetched into a staging area for miners to prepare and seal in data
-	log.Debug("Miner.processStorageDeal - FetchGraph")
-	if err := dag.FetchGraph(ctx, d.Proposal.PieceRef, dag.NewDAGService(sm.node.BlockService())); err != nil {
-		log.Errorf("failed to fetch data: %s", err)
-		err := sm.updateDealResponse(ctx, proposalCid, func(resp *storagedeal.Response) {
-			resp.Message = "Transfer failed"
-			resp.State = storagedeal.Failed
-		})
-		if err != nil {
-			log.Errorf("could not update to deal to 'Failed' state: %s", err)
-		}
-		return
-	}
-
-	fail := func(message, logerr string) {
-		log.Errorf(logerr)
-		err := sm.updateDealResponse(ctx, proposalCid, func(resp *storagedeal.Response) {
-			resp.Message = message
-			resp.State = storagedeal.Failed
-		})
-		if err != nil {
-			log.Errorf("could not update to deal to 'Failed' state in fail callback: %s", err)
-		}
-	}
-
-	dagService := dag.NewDAGService(sm.node.BlockService())
-
-	rootIpldNode, err := dagService.Get(ctx, d.Proposal.PieceRef)
-	if err != nil {
-		fail("internal error", fmt.Sprintf("failed to add piece: %s", err))
-		return
-	}
-
-	// Before adding piece, confirm that client has generated payment conditions correctly now that
-	// we can compute CommP
-	if err := sm.validatePieceCommitments(ctx, d, rootIpldNode, dagService); err != nil {
-		fail("payment error", fmt.Sprintf("failed to add piece: %s", err))
-		return
-	}
-
-	r, err := uio.NewDagReader(ctx, rootIpldNode, dagService)
-	if err != nil {
-		fail("internal error", fmt.Sprintf("failed to add piece: %s", err))
-		return
-	}
-
-	// There is a race here that requires us to use dealsAwaitingSeal below. If the
-	// sector gets sealed and OnCommitmentSent is called right after
-	// AddPiece returns but before we record the sector/deal mapping we might
-	// miss it. Hence, dealsAwaitingSeal. I'm told that sealing in practice is
-	// so slow that the race only exists in tests, but tests were flaky so
-	// we fixed it with dealsAwaitingSeal.
-	//
-	// Also, this pattern of not being able to set up book-keeping ahead of
-	// the call is inelegant.
-	sectorID, err := sm.porcelainAPI.SectorBuilder().AddPiece(ctx, d.Proposal.PieceRef, d.Proposal.Size.Uint64(), r)
-	if err != nil {
-		fail("failed to submit seal proof", fmt.Sprintf("failed to add piece: %s", err))
-		return
-	}
-
-	err = sm.updateDealResponse(ctx, proposalCid, func(resp *storagedeal.Response) {
-		resp.State = storagedeal.Staged
-	})
-	if err != nil {
-		log.Errorf("could update to 'Staged': %s", err)
-	}
-
-	// Careful: this might update state to success or failure so it should go after
-	// updating state to Staged.
-	sm.dealsAwaitingSeal.attachDealToSector(ctx, sectorID, proposalCid)
-	if err := sm.saveDealsAwaitingSeal(); err != nil {
-		log.Errorf("could not save deal awaiting seal: %s", err)
-	}
-}
-
 func (sm *Miner) validatePieceCommitments(ctx context.Context, deal *storagedeal.Deal, rootIpldNode format.Node, serv format.NodeGetter) error {
 	pieceReader, err := uio.NewDagReader(ctx, rootIpldNode, serv)
 	if err != nil {
@@ -634,9 +585,11 @@ func (sm *Miner) findPieceInfo(ctx context.Context, dealCid cid.Cid, sector *sec
 }
 
 func (sm *Miner) onCommitFail(ctx context.Context, dealCid cid.Cid, message string) {
-	err := sm.updateDealResponse(ctx, dealCid, func(resp *storagedeal.Response) {
-		resp.Message = message
-		resp.State = storagedeal.Failed
+	// The deal's sector failed, so a retry must stage the piece again.
+	err := sm.updateDeal(ctx, dealCid, func(deal *storagedeal.Deal) {
+		deal.SectorID = 0
+		deal.Response.Message = message
+		deal.Response.State = storagedeal.Failed
 	})
 	log.Errorf("commit failure but could not update to deal 'Failed' state: %s", err)
 }
@@ -707,18 +660,51 @@ func (sm *Miner) getActorSectorCommitments(ctx context.Context) (map[string]type
 func (sm *Miner) Query(ctx context.Context, c cid.Cid) *storagedeal.SignedResponse {
 	deal, err := sm.porcelainAPI.DealGet(ctx, c)
 	if err != nil {
-		return &storagedeal.SignedResponse{
-			Response: storagedeal.Response{
-				State:   storagedeal.Unknown,
-				Message: "no such deal",
-			},
-		}
+		return noSuchDealResponse()
+	}
+	if deal.BytesTransferred == 0 {
+		return deal.Response
 	}
 
+	// The stored response is signed without the transfer progress, which
+	// changes as the piece is transferred, so it is signed here.
+	resp := &storagedeal.SignedResponse{Response: deal.Response.Response}
+	resp.BytesTransferred = deal.BytesTransferred
+	if err := sm.addSignature(ctx, resp); err != nil {
+		log.Warningf("failed to sign transfer progress of deal %s: %s", c.String(), err)
+		return deal.Response
+	}
+	return resp
+}
+
+// queryV1 responds to a query with the stored response of a deal, which does
+// not include the transfer progress.
+func (sm *Miner) queryV1(ctx context.Context, c cid.Cid) *storagedeal.SignedResponse {
+	deal, err := sm.porcelainAPI.DealGet(ctx, c)
+	if err != nil {
+		return noSuchDealResponse()
+	}
 	return deal.Response
 }
 
+func noSuchDealResponse() *storagedeal.SignedResponse {
+	return &storagedeal.SignedResponse{
+		Response: storagedeal.Response{
+			State:   storagedeal.Unknown,
+			Message: "no such deal",
+		},
+	}
+}
+
 func (sm *Miner) handleQueryDeal(s inet.Stream) {
+	sm.serveQuery(s, sm.Query)
+}
+
+func (sm *Miner) handleQueryDealV1(s inet.Stream) {
+	sm.serveQuery(s, sm.queryV1)
+}
+
+func (sm *Miner) serveQuery(s inet.Stream, query func(context.Context, cid.Cid) *storagedeal.SignedResponse) {
 	defer s.Close() // nolint: errcheck
 
 	ctx := context.Background()
@@ -729,7 +715,7 @@ func (sm *Miner) handleQueryDeal(s inet.Stream) {
 		return
 	}
 
-	resp := sm.Query(ctx, q.Cid)
+	resp := query(ctx, q.Cid)
 
 	if err := cbu.NewMsgWriter(s).WriteMsg(resp); err != nil {
 		log.Errorf("failed to write query response: %s", err)
@@ -738,7 +724,8 @@ func (sm *Miner) handleQueryDeal(s inet.Stream) {
 
 // OnNewHeaviestTipSet is a callback called by node, every time the the latest
 // head is updated. It is used to check if we are in a new proving period and
-// need to trigger PoSt submission.
+// need to trigger PoSt submission, or if a PoSt that failed or was not mined
+// needs to be sent again.
 // If a PoSt computation is started as a result of this new tipset, the returned latch is held until
 // the computation completes.
 func (sm *Miner) OnNewHeaviestTipSet(ts types.TipSet) (*moresync.Latch, error) {
@@ -805,23 +792,34 @@ func (sm *Miner) OnNewHeaviestTipSet(ts types.TipSet) (*moresync.Latch, error) {
 	// the block height of the new heaviest tipset
 	h := types.NewBlockHeight(height)
 
-	if h.GreaterEqual(provingWindowStart.Add(types.NewBlockHeight(challengeDelayRounds))) {
-		if h.LessThan(provingWindowEnd) {
-			// we are in a new proving period, lets get this post going
-			sm.postInProcess = provingWindowEnd
-			postLatch := moresync.NewLatch(1)
-			go func() {
-				sm.submitPoSt(ctx, provingWindowStart, provingWindowEnd, inputs)
-				postLatch.Done()
-			}()
-			return postLatch, nil
-		}
+	status, err := sm.postStatusFor(provingWindowStart, provingWindowEnd)
+	if err != nil {
+		return doneLatch, errors.Errorf("failed to get PoSt status: %s", err)
+	}
+	if err := sm.checkPoStMessages(ctx, status); err != nil {
+		log.Errorf("failed to check PoSt messages: %s", err)
+	}
+
+	deadline := sm.postDeadline(provingWindowEnd)
+	sm.reportPoStProgress(ctx, status, h, deadline)
+	if h.GreaterEqual(deadline) {
 		// we are too late
 		// TODO: figure out faults and payments here #3406
-		return doneLatch, errors.Errorf("too late start=%s  end=%s current=%s", provingWindowStart, provingWindowEnd, h)
+		return doneLatch, errors.Errorf("too late start=%s  end=%s deadline=%s current=%s", provingWindowStart, provingWindowEnd, deadline, h)
 	}
 
-	return doneLatch, nil
+	if !sm.postDue(status, h) {
+		return doneLatch, nil
+	}
+
+	// compute or resend the proof of this period
+	sm.postInProcess = provingWindowEnd
+	postLatch := moresync.NewLatch(1)
+	go func() {
+		sm.provePeriod(ctx, status, h, inputs)
+		postLatch.Done()
+	}()
+	return postLatch, nil
 }
 
 func (sm *Miner) getProvingWindow() (*types.BlockHeight, *types.BlockHeight, error) {
@@ -839,31 +837,6 @@ func (sm *Miner) getProvingWindow() (*types.BlockHeight, *types.BlockHeight, err
 	return types.NewBlockHeightFromBytes(res[0]), types.NewBlockHeightFromBytes(res[1]), nil
 }
 
-func (sm *Miner) submitPoSt(ctx context.Context, start, end *types.BlockHeight, inputs []PoStInputs) {
-	submission, err := sm.prover.CalculatePoSt(ctx, start, end, inputs)
-	if err != nil {
-		log.Errorf("failed to calculate PoSt: %s", err)
-		return
-	}
-	// TODO #2998. The done set should be updated by CLI users.
-	// Using the 0 value is just a placeholder until that work lands.
-	done := types.EmptyIntSet()
-
-	gasPrice := types.NewGasPrice(submitPostGasPrice)
-	workerAddr, err := sm.porcelainAPI.MinerGetWorkerAddress(ctx, sm.minerAddr, sm.porcelainAPI.ChainHeadKey())
-	if err != nil {
-		log.Errorf("failed to get worker address: %s", err)
-		return
-	}
-	_, err = sm.porcelainAPI.MessageSend(ctx, workerAddr, sm.minerAddr, submission.Fee, gasPrice, submission.GasLimit, "submitPoSt", submission.Proof, submission.Faults, done)
-	if err != nil {
-		log.Errorf("failed to submit PoSt: %s", err)
-		return
-	}
-
-	log.Info("submitted PoSt")
-}
-
 func (sm *Miner) signResponse(ctx context.Context, response storagedeal.Response) (*storagedeal.SignedResponse, error) {
 	signed := storagedeal.SignedResponse{Response: response}
 	err := sm.addSignature(ctx, &signed)
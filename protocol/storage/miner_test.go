@@ -5,20 +5,31 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"testing"
 
+	bserv "github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/message"
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
+	"github.com/filecoin-project/go-filecoin/plumbing/dag"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
 	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
@@ -43,7 +54,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		vouchers := testPaymentVouchers(porcelainAPI, VoucherInterval, defaultAmountInc)
 		proposal := testSignedDealProposal(porcelainAPI, vouchers, defaultPieceSize)
 
-		_, err := miner.receiveStorageProposal(context.Background(), proposal)
+		_, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		// one deal should be stored and it should have been accepted
@@ -66,7 +77,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		// do not create payment info
 		proposal := testSignedDealProposal(porcelainAPI, nil, defaultPieceSize)
 
-		_, err := miner.receiveStorageProposal(context.Background(), proposal)
+		_, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		// one deal should be stored and it should have been accepted
@@ -80,7 +91,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 	t.Run("Accepted proposals have signed responses", func(t *testing.T) {
 		porcelainAPI, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
 
-		_, err := miner.receiveStorageProposal(context.Background(), proposal)
+		_, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		// one deal should be stored and it should have been accepted and signed
@@ -101,7 +112,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		// configure storage price
 		assert.NoError(t, porcelainAPI.config.Set("mining.storagePrice", `".0005"`))
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -114,7 +125,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		// configure storage price
 		assert.NoError(t, porcelainAPI.config.Set("mining.storagePrice", `".0005"`))
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		valid, err := res.VerifySignature(porcelainAPI.workerAddress)
@@ -127,7 +138,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 
 		porcelainAPI.noChannels = true
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -139,7 +150,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 
 		miner.ownerAddr = address.TestAddress
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -150,7 +161,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		porcelainAPI, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
 		porcelainAPI.channelEol = types.NewBlockHeight(1200)
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -161,7 +172,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		porcelainAPI, miner, _ := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
 		proposal := testSignedDealProposal(porcelainAPI, []*types.PaymentVoucher{}, defaultPieceSize)
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -175,7 +186,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		invalidSigVouchers[0].Signature = types.Signature([]byte{})
 		proposal := testSignedDealProposal(porcelainAPI, invalidSigVouchers, defaultPieceSize)
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -189,7 +200,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		miner, _ := newMinerTestSetup(porcelainAPI, VoucherInterval, defaultAmountInc)
 		proposal := testSignedDealProposal(porcelainAPI, testPaymentVouchers(porcelainAPI, VoucherInterval, defaultAmountInc), defaultPieceSize)
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -202,7 +213,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		porcelainAPI.paymentStart = porcelainAPI.paymentStart.Sub(types.NewBlockHeight(15))
 		proposal := testSignedDealProposal(porcelainAPI, testPaymentVouchers(porcelainAPI, VoucherInterval+15, defaultAmountInc), defaultPieceSize)
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -214,7 +225,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 
 		proposal := testSignedDealProposal(porcelainAPI, testPaymentVouchers(porcelainAPI, VoucherInterval, 1), defaultPieceSize)
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -225,7 +236,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		_, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
 		proposal.Signature = []byte{'0', '0', '0'}
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -238,7 +249,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		vouchers := testPaymentVouchers(porcelainAPI, VoucherInterval, 2*defaultAmountInc)
 		proposal := testSignedDealProposal(porcelainAPI, vouchers, 2*defaultPieceSize)
 
-		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		res, err := miner.receiveStorageProposal(context.Background(), proposal, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, storagedeal.Rejected, res.State)
@@ -511,7 +522,7 @@ func TestOnNewHeaviestTipSet(t *testing.T) {
 		done.Wait()
 	})
 
-	t.Run("Errors if past proving period", func(t *testing.T) {
+	t.Run("Errors if past the PoSt deadline", func(t *testing.T) {
 		// create new miner with deal in the accepted state and mapped to a sector
 		api, miner, _ := minerWithAcceptedDealTestSetup(t, proposalCid, sector.SectorID)
 
@@ -525,7 +536,7 @@ func TestOnNewHeaviestTipSet(t *testing.T) {
 		}
 		api.messageHandlers = handlers
 
-		height := uint64(400)
+		height := uint64(400) + minerActor.LatePoStGracePeriod(types.OneKiBSectorSize).AsBigInt().Uint64()
 		api.blockHeight = height
 		block := &types.Block{Height: types.Uint64(height)}
 		ts, err := types.NewTipSet(block)
@@ -588,6 +599,10 @@ type minerTestPorcelain struct {
 	deals           map[cid.Cid]*storagedeal.Deal
 	walletBalance   types.AttoFIL
 	messageHandlers map[string]func(address.Address, types.AttoFIL, ...interface{}) ([][]byte, error)
+	blockService    bserv.BlockService
+	sectorBuilder   sectorbuilder.SectorBuilder
+	outbox          map[address.Address][]*message.Queued
+	receipts        map[cid.Cid]*types.MessageReceipt
 
 	testing *testing.T
 }
@@ -611,6 +626,8 @@ func newMinerTestPorcelain(t *testing.T, minerPriceString string) *minerTestPorc
 	config := cfg.NewConfig(repo.NewInMemoryRepo())
 	require.NoError(t, config.Set("mining.storagePrice", fmt.Sprintf("%q", minerPriceString)))
 
+	bs := bstore.NewBlockstore(datastore.NewMapDatastore())
+
 	blockHeight := uint64(773)
 	return &minerTestPorcelain{
 		config:          config,
@@ -627,6 +644,9 @@ func newMinerTestPorcelain(t *testing.T, minerPriceString string) *minerTestPorc
 		deals:           make(map[cid.Cid]*storagedeal.Deal),
 		walletBalance:   types.NewAttoFILFromFIL(100),
 		messageHandlers: messageHandlerMap{},
+		blockService:    bserv.New(bs, offline.Exchange(bs)),
+		outbox:          make(map[address.Address][]*message.Queued),
+		receipts:        make(map[cid.Cid]*types.MessageReceipt),
 
 		testing: t,
 	}
@@ -644,9 +664,9 @@ func (mtp *minerTestPorcelain) MessageSend(ctx context.Context, from, to address
 	handler, ok := mtp.messageHandlers[method]
 	if ok {
 		_, err := handler(to, val, params...)
-		return cid.Cid{}, err
+		return *mtp.messageCid, err
 	}
-	return cid.Cid{}, nil
+	return *mtp.messageCid, nil
 }
 
 func (mtp *minerTestPorcelain) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ types.TipSetKey, params ...interface{}) ([][]byte, error) {
@@ -660,6 +680,18 @@ func (mtp *minerTestPorcelain) MessageQuery(ctx context.Context, optFrom, to add
 	return mtp.messageQueryPaymentBrokerLs()
 }
 
+func (mtp *minerTestPorcelain) MessageFind(_ context.Context, msgCid cid.Cid) (*msg.ChainMessage, bool, error) {
+	receipt, ok := mtp.receipts[msgCid]
+	if !ok {
+		return nil, false, nil
+	}
+	return &msg.ChainMessage{Receipt: receipt}, true, nil
+}
+
+func (mtp *minerTestPorcelain) OutboxQueueLs(sender address.Address) []*message.Queued {
+	return mtp.outbox[sender]
+}
+
 func (mtp *minerTestPorcelain) MinerGetWorkerAddress(_ context.Context, _ address.Address, _ types.TipSetKey) (address.Address, error) {
 	return mtp.workerAddress, nil
 }
@@ -691,6 +723,10 @@ func (mtp *minerTestPorcelain) ConfigGet(dottedPath string) (interface{}, error)
 	return mtp.config.Get(dottedPath)
 }
 
+func (mtp *minerTestPorcelain) DAGImportData(ctx context.Context, data io.Reader) (ipld.Node, error) {
+	return dag.NewDAG(merkledag.NewDAGService(mtp.blockService)).ImportData(ctx, data)
+}
+
 func (mtp *minerTestPorcelain) ChainHeadKey() types.TipSetKey {
 	return types.NewTipSetKey()
 }
@@ -735,6 +771,7 @@ func newTestMiner(api *minerTestPorcelain) *Miner {
 		prover:            &FakeProver{},
 		sectorSize:        types.OneKiBSectorSize,
 		proposalProcessor: func(ctx context.Context, m *Miner, cid cid.Cid) {},
+		faults:            make(map[uint64]*SectorFault),
 	}
 }
 
@@ -866,6 +903,18 @@ func (mtp *minerTestPorcelain) DealPut(storageDeal *storagedeal.Deal) error {
 	return nil
 }
 
+func (mtp *minerTestPorcelain) DealsLs(_ context.Context) (<-chan *porcelain.StorageDealLsResult, error) {
+	dealCh := make(chan *porcelain.StorageDealLsResult, len(mtp.deals))
+	for _, storageDeal := range mtp.deals {
+		dealCh <- &porcelain.StorageDealLsResult{Deal: *storageDeal}
+	}
+	close(dealCh)
+	return dealCh, nil
+}
+
 func (mtp *minerTestPorcelain) SectorBuilder() sectorbuilder.SectorBuilder {
+	if mtp.sectorBuilder != nil {
+		return mtp.sectorBuilder
+	}
 	return &sectorbuilder.RustSectorBuilder{}
 }
@@ -0,0 +1,382 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/metrics"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func init() {
+	cbor.RegisterCborType(PoStStatus{})
+	cbor.RegisterCborType(PoStMessage{})
+	cbor.RegisterCborType(PoStSubmission{})
+}
+
+const (
+	postStatusDatastorePrefix = "postStatus"
+
+	// Minimum number of rounds after a PoSt message was sent before it is sent
+	// again when it is neither queued nor found on chain. It gives the message
+	// index time to catch up with a chain the message was just mined in.
+	postResubmitDelayRounds = 5
+
+	// Factor the gas price of a PoSt message is multiplied by each time it is
+	// sent again.
+	postGasPriceIncrease = 2
+
+	// Number of rounds before the PoSt deadline at which a missing PoSt is
+	// reported as an error rather than a warning.
+	postDeadlineAlertRounds = 50
+)
+
+var (
+	mPoStRoundsToDeadline = metrics.NewInt64Gauge("storage/post_rounds_to_deadline", "Rounds left before a PoSt for the current proving period is rejected")
+	mPoStSubmissions      = metrics.NewInt64Counter("storage/post_submissions", "Number of PoSt messages sent, including resubmissions")
+	mPoStResubmissions    = metrics.NewInt64Counter("storage/post_resubmissions", "Number of PoSt messages sent again because they were dropped without being mined")
+	mPoStFailures         = metrics.NewInt64Counter("storage/post_failures", "Number of failed PoSt computations and submissions")
+)
+
+// PoStStage is how far the miner got proving a proving period.
+type PoStStage int
+
+const (
+	// PoStPending means the challenge window has not opened yet.
+	PoStPending PoStStage = iota
+	// PoStChallengeSampled means the challenge seed was sampled and the proof
+	// is being computed.
+	PoStChallengeSampled
+	// PoStComputed means the proof is ready to be sent.
+	PoStComputed
+	// PoStSubmitted means a submitPoSt message was sent but is not known to
+	// be mined.
+	PoStSubmitted
+	// PoStConfirmed means a submitPoSt message was mined successfully, or the
+	// proving period moved on.
+	PoStConfirmed
+)
+
+func (s PoStStage) String() string {
+	switch s {
+	case PoStPending:
+		return "pending"
+	case PoStChallengeSampled:
+		return "challenge sampled"
+	case PoStComputed:
+		return "computed"
+	case PoStSubmitted:
+		return "submitted"
+	case PoStConfirmed:
+		return "confirmed"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the stage as its name.
+func (s PoStStage) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a stage from its name.
+func (s *PoStStage) UnmarshalText(text []byte) error {
+	for _, stage := range []PoStStage{PoStPending, PoStChallengeSampled, PoStComputed, PoStSubmitted, PoStConfirmed} {
+		if stage.String() == string(text) {
+			*s = stage
+			return nil
+		}
+	}
+	return errors.Errorf("unknown PoSt stage %q", text)
+}
+
+// PoStMessage is a submitPoSt message sent by the miner.
+type PoStMessage struct {
+	Cid cid.Cid
+	// From is the worker address the message was sent from.
+	From     address.Address
+	GasPrice types.AttoFIL
+	SentAt   *types.BlockHeight
+	// Receipt is the receipt of the message, nil until it is found on chain.
+	Receipt *types.MessageReceipt
+}
+
+// PoStStatus is the progress of the PoSt of a proving period. It is persisted
+// so a restarted miner resumes where it stopped.
+type PoStStatus struct {
+	Start *types.BlockHeight
+	End   *types.BlockHeight
+	Stage PoStStage
+	// Submission is the computed proof, nil until the stage is PoStComputed.
+	Submission *PoStSubmission
+	// Messages are the submitPoSt messages sent, the latest last.
+	Messages []PoStMessage
+	// Error is the last failure to compute or send the proof.
+	Error string
+}
+
+// PoStStatus returns the progress of the PoSt of the latest proving period,
+// nil if the miner has not started proving one.
+func (sm *Miner) PoStStatus() (*PoStStatus, error) {
+	return sm.loadPoStStatus()
+}
+
+// postStatusFor returns the status of the proving period ending at end. A
+// stored status of an earlier period is marked confirmed, as the period only
+// moves on once its PoSt is accepted.
+func (sm *Miner) postStatusFor(start, end *types.BlockHeight) (*PoStStatus, error) {
+	status, err := sm.loadPoStStatus()
+	if err != nil {
+		return nil, err
+	}
+	if status != nil && status.End.Equal(end) {
+		return status, nil
+	}
+
+	if status != nil && status.Stage != PoStConfirmed {
+		if status.Stage == PoStSubmitted {
+			log.Infof("PoSt for proving period ending %s was mined", status.End)
+		} else {
+			log.Warningf("proving period ending %s moved on without a PoSt from this miner", status.End)
+		}
+		status.Stage = PoStConfirmed
+		status.Error = ""
+		if err := sm.savePoStStatus(status); err != nil {
+			return nil, err
+		}
+	}
+	return &PoStStatus{Start: start, End: end, Stage: PoStPending}, nil
+}
+
+// checkPoStMessages looks up the receipts of the submitPoSt messages sent for
+// a submitted proof. A successful message confirms the proof. When the latest
+// message failed the proof is computed and sent again, since the late fee or
+// faults it was sent with may be out of date.
+func (sm *Miner) checkPoStMessages(ctx context.Context, status *PoStStatus) error {
+	if status.Stage != PoStSubmitted {
+		return nil
+	}
+
+	found := false
+	for i := range status.Messages {
+		msg := &status.Messages[i]
+		if msg.Receipt != nil {
+			continue
+		}
+		chainMsg, ok, err := sm.porcelainAPI.MessageFind(ctx, msg.Cid)
+		if err != nil {
+			return errors.Wrapf(err, "failed to look up PoSt message %s", msg.Cid)
+		}
+		if !ok {
+			continue
+		}
+		msg.Receipt = chainMsg.Receipt
+		found = true
+		if msg.Receipt.ExitCode == 0 {
+			log.Infof("PoSt for proving period ending %s was mined in message %s", status.End, msg.Cid)
+			status.Stage = PoStConfirmed
+			status.Error = ""
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	last := status.Messages[len(status.Messages)-1]
+	if status.Stage == PoStSubmitted && last.Receipt != nil {
+		status.Error = fmt.Sprintf("PoSt message %s failed with exit code %d", last.Cid, last.Receipt.ExitCode)
+		log.Errorf("%s, computing the proof again", status.Error)
+		mPoStFailures.Inc(ctx, 1)
+		status.Stage = PoStChallengeSampled
+	}
+	return sm.savePoStStatus(status)
+}
+
+// postDue returns true if the proof of the period should be computed or sent
+// at height h.
+func (sm *Miner) postDue(status *PoStStatus, h *types.BlockHeight) bool {
+	switch status.Stage {
+	case PoStConfirmed:
+		return false
+	case PoStSubmitted:
+		last := status.Messages[len(status.Messages)-1]
+		return h.GreaterEqual(last.SentAt.Add(types.NewBlockHeight(postResubmitDelayRounds))) && !sm.postMessageQueued(last)
+	default:
+		return h.GreaterEqual(status.Start.Add(types.NewBlockHeight(challengeDelayRounds)))
+	}
+}
+
+// postMessageQueued returns true if a PoSt message is still in the outbound
+// queue of its sender, waiting to be mined. The proof is only sent again once
+// the message left the queue without being mined: a message sent while it is
+// queued would take the next nonce and could not be mined before it.
+func (sm *Miner) postMessageQueued(msg PoStMessage) bool {
+	for _, queued := range sm.porcelainAPI.OutboxQueueLs(msg.From) {
+		c, err := queued.Msg.Cid()
+		if err == nil && c.Equals(msg.Cid) {
+			return true
+		}
+	}
+	return false
+}
+
+// provePeriod computes the proof of the period if it is not computed yet and
+// sends it, recording its progress as it goes. Failures are recorded and
+// retried on a later tipset.
+func (sm *Miner) provePeriod(ctx context.Context, status *PoStStatus, h *types.BlockHeight, inputs []PoStInputs) {
+	defer func() {
+		sm.postInProcessLk.Lock()
+		defer sm.postInProcessLk.Unlock()
+		sm.postInProcess = nil
+	}()
+
+	if status.Stage == PoStSubmitted {
+		log.Warningf("PoSt message %s was dropped without being mined, sending it again", status.Messages[len(status.Messages)-1].Cid)
+		mPoStResubmissions.Inc(ctx, 1)
+		if h.Add(types.NewBlockHeight(postSubmissionDelayBufferRounds)).GreaterEqual(status.End) {
+			// The proof may be mined after the period ended, so its late fee
+			// has to be calculated again.
+			status.Stage = PoStChallengeSampled
+		}
+	}
+
+	if status.Stage < PoStComputed {
+		status.Stage = PoStChallengeSampled
+		if err := sm.savePoStStatus(status); err != nil {
+			log.Errorf("failed to save PoSt status: %s", err)
+		}
+
+		submission, err := sm.prover.CalculatePoSt(ctx, status.Start, status.End, inputs, sm.postFaults(inputs))
+		if err != nil {
+			sm.postFailed(ctx, status, errors.Wrap(err, "failed to calculate PoSt"))
+			return
+		}
+		status.Submission = submission
+		status.Stage = PoStComputed
+		status.Error = ""
+		if err := sm.savePoStStatus(status); err != nil {
+			log.Errorf("failed to save PoSt status: %s", err)
+		}
+	}
+
+	// TODO #2998. The done set should be updated by CLI users.
+	// Using the 0 value is just a placeholder until that work lands.
+	done := types.EmptyIntSet()
+
+	gasPrice := nextPoStGasPrice(status)
+	workerAddr, err := sm.porcelainAPI.MinerGetWorkerAddress(ctx, sm.minerAddr, sm.porcelainAPI.ChainHeadKey())
+	if err != nil {
+		sm.postFailed(ctx, status, errors.Wrap(err, "failed to get worker address"))
+		return
+	}
+	submission := status.Submission
+	msgCid, err := sm.porcelainAPI.MessageSend(ctx, workerAddr, sm.minerAddr, submission.Fee, gasPrice, submission.GasLimit, "submitPoSt", submission.Proof, submission.Faults, done)
+	if err != nil {
+		sm.postFailed(ctx, status, errors.Wrap(err, "failed to submit PoSt"))
+		return
+	}
+
+	status.Stage = PoStSubmitted
+	status.Messages = append(status.Messages, PoStMessage{Cid: msgCid, From: workerAddr, GasPrice: gasPrice, SentAt: h})
+	status.Error = ""
+	if err := sm.savePoStStatus(status); err != nil {
+		log.Errorf("failed to save PoSt status: %s", err)
+	}
+	mPoStSubmissions.Inc(ctx, 1)
+
+	log.Infof("submitted PoSt for proving period ending %s in message %s", status.End, msgCid)
+}
+
+// nextPoStGasPrice returns the gas price of the next message sending the
+// proof, which is higher than the price of any message sent before.
+func nextPoStGasPrice(status *PoStStatus) types.AttoFIL {
+	if len(status.Messages) == 0 {
+		return types.NewGasPrice(submitPostGasPrice)
+	}
+	return status.Messages[len(status.Messages)-1].GasPrice.MulBigInt(big.NewInt(postGasPriceIncrease))
+}
+
+func (sm *Miner) postFailed(ctx context.Context, status *PoStStatus, err error) {
+	log.Errorf("%s, retrying on the next tipset", err)
+	mPoStFailures.Inc(ctx, 1)
+	status.Error = err.Error()
+	if err := sm.savePoStStatus(status); err != nil {
+		log.Errorf("failed to save PoSt status: %s", err)
+	}
+}
+
+// reportPoStProgress updates the PoSt metrics and alerts when the period
+// ended before a PoSt was mined. Each alert is logged once per period.
+func (sm *Miner) reportPoStProgress(ctx context.Context, status *PoStStatus, h, deadline *types.BlockHeight) {
+	if h.GreaterEqual(deadline) {
+		mPoStRoundsToDeadline.Set(ctx, 0)
+	} else {
+		mPoStRoundsToDeadline.Set(ctx, deadline.Sub(h).AsBigInt().Int64())
+	}
+
+	if sm.postAlertedEnd == nil || !sm.postAlertedEnd.Equal(status.End) {
+		sm.postAlertedEnd = status.End
+		sm.postAlerted = postAlertNone
+	}
+
+	switch {
+	case h.LessThan(status.End):
+		return
+	case h.Add(types.NewBlockHeight(postDeadlineAlertRounds)).GreaterEqual(deadline):
+		if sm.postAlerted < postAlertDeadline {
+			log.Errorf("no PoSt mined for proving period ending %s (%s), it will be rejected in %s rounds at %s", status.End, status.Stage, deadline.Sub(h), deadline)
+			sm.postAlerted = postAlertDeadline
+		}
+	default:
+		if sm.postAlerted < postAlertLate {
+			log.Warningf("no PoSt mined for proving period ending %s (%s), late fees are due until the deadline at %s", status.End, status.Stage, deadline)
+			sm.postAlerted = postAlertLate
+		}
+	}
+}
+
+// postDeadline returns the height from which a PoSt for the period ending at
+// end is rejected.
+func (sm *Miner) postDeadline(end *types.BlockHeight) *types.BlockHeight {
+	return end.Add(miner.LatePoStGracePeriod(sm.sectorSize))
+}
+
+const (
+	postAlertNone = iota
+	postAlertLate
+	postAlertDeadline
+)
+
+func (sm *Miner) loadPoStStatus() (*PoStStatus, error) {
+	key := datastore.KeyWithNamespaces([]string{postStatusDatastorePrefix})
+	data, err := sm.dealsAwaitingSealDs.Get(key)
+	if err == datastore.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read PoSt status")
+	}
+
+	var status PoStStatus
+	if err := cbor.DecodeInto(data, &status); err != nil {
+		return nil, errors.Wrap(err, "failed to decode PoSt status")
+	}
+	return &status, nil
+}
+
+func (sm *Miner) savePoStStatus(status *PoStStatus) error {
+	data, err := cbor.DumpObject(status)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode PoSt status")
+	}
+	key := datastore.KeyWithNamespaces([]string{postStatusDatastorePrefix})
+	return errors.Wrap(sm.dealsAwaitingSealDs.Put(key, data), "failed to write PoSt status")
+}
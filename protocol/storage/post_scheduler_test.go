@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/message"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestPoStScheduler(t *testing.T) {
+	tf.UnitTest(t)
+
+	cidGetter := types.NewCidForTestGetter()
+	proposalCid := cidGetter()
+	sector := testSectorMetadata(proposalCid)
+
+	setup := func(t *testing.T) (*minerTestPorcelain, *Miner, *int) {
+		api, miner, _ := minerWithAcceptedDealTestSetup(t, proposalCid, sector.SectorID)
+
+		submissions := 0
+		api.messageHandlers = successMessageHandlers(t)
+		api.messageHandlers["getProvingWindow"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			return mustEncodeResults(t, types.NewBlockHeight(200), types.NewBlockHeight(400)), nil
+		}
+		api.messageHandlers["submitPoSt"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			// queue the message in the worker's outbox, where it stays until
+			// the test mines or drops it
+			msg := types.NewMessage(api.workerAddress, a, uint64(submissions), v, "submitPoSt", nil)
+			signed, err := types.NewSignedMessage(*msg, api.signer, types.NewGasPrice(1), types.NewGasUnits(300))
+			require.NoError(t, err)
+			msgCid, err := signed.Cid()
+			require.NoError(t, err)
+			api.messageCid = &msgCid
+			api.outbox[api.workerAddress] = append(api.outbox[api.workerAddress], &message.Queued{Msg: signed, Stamp: api.blockHeight})
+			submissions++
+			return [][]byte{}, nil
+		}
+		return api, miner, &submissions
+	}
+
+	// mine takes the latest PoSt message out of the outbox and gives it a
+	// receipt with the exit code.
+	mine := func(t *testing.T, api *minerTestPorcelain, miner *Miner, exitCode uint8) cid.Cid {
+		status, err := miner.PoStStatus()
+		require.NoError(t, err)
+		msgCid := status.Messages[len(status.Messages)-1].Cid
+		api.outbox[api.workerAddress] = nil
+		api.receipts[msgCid] = &types.MessageReceipt{ExitCode: exitCode}
+		return msgCid
+	}
+
+	onTipSetAt := func(t *testing.T, api *minerTestPorcelain, miner *Miner, height uint64) {
+		api.blockHeight = height
+		ts, err := types.NewTipSet(&types.Block{Height: types.Uint64(height)})
+		require.NoError(t, err)
+
+		done, err := miner.OnNewHeaviestTipSet(ts)
+		require.NoError(t, err)
+		done.Wait()
+	}
+
+	t.Run("confirms the PoSt from the receipt of its message", func(t *testing.T) {
+		api, miner, submissions := setup(t)
+
+		onTipSetAt(t, api, miner, 215)
+		assert.Equal(t, 1, *submissions)
+
+		msgCid := mine(t, api, miner, 0)
+		onTipSetAt(t, api, miner, 216)
+
+		status, err := miner.PoStStatus()
+		require.NoError(t, err)
+		assert.Equal(t, PoStConfirmed, status.Stage)
+		require.Len(t, status.Messages, 1)
+		assert.Equal(t, msgCid, status.Messages[0].Cid)
+		assert.Equal(t, uint8(0), status.Messages[0].Receipt.ExitCode)
+
+		onTipSetAt(t, api, miner, 215+postResubmitDelayRounds)
+		assert.Equal(t, 1, *submissions)
+	})
+
+	t.Run("computes and sends the PoSt again when its message fails", func(t *testing.T) {
+		api, miner, submissions := setup(t)
+		onTipSetAt(t, api, miner, 215)
+
+		msgCid := mine(t, api, miner, 1)
+		onTipSetAt(t, api, miner, 216)
+		assert.Equal(t, 2, *submissions)
+
+		status, err := miner.PoStStatus()
+		require.NoError(t, err)
+		assert.Equal(t, PoStSubmitted, status.Stage)
+		require.Len(t, status.Messages, 2)
+		assert.Equal(t, uint8(1), status.Messages[0].Receipt.ExitCode)
+		assert.Contains(t, status.Error, msgCid.String())
+	})
+
+	t.Run("resends the PoSt at a higher gas price once its message is dropped", func(t *testing.T) {
+		api, miner, submissions := setup(t)
+
+		onTipSetAt(t, api, miner, 215)
+		assert.Equal(t, 1, *submissions)
+
+		onTipSetAt(t, api, miner, 215+postResubmitDelayRounds+10)
+		assert.Equal(t, 1, *submissions, "the queued message may still be mined")
+
+		api.outbox[api.workerAddress] = nil
+		onTipSetAt(t, api, miner, 215+postResubmitDelayRounds+11)
+		assert.Equal(t, 2, *submissions)
+
+		status, err := miner.PoStStatus()
+		require.NoError(t, err)
+		assert.Equal(t, PoStSubmitted, status.Stage)
+		require.Len(t, status.Messages, 2)
+		assert.Equal(t, types.NewGasPrice(submitPostGasPrice), status.Messages[0].GasPrice)
+		assert.Equal(t, types.NewGasPrice(submitPostGasPrice*postGasPriceIncrease), status.Messages[1].GasPrice)
+	})
+
+	t.Run("retries a failed PoSt computation on the next tipset", func(t *testing.T) {
+		api, miner, submissions := setup(t)
+		miner.prover = &failingProver{failures: 1}
+
+		onTipSetAt(t, api, miner, 215)
+		assert.Equal(t, 0, *submissions)
+
+		status, err := miner.PoStStatus()
+		require.NoError(t, err)
+		assert.Equal(t, PoStChallengeSampled, status.Stage)
+		assert.Contains(t, status.Error, "failed to calculate PoSt")
+
+		onTipSetAt(t, api, miner, 216)
+		assert.Equal(t, 1, *submissions)
+
+		status, err = miner.PoStStatus()
+		require.NoError(t, err)
+		assert.Equal(t, PoStSubmitted, status.Stage)
+		assert.Empty(t, status.Error)
+	})
+
+	t.Run("sends a computed proof again after a restart", func(t *testing.T) {
+		api, miner, _ := setup(t)
+
+		var sent []interface{}
+		api.messageHandlers["submitPoSt"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			sent = p
+			return [][]byte{}, nil
+		}
+		require.NoError(t, miner.savePoStStatus(&PoStStatus{
+			Start:      types.NewBlockHeight(200),
+			End:        types.NewBlockHeight(400),
+			Stage:      PoStComputed,
+			Submission: &PoStSubmission{Proof: []byte("stored proof"), Faults: types.EmptyFaultSet()},
+		}))
+
+		// the restarted miner only has the stored status to go by
+		miner.prover = &failingProver{failures: 1}
+		onTipSetAt(t, api, miner, 220)
+
+		require.Len(t, sent, 3)
+		assert.Equal(t, types.PoStProof([]byte("stored proof")), sent[0])
+	})
+
+	t.Run("confirms the PoSt when the proving period moves on", func(t *testing.T) {
+		api, miner, _ := setup(t)
+		onTipSetAt(t, api, miner, 215)
+
+		api.messageHandlers["getProvingWindow"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			return mustEncodeResults(t, types.NewBlockHeight(400), types.NewBlockHeight(700)), nil
+		}
+		onTipSetAt(t, api, miner, 401)
+
+		status, err := miner.PoStStatus()
+		require.NoError(t, err)
+		assert.Equal(t, types.NewBlockHeight(400), status.End)
+		assert.Equal(t, PoStConfirmed, status.Stage)
+	})
+}
+
+// failingProver fails its first PoSt computations.
+type failingProver struct {
+	FakeProver
+	failures int
+}
+
+func (p *failingProver) CalculatePoSt(ctx context.Context, start, end *types.BlockHeight, inputs []PoStInputs, faults types.FaultSet) (*PoStSubmission, error) {
+	if p.failures > 0 {
+		p.failures--
+		return nil, errors.New("test error")
+	}
+	return p.FakeProver.CalculatePoSt(ctx, start, end, inputs, faults)
+}
@@ -42,9 +42,9 @@ type ProofReader interface {
 
 // ProofCalculator creates the proof-of-spacetime bytes.
 type ProofCalculator interface {
-	// CalculatePoSt computes a proof-of-spacetime for a list of sector ids and matching seeds.
-	// It returns the Snark Proof for the PoSt and a list of sector ids that failed.
-	CalculatePoSt(ctx context.Context, sectorInfo go_sectorbuilder.SortedSectorInfo, seed types.PoStChallengeSeed) (types.PoStProof, error)
+	// CalculatePoSt computes a proof-of-spacetime for a list of sector ids and matching seeds,
+	// leaving out the faulty sectors. It returns the Snark Proof for the PoSt.
+	CalculatePoSt(ctx context.Context, sectorInfo go_sectorbuilder.SortedSectorInfo, seed types.PoStChallengeSeed, faults []uint64) (types.PoStProof, error)
 }
 
 // Prover orchestrates the calculation and submission of a proof-of-spacetime.
@@ -82,7 +82,8 @@ func NewProver(actor address.Address, sectorSize *types.BytesAmount, reader Proo
 }
 
 // CalculatePoSt computes and returns a proof-of-spacetime ready for posting on chain.
-func (p *Prover) CalculatePoSt(ctx context.Context, start, end *types.BlockHeight, inputs []PoStInputs) (*PoStSubmission, error) {
+// The sectors in faults are declared faulty and left out of the proof.
+func (p *Prover) CalculatePoSt(ctx context.Context, start, end *types.BlockHeight, inputs []PoStInputs, faults types.FaultSet) (*PoStSubmission, error) {
 	// Gather PoSt request inputs.
 	seed, err := p.challengeSeed(ctx, start)
 	if err != nil {
@@ -101,7 +102,7 @@ func (p *Prover) CalculatePoSt(ctx context.Context, start, end *types.BlockHeigh
 		logProver.Infof("ssi %d: sector id %d -- commR %x", i, ssi.SectorID, ssi.CommR)
 	}
 
-	proof, err := p.calculator.CalculatePoSt(ctx, go_sectorbuilder.NewSortedSectorInfo(sectorInfos...), seed)
+	proof, err := p.calculator.CalculatePoSt(ctx, go_sectorbuilder.NewSortedSectorInfo(sectorInfos...), seed, faults.SectorIds.Values())
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to calculate PoSt")
 	}
@@ -144,7 +145,7 @@ func (p *Prover) CalculatePoSt(ctx context.Context, start, end *types.BlockHeigh
 		Proof:    proof,
 		Fee:      feeDue,
 		GasLimit: types.NewGasUnits(submitPostGasLimit),
-		Faults:   types.EmptyFaultSet(),
+		Faults:   faults,
 	}, nil
 }
 
@@ -47,12 +47,22 @@ func TestProver(t *testing.T) {
 	t.Run("produces on-time proof", func(t *testing.T) {
 		pc := makeProofContext()
 		prover := storage.NewProver(actorAddress, sectorSize, pc, pc)
-		submission, e := prover.CalculatePoSt(ctx, start, end, fakeInputs)
+		submission, e := prover.CalculatePoSt(ctx, start, end, fakeInputs, types.EmptyFaultSet())
 		require.NoError(t, e)
 		assert.Equal(t, pc.proof, submission.Proof)
 		assert.Equal(t, types.ZeroAttoFIL, submission.Fee)
 	})
 
+	t.Run("leaves faults out of the proof", func(t *testing.T) {
+		pc := makeProofContext()
+		prover := storage.NewProver(actorAddress, sectorSize, pc, pc)
+		faults := types.NewFaultSet([]uint64{3, 7})
+		submission, e := prover.CalculatePoSt(ctx, start, end, fakeInputs, faults)
+		require.NoError(t, e)
+		assert.Equal(t, faults, submission.Faults)
+		assert.Equal(t, []uint64{3, 7}, pc.faults)
+	})
+
 	t.Run("attaches a fee", func(t *testing.T) {
 		pc := makeProofContext()
 		pc.lateFee = types.NewAttoFILFromFIL(1)
@@ -67,7 +77,7 @@ func TestProver(t *testing.T) {
 		for _, height := range heights {
 			pc.height = height
 			prover := storage.NewProver(actorAddress, sectorSize, pc, pc)
-			submission, e := prover.CalculatePoSt(ctx, start, end, fakeInputs)
+			submission, e := prover.CalculatePoSt(ctx, start, end, fakeInputs, types.EmptyFaultSet())
 			require.NoError(t, e)
 			assert.Equal(t, pc.proof, submission.Proof)
 			assert.True(t, submission.Fee.GreaterThan(types.ZeroAttoFIL))
@@ -79,7 +89,7 @@ func TestProver(t *testing.T) {
 		pc.height = deadline // proof could only appear in block deadline+1
 
 		prover := storage.NewProver(actorAddress, sectorSize, pc, pc)
-		_, e := prover.CalculatePoSt(ctx, start, end, fakeInputs)
+		_, e := prover.CalculatePoSt(ctx, start, end, fakeInputs, types.EmptyFaultSet())
 		require.Error(t, e)
 	})
 
@@ -88,7 +98,7 @@ func TestProver(t *testing.T) {
 		pc.height = start.Sub(types.NewBlockHeight(1))
 
 		prover := storage.NewProver(actorAddress, sectorSize, pc, pc)
-		_, e := prover.CalculatePoSt(ctx, start, end, fakeInputs)
+		_, e := prover.CalculatePoSt(ctx, start, end, fakeInputs, types.EmptyFaultSet())
 		require.Error(t, e)
 	})
 
@@ -97,7 +107,7 @@ func TestProver(t *testing.T) {
 		pc.height = nil
 
 		prover := storage.NewProver(actorAddress, sectorSize, pc, pc)
-		_, e := prover.CalculatePoSt(ctx, start, end, fakeInputs)
+		_, e := prover.CalculatePoSt(ctx, start, end, fakeInputs, types.EmptyFaultSet())
 		require.Error(t, e)
 	})
 
@@ -106,7 +116,7 @@ func TestProver(t *testing.T) {
 		pc.seed = nil
 
 		prover := storage.NewProver(actorAddress, sectorSize, pc, pc)
-		_, e := prover.CalculatePoSt(ctx, start, end, fakeInputs)
+		_, e := prover.CalculatePoSt(ctx, start, end, fakeInputs, types.EmptyFaultSet())
 		require.Error(t, e)
 	})
 }
@@ -151,7 +161,8 @@ func (f *fakeProverContext) WalletBalance(ctx context.Context, addr address.Addr
 	return types.ZeroAttoFIL, errors.New("no balance for worker")
 }
 
-func (f *fakeProverContext) CalculatePoSt(ctx context.Context, sortedCommRs go_sectorbuilder.SortedSectorInfo, seed types.PoStChallengeSeed) (types.PoStProof, error) {
+func (f *fakeProverContext) CalculatePoSt(ctx context.Context, sortedCommRs go_sectorbuilder.SortedSectorInfo, seed types.PoStChallengeSeed, faults []uint64) (types.PoStProof, error) {
+	f.faults = faults
 	return f.proof, nil
 }
 
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+const (
+	// TODO: replace this with a queries to pick reasonable gas price and limits.
+	addFaultsGasPrice = 1
+	addFaultsGasLimit = 300
+)
+
+// SectorFault is a sealed sector the miner found to be unhealthy.
+type SectorFault struct {
+	SectorID uint64
+	// Error describes why the sector is unhealthy.
+	Error      string
+	DetectedAt time.Time
+	// DeclaredIn is the addFaults message declaring the fault, undefined if
+	// declaring it failed. The fault is also declared by the next PoSt.
+	DeclaredIn cid.Cid
+}
+
+// CheckSectorHealth checks every sector the miner is proving and declares the
+// unhealthy ones faulty with an addFaults message. Faulty sectors are also
+// left out of the next PoSt and declared in its fault set, which removes them
+// from the proving set. Declaring a fault costs the miner less than failing
+// the PoSt of a sector it can no longer read. Deep checks read back each
+// sector's data, see sectorbuilder.SectorChecker.
+func (sm *Miner) CheckSectorHealth(ctx context.Context, deep bool) error {
+	checker, ok := sm.porcelainAPI.SectorBuilder().(sectorbuilder.SectorChecker)
+	if !ok {
+		return errors.New("the sector builder cannot check sealed sectors")
+	}
+
+	commitments, err := sm.getActorSectorCommitments(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get miner actor commitments")
+	}
+	proving := make(map[uint64]bool)
+	for k := range commitments {
+		sectorID, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse commitment sector id")
+		}
+		proving[sectorID] = true
+	}
+
+	sm.faultsLk.Lock()
+	// faults of sectors that left the proving set have been dealt with
+	for sectorID := range sm.faults {
+		if !proving[sectorID] {
+			delete(sm.faults, sectorID)
+		}
+	}
+	sm.faultsLk.Unlock()
+
+	var detected []*SectorFault
+	for sectorID := range proving {
+		if sm.isFaulty(sectorID) {
+			continue
+		}
+		if err := checker.CheckSealedSector(ctx, sectorID, deep); err != nil {
+			log.Warningf("sector %d is unhealthy: %s", sectorID, err)
+			detected = append(detected, &SectorFault{SectorID: sectorID, Error: err.Error(), DetectedAt: time.Now()})
+		}
+	}
+	if len(detected) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(detected))
+	for i, fault := range detected {
+		ids[i] = fault.SectorID
+	}
+	msgCid, err := sm.declareFaults(ctx, ids)
+	if err != nil {
+		log.Errorf("failed to declare faults of sectors %v, they will be declared by the next PoSt: %s", ids, err)
+	}
+
+	sm.faultsLk.Lock()
+	defer sm.faultsLk.Unlock()
+	for _, fault := range detected {
+		fault.DeclaredIn = msgCid
+		sm.faults[fault.SectorID] = fault
+	}
+	return nil
+}
+
+// RunSectorHealthChecks quickly checks the health of the miner's sectors
+// every interval until ctx is done. Quick checks stat and sample-read each
+// sector's replica, see sectorbuilder.SectorChecker.
+func (sm *Miner) RunSectorHealthChecks(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+			if err := sm.CheckSectorHealth(ctx, false); err != nil {
+				log.Errorf("failed to check sector health: %s", err)
+			}
+		}
+	}
+}
+
+// SectorFaults returns the unhealthy sectors the miner is still proving,
+// ordered by sector id.
+func (sm *Miner) SectorFaults() []SectorFault {
+	sm.faultsLk.Lock()
+	defer sm.faultsLk.Unlock()
+
+	faults := make([]SectorFault, 0, len(sm.faults))
+	for _, fault := range sm.faults {
+		faults = append(faults, *fault)
+	}
+	sort.Slice(faults, func(i, j int) bool { return faults[i].SectorID < faults[j].SectorID })
+	return faults
+}
+
+// postFaults returns the fault set of a PoSt over the given inputs.
+func (sm *Miner) postFaults(inputs []PoStInputs) types.FaultSet {
+	var ids []uint64
+	for _, input := range inputs {
+		if sm.isFaulty(input.SectorID) {
+			ids = append(ids, input.SectorID)
+		}
+	}
+	return types.NewFaultSet(ids)
+}
+
+func (sm *Miner) isFaulty(sectorID uint64) bool {
+	sm.faultsLk.Lock()
+	defer sm.faultsLk.Unlock()
+	_, ok := sm.faults[sectorID]
+	return ok
+}
+
+// declareFaults sends an addFaults message for the given sectors.
+func (sm *Miner) declareFaults(ctx context.Context, sectorIDs []uint64) (cid.Cid, error) {
+	workerAddr, err := sm.porcelainAPI.MinerGetWorkerAddress(ctx, sm.minerAddr, sm.porcelainAPI.ChainHeadKey())
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to get worker address")
+	}
+	return sm.porcelainAPI.MessageSend(
+		ctx,
+		workerAddr,
+		sm.minerAddr,
+		types.ZeroAttoFIL,
+		types.NewGasPrice(addFaultsGasPrice),
+		types.NewGasUnits(addFaultsGasLimit),
+		"addFaults",
+		types.NewFaultSet(sectorIDs),
+	)
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestCheckSectorHealth(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	setup := func(t *testing.T, sectorIDs ...string) (*minerTestPorcelain, *Miner, *[]types.FaultSet) {
+		api := newMinerTestPorcelain(t, defaultMinerPrice)
+		api.sectorBuilder = &checkingSectorBuilder{unhealthy: map[uint64]bool{42: true}}
+
+		var declared []types.FaultSet
+		api.messageHandlers = successMessageHandlers(t)
+		api.messageHandlers["getProvingSetCommitments"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			commitments := map[string]types.Commitments{}
+			for _, id := range sectorIDs {
+				commitments[id] = types.Commitments{}
+			}
+			return mustEncodeResults(t, commitments), nil
+		}
+		api.messageHandlers["addFaults"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			declared = append(declared, p[0].(types.FaultSet))
+			return nil, nil
+		}
+		return api, newTestMiner(api), &declared
+	}
+
+	t.Run("declares unhealthy sectors once", func(t *testing.T) {
+		_, miner, declared := setup(t, "42", "43")
+
+		require.NoError(t, miner.CheckSectorHealth(ctx, false))
+		require.NoError(t, miner.CheckSectorHealth(ctx, false))
+
+		require.Len(t, *declared, 1)
+		assert.Equal(t, []uint64{42}, (*declared)[0].SectorIds.Values())
+
+		faults := miner.SectorFaults()
+		require.Len(t, faults, 1)
+		assert.Equal(t, uint64(42), faults[0].SectorID)
+		assert.Contains(t, faults[0].Error, "unreadable")
+	})
+
+	t.Run("declares faults in the next PoSt", func(t *testing.T) {
+		_, miner, _ := setup(t, "42", "43")
+		require.NoError(t, miner.CheckSectorHealth(ctx, false))
+
+		faults := miner.postFaults([]PoStInputs{{SectorID: 42}, {SectorID: 43}})
+		assert.Equal(t, []uint64{42}, faults.SectorIds.Values())
+	})
+
+	t.Run("forgets faults of sectors that are no longer proven", func(t *testing.T) {
+		api, miner, _ := setup(t, "42")
+		require.NoError(t, miner.CheckSectorHealth(ctx, false))
+		require.Len(t, miner.SectorFaults(), 1)
+
+		api.messageHandlers["getProvingSetCommitments"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			return mustEncodeResults(t, map[string]types.Commitments{}), nil
+		}
+		require.NoError(t, miner.CheckSectorHealth(ctx, false))
+		assert.Empty(t, miner.SectorFaults())
+	})
+
+	t.Run("only reads sector data in deep checks", func(t *testing.T) {
+		api, miner, _ := setup(t, "43")
+		sb := api.sectorBuilder.(*checkingSectorBuilder)
+
+		require.NoError(t, miner.CheckSectorHealth(ctx, false))
+		assert.Equal(t, 0, sb.deepChecks)
+
+		require.NoError(t, miner.CheckSectorHealth(ctx, true))
+		assert.Equal(t, 1, sb.deepChecks)
+	})
+
+	t.Run("errors if the sector builder cannot check sectors", func(t *testing.T) {
+		api, miner, _ := setup(t, "42")
+		api.sectorBuilder = struct{ sectorbuilder.SectorBuilder }{}
+
+		assert.Error(t, miner.CheckSectorHealth(ctx, false))
+	})
+}
+
+// checkingSectorBuilder fails the checks of its unhealthy sectors and counts
+// deep checks.
+type checkingSectorBuilder struct {
+	sectorbuilder.SectorBuilder
+	unhealthy  map[uint64]bool
+	deepChecks int
+}
+
+func (sb *checkingSectorBuilder) CheckSealedSector(ctx context.Context, sectorID uint64, deep bool) error {
+	if deep {
+		sb.deepChecks++
+	}
+	if sb.unhealthy[sectorID] {
+		return errors.New("sealed sector is unreadable")
+	}
+	return nil
+}
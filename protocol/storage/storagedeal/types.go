@@ -3,6 +3,7 @@ package storagedeal
 import (
 	"github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -62,6 +63,11 @@ type Proposal struct {
 	// will use to pay the miner. It should be verifiable by the
 	// miner using on-chain information.
 	Payment PaymentInfo
+
+	// Manual is set when the client delivers the piece to the miner out of
+	// band, e.g. by shipping a drive, instead of over the network. The miner
+	// operator imports the data once it arrives.
+	Manual bool
 }
 
 // Unmarshal a Proposal from bytes.
@@ -112,6 +118,13 @@ type Response struct {
 	// ProofInfo is a collection of information needed to convince the client that
 	// the miner has sealed the data into a sector.
 	ProofInfo *ProofInfo
+
+	// BytesTransferred is how many bytes of the deal's piece the miner has
+	// received from the client so far. Miners only set it in responses to the
+	// /fil/storage/qry/1.1.0 query protocol; it is omitted when zero, so stored
+	// and signed responses without it encode as they did before the field was
+	// added, and nodes that don't know the field can still decode them.
+	BytesTransferred uint64 `refmt:",omitempty"`
 }
 
 // SignedResponse is a signed wrapper around response
@@ -149,6 +162,23 @@ type Deal struct {
 	CommP    types.CommP
 	Proposal *SignedProposal
 	Response *SignedResponse
+
+	// SectorID is the sector the miner staged the deal's piece into. It is
+	// only meaningful to the miner once the deal has reached the Staged state.
+	SectorID uint64
+
+	// Verified is set by the client once it has confirmed on chain that the
+	// miner sealed the deal's piece into the sector named by its proof info.
+	Verified bool
+
+	// ClientPeer is the peer that proposed the deal. The miner pulls the deal's
+	// piece from it.
+	ClientPeer peer.ID
+
+	// BytesTransferred is how many bytes of the deal's piece have been
+	// transferred to the miner, as last recorded by the miner or reported to
+	// the client by a query.
+	BytesTransferred uint64
 }
 
 // ProofInfo contains the details about a seal proof, that the client needs to know to verify that his deal was posted on chain.
@@ -10,8 +10,9 @@ import (
 type FakeProver struct{}
 
 // CalculatePoSt returns a fixed fake proof.
-func (p *FakeProver) CalculatePoSt(ctx context.Context, start, end *types.BlockHeight, inputs []PoStInputs) (*PoStSubmission, error) {
+func (p *FakeProver) CalculatePoSt(ctx context.Context, start, end *types.BlockHeight, inputs []PoStInputs, faults types.FaultSet) (*PoStSubmission, error) {
 	return &PoStSubmission{
-		Proof: []byte("test proof"),
+		Proof:  []byte("test proof"),
+		Faults: faults,
 	}, nil
 }
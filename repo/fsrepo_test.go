@@ -44,6 +44,10 @@ const (
 		"type": "badgerds",
 		"path": "badger"
 	},
+	"explorer": {
+		"enabled": false,
+		"address": "/ip4/127.0.0.1/tcp/3454"
+	},
 	"heartbeat": {
 		"beatTarget": "",
 		"beatPeriod": "3s",
@@ -53,7 +57,12 @@ const (
 	"mining": {
 		"minerAddress": "empty",
 		"autoSealIntervalSeconds": 120,
-		"storagePrice": "0"
+		"storagePrice": "0",
+		"remoteSealing": false,
+		"sealWorkerToken": "",
+		"sectorHealthCheckIntervalSeconds": 3600,
+		"maxHeadLagRounds": 0,
+		"maxHeadAgeSeconds": 0
 	},
 	"mpool": {
 		"maxPoolSize": 10000,
@@ -69,10 +78,14 @@ const (
 			"jaegerTracingEnabled": false,
 			"probabilitySampler": 1,
 			"jaegerEndpoint": "http://localhost:14268/api/traces"
+		},
+		"executionTraces": {
+			"persist": false
 		}
 	},
 	"sectorbase": {
-		"rootdir": ""
+		"rootdir": "",
+		"storagePaths": []
 	},
 	"swarm": {
 		"address": "/ip4/0.0.0.0/tcp/6000"
@@ -3,10 +3,12 @@ package fast
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/ipfs/go-cid"
 
 	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/types"
 )
 
 // ChainHead runs the chain head command against the filecoin process.
@@ -32,3 +34,13 @@ func (f *Filecoin) ChainStatus(ctx context.Context) (*chain.Status, error) {
 	}
 	return out, nil
 }
+
+// ChainWaitHeight runs the chain wait-height command against the filecoin process.
+func (f *Filecoin) ChainWaitHeight(ctx context.Context, height *types.BlockHeight, confidence uint64) ([]cid.Cid, error) {
+	var out []cid.Cid
+	args := []string{"go-filecoin", "chain", "wait-height", height.String(), "--confidence", fmt.Sprintf("%d", confidence)}
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -3,12 +3,28 @@ package fast
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipfs-files"
 
 	"github.com/filecoin-project/go-filecoin/commands"
 )
 
+// DealsImportData runs the `deals import-data` command against the filecoin process
+func (f *Filecoin) DealsImportData(ctx context.Context, propCid cid.Cid, data files.File) error {
+	out, err := f.RunCmdWithStdin(ctx, data, "go-filecoin", "deals", "import-data", propCid.String())
+	if err != nil {
+		return err
+	}
+
+	if out.ExitCode() > 0 {
+		return fmt.Errorf("filecoin command: %s, exited with non-zero exitcode: %d", out.Args(), out.ExitCode())
+	}
+
+	return nil
+}
+
 // DealsList runs the `deals list` command against the filecoin process
 func (f *Filecoin) DealsList(ctx context.Context, options ...ActionOption) (*json.Decoder, error) {
 	args := []string{"go-filecoin", "deals", "list"}
@@ -36,6 +52,20 @@ func (f *Filecoin) DealsRedeem(ctx context.Context, dealCid cid.Cid, options ...
 	return out.Cid, nil
 }
 
+// DealsRetry runs the `deals retry` command against the filecoin process
+func (f *Filecoin) DealsRetry(ctx context.Context, propCid cid.Cid) error {
+	out, err := f.RunCmdWithStdin(ctx, nil, "go-filecoin", "deals", "retry", propCid.String())
+	if err != nil {
+		return err
+	}
+
+	if out.ExitCode() > 0 {
+		return fmt.Errorf("filecoin command: %s, exited with non-zero exitcode: %d", out.Args(), out.ExitCode())
+	}
+
+	return nil
+}
+
 // DealsShow runs the `deals show` command against the filecoin process
 func (f *Filecoin) DealsShow(ctx context.Context, propCid cid.Cid) (*commands.DealsShowResult, error) {
 
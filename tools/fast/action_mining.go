@@ -8,6 +8,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/commands"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -86,6 +87,17 @@ func (f *Filecoin) MiningStatus(ctx context.Context) (commands.MiningStatusResul
 	return out, nil
 }
 
+// MiningSealingStatus runs the `mining sealing-status` command against the filecoin process
+func (f *Filecoin) MiningSealingStatus(ctx context.Context) ([]sectorbuilder.SectorSealingStatus, error) {
+	var out []sectorbuilder.SectorSealingStatus
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, "go-filecoin", "mining", "sealing-status"); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
 // AddPiece runs the mining add-piece command
 func (f *Filecoin) AddPiece(ctx context.Context, data files.File) (commands.MiningAddPieceResult, error) {
 	var out commands.MiningAddPieceResult
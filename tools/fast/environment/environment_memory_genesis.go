@@ -246,7 +246,7 @@ func (e *MemoryGenesis) buildGenesis(funds *big.Int) error {
 
 	// ensure miners' sector size is set appropriately for the configured
 	// proofs mode
-	gengen.ApplyProofsModeDefaults(cfg, e.proofsMode == types.LiveProofsMode, true)
+	gengen.ApplyProofsModeDefaults(cfg, e.proofsMode, true)
 
 	var genbuffer bytes.Buffer
 
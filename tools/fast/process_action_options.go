@@ -125,6 +125,14 @@ func AOAllowDuplicates(allow bool) ActionOption {
 	}
 }
 
+// AOManual provides the --manual option to client propose-storage-deal
+func AOManual(manual bool) ActionOption {
+	sManual := fmt.Sprintf("--manual=%t", manual)
+	return func() []string {
+		return []string{sManual}
+	}
+}
+
 // AOSectorSize provides the `--sectorsize` option to actions
 func AOSectorSize(ba *types.BytesAmount) ActionOption {
 	return func() []string {
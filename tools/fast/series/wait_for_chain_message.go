@@ -2,7 +2,6 @@ package series
 
 import (
 	"context"
-	"fmt"
 	"io"
 
 	"github.com/filecoin-project/go-filecoin/tools/fast"
@@ -19,35 +18,63 @@ type MsgInfo struct {
 // MsgSearchFn is the function signature used to find a message
 type MsgSearchFn func(context.Context, *fast.Filecoin, *types.SignedMessage) (bool, error)
 
-// WaitForChainMessage iterates over the chain until the provided function `fn` returns true.
+// WaitForChainMessage searches the chain until the provided function `fn`
+// returns true. Once the chain has been searched it waits for the chain to
+// grow with the chain wait-height command, and only searches the blocks
+// added since.
 func WaitForChainMessage(ctx context.Context, node *fast.Filecoin, fn MsgSearchFn) (*MsgInfo, error) {
+	// next is the lowest height that has not been searched
+	next := types.NewBlockHeight(0)
 	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-CtxSleepDelay(ctx):
-			dec, err := node.ChainLs(ctx)
-			if err != nil {
-				return nil, err
-			}
+		head, err := GetHeadBlockHeight(ctx, node)
+		if err != nil {
+			return nil, err
+		}
 
-			for dec.More() {
-				var blks []types.Block
-				err := dec.Decode(&blks)
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
+		msgInfo, err := findMessageAbove(ctx, node, next, fn)
+		if err != nil {
+			return nil, err
+		}
+		if msgInfo != nil {
+			return msgInfo, nil
+		}
 
-					return nil, err
-				}
+		next = head.Add(types.NewBlockHeight(1))
+		if _, err := node.ChainWaitHeight(ctx, next, 0); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// findMessageAbove searches the blocks of the chain at or above height
+// `from`, returning nil if none holds a message for which `fn` returns true.
+func findMessageAbove(ctx context.Context, node *fast.Filecoin, from *types.BlockHeight, fn MsgSearchFn) (*MsgInfo, error) {
+	dec, err := node.ChainLs(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-				if msgInfo, err := findMessageInBlockSlice(ctx, node, blks, fn); err == nil {
-					return msgInfo, nil
-				}
+	for dec.More() {
+		var blks []types.Block
+		err := dec.Decode(&blks)
+		if err != nil {
+			if err == io.EOF {
+				break
 			}
+
+			return nil, err
+		}
+		if len(blks) > 0 && types.NewBlockHeight(uint64(blks[0].Height)).LessThan(from) {
+			break
+		}
+
+		msgInfo, err := findMessageInBlockSlice(ctx, node, blks, fn)
+		if err != nil || msgInfo != nil {
+			return msgInfo, err
 		}
 	}
+
+	return nil, nil
 }
 
 func findMessageInBlockSlice(ctx context.Context, node *fast.Filecoin, blks []types.Block, fn MsgSearchFn) (*MsgInfo, error) {
@@ -75,5 +102,5 @@ func findMessageInBlockSlice(ctx context.Context, node *fast.Filecoin, blks []ty
 		}
 	}
 
-	return nil, fmt.Errorf("Message not found")
+	return nil, nil
 }
@@ -0,0 +1,90 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrFailed is returned when a requester did not solve the challenge.
+var ErrFailed = errors.New("challenge verification failed")
+
+// Verifier checks the response to a challenge, such as a captcha, solved by a
+// requester before the faucet sends funds.
+type Verifier interface {
+	Verify(ctx context.Context, response string, remoteIP string) error
+}
+
+// None is a Verifier accepting every request.
+type None struct{}
+
+// Verify always succeeds.
+func (None) Verify(ctx context.Context, response string, remoteIP string) error {
+	return nil
+}
+
+// SiteVerifier verifies challenge responses with a siteverify endpoint, as
+// provided by reCAPTCHA and hCaptcha.
+type SiteVerifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewSiteVerifier returns a verifier posting responses to the siteverify
+// endpoint at url, authenticated with secret.
+func NewSiteVerifier(url, secret string) *SiteVerifier {
+	return &SiteVerifier{
+		url:    url,
+		secret: secret,
+		client: http.DefaultClient,
+	}
+}
+
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify returns ErrFailed if the endpoint rejects the response.
+func (v *SiteVerifier) Verify(ctx context.Context, response string, remoteIP string) error {
+	if response == "" {
+		return ErrFailed
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.secret)
+	form.Set("response", response)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequest("POST", v.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("challenge verification returned status %s", resp.Status)
+	}
+
+	var out siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return err
+	}
+	if !out.Success {
+		return ErrFailed
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSiteVerifier(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok := r.FormValue("secret") == "secret" && r.FormValue("response") == "solved" && r.FormValue("remoteip") == "1.2.3.4"
+		json.NewEncoder(w).Encode(siteVerifyResponse{Success: ok}) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	v := NewSiteVerifier(server.URL, "secret")
+
+	t.Run("Accepts a solved challenge", func(t *testing.T) {
+		assert.NoError(t, v.Verify(ctx, "solved", "1.2.3.4"))
+	})
+
+	t.Run("Rejects an unsolved challenge", func(t *testing.T) {
+		assert.Equal(t, ErrFailed, v.Verify(ctx, "guessed", "1.2.3.4"))
+	})
+
+	t.Run("Rejects a missing response", func(t *testing.T) {
+		assert.Equal(t, ErrFailed, v.Verify(ctx, "", "1.2.3.4"))
+	})
+}
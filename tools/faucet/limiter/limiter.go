@@ -1,6 +1,7 @@
 package limiter
 
 import (
+	"encoding/json"
 	"sync"
 	"time"
 )
@@ -37,6 +38,19 @@ func (l *Limiter) Add(addr string, t time.Time) {
 	l.addrs[addr] = t
 }
 
+// Reserve limits value till a given time if it is ready. Returns a
+// time.Duration for the time remaining till the value is ready if it is not.
+func (l *Limiter) Reserve(addr string, t time.Time) (time.Duration, bool) {
+	l.addrsMu.Lock()
+	defer l.addrsMu.Unlock()
+
+	readyIn, ok := l.ready(addr)
+	if ok {
+		l.addrs[addr] = t
+	}
+	return readyIn, ok
+}
+
 // Ready checks to see if the time has expired. Returns a time.Duration
 // for the time remaining till a true value will be returned
 func (l *Limiter) Ready(addr string) (time.Duration, bool) {
@@ -77,3 +91,37 @@ func (l *Limiter) Clean() {
 		}
 	}
 }
+
+// MarshalJSON encodes the times until which values are limited, so the
+// limits can be restored after a restart. Expired values are left out.
+func (l *Limiter) MarshalJSON() ([]byte, error) {
+	l.addrsMu.Lock()
+	defer l.addrsMu.Unlock()
+
+	addrs := make(map[string]time.Time)
+	for addr, t := range l.addrs {
+		if _, ok := l.ready(addr); !ok {
+			addrs[addr] = t
+		}
+	}
+	return json.Marshal(addrs)
+}
+
+// UnmarshalJSON adds the limits encoded by MarshalJSON that have not expired
+// yet to the limiter.
+func (l *Limiter) UnmarshalJSON(data []byte) error {
+	addrs := make(map[string]time.Time)
+	if err := json.Unmarshal(data, &addrs); err != nil {
+		return err
+	}
+
+	l.addrsMu.Lock()
+	defer l.addrsMu.Unlock()
+
+	for addr, t := range addrs {
+		if l.time.Until(t) > 0 {
+			l.addrs[addr] = t
+		}
+	}
+	return nil
+}
@@ -1,11 +1,13 @@
 package limiter
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
 	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type MockTime struct {
@@ -126,3 +128,73 @@ func TestClean(t *testing.T) {
 		assert.True(t, ok)
 	})
 }
+
+func TestReserve(t *testing.T) {
+	tf.UnitTest(t)
+
+	addr := "Qmaddr"
+
+	t.Run("Reserves a ready value", func(t *testing.T) {
+		lockedFor := time.Microsecond * 50
+		mt := &MockTime{}
+
+		l := NewLimiter(mt)
+
+		_, ok := l.Reserve(addr, time.Now().Add(lockedFor))
+		assert.True(t, ok)
+
+		mt.UntilReturn = lockedFor
+
+		d0, ok := l.Reserve(addr, time.Now().Add(lockedFor))
+		assert.False(t, ok)
+		assert.Equal(t, lockedFor, d0)
+	})
+}
+
+func TestMarshalJSON(t *testing.T) {
+	tf.UnitTest(t)
+
+	addr := "Qmaddr"
+
+	t.Run("Restores limits", func(t *testing.T) {
+		lockedFor := time.Microsecond * 50
+		mt := &MockTime{}
+		mt.UntilReturn = lockedFor
+
+		l := NewLimiter(mt)
+		until := time.Now().Add(lockedFor)
+		l.Add(addr, until)
+
+		data, err := json.Marshal(l)
+		require.NoError(t, err)
+
+		restored := NewLimiter(mt)
+		require.NoError(t, json.Unmarshal(data, restored))
+
+		assert.Len(t, restored.addrs, 1)
+		assert.True(t, until.Equal(restored.addrs[addr]))
+
+		_, ok := restored.Ready(addr)
+		assert.False(t, ok)
+	})
+
+	t.Run("Drops expired limits", func(t *testing.T) {
+		mt := &MockTime{}
+		mt.UntilReturn = time.Microsecond * 50
+
+		l := NewLimiter(mt)
+		l.Add(addr, time.Now().Add(mt.UntilReturn))
+
+		data, err := json.Marshal(l)
+		require.NoError(t, err)
+
+		mt.UntilReturn = 0
+		restored := NewLimiter(mt)
+		require.NoError(t, json.Unmarshal(data, restored))
+		assert.Empty(t, restored.addrs)
+
+		data, err = json.Marshal(l)
+		require.NoError(t, err)
+		assert.Equal(t, "{}", string(data))
+	})
+}
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"html/template"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/tools/faucet/challenge"
 	"github.com/filecoin-project/go-filecoin/tools/faucet/limiter"
 )
 
@@ -23,6 +32,12 @@ var limiterCleanTick = time.Minute * 15
 // Default timeout between wallet fund requests
 var defaultLimiterExpiry = time.Hour * 1
 
+// Default timeout between fund requests from the same IP
+var defaultIPLimiterExpiry = time.Minute * 10
+
+// Default time to wait for a message to be mined
+var defaultWaitTimeout = time.Minute * 5
+
 func init() {
 	// Info level
 	logging.SetAllLoggers(4)
@@ -35,10 +50,53 @@ func (mt *timeImpl) Until(t time.Time) time.Duration {
 	return time.Until(t)
 }
 
+// tapError is a failed request with the HTTP status to report it with.
+type tapError struct {
+	status  int
+	readyIn time.Duration
+	err     error
+}
+
+func (e *tapError) Error() string {
+	return e.err.Error()
+}
+
+type faucet struct {
+	filapi    string
+	wallet    string
+	value     int64
+	expiry    time.Duration
+	ipExpiry  time.Duration
+	stateFile string
+	trustXFF  bool
+	// challengeField is the form field holding the challenge response.
+	challengeField string
+
+	addrLimiter *limiter.Limiter
+	ipLimiter   *limiter.Limiter
+	verifier    challenge.Verifier
+
+	// saveMu serializes writes of the state file.
+	saveMu sync.Mutex
+}
+
+// limiterState is the rate limit state persisted to the state file.
+type limiterState struct {
+	Addresses *limiter.Limiter
+	IPs       *limiter.Limiter
+}
+
 func main() {
 	filapi := flag.String("fil-api", "localhost:3453", "set the api address of the filecoin node to use")
 	filwal := flag.String("fil-wallet", "", "(required) set the wallet address for the controlled filecoin node to send funds from")
 	expiry := flag.Duration("limiter-expiry", defaultLimiterExpiry, "minimum time duration between faucet request to the same wallet addr")
+	ipExpiry := flag.Duration("ip-limiter-expiry", defaultIPLimiterExpiry, "minimum time duration between faucet request from the same IP")
+	stateFile := flag.String("limiter-state", "faucet-limits.json", "set the file the rate limits are persisted to, empty to keep them in memory")
+	trustXFF := flag.Bool("trust-forwarded-for", false, "use the X-Forwarded-For header as the requester IP when running behind a proxy")
+	challengeURL := flag.String("challenge-verify-url", "", "set the siteverify url to check challenge responses with, empty to disable challenges")
+	challengeSecret := flag.String("challenge-secret", "", "set the secret used to verify challenge responses")
+	challengeSnippet := flag.String("challenge-snippet", "", "set a file with the html added to the form to render the challenge")
+	challengeField := flag.String("challenge-field", "challenge", "set the form field the challenge snippet posts its response in")
 	faucetval := flag.Int64("faucet-val", 500, "set the amount of fil to pay to each requester")
 	flag.Parse()
 
@@ -48,85 +106,320 @@ func main() {
 		return
 	}
 
-	addrLimiter := limiter.NewLimiter(&timeImpl{})
+	f := &faucet{
+		filapi:         *filapi,
+		wallet:         *filwal,
+		value:          *faucetval,
+		expiry:         *expiry,
+		ipExpiry:       *ipExpiry,
+		stateFile:      *stateFile,
+		trustXFF:       *trustXFF,
+		challengeField: *challengeField,
+		addrLimiter:    limiter.NewLimiter(&timeImpl{}),
+		ipLimiter:      limiter.NewLimiter(&timeImpl{}),
+		verifier:       challenge.None{},
+	}
+	if *challengeURL != "" {
+		f.verifier = challenge.NewSiteVerifier(*challengeURL, *challengeSecret)
+	}
+
+	if err := f.loadLimits(); err != nil {
+		log.Errorf("failed to load rate limits from %s: %s", f.stateFile, err)
+		os.Exit(1)
+	}
+
+	var snippet template.HTML
+	if *challengeSnippet != "" {
+		data, err := ioutil.ReadFile(*challengeSnippet)
+		if err != nil {
+			log.Errorf("failed to read challenge snippet: %s", err)
+			os.Exit(1)
+		}
+		snippet = template.HTML(data) // nolint: gosec
+	}
 
 	// Clean the limiter every limiterCleanTick
 	go func() {
 		c := time.Tick(limiterCleanTick)
 		for range c {
-			addrLimiter.Clean()
+			f.addrLimiter.Clean()
+			f.ipLimiter.Clean()
+			f.saveLimits()
 		}
 	}()
 
-	http.HandleFunc("/", displayForm)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		form.Execute(w, snippet) // nolint: errcheck
+	})
 	http.HandleFunc("/tap", func(w http.ResponseWriter, r *http.Request) {
-		target := r.FormValue("target")
-		if target == "" {
-			http.Error(w, "must specify a target address to send FIL to", 400)
-			return
-		}
-		log.Infof("Request to send funds to: %s", target)
-
-		addr, err := address.NewFromString(target)
+		msgcid, err := f.tap(r.Context(), r.FormValue("target"), f.requesterIP(r), r.FormValue(f.challengeField))
 		if err != nil {
-			log.Errorf("failed to parse target address: %s %s", target, err)
-			http.Error(w, fmt.Sprintf("Failed to parse target address %s %s", target, err.Error()), 400)
+			writeTapError(w, err)
 			return
 		}
 
-		if readyIn, ok := addrLimiter.Ready(target); !ok {
-			log.Errorf("limit hit for target address %s", target)
-			w.Header().Add("Retry-After", fmt.Sprintf("%d", int64(readyIn/time.Second)))
-			http.Error(w, fmt.Sprintf("Too Many Requests, please wait %s", readyIn), http.StatusTooManyRequests)
-			return
-		}
+		w.Header().Add("Message-Cid", msgcid.String())
+		w.WriteHeader(200)
+		fmt.Fprint(w, "Success! Message CID: ") // nolint: errcheck
+		fmt.Fprintln(w, msgcid.String())        // nolint: errcheck
+	})
+	http.HandleFunc("/api/tap", f.handleAPITap)
+
+	panic(http.ListenAndServe(":9797", nil))
+}
 
-		reqStr := fmt.Sprintf("http://%s/api/message/send?arg=%s&value=%d&from=%s&gas-price=1&gas-limit=0", *filapi, addr.String(), *faucetval, *filwal)
-		log.Infof("Request URL: %s", reqStr)
+// apiTapRequest is the body of a request to the JSON API.
+type apiTapRequest struct {
+	Target    string `json:"target"`
+	Challenge string `json:"challenge"`
+	// Wait makes the request return once the message is mined.
+	Wait bool `json:"wait"`
+}
 
-		resp, err := http.Post(reqStr, "application/json", nil)
-		if err != nil {
-			log.Errorf("failed to Post request. Status: %s Error: %s", resp.Status, err)
-			http.Error(w, err.Error(), 500)
-			return
+// apiTapResponse is the response of the JSON API.
+type apiTapResponse struct {
+	Cid string `json:"cid,omitempty"`
+	// ExitCode is the exit code of the mined message, set if the request
+	// waited for it.
+	ExitCode *uint8 `json:"exitCode,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// RetryAfter is the number of seconds until a rate limited request may
+	// be made again.
+	RetryAfter int64 `json:"retryAfter,omitempty"`
+}
+
+func (f *faucet) handleAPITap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, apiTapResponse{Error: "requests must use POST"})
+		return
+	}
+
+	var req apiTapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiTapResponse{Error: fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	msgcid, err := f.tap(r.Context(), req.Target, f.requesterIP(r), req.Challenge)
+	if err != nil {
+		res := apiTapResponse{Error: err.Error()}
+		status := http.StatusInternalServerError
+		if terr, ok := err.(*tapError); ok {
+			status = terr.status
+			if terr.readyIn > 0 {
+				res.RetryAfter = int64(terr.readyIn / time.Second)
+				w.Header().Add("Retry-After", fmt.Sprintf("%d", res.RetryAfter))
+			}
 		}
+		writeJSON(w, status, res)
+		return
+	}
 
-		out, err := ioutil.ReadAll(resp.Body)
+	res := apiTapResponse{Cid: msgcid.String()}
+	if req.Wait {
+		exitCode, err := f.waitForMessage(r.Context(), msgcid)
 		if err != nil {
-			log.Errorf("failed to read response body: %s", err)
-			http.Error(w, "failed to read response", 500)
+			log.Errorf("failed to wait for message %s: %s", msgcid, err)
+			res.Error = fmt.Sprintf("message was sent but not seen mined: %s", err)
+			writeJSON(w, http.StatusGatewayTimeout, res)
 			return
 		}
-		if resp.StatusCode != 200 {
-			log.Errorf("status: %s body: %s", resp.Status, string(out))
-			http.Error(w, "failed to send funds", 500)
-			return
+		res.ExitCode = &exitCode
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// tap sends funds to target if neither target nor the requester IP are rate
+// limited and the challenge response is valid.
+func (f *faucet) tap(ctx context.Context, target, ip, challengeResponse string) (cid.Cid, error) {
+	if target == "" {
+		return cid.Undef, &tapError{status: 400, err: errors.New("must specify a target address to send FIL to")}
+	}
+	log.Infof("Request to send funds to: %s from %s", target, ip)
+
+	addr, err := address.NewFromString(target)
+	if err != nil {
+		log.Errorf("failed to parse target address: %s %s", target, err)
+		return cid.Undef, &tapError{status: 400, err: fmt.Errorf("Failed to parse target address %s %s", target, err.Error())}
+	}
+
+	if err := f.verifier.Verify(ctx, challengeResponse, ip); err != nil {
+		log.Errorf("challenge verification failed for %s: %s", ip, err)
+		return cid.Undef, &tapError{status: http.StatusForbidden, err: challenge.ErrFailed}
+	}
+
+	if readyIn, ok := f.ipLimiter.Reserve(ip, time.Now().Add(f.ipExpiry)); !ok {
+		log.Errorf("limit hit for ip %s", ip)
+		return cid.Undef, &tapError{status: http.StatusTooManyRequests, readyIn: readyIn, err: fmt.Errorf("Too Many Requests, please wait %s", readyIn)}
+	}
+	if readyIn, ok := f.addrLimiter.Reserve(addr.String(), time.Now().Add(f.expiry)); !ok {
+		log.Errorf("limit hit for target address %s", target)
+		f.ipLimiter.Clear(ip)
+		return cid.Undef, &tapError{status: http.StatusTooManyRequests, readyIn: readyIn, err: fmt.Errorf("Too Many Requests, please wait %s", readyIn)}
+	}
+
+	msgcid, err := f.sendFunds(ctx, addr)
+	if err != nil {
+		log.Errorf("failed to send funds to %s: %s", addr, err)
+		f.addrLimiter.Clear(addr.String())
+		f.ipLimiter.Clear(ip)
+		return cid.Undef, &tapError{status: 500, err: errors.New("failed to send funds")}
+	}
+	f.saveLimits()
+
+	log.Infof("Request successful. Message CID: %s", msgcid.String())
+	return msgcid, nil
+}
+
+// sendFunds sends the faucet value to addr with the message send command of
+// the node.
+func (f *faucet) sendFunds(ctx context.Context, addr address.Address) (cid.Cid, error) {
+	reqStr := fmt.Sprintf("http://%s/api/message/send?arg=%s&value=%d&from=%s&gas-price=1&gas-limit=0", f.filapi, addr.String(), f.value, f.wallet)
+	log.Infof("Request URL: %s", reqStr)
+
+	out, err := postAPI(ctx, reqStr)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	msgResp := struct{ Cid cid.Cid }{}
+
+	// result should be a message cid
+	if err := json.Unmarshal(out, &msgResp); err != nil {
+		log.Errorf("response data was: %s", out)
+		return cid.Undef, errors.Wrap(err, "json unmarshal from response failed")
+	}
+	return msgResp.Cid, nil
+}
+
+// waitForMessage waits for the message to be mined with the message wait
+// command of the node and returns its exit code.
+func (f *faucet) waitForMessage(ctx context.Context, msgcid cid.Cid) (uint8, error) {
+	reqStr := fmt.Sprintf("http://%s/api/message/wait?arg=%s&timeout=%s", f.filapi, msgcid.String(), url.QueryEscape(defaultWaitTimeout.String()))
+
+	out, err := postAPI(ctx, reqStr)
+	if err != nil {
+		return 0, err
+	}
+
+	waitResp := struct {
+		Receipt *struct {
+			ExitCode uint8 `json:"exitCode"`
 		}
+	}{}
+	if err := json.Unmarshal(out, &waitResp); err != nil {
+		log.Errorf("response data was: %s", out)
+		return 0, errors.Wrap(err, "json unmarshal from response failed")
+	}
+	if waitResp.Receipt == nil {
+		return 0, errors.New("no receipt for message")
+	}
+	return waitResp.Receipt.ExitCode, nil
+}
 
-		msgResp := struct{ Cid cid.Cid }{}
+func postAPI(ctx context.Context, reqStr string) ([]byte, error) {
+	req, err := http.NewRequest("POST", reqStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
 
-		// result should be a message cid
-		if err := json.Unmarshal(out, &msgResp); err != nil {
-			log.Errorf("json unmarshal from response failed: %s", err)
-			log.Errorf("response data was: %s", out)
-			http.Error(w, "faucet unmarshal failed", 500)
-			return
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to post request")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	out, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status: %s body: %s", resp.Status, string(out))
+	}
+	return out, nil
+}
+
+// requesterIP returns the IP the request was made from. Behind a proxy this
+// is the last X-Forwarded-For entry, the one the proxy itself appended, as
+// the client controls all entries before it.
+func (f *faucet) requesterIP(r *http.Request) string {
+	if f.trustXFF {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			entries := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+				return ip
+			}
 		}
-		msgcid := msgResp.Cid
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
 
-		addrLimiter.Add(target, time.Now().Add(*expiry))
+func (f *faucet) loadLimits() error {
+	if f.stateFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(f.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &limiterState{Addresses: f.addrLimiter, IPs: f.ipLimiter})
+}
 
-		log.Info("Request successful. Message CID: %s", msgcid.String())
-		w.Header().Add("Message-Cid", msgcid.String())
-		w.WriteHeader(200)
-		fmt.Fprint(w, "Success! Message CID: ") // nolint: errcheck
-		fmt.Fprintln(w, msgcid.String())        // nolint: errcheck
-	})
+// saveLimits writes the rate limits to the state file. Failures are logged,
+// as the limits are still enforced until the faucet restarts.
+func (f *faucet) saveLimits() {
+	if f.stateFile == "" {
+		return
+	}
+	f.saveMu.Lock()
+	defer f.saveMu.Unlock()
 
-	panic(http.ListenAndServe(":9797", nil))
+	data, err := json.Marshal(limiterState{Addresses: f.addrLimiter, IPs: f.ipLimiter})
+	if err != nil {
+		log.Errorf("failed to encode rate limits: %s", err)
+		return
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial state.
+	tmp := f.stateFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Errorf("failed to save rate limits: %s", err)
+		return
+	}
+	if err := os.Rename(tmp, f.stateFile); err != nil {
+		log.Errorf("failed to save rate limits: %s", err)
+	}
+}
+
+func writeTapError(w http.ResponseWriter, err error) {
+	terr, ok := err.(*tapError)
+	if !ok {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if terr.readyIn > 0 {
+		w.Header().Add("Retry-After", fmt.Sprintf("%d", int64(terr.readyIn/time.Second)))
+	}
+	http.Error(w, terr.Error(), terr.status)
 }
 
-const form = `
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("failed to write response: %s", err)
+	}
+}
+
+var form = template.Must(template.New("form").Parse(`
 <html>
 	<body>
 		<h1> What is your wallet address </h1>
@@ -135,12 +428,9 @@ const form = `
 		<p> Address: </p>
 		<form action="/tap" method="post">
 			<input type="text" name="target" size="30" />
+			{{.}}
 			<input type="submit" value="Submit" size="30" />
 		</form>
 	</body>
 </html>
-`
-
-func displayForm(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, form) // nolint: errcheck
-}
+`))
@@ -0,0 +1,215 @@
+package collector
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p-core/host"
+	net "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-filecoin/metrics"
+)
+
+var log = logging.Logger("heartbeat-collector")
+
+// Clock returns the current time. Primarily used for testing.
+type Clock func() time.Time
+
+// NodeStatus is the last known state of a node sending heartbeats.
+type NodeStatus struct {
+	PeerID       string
+	Nickname     string
+	MinerAddress string
+	GenesisCID   string
+	Head         string
+	Height       uint64
+	// LastSeen is the time the last heartbeat of the node was received.
+	LastSeen time.Time
+	// Connected is true while the node has a heartbeat stream open.
+	Connected bool
+	// Alive is true if the node sent a heartbeat within the liveness timeout.
+	Alive bool
+	// Behind is the number of rounds the node is behind the highest alive
+	// node of its network.
+	Behind uint64
+}
+
+// Head is a chain head reported by alive nodes of a network.
+type Head struct {
+	Head   string
+	Height uint64
+	// Nodes are the peer IDs of the nodes reporting the head.
+	Nodes []string
+}
+
+// Network is the status of the nodes sharing a genesis block.
+type Network struct {
+	GenesisCID string
+	MaxHeight  uint64
+	// MaxDivergence is the largest height difference between alive nodes.
+	MaxDivergence uint64
+	// Heads are the distinct heads of alive nodes, the highest first.
+	Heads []Head
+	// Forked is true if alive nodes report different heads at the same height.
+	Forked bool
+}
+
+// Status is the status of all nodes known to the collector.
+type Status struct {
+	Nodes    []NodeStatus
+	Networks []Network
+	Alive    int
+}
+
+// Collector receives heartbeats on the heartbeat protocol and tracks the
+// state of the nodes sending them.
+type Collector struct {
+	nodesMu sync.Mutex
+	// nodes maps a peer to the last state it reported
+	nodes map[peer.ID]*NodeStatus
+
+	timeout time.Duration
+	clock   Clock
+}
+
+// NewCollector returns a collector receiving heartbeats on h. Nodes that did
+// not send a heartbeat within timeout are reported as not alive.
+func NewCollector(h host.Host, timeout time.Duration, clock Clock) *Collector {
+	c := &Collector{
+		nodes:   make(map[peer.ID]*NodeStatus),
+		timeout: timeout,
+		clock:   clock,
+	}
+	h.SetStreamHandler(metrics.HeartbeatProtocol, c.handleStream)
+	return c
+}
+
+func (c *Collector) handleStream(s net.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	p := s.Conn().RemotePeer()
+	log.Infof("heartbeat stream opened by %s", p)
+
+	dec := json.NewDecoder(s)
+	for {
+		var hb metrics.Heartbeat
+		if err := dec.Decode(&hb); err != nil {
+			if err != io.EOF {
+				log.Warningf("failed to decode heartbeat from %s: %s", p, err)
+			}
+			break
+		}
+		c.Record(p, hb)
+	}
+
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+	if n, ok := c.nodes[p]; ok {
+		n.Connected = false
+	}
+	log.Infof("heartbeat stream closed by %s", p)
+}
+
+// Record updates the state of node p with the heartbeat hb.
+func (c *Collector) Record(p peer.ID, hb metrics.Heartbeat) {
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+
+	genesis := ""
+	if hb.GenesisCID.Defined() {
+		genesis = hb.GenesisCID.String()
+	}
+	c.nodes[p] = &NodeStatus{
+		PeerID:       p.Pretty(),
+		Nickname:     hb.Nickname,
+		MinerAddress: hb.MinerAddress.String(),
+		GenesisCID:   genesis,
+		Head:         hb.Head,
+		Height:       hb.Height,
+		LastSeen:     c.clock(),
+		Connected:    true,
+	}
+}
+
+// Status returns the status of all nodes that sent a heartbeat.
+func (c *Collector) Status() Status {
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+
+	now := c.clock()
+	var status Status
+	for _, n := range c.nodes {
+		node := *n
+		node.Alive = now.Sub(node.LastSeen) <= c.timeout
+		if node.Alive {
+			status.Alive++
+		}
+		status.Nodes = append(status.Nodes, node)
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool {
+		return status.Nodes[i].PeerID < status.Nodes[j].PeerID
+	})
+
+	networks := make(map[string]*Network)
+	minHeights := make(map[string]uint64)
+	for _, node := range status.Nodes {
+		if !node.Alive {
+			continue
+		}
+		nw, ok := networks[node.GenesisCID]
+		if !ok {
+			nw = &Network{GenesisCID: node.GenesisCID}
+			networks[node.GenesisCID] = nw
+			minHeights[node.GenesisCID] = node.Height
+		}
+		if node.Height > nw.MaxHeight {
+			nw.MaxHeight = node.Height
+		}
+		if node.Height < minHeights[node.GenesisCID] {
+			minHeights[node.GenesisCID] = node.Height
+		}
+		nw.Heads = addToHead(nw.Heads, node)
+	}
+
+	for i, node := range status.Nodes {
+		if nw, ok := networks[node.GenesisCID]; ok && node.Alive {
+			status.Nodes[i].Behind = nw.MaxHeight - node.Height
+		}
+	}
+
+	for genesis, nw := range networks {
+		nw.MaxDivergence = nw.MaxHeight - minHeights[genesis]
+		sort.Slice(nw.Heads, func(i, j int) bool {
+			if nw.Heads[i].Height != nw.Heads[j].Height {
+				return nw.Heads[i].Height > nw.Heads[j].Height
+			}
+			return nw.Heads[i].Head < nw.Heads[j].Head
+		})
+		for i := 1; i < len(nw.Heads); i++ {
+			if nw.Heads[i].Height == nw.Heads[i-1].Height {
+				nw.Forked = true
+			}
+		}
+		status.Networks = append(status.Networks, *nw)
+	}
+	sort.Slice(status.Networks, func(i, j int) bool {
+		return status.Networks[i].GenesisCID < status.Networks[j].GenesisCID
+	})
+
+	return status
+}
+
+func addToHead(heads []Head, node NodeStatus) []Head {
+	for i := range heads {
+		if heads[i].Head == node.Head {
+			heads[i].Nodes = append(heads[i].Nodes, node.PeerID)
+			return heads
+		}
+	}
+	return append(heads, Head{Head: node.Head, Height: node.Height, Nodes: []string{node.PeerID}})
+}
@@ -0,0 +1,158 @@
+package collector_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/metrics"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/tools/heartbeat-collector/collector"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func newHost(t *testing.T) host.Host {
+	priv, _, err := crypto.GenerateKeyPairWithReader(crypto.RSA, 2048, rand.Reader)
+	require.NoError(t, err)
+
+	h, err := libp2p.New(context.Background(),
+		libp2p.DisableRelay(),
+		libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		libp2p.Identity(priv),
+	)
+	require.NoError(t, err)
+	return h
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestCollectorReceivesHeartbeats(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	collectorHost := newHost(t)
+	nodeHost := newHost(t)
+	defer collectorHost.Close() // nolint: errcheck
+	defer nodeHost.Close()      // nolint: errcheck
+
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := collector.NewCollector(collectorHost, time.Minute, clock.Now)
+
+	require.NoError(t, nodeHost.Connect(ctx, peer.AddrInfo{ID: collectorHost.ID(), Addrs: collectorHost.Addrs()}))
+	s, err := nodeHost.NewStream(ctx, collectorHost.ID(), metrics.HeartbeatProtocol)
+	require.NoError(t, err)
+
+	genesis := types.NewCidForTestGetter()()
+	enc := json.NewEncoder(s)
+	require.NoError(t, enc.Encode(metrics.Heartbeat{Head: "{ a }", Height: 10, Nickname: "node", GenesisCID: genesis}))
+	require.NoError(t, enc.Encode(metrics.Heartbeat{Head: "{ b }", Height: 11, Nickname: "node", GenesisCID: genesis}))
+
+	var status collector.Status
+	for i := 0; i < 100; i++ {
+		status = c.Status()
+		if len(status.Nodes) == 1 && status.Nodes[0].Height == 11 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.Len(t, status.Nodes, 1)
+	node := status.Nodes[0]
+	assert.Equal(t, nodeHost.ID().Pretty(), node.PeerID)
+	assert.Equal(t, "node", node.Nickname)
+	assert.Equal(t, "{ b }", node.Head)
+	assert.Equal(t, uint64(11), node.Height)
+	assert.Equal(t, genesis.String(), node.GenesisCID)
+	assert.True(t, node.Connected)
+	assert.True(t, node.Alive)
+
+	require.NoError(t, s.Close())
+	for i := 0; i < 100 && c.Status().Nodes[0].Connected; i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.False(t, c.Status().Nodes[0].Connected)
+}
+
+func TestCollectorStatus(t *testing.T) {
+	tf.UnitTest(t)
+
+	h := newHost(t)
+	defer h.Close() // nolint: errcheck
+
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := collector.NewCollector(h, time.Minute, clock.Now)
+
+	genesis := types.NewCidForTestGetter()()
+	peers := []peer.ID{"peer-a", "peer-b", "peer-c", "peer-d"}
+
+	c.Record(peers[0], metrics.Heartbeat{Head: "{ a }", Height: 20, GenesisCID: genesis})
+	c.Record(peers[1], metrics.Heartbeat{Head: "{ b }", Height: 20, GenesisCID: genesis})
+	c.Record(peers[2], metrics.Heartbeat{Head: "{ c }", Height: 17, GenesisCID: genesis})
+
+	t.Run("reports divergence and forks", func(t *testing.T) {
+		status := c.Status()
+		assert.Equal(t, 3, status.Alive)
+		require.Len(t, status.Networks, 1)
+
+		nw := status.Networks[0]
+		assert.Equal(t, uint64(20), nw.MaxHeight)
+		assert.Equal(t, uint64(3), nw.MaxDivergence)
+		assert.True(t, nw.Forked)
+		require.Len(t, nw.Heads, 3)
+		assert.Equal(t, "{ c }", nw.Heads[2].Head)
+
+		behind := map[string]uint64{}
+		for _, n := range status.Nodes {
+			behind[n.PeerID] = n.Behind
+		}
+		assert.Equal(t, uint64(0), behind[peers[0].Pretty()])
+		assert.Equal(t, uint64(3), behind[peers[2].Pretty()])
+	})
+
+	t.Run("ignores nodes that are not alive", func(t *testing.T) {
+		clock.now = clock.now.Add(2 * time.Minute)
+		c.Record(peers[3], metrics.Heartbeat{Head: "{ a }", Height: 21, GenesisCID: genesis})
+
+		status := c.Status()
+		assert.Len(t, status.Nodes, 4)
+		assert.Equal(t, 1, status.Alive)
+		require.Len(t, status.Networks, 1)
+		assert.Equal(t, uint64(21), status.Networks[0].MaxHeight)
+		assert.Equal(t, uint64(0), status.Networks[0].MaxDivergence)
+		assert.False(t, status.Networks[0].Forked)
+	})
+
+	t.Run("serves the status", func(t *testing.T) {
+		server := httptest.NewServer(c.Handler())
+		defer server.Close()
+
+		res, err := http.Get(server.URL + "/api/status")
+		require.NoError(t, err)
+		defer res.Body.Close() // nolint: errcheck
+
+		var status collector.Status
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+		assert.Len(t, status.Nodes, 4)
+
+		page, err := http.Get(server.URL + "/")
+		require.NoError(t, err)
+		defer page.Body.Close() // nolint: errcheck
+		assert.Equal(t, http.StatusOK, page.StatusCode)
+	})
+}
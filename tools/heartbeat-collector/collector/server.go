@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+)
+
+// Handler returns an http.Handler serving the status of the collector as
+// JSON under /api and as an HTML page at /.
+func (c *Collector) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, c.Status())
+	})
+	mux.HandleFunc("/api/nodes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, c.Status().Nodes)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := statusPage.Execute(w, c.Status()); err != nil {
+			log.Errorf("failed to render status page: %s", err)
+		}
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("failed to write response: %s", err)
+	}
+}
+
+var statusPage = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta http-equiv="refresh" content="10">
+  <title>Filecoin Heartbeats</title>
+  <style>
+    body { font-family: sans-serif; }
+    table { border-collapse: collapse; margin-bottom: 2em; }
+    td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+    .dead { color: #999; }
+    .forked { color: #c00; }
+  </style>
+</head>
+<body>
+  <h1>Filecoin Heartbeats</h1>
+  <p>{{.Alive}} of {{len .Nodes}} nodes alive</p>
+  {{range .Networks}}
+  <h2>Network {{.GenesisCID}}</h2>
+  <p>Height {{.MaxHeight}}, divergence {{.MaxDivergence}}{{if .Forked}}, <span class="forked">forked</span>{{end}}</p>
+  <table>
+    <tr><th>Height</th><th>Head</th><th>Nodes</th></tr>
+    {{range .Heads}}
+    <tr><td>{{.Height}}</td><td>{{.Head}}</td><td>{{len .Nodes}}</td></tr>
+    {{end}}
+  </table>
+  {{end}}
+  <h2>Nodes</h2>
+  <table>
+    <tr><th>Nickname</th><th>Peer</th><th>Miner</th><th>Height</th><th>Behind</th><th>Head</th><th>Last Seen</th></tr>
+    {{range .Nodes}}
+    <tr{{if not .Alive}} class="dead"{{end}}>
+      <td>{{.Nickname}}</td><td>{{.PeerID}}</td><td>{{.MinerAddress}}</td><td>{{.Height}}</td><td>{{.Behind}}</td><td>{{.Head}}</td><td>{{.LastSeen.Format "2006-01-02 15:04:05"}}</td>
+    </tr>
+    {{end}}
+  </table>
+</body>
+</html>
+`))
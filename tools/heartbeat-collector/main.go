@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/filecoin-project/go-filecoin/tools/heartbeat-collector/collector"
+)
+
+var log = logging.Logger("heartbeat-collector")
+
+func init() {
+	// Info level
+	logging.SetAllLoggers(4)
+}
+
+func main() {
+	listen := flag.String("listen", "/ip4/0.0.0.0/tcp/9091", "set the libp2p address to receive heartbeats on")
+	port := flag.Int("port", 9092, "set the port of the status page and api")
+	keyFile := flag.String("key-file", "heartbeat-collector.key", "set the file holding the libp2p identity, created if it does not exist")
+	timeout := flag.Duration("timeout", time.Minute, "time after which a node without heartbeats is reported as not alive")
+	flag.Parse()
+
+	priv, err := loadOrCreateKey(*keyFile)
+	if err != nil {
+		log.Errorf("failed to load identity: %s", err)
+		os.Exit(1)
+	}
+
+	h, err := libp2p.New(context.Background(),
+		libp2p.DisableRelay(),
+		libp2p.ListenAddrStrings(*listen),
+		libp2p.Identity(priv),
+	)
+	if err != nil {
+		log.Errorf("failed to start libp2p host: %s", err)
+		os.Exit(1)
+	}
+
+	peerAddr, err := ma.NewMultiaddr(fmt.Sprintf("/p2p/%s", h.ID().Pretty()))
+	if err != nil {
+		log.Errorf("failed to build peer address: %s", err)
+		os.Exit(1)
+	}
+	for _, addr := range h.Addrs() {
+		log.Infof("receiving heartbeats on %s", addr.Encapsulate(peerAddr))
+	}
+
+	c := collector.NewCollector(h, *timeout, time.Now)
+
+	log.Infof("serving status on port %d", *port)
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", *port), c.Handler()); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+}
+
+// loadOrCreateKey reads the private key in path, generating it if the file
+// does not exist so the collector keeps its peer ID across restarts.
+func loadOrCreateKey(path string) (crypto.PrivKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		return crypto.UnmarshalPrivateKey(data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	priv, _, err := crypto.GenerateKeyPairWithReader(crypto.RSA, 2048, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	data, err = crypto.MarshalPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
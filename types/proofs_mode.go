@@ -12,4 +12,7 @@ const (
 	TestProofsMode
 	// LiveProofsMode changes sealing, sector packing, PoSt, etc. to be compatible with non-test environments
 	LiveProofsMode
+	// FakeProofsMode skips the proofs entirely, using test sector sizes. Nodes on a network in this mode
+	// must run with fake proofs, which accept any proof.
+	FakeProofsMode
 )
@@ -35,6 +35,8 @@ type Context struct {
 	blockHeight *types.BlockHeight
 	ancestors   []types.TipSet
 	actors      ExecutableActorLookup
+	verifier    verification.Verifier
+	tracer      *Tracer
 
 	deps *deps // Inject external dependencies so we can unit test robustly.
 }
@@ -52,6 +54,10 @@ type NewContextParams struct {
 	BlockHeight *types.BlockHeight
 	Ancestors   []types.TipSet
 	Actors      ExecutableActorLookup
+	// Verifier verifies proofs, the proofs library is used if it is nil.
+	Verifier verification.Verifier
+	// Tracer records the sends executed, nothing is recorded if it is nil.
+	Tracer *Tracer
 }
 
 // NewVMContext returns an initialized context.
@@ -66,6 +72,8 @@ func NewVMContext(params NewContextParams) *Context {
 		blockHeight: params.BlockHeight,
 		ancestors:   params.Ancestors,
 		actors:      params.Actors,
+		verifier:    params.Verifier,
+		tracer:      params.Tracer,
 		deps:        makeDeps(params.State),
 	}
 }
@@ -149,6 +157,8 @@ func (ctx *Context) Send(to address.Address, method string, value types.AttoFIL,
 		BlockHeight: ctx.blockHeight,
 		Ancestors:   ctx.ancestors,
 		Actors:      ctx.actors,
+		Verifier:    ctx.verifier,
+		Tracer:      ctx.tracer,
 	}
 	innerCtx := NewVMContext(innerParams)
 
@@ -227,6 +237,9 @@ func (ctx *Context) SampleChainRandomness(sampleHeight *types.BlockHeight) ([]by
 
 // Verifier returns an interface to the proof verification code
 func (ctx *Context) Verifier() verification.Verifier {
+	if ctx.verifier != nil {
+		return ctx.verifier
+	}
 	return &verification.RustVerifier{}
 }
 
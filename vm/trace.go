@@ -0,0 +1,89 @@
+package vm
+
+import (
+	cbor "github.com/ipfs/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func init() {
+	cbor.RegisterCborType(Trace{})
+}
+
+// Trace records a message send executed by the VM and, as Calls, the sends
+// made by the actor while handling it.
+type Trace struct {
+	From     address.Address `json:"from"`
+	To       address.Address `json:"to"`
+	Method   string          `json:"method"`
+	Value    types.AttoFIL   `json:"value"`
+	Params   []byte          `json:"params"`
+	Return   [][]byte        `json:"return"`
+	ExitCode uint8           `json:"exitCode"`
+	Error    string          `json:"error,omitempty"`
+	// GasUsed is the gas charged by this send, including the sends it made.
+	GasUsed types.GasUnits `json:"gasUsed"`
+	Calls   []*Trace       `json:"calls"`
+}
+
+// Tracer builds the trace of a message as the VM executes it. A nil Tracer
+// records nothing.
+type Tracer struct {
+	root  *Trace
+	stack []*Trace
+	gas   []types.GasUnits
+}
+
+// NewTracer returns a tracer with an empty trace.
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+// Trace returns the trace of the first send the tracer recorded, or nil if
+// the VM never ran.
+func (t *Tracer) Trace() *Trace {
+	return t.root
+}
+
+// enter records the start of a send.
+func (t *Tracer) enter(msg *types.Message, gasUsed types.GasUnits) {
+	trace := &Trace{
+		From:   msg.From,
+		To:     msg.To,
+		Method: msg.Method,
+		Value:  msg.Value,
+		Params: msg.Params,
+		Calls:  []*Trace{},
+	}
+	if len(t.stack) == 0 {
+		if t.root != nil {
+			// A tracer traces a single message.
+			return
+		}
+		t.root = trace
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Calls = append(parent.Calls, trace)
+	}
+	t.stack = append(t.stack, trace)
+	t.gas = append(t.gas, gasUsed)
+}
+
+// exit records the outcome of the send last entered.
+func (t *Tracer) exit(ret [][]byte, exitCode uint8, err error, gasUsed types.GasUnits) {
+	if len(t.stack) == 0 {
+		return
+	}
+	last := len(t.stack) - 1
+	trace := t.stack[last]
+	trace.Return = ret
+	trace.ExitCode = exitCode
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	trace.GasUsed = gasUsed - t.gas[last]
+
+	t.stack = t.stack[:last]
+	t.gas = t.gas[:last]
+}
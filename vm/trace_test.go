@@ -0,0 +1,52 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestTracer(t *testing.T) {
+	tf.UnitTest(t)
+
+	newMsg := types.NewMessageForTestGetter()
+	outer, inner, other := newMsg(), newMsg(), newMsg()
+
+	tracer := NewTracer()
+	assert.Nil(t, tracer.Trace())
+
+	tracer.enter(outer, types.NewGasUnits(10))
+	tracer.enter(inner, types.NewGasUnits(15))
+	tracer.exit([][]byte{[]byte("ok")}, 0, nil, types.NewGasUnits(20))
+	tracer.enter(other, types.NewGasUnits(20))
+	tracer.exit(nil, 2, errors.New("boom"), types.NewGasUnits(30))
+	tracer.exit(nil, 2, errors.New("outer boom"), types.NewGasUnits(35))
+
+	trace := tracer.Trace()
+	require.NotNil(t, trace)
+	assert.Equal(t, outer.To, trace.To)
+	assert.Equal(t, types.NewGasUnits(25), trace.GasUsed)
+	assert.Equal(t, "outer boom", trace.Error)
+	require.Len(t, trace.Calls, 2)
+
+	assert.Equal(t, inner.Method, trace.Calls[0].Method)
+	assert.Equal(t, [][]byte{[]byte("ok")}, trace.Calls[0].Return)
+	assert.Equal(t, types.NewGasUnits(5), trace.Calls[0].GasUsed)
+	assert.Empty(t, trace.Calls[0].Error)
+
+	assert.Equal(t, uint8(2), trace.Calls[1].ExitCode)
+	assert.Equal(t, "boom", trace.Calls[1].Error)
+	assert.Equal(t, types.NewGasUnits(10), trace.Calls[1].GasUsed)
+
+	t.Run("traces a single message", func(t *testing.T) {
+		tracer.enter(newMsg(), types.NewGasUnits(0))
+		tracer.exit(nil, 0, nil, types.NewGasUnits(1))
+		assert.Equal(t, trace, tracer.Trace())
+		assert.Equal(t, types.NewGasUnits(25), tracer.Trace().GasUsed)
+	})
+}
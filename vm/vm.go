@@ -18,7 +18,14 @@ func Send(ctx context.Context, vmCtx *Context) ([][]byte, uint8, error) {
 	deps := sendDeps{
 		transfer: Transfer,
 	}
-	return send(ctx, deps, vmCtx)
+	if vmCtx.tracer == nil {
+		return send(ctx, deps, vmCtx)
+	}
+
+	vmCtx.tracer.enter(vmCtx.message, vmCtx.GasUnits())
+	ret, code, err := send(ctx, deps, vmCtx)
+	vmCtx.tracer.exit(ret, code, err, vmCtx.GasUnits())
+	return ret, code, err
 }
 
 type sendDeps struct {